@@ -43,6 +43,22 @@ func (a *AuthenticationConfig) SetPassword(value string) *AuthenticationConfig {
 	return a
 }
 
+func (a *AuthenticationConfig) SetIssuer(value TokenIssuer) *AuthenticationConfig {
+	a.Issuer = value
+	return a
+}
+
+// BearerToken returns the static token when set, otherwise a token issued for Username by the issuer.
+func (a *AuthenticationConfig) BearerToken() (string, error) {
+	if utils.IsNotEmpty(a.Token) {
+		return a.Token, nil
+	}
+	if a.Issuer == nil {
+		return "", fmt.Errorf("Bearer token or token issuer is required")
+	}
+	return a.Issuer.Issue(a.Username, nil)
+}
+
 func (a *AuthenticationConfig) Json() string {
 	return utils.ToJson(a)
 }
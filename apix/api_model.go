@@ -12,6 +12,13 @@ type AuthenticationConfig struct {
 	Token     string `json:"-" yaml:"token"`
 	Username  string `json:"username,omitempty" yaml:"username"`
 	Password  string `json:"-" yaml:"password"`
+	// Issuer mints the bearer token, with Username as subject, when Type is "bearer" and Token is empty
+	Issuer TokenIssuer `json:"-" yaml:"-"`
+}
+
+// TokenIssuer mints tokens for a subject, e.g: the authz.JwtService, created once and shared by the requests.
+type TokenIssuer interface {
+	Issue(subject string, extras map[string]interface{}) (string, error)
 }
 
 type RetryConfig struct {
@@ -153,6 +153,13 @@ func (s *apiServiceImpl) execute(client *restify.Client, endpoint EndpointConfig
 		if strings.EqualFold("token", auth.Type) {
 			client.SetHeader("Authorization", auth.Token)
 		}
+		if strings.EqualFold("bearer", auth.Type) {
+			token, err := auth.BearerToken()
+			if err != nil {
+				return nil, err
+			}
+			client.SetAuthToken(token)
+		}
 	}
 	if retry.IsEnabled {
 		retryFunc := func(_response *restify.Response, err error) bool {
@@ -1 +1,711 @@
 package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/configx"
+	"github.com/sivaosorg/govm/match"
+	"github.com/sivaosorg/govm/utils"
+)
+
+func NewRole() *Role {
+	return &Role{}
+}
+
+func (r *Role) SetName(value string) *Role {
+	r.Name = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *Role) SetDescription(value string) *Role {
+	r.Description = value
+	return r
+}
+
+func (r *Role) SetPermissions(values []string) *Role {
+	r.Permissions = values
+	return r
+}
+
+func (r *Role) AppendPermissions(values ...string) *Role {
+	r.Permissions = append(r.Permissions, values...)
+	return r
+}
+
+// AppendPermission appends the permission of the action on the resource.
+func (r *Role) AppendPermission(resource, action string) *Role {
+	return r.AppendPermissions(Permission(resource, action))
+}
+
+func (r *Role) SetDenies(values []string) *Role {
+	r.Denies = values
+	return r
+}
+
+func (r *Role) AppendDenies(values ...string) *Role {
+	r.Denies = append(r.Denies, values...)
+	return r
+}
+
+func (r *Role) SetInherits(values []string) *Role {
+	r.Inherits = values
+	return r
+}
+
+func (r *Role) AppendInherits(values ...string) *Role {
+	r.Inherits = append(r.Inherits, values...)
+	return r
+}
+
+func (r *Role) Json() string {
+	return utils.ToJson(r)
+}
+
+func NewRoleBinding() *RoleBinding {
+	return &RoleBinding{}
+}
+
+func (r *RoleBinding) SetSubject(value string) *RoleBinding {
+	r.Subject = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *RoleBinding) SetRoles(values []string) *RoleBinding {
+	r.Roles = values
+	return r
+}
+
+func (r *RoleBinding) AppendRoles(values ...string) *RoleBinding {
+	r.Roles = append(r.Roles, values...)
+	return r
+}
+
+func (r *RoleBinding) Json() string {
+	return utils.ToJson(r)
+}
+
+// NewPolicyConfig creates an enabled policy denying the requests no route matches,
+// so that a policy denies what it does not permit unless told otherwise explicitly.
+func NewPolicyConfig() *PolicyConfig {
+	return &PolicyConfig{IsEnabled: true, IsDenyUnmatched: true}
+}
+
+// UnmarshalYAML decodes the policy, enabled and denying unmatched requests when the file omits "enabled" and "deny_unmatched".
+func (p *PolicyConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain PolicyConfig
+	v := plain(*NewPolicyConfig())
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*p = PolicyConfig(v)
+	return nil
+}
+
+// UnmarshalJSON decodes the policy, enabled and denying unmatched requests when the json omits "enabled" and "deny_unmatched".
+func (p *PolicyConfig) UnmarshalJSON(data []byte) error {
+	type plain PolicyConfig
+	v := plain(*NewPolicyConfig())
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = PolicyConfig(v)
+	return nil
+}
+
+func (p *PolicyConfig) SetEnabled(value bool) *PolicyConfig {
+	p.IsEnabled = value
+	return p
+}
+
+func (p *PolicyConfig) SetRoles(values []Role) *PolicyConfig {
+	p.Roles = values
+	return p
+}
+
+func (p *PolicyConfig) AppendRoles(values ...Role) *PolicyConfig {
+	p.Roles = append(p.Roles, values...)
+	return p
+}
+
+func (p *PolicyConfig) SetBindings(values []RoleBinding) *PolicyConfig {
+	p.Bindings = values
+	return p
+}
+
+func (p *PolicyConfig) AppendBindings(values ...RoleBinding) *PolicyConfig {
+	p.Bindings = append(p.Bindings, values...)
+	return p
+}
+
+func (p *PolicyConfig) SetDenyUnmatched(value bool) *PolicyConfig {
+	p.IsDenyUnmatched = value
+	return p
+}
+
+func (p *PolicyConfig) SetRoutes(values []RouteRule) *PolicyConfig {
+	p.Routes = values
+	return p
+}
+
+func (p *PolicyConfig) AppendRoutes(values ...RouteRule) *PolicyConfig {
+	p.Routes = append(p.Routes, values...)
+	return p
+}
+
+func (p *PolicyConfig) Json() string {
+	return utils.ToJson(p)
+}
+
+// PolicyConfigValidator checks that every role has a name, and that inherited and bound roles exist
+// without inheritance cycles.
+func PolicyConfigValidator(p PolicyConfig) error {
+	roles := make(map[string]Role, len(p.Roles))
+	for _, r := range p.Roles {
+		if utils.IsEmpty(r.Name) {
+			return fmt.Errorf("Role name is required")
+		}
+		if _, ok := roles[r.Name]; ok {
+			return fmt.Errorf("Role %s is duplicated", r.Name)
+		}
+		roles[r.Name] = r
+	}
+	for _, r := range p.Roles {
+		for _, parent := range r.Inherits {
+			if _, ok := roles[parent]; !ok {
+				return fmt.Errorf("Role %s inherits unknown role %s", r.Name, parent)
+			}
+		}
+		if cycle := findCycle(roles, r.Name, nil); len(cycle) > 0 {
+			return fmt.Errorf("Role inheritance cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	for _, r := range p.Routes {
+		if utils.IsEmpty(r.Pattern) || utils.IsEmpty(r.Resource) {
+			return fmt.Errorf("Route pattern and resource are required")
+		}
+	}
+	for _, b := range p.Bindings {
+		if utils.IsEmpty(b.Subject) {
+			return fmt.Errorf("Binding subject is required")
+		}
+		for _, name := range b.Roles {
+			if _, ok := roles[name]; !ok {
+				return fmt.Errorf("Subject %s is bound to unknown role %s", b.Subject, name)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadPolicyConfig reads a YAML or JSON policy file through configx.ReadConfig and validates it.
+func LoadPolicyConfig(path string) (*PolicyConfig, error) {
+	p, err := configx.ReadConfig[PolicyConfig](path)
+	if err != nil {
+		return nil, err
+	}
+	if err := PolicyConfigValidator(*p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// WritePolicyConfig writes the policy as YAML through configx.CreateConfigPerm, readable by others but writable by the owner only.
+func WritePolicyConfig(path string, p *PolicyConfig) error {
+	return configx.CreateConfigPerm[PolicyConfig](path, p, PolicyFileMode)
+}
+
+func NewRouteRule() *RouteRule {
+	return &RouteRule{}
+}
+
+func (r *RouteRule) SetMethod(value string) *RouteRule {
+	r.Method = strings.ToUpper(utils.TrimSpaces(value))
+	return r
+}
+
+func (r *RouteRule) SetPattern(value string) *RouteRule {
+	r.Pattern = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *RouteRule) SetResource(value string) *RouteRule {
+	r.Resource = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *RouteRule) SetAction(value string) *RouteRule {
+	r.Action = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *RouteRule) SetPublic(value bool) *RouteRule {
+	r.IsPublic = value
+	return r
+}
+
+func (r *RouteRule) Json() string {
+	return utils.ToJson(r)
+}
+
+// Matches checks whether the method and path of a request match the rule.
+func (r *RouteRule) Matches(method, path string) bool {
+	if utils.IsNotEmpty(r.Method) && r.Method != Wildcard && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	return match.Match(path, r.Pattern)
+}
+
+// ActionOf returns the action of the rule, derived from the method when not set.
+func (r *RouteRule) ActionOf(method string) string {
+	if utils.IsNotEmpty(r.Action) {
+		return r.Action
+	}
+	if action, ok := MethodActions[strings.ToUpper(method)]; ok {
+		return action
+	}
+	return strings.ToLower(method)
+}
+
+func GetPolicyConfigSample() *PolicyConfig {
+	viewer := NewRole().
+		SetName("viewer").
+		SetDescription("Read any resource").
+		AppendPermissions("*:read", "*:list")
+	editor := NewRole().
+		SetName("editor").
+		SetDescription("Manage articles").
+		AppendPermissions("articles:*").
+		AppendInherits("viewer")
+	admin := NewRole().
+		SetName("admin").
+		SetDescription("Manage everything except audit logs").
+		AppendPermissions("*").
+		AppendDenies("audits:delete").
+		AppendInherits("editor")
+	p := NewPolicyConfig().
+		SetEnabled(true).
+		SetDenyUnmatched(false).
+		AppendRoles(*viewer, *editor, *admin).
+		AppendRoutes(*NewRouteRule().SetMethod("GET").SetPattern("/health").SetResource("health").SetPublic(true),
+			*NewRouteRule().SetMethod("POST").SetPattern("/api/v1/articles/*/publish").SetResource("articles").SetAction("publish"),
+			*NewRouteRule().SetPattern("/api/v1/articles*").SetResource("articles")).
+		AppendBindings(*NewRoleBinding().SetSubject("alice").AppendRoles("admin"),
+			*NewRoleBinding().SetSubject("bob").AppendRoles("editor"))
+	return p
+}
+
+// Permission returns the permission of the action on the resource, e.g: "orders:read".
+func Permission(resource, action string) string {
+	return resource + PermissionSeparator + action
+}
+
+// findCycle returns the inheritance chain that leads back to a role already visited, if any.
+func findCycle(roles map[string]Role, name string, path []string) []string {
+	for i, v := range path {
+		if v == name {
+			return append(path[i:], name)
+		}
+	}
+	path = append(path, name)
+	for _, parent := range roles[name].Inherits {
+		if cycle := findCycle(roles, parent, path); len(cycle) > 0 {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// NewJwtConfig creates a config requiring the expiration of tokens, so that no token is valid forever.
+func NewJwtConfig() *JwtConfig {
+	j := &JwtConfig{IsRequireExpiration: true}
+	return j
+}
+
+// UnmarshalYAML decodes the config, requiring the expiration of tokens when the file omits "require_expiration".
+func (j *JwtConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain JwtConfig
+	v := plain(*NewJwtConfig())
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*j = JwtConfig(v)
+	return nil
+}
+
+// UnmarshalJSON decodes the config, requiring the expiration of tokens when the json omits "require_expiration".
+func (j *JwtConfig) UnmarshalJSON(data []byte) error {
+	type plain JwtConfig
+	v := plain(*NewJwtConfig())
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*j = JwtConfig(v)
+	return nil
+}
+
+func (j *JwtConfig) SetEnabled(value bool) *JwtConfig {
+	j.IsEnabled = value
+	return j
+}
+
+func (j *JwtConfig) SetAlgorithm(value string) *JwtConfig {
+	j.Algorithm = strings.ToUpper(utils.TrimSpaces(value))
+	return j
+}
+
+func (j *JwtConfig) SetSecret(value string) *JwtConfig {
+	j.Secret = value
+	return j
+}
+
+func (j *JwtConfig) SetPrivateKeyFile(value string) *JwtConfig {
+	j.PrivateKeyFile = utils.TrimSpaces(value)
+	return j
+}
+
+func (j *JwtConfig) SetPublicKeyFile(value string) *JwtConfig {
+	j.PublicKeyFile = utils.TrimSpaces(value)
+	return j
+}
+
+func (j *JwtConfig) SetKeySetFile(value string) *JwtConfig {
+	j.KeySetFile = utils.TrimSpaces(value)
+	return j
+}
+
+func (j *JwtConfig) SetKid(value string) *JwtConfig {
+	j.Kid = utils.TrimSpaces(value)
+	return j
+}
+
+func (j *JwtConfig) SetIssuer(value string) *JwtConfig {
+	j.Issuer = utils.TrimSpaces(value)
+	return j
+}
+
+func (j *JwtConfig) SetAudience(values []string) *JwtConfig {
+	j.Audience = values
+	return j
+}
+
+func (j *JwtConfig) AppendAudience(values ...string) *JwtConfig {
+	j.Audience = append(j.Audience, values...)
+	return j
+}
+
+func (j *JwtConfig) SetExpiration(value time.Duration) *JwtConfig {
+	j.Expiration = value
+	return j
+}
+
+func (j *JwtConfig) SetLeeway(value time.Duration) *JwtConfig {
+	j.Leeway = value
+	return j
+}
+
+func (j *JwtConfig) SetRequireExpiration(value bool) *JwtConfig {
+	j.IsRequireExpiration = value
+	return j
+}
+
+func (j *JwtConfig) Json() string {
+	return utils.ToJson(j)
+}
+
+// JwtConfigValidator fills the default algorithm and expiration, and checks that the algorithm
+// is supported and that some key material is configured.
+func JwtConfigValidator(j *JwtConfig) error {
+	if utils.IsEmpty(j.Algorithm) {
+		j.SetAlgorithm(AlgorithmHS256)
+	}
+	if !Algorithms[j.Algorithm] {
+		return fmt.Errorf("Algorithm %s is not supported", j.Algorithm)
+	}
+	if j.Expiration <= 0 {
+		j.SetExpiration(DefaultJwtExpiration)
+	}
+	if j.Leeway < 0 {
+		j.SetLeeway(0)
+	}
+	if utils.IsNotEmpty(j.KeySetFile) {
+		return nil
+	}
+	if j.Algorithm == AlgorithmHS256 {
+		if utils.IsEmpty(j.Secret) {
+			return fmt.Errorf("Secret is required for algorithm %s", j.Algorithm)
+		}
+		return nil
+	}
+	if utils.IsEmpty(j.PrivateKeyFile) && utils.IsEmpty(j.PublicKeyFile) {
+		return fmt.Errorf("Private or public key file is required for algorithm %s", j.Algorithm)
+	}
+	return nil
+}
+
+func GetJwtConfigSample() *JwtConfig {
+	j := NewJwtConfig().
+		SetEnabled(true).
+		SetAlgorithm(AlgorithmHS256).
+		SetSecret("5f0b6a1e2c9d4e7f8a3b0c1d2e3f4a5b").
+		SetIssuer("govm").
+		AppendAudience("api").
+		SetExpiration(15 * time.Minute).
+		SetLeeway(30 * time.Second)
+	return j
+}
+
+func NewAbacPolicy() *AbacPolicy {
+	return &AbacPolicy{}
+}
+
+func (a *AbacPolicy) SetName(value string) *AbacPolicy {
+	a.Name = utils.TrimSpaces(value)
+	return a
+}
+
+func (a *AbacPolicy) SetDescription(value string) *AbacPolicy {
+	a.Description = value
+	return a
+}
+
+func (a *AbacPolicy) SetEffect(value string) *AbacPolicy {
+	a.Effect = strings.ToLower(utils.TrimSpaces(value))
+	return a
+}
+
+func (a *AbacPolicy) SetResources(values []string) *AbacPolicy {
+	a.Resources = values
+	return a
+}
+
+func (a *AbacPolicy) AppendResources(values ...string) *AbacPolicy {
+	a.Resources = append(a.Resources, values...)
+	return a
+}
+
+func (a *AbacPolicy) SetActions(values []string) *AbacPolicy {
+	a.Actions = values
+	return a
+}
+
+func (a *AbacPolicy) AppendActions(values ...string) *AbacPolicy {
+	a.Actions = append(a.Actions, values...)
+	return a
+}
+
+func (a *AbacPolicy) SetCondition(value string) *AbacPolicy {
+	a.Condition = value
+	return a
+}
+
+func (a *AbacPolicy) Json() string {
+	return utils.ToJson(a)
+}
+
+// Targets checks whether the policy targets the action on the resource.
+// Empty resources or actions target any.
+func (a *AbacPolicy) Targets(resource, action string) bool {
+	return matchAny(a.Resources, resource) && matchAny(a.Actions, action)
+}
+
+// NewAbacConfig creates an enabled config, so that attributes are evaluated unless disabled explicitly.
+func NewAbacConfig() *AbacConfig {
+	return &AbacConfig{IsEnabled: true}
+}
+
+// UnmarshalYAML decodes the config, enabled when the file omits "enabled".
+func (a *AbacConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AbacConfig
+	v := plain(*NewAbacConfig())
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*a = AbacConfig(v)
+	return nil
+}
+
+// UnmarshalJSON decodes the config, enabled when the json omits "enabled".
+func (a *AbacConfig) UnmarshalJSON(data []byte) error {
+	type plain AbacConfig
+	v := plain(*NewAbacConfig())
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = AbacConfig(v)
+	return nil
+}
+
+func (a *AbacConfig) SetEnabled(value bool) *AbacConfig {
+	a.IsEnabled = value
+	return a
+}
+
+func (a *AbacConfig) SetAlgorithm(value string) *AbacConfig {
+	a.Algorithm = strings.ToLower(utils.TrimSpaces(value))
+	return a
+}
+
+func (a *AbacConfig) SetPolicies(values []AbacPolicy) *AbacConfig {
+	a.Policies = values
+	return a
+}
+
+func (a *AbacConfig) AppendPolicies(values ...AbacPolicy) *AbacConfig {
+	a.Policies = append(a.Policies, values...)
+	return a
+}
+
+func (a *AbacConfig) Json() string {
+	return utils.ToJson(a)
+}
+
+// AbacConfigValidator fills the default combining algorithm, deny-overrides, and checks that
+// every policy is named, has a known effect and a condition that compiles.
+func AbacConfigValidator(a *AbacConfig) error {
+	if utils.IsEmpty(a.Algorithm) {
+		a.SetAlgorithm(CombiningDenyOverrides)
+	}
+	if !CombiningAlgorithms[a.Algorithm] {
+		return fmt.Errorf("Combining algorithm %s is not supported", a.Algorithm)
+	}
+	names := make(map[string]bool, len(a.Policies))
+	for _, p := range a.Policies {
+		if utils.IsEmpty(p.Name) {
+			return fmt.Errorf("Policy name is required")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("Policy %s is duplicated", p.Name)
+		}
+		names[p.Name] = true
+		if p.Effect != EffectPermit && p.Effect != EffectDeny {
+			return fmt.Errorf("Policy %s has invalid effect '%s'", p.Name, p.Effect)
+		}
+		if utils.IsNotEmpty(p.Condition) {
+			if _, err := CompileExpression(p.Condition); err != nil {
+				return fmt.Errorf("Policy %s: %v", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadAbacConfig reads a YAML or JSON file of attribute-based policies through configx.ReadConfig and validates it.
+func LoadAbacConfig(path string) (*AbacConfig, error) {
+	a, err := configx.ReadConfig[AbacConfig](path)
+	if err != nil {
+		return nil, err
+	}
+	if err := AbacConfigValidator(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func GetAbacConfigSample() *AbacConfig {
+	tenant := NewAbacPolicy().
+		SetName("same-tenant-business-hours").
+		SetDescription("Members edit documents of their tenant during business hours").
+		SetEffect(EffectPermit).
+		AppendResources("documents").
+		AppendActions("read", "update").
+		SetCondition("subject.tenant == resource.tenant and between('09:00', '18:00') and not isWeekend()")
+	owner := NewAbacPolicy().
+		SetName("owner").
+		SetDescription("Owners read their documents at any time").
+		SetEffect(EffectPermit).
+		AppendResources("documents").
+		AppendActions("read").
+		SetCondition("resource.owner == subject.id")
+	locked := NewAbacPolicy().
+		SetName("locked").
+		SetDescription("Locked documents are read-only").
+		SetEffect(EffectDeny).
+		AppendResources("documents").
+		AppendActions("update", "delete").
+		SetCondition("resource.locked == true")
+	a := NewAbacConfig().
+		SetEnabled(true).
+		SetAlgorithm(CombiningDenyOverrides).
+		AppendPolicies(*tenant, *owner, *locked)
+	return a
+}
+
+func NewAbacAttributes() *AbacAttributes {
+	return &AbacAttributes{
+		Subject:     make(map[string]interface{}),
+		Resource:    make(map[string]interface{}),
+		Request:     make(map[string]interface{}),
+		Environment: make(map[string]interface{}),
+	}
+}
+
+func (a *AbacAttributes) SetSubject(value map[string]interface{}) *AbacAttributes {
+	a.Subject = value
+	return a
+}
+
+func (a *AbacAttributes) AppendSubject(key string, value interface{}) *AbacAttributes {
+	if a.Subject == nil {
+		a.Subject = make(map[string]interface{})
+	}
+	a.Subject[key] = value
+	return a
+}
+
+func (a *AbacAttributes) SetResource(value map[string]interface{}) *AbacAttributes {
+	a.Resource = value
+	return a
+}
+
+func (a *AbacAttributes) AppendResource(key string, value interface{}) *AbacAttributes {
+	if a.Resource == nil {
+		a.Resource = make(map[string]interface{})
+	}
+	a.Resource[key] = value
+	return a
+}
+
+func (a *AbacAttributes) SetRequest(value map[string]interface{}) *AbacAttributes {
+	a.Request = value
+	return a
+}
+
+func (a *AbacAttributes) AppendRequest(key string, value interface{}) *AbacAttributes {
+	if a.Request == nil {
+		a.Request = make(map[string]interface{})
+	}
+	a.Request[key] = value
+	return a
+}
+
+func (a *AbacAttributes) SetEnvironment(value map[string]interface{}) *AbacAttributes {
+	a.Environment = value
+	return a
+}
+
+func (a *AbacAttributes) AppendEnvironment(key string, value interface{}) *AbacAttributes {
+	if a.Environment == nil {
+		a.Environment = make(map[string]interface{})
+	}
+	a.Environment[key] = value
+	return a
+}
+
+func (a *AbacAttributes) Json() string {
+	return utils.ToJson(a)
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == Wildcard || match.Match(value, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package authz
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/charge"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type AbacService interface {
+	Evaluate(resource, action string, attributes string) Decision
+	EvaluateAttributes(resource, action string, attributes AbacAttributes) Decision
+	AddPolicy(policy AbacPolicy) error
+	RemovePolicy(name string)
+	Policies() []AbacPolicy
+	Config() AbacConfig
+}
+
+// NewAbacService creates the ABAC engine of the config, compiling the condition of every policy once.
+func NewAbacService(conf AbacConfig) (AbacService, error) {
+	if err := AbacConfigValidator(&conf); err != nil {
+		return nil, err
+	}
+	s := &abacServiceImpl{conf: conf}
+	for _, p := range conf.Policies {
+		compiled, err := compileAbacPolicy(p)
+		if err != nil {
+			return nil, err
+		}
+		s.policies = append(s.policies, compiled)
+	}
+	return s, nil
+}
+
+// AbacAttributesFromRequest creates the attributes of the subject from the request:
+// method, path, host, client ip and headers under "request", and the current time under "environment".
+func AbacAttributesFromRequest(request *http.Request, subject map[string]interface{}) *AbacAttributes {
+	headers := make(map[string]interface{}, len(request.Header))
+	for k := range request.Header {
+		headers[strings.ToLower(k)] = request.Header.Get(k)
+	}
+	a := NewAbacAttributes().
+		SetSubject(subject).
+		AppendRequest("method", request.Method).
+		AppendRequest("path", charge.GetRequestPath(request)).
+		AppendRequest("host", request.Host).
+		AppendRequest("ip", charge.GetClientIP(request)).
+		AppendRequest("headers", headers).
+		AppendEnvironment("time", time.Now())
+	return a
+}
+
+// Evaluate decides on the action on the resource, combining the effects of the policies
+// that target them and whose condition holds over the attributes document.
+// Under deny-overrides, a deny policy whose condition fails to evaluate denies.
+func (s *abacServiceImpl) Evaluate(resource, action string, attributes string) Decision {
+	d := Decision{Resource: resource, Action: action}
+	if !s.conf.IsEnabled {
+		d.IsAllowed = true
+		d.Reason = ReasonDisabled
+		return d
+	}
+	if utils.IsEmpty(resource) || utils.IsEmpty(action) {
+		d.Reason = ReasonInvalidResource
+		return d
+	}
+	ctx := NewExpressionContext(attributes)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var permit, deny *Decision
+	for _, p := range s.policies {
+		if !p.Targets(resource, action) {
+			continue
+		}
+		holds, err := true, error(nil)
+		if p.condition != nil {
+			holds, err = p.condition.EvaluateContext(ctx)
+		}
+		if err != nil {
+			if p.Effect == EffectDeny && s.conf.Algorithm == CombiningDenyOverrides {
+				r := d
+				r.Policy = p.Name
+				r.Reason = fmt.Sprintf("denied by policy '%s' failing to evaluate: %v", p.Name, err)
+				return r
+			}
+			continue
+		}
+		if !holds {
+			continue
+		}
+		r := d
+		r.Policy = p.Name
+		r.IsAllowed = p.Effect == EffectPermit
+		if r.IsAllowed {
+			r.Reason = fmt.Sprintf("permitted by policy '%s'", p.Name)
+		} else {
+			r.Reason = fmt.Sprintf("denied by policy '%s'", p.Name)
+		}
+		switch s.conf.Algorithm {
+		case CombiningFirstApplicable:
+			return r
+		case CombiningDenyOverrides:
+			if !r.IsAllowed {
+				return r
+			}
+		case CombiningPermitOverrides:
+			if r.IsAllowed {
+				return r
+			}
+		}
+		if r.IsAllowed && permit == nil {
+			permit = &r
+		}
+		if !r.IsAllowed && deny == nil {
+			deny = &r
+		}
+	}
+	if permit != nil {
+		return *permit
+	}
+	if deny != nil {
+		return *deny
+	}
+	d.Reason = ReasonNotApplicable
+	return d
+}
+
+// EvaluateAttributes decides on the action on the resource for the attributes.
+func (s *abacServiceImpl) EvaluateAttributes(resource, action string, attributes AbacAttributes) Decision {
+	return s.Evaluate(resource, action, attributes.Json())
+}
+
+// AddPolicy adds or replaces a policy of the same name at runtime, appended last when new.
+func (s *abacServiceImpl) AddPolicy(policy AbacPolicy) error {
+	conf := *NewAbacConfig().SetAlgorithm(s.conf.Algorithm).AppendPolicies(policy)
+	if err := AbacConfigValidator(&conf); err != nil {
+		return err
+	}
+	compiled, err := compileAbacPolicy(policy)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, p := range s.policies {
+		if p.Name == policy.Name {
+			s.policies[i] = compiled
+			return nil
+		}
+	}
+	s.policies = append(s.policies, compiled)
+	return nil
+}
+
+// RemovePolicy removes the policy of the name.
+func (s *abacServiceImpl) RemovePolicy(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var kept []abacPolicy
+	for _, p := range s.policies {
+		if p.Name != name {
+			kept = append(kept, p)
+		}
+	}
+	s.policies = kept
+}
+
+// Policies returns the current policies, in evaluation order.
+func (s *abacServiceImpl) Policies() []AbacPolicy {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	policies := make([]AbacPolicy, 0, len(s.policies))
+	for _, p := range s.policies {
+		policies = append(policies, p.AbacPolicy)
+	}
+	return policies
+}
+
+// Config returns the config, including runtime changes of the policies.
+func (s *abacServiceImpl) Config() AbacConfig {
+	return *NewAbacConfig().
+		SetEnabled(s.conf.IsEnabled).
+		SetAlgorithm(s.conf.Algorithm).
+		SetPolicies(s.Policies())
+}
+
+func compileAbacPolicy(p AbacPolicy) (abacPolicy, error) {
+	compiled := abacPolicy{AbacPolicy: p}
+	if utils.IsEmpty(p.Condition) {
+		return compiled, nil
+	}
+	e, err := CompileExpression(p.Condition)
+	if err != nil {
+		return compiled, fmt.Errorf("Policy %s: %v", p.Name, err)
+	}
+	compiled.condition = e
+	return compiled, nil
+}
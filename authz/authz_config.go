@@ -1 +1,141 @@
 package authz
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// PermissionSeparator separates the resource from the action within a permission.
+	PermissionSeparator = ":"
+	// Wildcard matches any resource or action.
+	Wildcard = "*"
+	// PolicyFileMode is the permissions of the policy files created by WritePolicyConfig.
+	PolicyFileMode = 0644
+)
+
+const (
+	ReasonDisabled         = "authorization disabled"
+	ReasonNoRole           = "subject has no role"
+	ReasonNoPermission     = "no permission matches"
+	ReasonAllowed          = "allowed by permission"
+	ReasonDenied           = "denied by permission"
+	ReasonInvalidResource  = "resource and action are required"
+	ReasonUnmatchedAllowed = "no route matches, allowed as deny_unmatched is off"
+	ReasonUnmatchedDenied  = "no route matches, denied by default"
+)
+
+const (
+	ActionRead   = "read"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+var (
+	// MethodActions maps HTTP methods to the default action of a route rule.
+	MethodActions map[string]string = map[string]string{
+		"GET":     ActionRead,
+		"HEAD":    ActionRead,
+		"OPTIONS": ActionRead,
+		"POST":    ActionCreate,
+		"PUT":     ActionUpdate,
+		"PATCH":   ActionUpdate,
+		"DELETE":  ActionDelete,
+	}
+)
+
+const (
+	// ContextKeySubject is the context key of the subject resolved by the authz middleware.
+	ContextKeySubject contextKey = "govm_authz_subject"
+	// ContextKeyDecision is the context key of the decision made by the authz middleware.
+	ContextKeyDecision contextKey = "govm_authz_decision"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+var (
+	Algorithms map[string]bool = map[string]bool{
+		AlgorithmHS256: true,
+		AlgorithmRS256: true,
+		AlgorithmES256: true,
+	}
+)
+
+const (
+	ClaimIssuer    = "iss"
+	ClaimSubject   = "sub"
+	ClaimAudience  = "aud"
+	ClaimExpiresAt = "exp"
+	ClaimNotBefore = "nbf"
+	ClaimIssuedAt  = "iat"
+	ClaimId        = "jti"
+	ClaimRoles     = "roles"
+)
+
+const (
+	// DefaultJwtExpiration is the lifetime of issued tokens when the config does not set one.
+	DefaultJwtExpiration = time.Hour
+	// JwtKeySetReloadInterval throttles reloading of the key set file on unknown kids.
+	JwtKeySetReloadInterval = 30 * time.Second
+)
+
+var (
+	ErrorJwtMalformed        = errors.New("Token is malformed")
+	ErrorJwtAlgorithm        = errors.New("Token algorithm is not accepted")
+	ErrorJwtKeyNotFound      = errors.New("Token signing key not found")
+	ErrorJwtSignature        = errors.New("Token signature is invalid")
+	ErrorJwtExpired          = errors.New("Token is expired")
+	ErrorJwtExpirationAbsent = errors.New("Token has no expiration")
+	ErrorJwtNotValidYet      = errors.New("Token is not valid yet")
+	ErrorJwtIssuedInFuture   = errors.New("Token is issued in the future")
+	ErrorJwtInvalidIssuer    = errors.New("Token issuer is invalid")
+	ErrorJwtInvalidAudience  = errors.New("Token audience is invalid")
+	ErrorJwtSigningKeyAbsent = errors.New("No key able to sign tokens")
+)
+
+var (
+	ErrorSubjectExtractorRequired = errors.New("Subject extractor is required")
+)
+
+const (
+	EffectPermit = "permit"
+	EffectDeny   = "deny"
+)
+
+const (
+	// CombiningDenyOverrides denies when any applicable policy denies, otherwise permits when any permits.
+	CombiningDenyOverrides = "deny-overrides"
+	// CombiningPermitOverrides permits when any applicable policy permits, otherwise denies when any denies.
+	CombiningPermitOverrides = "permit-overrides"
+	// CombiningFirstApplicable takes the effect of the first applicable policy, in order.
+	CombiningFirstApplicable = "first-applicable"
+)
+
+var (
+	CombiningAlgorithms map[string]bool = map[string]bool{
+		CombiningDenyOverrides:   true,
+		CombiningPermitOverrides: true,
+		CombiningFirstApplicable: true,
+	}
+)
+
+const (
+	AttributeEnvironmentTime     = "environment.time"
+	AttributeEnvironmentTimezone = "environment.timezone"
+)
+
+const (
+	ReasonNotApplicable = "no policy applies"
+)
+
+var (
+	expressionFunctions map[string]ExpressionFunc
+	// expressionFunctionsMutex guards expressionFunctions, registered into at runtime while expressions compile
+	expressionFunctionsMutex sync.RWMutex
+)
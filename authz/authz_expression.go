@@ -0,0 +1,876 @@
+package authz
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sivaosorg/govm/bjson"
+	"github.com/sivaosorg/govm/match"
+	"github.com/sivaosorg/govm/timex"
+	"github.com/sivaosorg/govm/utils"
+)
+
+// CompileExpression parses an ABAC condition such as
+// "subject.tenant == resource.tenant and between('09:00', '17:00') and not isWeekend()".
+//
+// Attributes are bjson paths over the attributes document, e.g: subject.roles.#, or any bjson path
+// quoted with backticks, e.g: `resource.owners.#(id==42)`. Missing attributes evaluate to null,
+// and every comparison with a missing attribute is false, e.g: subject.tenant == resource.tenant when both are missing.
+// Operators, loosest first: or (||), and (&&), not (!), then == != < <= > >= in matches.
+// Literals are strings in single or double quotes, numbers, true, false, null and lists [a, b].
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, fmt.Errorf("Unexpected '%s' at position %d", t.text, t.pos)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// MustCompileExpression compiles the expression or panics.
+func MustCompileExpression(source string) *Expression {
+	e, err := CompileExpression(source)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// AddExpressionFunction registers a function callable from every expression.
+// Expressions compiled before keep the function they resolved.
+func AddExpressionFunction(name string, fn ExpressionFunc) {
+	expressionFunctionsMutex.Lock()
+	defer expressionFunctionsMutex.Unlock()
+	expressionFunctions[name] = fn
+}
+
+// ExpressionFunctionExists checks whether a function of the name is registered.
+func ExpressionFunctionExists(name string) bool {
+	_, ok := lookupExpressionFunction(name)
+	return ok
+}
+
+func lookupExpressionFunction(name string) (ExpressionFunc, bool) {
+	expressionFunctionsMutex.RLock()
+	defer expressionFunctionsMutex.RUnlock()
+	fn, ok := expressionFunctions[name]
+	return fn, ok
+}
+
+// NewExpressionContext creates the context evaluating expressions over the attributes document.
+// The clock is read from "environment.time" (RFC 3339) when present, otherwise it is the current time,
+// and is moved to "environment.timezone" when present.
+func NewExpressionContext(json string) *ExpressionContext {
+	now := time.Now()
+	if t := bjson.Get(json, AttributeEnvironmentTime); t.Exists() {
+		if at := t.Time(); !at.IsZero() {
+			now = at
+		}
+	}
+	if tz := bjson.Get(json, AttributeEnvironmentTimezone).String(); utils.IsNotEmpty(tz) {
+		now = timex.AdjustTimezone(now, tz)
+	}
+	return &ExpressionContext{Json: json, Now: now}
+}
+
+// Timex returns the timex helpers around the time given, or the clock of the context.
+func (c *ExpressionContext) Timex(at ...time.Time) *timex.Timex {
+	if len(at) > 0 {
+		return timex.With(at[0])
+	}
+	return timex.With(c.Now)
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression over the attributes document and returns its value.
+func (e *Expression) Eval(json string) (interface{}, error) {
+	return e.EvalContext(NewExpressionContext(json))
+}
+
+// EvalContext evaluates the expression within the context.
+func (e *Expression) EvalContext(ctx *ExpressionContext) (interface{}, error) {
+	return e.root.eval(ctx)
+}
+
+// Evaluate evaluates the expression over the attributes document as a condition.
+// A value that is not a boolean is an error.
+func (e *Expression) Evaluate(json string) (bool, error) {
+	return e.EvaluateContext(NewExpressionContext(json))
+}
+
+// EvaluateContext evaluates the expression within the context as a condition.
+func (e *Expression) EvaluateContext(ctx *ExpressionContext) (bool, error) {
+	v, err := e.root.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("Expression '%s' is not a condition: %v", e.source, v)
+	}
+	return b, nil
+}
+
+const (
+	tokenEnd = iota
+	tokenIdent
+	tokenPath
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenPunct
+)
+
+type expressionToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+type expressionNode interface {
+	eval(ctx *ExpressionContext) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type attributeNode struct {
+	path string
+}
+
+type listNode struct {
+	items []expressionNode
+}
+
+type notNode struct {
+	operand expressionNode
+}
+
+type logicalNode struct {
+	op          string
+	left, right expressionNode
+}
+
+type compareNode struct {
+	op          string
+	left, right expressionNode
+}
+
+type callNode struct {
+	name string
+	fn   ExpressionFunc
+	args []expressionNode
+}
+
+type expressionParser struct {
+	tokens []expressionToken
+	pos    int
+}
+
+func tokenizeExpression(source string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(source) && source[i] != c; i++ {
+				if source[i] == '\\' && c != '`' && i+1 < len(source) {
+					i++
+				}
+				sb.WriteByte(source[i])
+			}
+			if i >= len(source) {
+				return nil, fmt.Errorf("Unterminated string at position %d", start)
+			}
+			i++
+			kind := tokenString
+			if c == '`' {
+				kind = tokenPath
+			}
+			tokens = append(tokens, expressionToken{kind: kind, text: sb.String(), pos: start})
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9':
+			start := i
+			i++
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: source[start:i], pos: start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(source) && isPathChar(source[i]) {
+				i++
+			}
+			text := source[start:i]
+			kind := tokenIdent
+			if strings.Contains(text, ".") {
+				kind = tokenPath
+			}
+			tokens = append(tokens, expressionToken{kind: kind, text: text, pos: start})
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			start := i
+			op := string(c)
+			if i+1 < len(source) {
+				if two := source[i : i+2]; two == "==" || two == "!=" || two == "<=" || two == ">=" || two == "&&" || two == "||" {
+					op = two
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return nil, fmt.Errorf("Unexpected '%s' at position %d", op, start)
+			}
+			i += len(op)
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: op, pos: start})
+		case strings.ContainsRune("()[],", rune(c)):
+			tokens = append(tokens, expressionToken{kind: tokenPunct, text: string(c), pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("Unexpected '%c' at position %d", c, i)
+		}
+	}
+	return append(tokens, expressionToken{kind: tokenEnd, pos: len(source)}), nil
+}
+
+func isPathChar(c byte) bool {
+	return c == '_' || c == '.' || c == '#' || c == '@' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() expressionToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token when it is one of the texts, keywords matched case-insensitively.
+func (p *expressionParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenPunct && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, v := range texts {
+		if t.text == v || t.kind == tokenIdent && strings.EqualFold(t.text, v) {
+			p.next()
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (p *expressionParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		if t.kind == tokenEnd {
+			return fmt.Errorf("Expected '%s' at end of expression", text)
+		}
+		return fmt.Errorf("Expected '%s' at position %d, got '%s'", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+}
+
+func (p *expressionParser) parseNot() (expressionNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "in", "matches")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number '%s' at position %d", t.text, t.pos)
+		}
+		return &literalNode{value: n}, nil
+	case tokenPath:
+		return &attributeNode{path: t.text}, nil
+	case tokenIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+		return &attributeNode{path: t.text}, nil
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if _, ok := p.accept(","); !ok {
+					return list, p.expect("]")
+				}
+			}
+		}
+	case tokenEnd:
+		return nil, fmt.Errorf("Unexpected end of expression")
+	}
+	return nil, fmt.Errorf("Unexpected '%s' at position %d", t.text, t.pos)
+}
+
+func (p *expressionParser) parseCall(name expressionToken) (expressionNode, error) {
+	fn, ok := lookupExpressionFunction(name.text)
+	if !ok {
+		return nil, fmt.Errorf("Unknown function '%s' at position %d", name.text, name.pos)
+	}
+	call := &callNode{name: name.text, fn: fn}
+	if _, ok := p.accept(")"); ok {
+		return call, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if _, ok := p.accept(","); !ok {
+			return call, p.expect(")")
+		}
+	}
+}
+
+func (n *literalNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *attributeNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	r := bjson.Get(ctx.Json, n.path)
+	if !r.Exists() {
+		return nil, nil
+	}
+	return r.Value(), nil
+}
+
+func (n *listNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (n *notNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	v, err := evalBool(ctx, n.operand)
+	if err != nil {
+		return nil, err
+	}
+	return !v, nil
+}
+
+// eval short-circuits: the right operand is evaluated only when the left one does not decide.
+func (n *logicalNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	left, err := evalBool(ctx, n.left)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "or" && left || n.op == "and" && !left {
+		return left, nil
+	}
+	return evalBool(ctx, n.right)
+}
+
+// eval is false whenever an operand is a missing attribute, so that absent attributes never match each other.
+func (n *compareNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	left, missing, err := evalOperand(ctx, n.left)
+	if err != nil || missing {
+		return false, err
+	}
+	right, missing, err := evalOperand(ctx, n.right)
+	if err != nil || missing {
+		return false, err
+	}
+	switch n.op {
+	case "==":
+		return equalValues(ctx, left, right), nil
+	case "!=":
+		return !equalValues(ctx, left, right), nil
+	case "in":
+		return containsValue(ctx, right, left), nil
+	case "matches":
+		s, ok1 := left.(string)
+		pattern, ok2 := right.(string)
+		return ok1 && ok2 && match.Match(s, pattern), nil
+	}
+	c, err := compareValues(ctx, left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func (n *callNode) eval(ctx *ExpressionContext) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := n.fn(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %v", n.name, err)
+	}
+	return v, nil
+}
+
+// evalOperand evaluates the operand of a comparison, telling whether it is a missing attribute.
+func evalOperand(ctx *ExpressionContext, node expressionNode) (interface{}, bool, error) {
+	if a, ok := node.(*attributeNode); ok {
+		r := bjson.Get(ctx.Json, a.path)
+		if !r.Exists() {
+			return nil, true, nil
+		}
+		return r.Value(), false, nil
+	}
+	v, err := node.eval(ctx)
+	return v, false, err
+}
+
+func evalBool(ctx *ExpressionContext, node expressionNode) (bool, error) {
+	v, err := node.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("Value %v is not a boolean", v)
+}
+
+// equalValues compares numbers by value, times by instant, a time against a string parsed with timex,
+// and anything else deeply.
+func equalValues(ctx *ExpressionContext, a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	if x, y, ok := toTimes(ctx, a, b); ok {
+		return x.Equal(y)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders numbers, times and strings, returning -1, 0 or 1.
+func compareValues(ctx *ExpressionContext, a, b interface{}) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if x, y, ok := toTimes(ctx, a, b); ok {
+		switch {
+		case x.Before(y):
+			return -1, nil
+		case x.After(y):
+			return 1, nil
+		}
+		return 0, nil
+	}
+	x, ok1 := a.(string)
+	y, ok2 := b.(string)
+	if ok1 && ok2 {
+		return strings.Compare(x, y), nil
+	}
+	return 0, fmt.Errorf("Cannot compare %v with %v", a, b)
+}
+
+// containsValue checks membership of the item in a list, a substring in a string, or a key in an object.
+func containsValue(ctx *ExpressionContext, container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if equalValues(ctx, v, item) {
+				return true
+			}
+		}
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s)
+	case map[string]interface{}:
+		s, ok := item.(string)
+		if ok {
+			_, ok = c[s]
+		}
+		return ok
+	}
+	return false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// toTimes converts both values to times when at least one of them is a time.
+func toTimes(ctx *ExpressionContext, a, b interface{}) (time.Time, time.Time, bool) {
+	x, ok1 := a.(time.Time)
+	y, ok2 := b.(time.Time)
+	if !ok1 && !ok2 {
+		return x, y, false
+	}
+	var err error
+	if !ok1 {
+		if x, err = toTime(ctx, a); err != nil {
+			return x, y, false
+		}
+	}
+	if !ok2 {
+		if y, err = toTime(ctx, b); err != nil {
+			return x, y, false
+		}
+	}
+	return x, y, true
+}
+
+// toTime converts a time, or a string parsed by timex relative to the clock of the context,
+// e.g: "17:00" is today at 5 PM.
+func toTime(ctx *ExpressionContext, v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		if at, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return at.In(ctx.Now.Location()), nil
+		}
+		return ctx.Timex().Parse(t)
+	case float64:
+		return time.Unix(int64(t), 0).In(ctx.Now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("Value %v is not a time", v)
+}
+
+// timeArg returns the time argument at the index, or the clock of the context when absent.
+func timeArg(ctx *ExpressionContext, args []interface{}, index int) (time.Time, error) {
+	if len(args) <= index {
+		return ctx.Now, nil
+	}
+	return toTime(ctx, args[index])
+}
+
+func stringArg(args []interface{}, index int) (string, error) {
+	if len(args) <= index {
+		return "", fmt.Errorf("Argument %d is required", index+1)
+	}
+	s, ok := args[index].(string)
+	if !ok {
+		return "", fmt.Errorf("Argument %d is not a string", index+1)
+	}
+	return s, nil
+}
+
+func arity(args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("Expected %d arguments, got %d", min, len(args))
+		}
+		return fmt.Errorf("Expected %d to %d arguments, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+// timexFunc exposes a timex boundary helper, e.g: endOfMonth() or endOfMonth(resource.created_at).
+func timexFunc(fn func(t *timex.Timex) time.Time) ExpressionFunc {
+	return func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+		if err := arity(args, 0, 1); err != nil {
+			return nil, err
+		}
+		at, err := timeArg(ctx, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx.Timex(at)), nil
+	}
+}
+
+func stringFunc(fn func(s, arg string) interface{}) ExpressionFunc {
+	return func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+		if err := arity(args, 2, 2); err != nil {
+			return nil, err
+		}
+		s, _ := args[0].(string)
+		arg, err := stringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, arg), nil
+	}
+}
+
+func init() {
+	expressionFunctions = map[string]ExpressionFunc{
+		"len": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 1, 1); err != nil {
+				return nil, err
+			}
+			switch v := args[0].(type) {
+			case string:
+				return float64(len(v)), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			case nil:
+				return float64(0), nil
+			}
+			return nil, fmt.Errorf("Value %v has no length", args[0])
+		},
+		"exists": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 1, 1); err != nil {
+				return nil, err
+			}
+			return args[0] != nil, nil
+		},
+		"contains": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 2, 2); err != nil {
+				return nil, err
+			}
+			return containsValue(ctx, args[0], args[1]), nil
+		},
+		"lower": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			s, err := stringArg(args, 0)
+			return strings.ToLower(s), err
+		},
+		"upper": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			s, err := stringArg(args, 0)
+			return strings.ToUpper(s), err
+		},
+		"startsWith": stringFunc(func(s, prefix string) interface{} { return strings.HasPrefix(s, prefix) }),
+		"endsWith":   stringFunc(func(s, suffix string) interface{} { return strings.HasSuffix(s, suffix) }),
+		"now": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			return ctx.Now, arity(args, 0, 0)
+		},
+		"time": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 1, 1); err != nil {
+				return nil, err
+			}
+			return toTime(ctx, args[0])
+		},
+		"inTimezone": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 2, 2); err != nil {
+				return nil, err
+			}
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			tz, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return timex.ApplyTimezone(at, tz)
+		},
+		"addDuration": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 2, 2); err != nil {
+				return nil, err
+			}
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			s, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, err
+			}
+			return at.Add(d), nil
+		},
+		// between(begin, end[, at]) checks that the time lies within [begin, end), bounds parsed by timex,
+		// e.g: between("09:00", "17:00") for business hours of the current day.
+		"between": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			if err := arity(args, 2, 3); err != nil {
+				return nil, err
+			}
+			at, err := timeArg(ctx, args, 2)
+			if err != nil {
+				return nil, err
+			}
+			tx := ctx.Timex(at)
+			var bounds [2]time.Time
+			for i := range bounds {
+				if s, ok := args[i].(string); ok {
+					bounds[i], err = tx.Parse(s)
+				} else {
+					bounds[i], err = toTime(ctx, args[i])
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			return !at.Before(bounds[0]) && at.Before(bounds[1]), nil
+		},
+		"hour": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return float64(at.Hour()), nil
+		},
+		"weekday": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return strings.ToLower(at.Weekday().String()), nil
+		},
+		"isWeekend": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return at.Weekday() == time.Saturday || at.Weekday() == time.Sunday, nil
+		},
+		"quarter": func(ctx *ExpressionContext, args []interface{}) (interface{}, error) {
+			at, err := timeArg(ctx, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return float64(ctx.Timex(at).Quarter()), nil
+		},
+		"beginningOfDay":     timexFunc((*timex.Timex).BeginningOfDay),
+		"endOfDay":           timexFunc((*timex.Timex).EndOfDay),
+		"beginningOfWeek":    timexFunc((*timex.Timex).BeginningOfWeek),
+		"endOfWeek":          timexFunc((*timex.Timex).EndOfWeek),
+		"beginningOfMonth":   timexFunc((*timex.Timex).BeginningOfMonth),
+		"endOfMonth":         timexFunc((*timex.Timex).EndOfMonth),
+		"beginningOfQuarter": timexFunc((*timex.Timex).BeginningOfQuarter),
+		"endOfQuarter":       timexFunc((*timex.Timex).EndOfQuarter),
+		"beginningOfYear":    timexFunc((*timex.Timex).BeginningOfYear),
+		"endOfYear":          timexFunc((*timex.Timex).EndOfYear),
+	}
+}
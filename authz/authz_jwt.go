@@ -0,0 +1,685 @@
+package authz
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/charge"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type JwtService interface {
+	Sign(claims JwtClaims) (string, error)
+	Issue(subject string, extras map[string]interface{}) (string, error)
+	Verify(token string) (JwtClaims, error)
+	Reload() error
+	Config() JwtConfig
+}
+
+// NewJwtService creates the service issuing and verifying tokens of the config,
+// loading its keys from the secret, the PEM key files or the JWKS key set file.
+func NewJwtService(conf JwtConfig) (JwtService, error) {
+	if err := JwtConfigValidator(&conf); err != nil {
+		return nil, err
+	}
+	s := &jwtServiceImpl{
+		conf: conf,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SubjectByJwt resolves the subject from the "sub" claim of the verified bearer token.
+func SubjectByJwt(svc JwtService) SubjectExtractorFunc {
+	return SubjectByBearerToken(func(token string) (string, error) {
+		claims, err := svc.Verify(token)
+		if err != nil {
+			return "", err
+		}
+		return claims.Subject(), nil
+	})
+}
+
+// JwtClaimsFromRequest verifies the bearer token of the request and returns its claims.
+func JwtClaimsFromRequest(svc JwtService, request *http.Request) (JwtClaims, error) {
+	token := charge.GetRequestAuthBearerToken(request)
+	if utils.IsEmpty(token) {
+		return nil, ErrorJwtMalformed
+	}
+	return svc.Verify(token)
+}
+
+// Sign signs the claims as they are, with the signing key of the config.
+func (s *jwtServiceImpl) Sign(claims JwtClaims) (string, error) {
+	key, ok := s.signingKey()
+	if !ok {
+		return "", ErrorJwtSigningKeyAbsent
+	}
+	header, err := json.Marshal(jwtHeader{Alg: s.conf.Algorithm, Typ: "JWT", Kid: key.Kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := encodeSegment(header) + "." + encodeSegment(payload)
+	signature, err := key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encodeSegment(signature), nil
+}
+
+// Issue signs a token for the subject, setting the registered claims of the config:
+// issuer, audience, issued at, not before and expiration. Extras are added as private claims
+// and may override the registered ones.
+func (s *jwtServiceImpl) Issue(subject string, extras map[string]interface{}) (string, error) {
+	now := time.Now()
+	claims := JwtClaims{
+		ClaimIssuedAt:  now.Unix(),
+		ClaimNotBefore: now.Unix(),
+		ClaimExpiresAt: now.Add(s.conf.Expiration).Unix(),
+	}
+	if utils.IsNotEmpty(subject) {
+		claims[ClaimSubject] = subject
+	}
+	if utils.IsNotEmpty(s.conf.Issuer) {
+		claims[ClaimIssuer] = s.conf.Issuer
+	}
+	switch len(s.conf.Audience) {
+	case 0:
+	case 1:
+		claims[ClaimAudience] = s.conf.Audience[0]
+	default:
+		claims[ClaimAudience] = s.conf.Audience
+	}
+	for k, v := range extras {
+		claims[k] = v
+	}
+	return s.Sign(claims)
+}
+
+// Verify checks the signature of the token and validates its registered claims,
+// allowing the leeway of the config on exp, nbf and iat.
+// An unknown kid triggers a reload of the key set file, at most once per JwtKeySetReloadInterval.
+func (s *jwtServiceImpl) Verify(token string) (JwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrorJwtMalformed
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrorJwtMalformed
+	}
+	if header.Alg != s.conf.Algorithm {
+		return nil, ErrorJwtAlgorithm
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrorJwtMalformed
+	}
+	key, ok := s.verifyingKey(header.Kid)
+	if !ok {
+		return nil, ErrorJwtKeyNotFound
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrorJwtSignature
+	}
+	var claims JwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrorJwtMalformed
+	}
+	if err := s.validate(claims, time.Now()); err != nil {
+		return claims, err
+	}
+	return claims, nil
+}
+
+// Reload reloads the keys from the key set file or from the secret and PEM key files.
+func (s *jwtServiceImpl) Reload() error {
+	var keys *JwtKeySet
+	var err error
+	if utils.IsNotEmpty(s.conf.KeySetFile) {
+		keys, err = LoadJwtKeySet(s.conf.KeySetFile)
+	} else {
+		keys, err = s.loadKeyFiles()
+	}
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.keys = keys
+	s.reload = time.Now()
+	s.mutex.Unlock()
+	return nil
+}
+
+// Config returns the config of the service, defaults filled.
+func (s *jwtServiceImpl) Config() JwtConfig {
+	return s.conf
+}
+
+// validate checks the registered claims against the time given and the config.
+func (s *jwtServiceImpl) validate(claims JwtClaims, now time.Time) error {
+	leeway := s.conf.Leeway
+	exp, ok := claims.ExpiresAt()
+	if !ok && s.conf.IsRequireExpiration {
+		return ErrorJwtExpirationAbsent
+	}
+	if ok && now.After(exp.Add(leeway)) {
+		return ErrorJwtExpired
+	}
+	if nbf, ok := claims.NotBefore(); ok && now.Add(leeway).Before(nbf) {
+		return ErrorJwtNotValidYet
+	}
+	if iat, ok := claims.IssuedAt(); ok && now.Add(leeway).Before(iat) {
+		return ErrorJwtIssuedInFuture
+	}
+	if utils.IsNotEmpty(s.conf.Issuer) && claims.Issuer() != s.conf.Issuer {
+		return ErrorJwtInvalidIssuer
+	}
+	if len(s.conf.Audience) > 0 {
+		accepted := false
+		for _, v := range claims.Audience() {
+			if contains(s.conf.Audience, v) {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return ErrorJwtInvalidAudience
+		}
+	}
+	return nil
+}
+
+// signingKey returns the key of the configured kid, or the first key of the algorithm able to sign.
+func (s *jwtServiceImpl) signingKey() (JwtKey, bool) {
+	s.mutex.Lock()
+	keys := s.keys
+	s.mutex.Unlock()
+	for _, k := range keys.Keys() {
+		if !k.accepts(s.conf.Algorithm) || !k.canSign() {
+			continue
+		}
+		if utils.IsEmpty(s.conf.Kid) || k.Kid == s.conf.Kid {
+			return k, true
+		}
+	}
+	return JwtKey{}, false
+}
+
+// verifyingKey returns the key of the kid, reloading the key set file on a miss.
+func (s *jwtServiceImpl) verifyingKey(kid string) (JwtKey, bool) {
+	s.mutex.Lock()
+	keys := s.keys
+	stale := utils.IsNotEmpty(s.conf.KeySetFile) && time.Since(s.reload) >= JwtKeySetReloadInterval
+	s.mutex.Unlock()
+	if k, ok := keys.Find(kid, s.conf.Algorithm); ok {
+		return k, true
+	}
+	if !stale || s.Reload() != nil {
+		return JwtKey{}, false
+	}
+	s.mutex.Lock()
+	keys = s.keys
+	s.mutex.Unlock()
+	return keys.Find(kid, s.conf.Algorithm)
+}
+
+// loadKeyFiles builds the single key of the config from the secret or the PEM key files.
+func (s *jwtServiceImpl) loadKeyFiles() (*JwtKeySet, error) {
+	key := JwtKey{Kid: s.conf.Kid, Algorithm: s.conf.Algorithm}
+	if s.conf.Algorithm == AlgorithmHS256 {
+		key.Secret = []byte(s.conf.Secret)
+		return NewJwtKeySet(key), nil
+	}
+	if utils.IsNotEmpty(s.conf.PrivateKeyFile) {
+		data, err := os.ReadFile(s.conf.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	}
+	if utils.IsNotEmpty(s.conf.PublicKeyFile) {
+		data, err := os.ReadFile(s.conf.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		public, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = public
+	}
+	if !key.accepts(s.conf.Algorithm) {
+		return nil, fmt.Errorf("Key type does not match algorithm %s", s.conf.Algorithm)
+	}
+	return NewJwtKeySet(key), nil
+}
+
+// Subject returns the "sub" claim.
+func (c JwtClaims) Subject() string {
+	return c.GetString(ClaimSubject)
+}
+
+// Issuer returns the "iss" claim.
+func (c JwtClaims) Issuer() string {
+	return c.GetString(ClaimIssuer)
+}
+
+// Id returns the "jti" claim.
+func (c JwtClaims) Id() string {
+	return c.GetString(ClaimId)
+}
+
+// Audience returns the "aud" claim, which may be a single string or an array.
+func (c JwtClaims) Audience() []string {
+	return c.GetStrings(ClaimAudience)
+}
+
+// Roles returns the "roles" claim, e.g: to be enforced through RbacService.EnforceRoles.
+func (c JwtClaims) Roles() []string {
+	return c.GetStrings(ClaimRoles)
+}
+
+// ExpiresAt returns the "exp" claim.
+func (c JwtClaims) ExpiresAt() (time.Time, bool) {
+	return c.GetTime(ClaimExpiresAt)
+}
+
+// NotBefore returns the "nbf" claim.
+func (c JwtClaims) NotBefore() (time.Time, bool) {
+	return c.GetTime(ClaimNotBefore)
+}
+
+// IssuedAt returns the "iat" claim.
+func (c JwtClaims) IssuedAt() (time.Time, bool) {
+	return c.GetTime(ClaimIssuedAt)
+}
+
+// GetString returns the claim as a string, or empty when absent or not a string.
+func (c JwtClaims) GetString(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+// GetStrings returns the claim as a list of strings, a single string included.
+func (c JwtClaims) GetStrings(key string) []string {
+	switch t := c[key].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		values := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// GetTime returns the claim, a NumericDate in seconds since the epoch, as a time.Time.
+func (c JwtClaims) GetTime(key string) (time.Time, bool) {
+	var seconds float64
+	switch t := c[key].(type) {
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = f
+	case float64:
+		seconds = t
+	case int64:
+		seconds = float64(t)
+	case int:
+		seconds = float64(t)
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = f
+	default:
+		return time.Time{}, false
+	}
+	sec := int64(seconds)
+	return time.Unix(sec, int64((seconds-float64(sec))*1e9)), true
+}
+
+func (c JwtClaims) Json() string {
+	return utils.ToJson(c)
+}
+
+// NewJwtKeySet creates a key set of the keys.
+func NewJwtKeySet(keys ...JwtKey) *JwtKeySet {
+	return &JwtKeySet{keys: keys}
+}
+
+// LoadJwtKeySet reads a JWKS document (RFC 7517) from the file.
+func LoadJwtKeySet(path string) (*JwtKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJwtKeySet(data)
+}
+
+// ParseJwtKeySet parses a JWKS document holding "oct", "RSA" and "EC" (P-256) keys.
+// Keys of other types or curves are skipped; private members, when present, allow signing.
+func ParseJwtKeySet(data []byte) (*JwtKeySet, error) {
+	var doc jsonWebKeySet
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	set := NewJwtKeySet()
+	for i, jwk := range doc.Keys {
+		if utils.IsNotEmpty(jwk.Use) && jwk.Use != "sig" {
+			continue
+		}
+		key, ok, err := jwk.toKey()
+		if err != nil {
+			return nil, fmt.Errorf("Key %d (%s): %v", i, jwk.Kid, err)
+		}
+		if ok {
+			set.keys = append(set.keys, key)
+		}
+	}
+	return set, nil
+}
+
+// Keys returns the keys of the set.
+func (k *JwtKeySet) Keys() []JwtKey {
+	if k == nil {
+		return nil
+	}
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return append([]JwtKey(nil), k.keys...)
+}
+
+// Append adds keys to the set, replacing keys of the same kid.
+func (k *JwtKeySet) Append(keys ...JwtKey) *JwtKeySet {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	for _, key := range keys {
+		replaced := false
+		for i, v := range k.keys {
+			if utils.IsNotEmpty(key.Kid) && v.Kid == key.Kid {
+				k.keys[i] = key
+				replaced = true
+			}
+		}
+		if !replaced {
+			k.keys = append(k.keys, key)
+		}
+	}
+	return k
+}
+
+// Find returns the key of the kid usable with the algorithm.
+// Without kid, the only key of the algorithm is returned, if there is exactly one.
+func (k *JwtKeySet) Find(kid, algorithm string) (JwtKey, bool) {
+	var candidates []JwtKey
+	for _, key := range k.Keys() {
+		if !key.accepts(algorithm) {
+			continue
+		}
+		if utils.IsNotEmpty(kid) {
+			if key.Kid == kid {
+				return key, true
+			}
+			continue
+		}
+		candidates = append(candidates, key)
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return JwtKey{}, false
+}
+
+// ParsePrivateKeyPEM parses an RSA or EC private key in PKCS#1, SEC 1 or PKCS#8 PEM encoding.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 public key, or the public key of a certificate, in PEM encoding.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid PEM public key")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return cert.PublicKey, nil
+}
+
+// accepts checks whether the key may be used with the algorithm.
+func (k JwtKey) accepts(algorithm string) bool {
+	if utils.IsNotEmpty(k.Algorithm) && k.Algorithm != algorithm {
+		return false
+	}
+	switch algorithm {
+	case AlgorithmHS256:
+		return len(k.Secret) > 0
+	case AlgorithmRS256:
+		_, ok := k.PublicKey.(*rsa.PublicKey)
+		return ok
+	case AlgorithmES256:
+		pub, ok := k.PublicKey.(*ecdsa.PublicKey)
+		return ok && pub.Curve == elliptic.P256()
+	}
+	return false
+}
+
+// canSign checks whether the key holds the material to sign.
+func (k JwtKey) canSign() bool {
+	return len(k.Secret) > 0 || k.PrivateKey != nil
+}
+
+func (k JwtKey) sign(input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS carries ES256 signatures as the 32-byte big-endian R and S concatenated (RFC 7518 §3.4)
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	}
+	if len(k.Secret) > 0 {
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+	return nil, ErrorJwtSigningKeyAbsent
+}
+
+func (k JwtKey) verify(input, signature []byte) bool {
+	digest := sha256.Sum256(input)
+	switch key := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	}
+	if len(k.Secret) > 0 {
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	}
+	return false
+}
+
+// toKey converts the JSON web key, reporting false for key types that are not supported.
+func (j jsonWebKey) toKey() (JwtKey, bool, error) {
+	key := JwtKey{Kid: j.Kid, Algorithm: j.Alg}
+	switch j.Kty {
+	case "oct":
+		secret, err := decodeBase64URL(j.K)
+		if err != nil || len(secret) == 0 {
+			return key, false, fmt.Errorf("Invalid secret")
+		}
+		key.Secret = secret
+		if utils.IsEmpty(key.Algorithm) {
+			key.Algorithm = AlgorithmHS256
+		}
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return key, false, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return key, false, err
+		}
+		public := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		key.PublicKey = public
+		if utils.IsNotEmpty(j.D) {
+			d, err := decodeBigInt(j.D)
+			if err != nil {
+				return key, false, err
+			}
+			private := &rsa.PrivateKey{PublicKey: *public, D: d}
+			if utils.IsNotEmpty(j.P) && utils.IsNotEmpty(j.Q) {
+				p, err := decodeBigInt(j.P)
+				if err != nil {
+					return key, false, err
+				}
+				q, err := decodeBigInt(j.Q)
+				if err != nil {
+					return key, false, err
+				}
+				private.Primes = []*big.Int{p, q}
+				private.Precompute()
+			}
+			key.PrivateKey = private
+		}
+		if utils.IsEmpty(key.Algorithm) {
+			key.Algorithm = AlgorithmRS256
+		}
+	case "EC":
+		if j.Crv != "P-256" {
+			return key, false, nil
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return key, false, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return key, false, err
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		key.PublicKey = public
+		if utils.IsNotEmpty(j.D) {
+			d, err := decodeBigInt(j.D)
+			if err != nil {
+				return key, false, err
+			}
+			key.PrivateKey = &ecdsa.PrivateKey{PublicKey: *public, D: d}
+		}
+		if utils.IsEmpty(key.Algorithm) {
+			key.Algorithm = AlgorithmES256
+		}
+	default:
+		return key, false, nil
+	}
+	return key, true, nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSegment decodes a base64url JSON segment, keeping numbers as json.Number.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// decodeBase64URL decodes base64url, tolerating padding.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := decodeBase64URL(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("Invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,164 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/sivaosorg/govm/charge"
+	"github.com/sivaosorg/govm/common"
+	"github.com/sivaosorg/govm/entity"
+	"github.com/sivaosorg/govm/session"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type AuthzMiddlewareService interface {
+	ResolveRoute(request *http.Request) (RouteRule, bool)
+	Authorize(request *http.Request) (Decision, int)
+	AuthorizeMiddleware(next http.Handler) http.Handler
+}
+
+// NewAuthzMiddlewareService creates a middleware that authorizes requests against the RBAC engine,
+// mapping them to resources and actions through the route rules of its policy.
+// The subject extractor is required, e.g: SubjectByJwt, it returns ErrorSubjectExtractorRequired otherwise.
+func NewAuthzMiddlewareService(rbac RbacService, subjectFunc SubjectExtractorFunc) (AuthzMiddlewareService, error) {
+	if subjectFunc == nil {
+		return nil, ErrorSubjectExtractorRequired
+	}
+	s := &authzMiddlewareServiceImpl{
+		rbac:        rbac,
+		policy:      rbac.Policy(),
+		subjectFunc: subjectFunc,
+	}
+	return s, nil
+}
+
+// SubjectByBearerToken resolves the subject from the bearer token of the Authorization header.
+// The resolver maps the token to its subject, e.g: by verifying a JWT; no subject is resolved when nil.
+func SubjectByBearerToken(resolver func(token string) (string, error)) SubjectExtractorFunc {
+	return func(request *http.Request) string {
+		token := charge.GetRequestAuthBearerToken(request)
+		if utils.IsEmpty(token) || resolver == nil {
+			return ""
+		}
+		subject, err := resolver(token)
+		if err != nil {
+			return ""
+		}
+		return subject
+	}
+}
+
+// SubjectBySession resolves the subject from the string value of the key within the session of the request,
+// or from the session id when the key is empty.
+func SubjectBySession(svc session.SessionManagerService, key string) SubjectExtractorFunc {
+	return func(request *http.Request) string {
+		s := svc.GetSession(request)
+		if s == nil {
+			return ""
+		}
+		if utils.IsEmpty(key) {
+			return s.Id
+		}
+		subject, _ := s.GetString(key)
+		return subject
+	}
+}
+
+// SubjectByHeader resolves the subject from a header, e.g: set by a trusted gateway.
+func SubjectByHeader(name string) SubjectExtractorFunc {
+	return func(request *http.Request) string {
+		return charge.GetHeader(request, name)
+	}
+}
+
+// SubjectByFirst tries the extractors in order and returns the first non-empty subject.
+func SubjectByFirst(extractors ...SubjectExtractorFunc) SubjectExtractorFunc {
+	return func(request *http.Request) string {
+		for _, fn := range extractors {
+			if subject := fn(request); utils.IsNotEmpty(subject) {
+				return subject
+			}
+		}
+		return ""
+	}
+}
+
+// ResolveRoute returns the first route rule matching the method and path of the request.
+func (s *authzMiddlewareServiceImpl) ResolveRoute(request *http.Request) (RouteRule, bool) {
+	for _, r := range s.policy.Routes {
+		if r.Matches(request.Method, charge.GetRequestPath(request)) {
+			return r, true
+		}
+	}
+	return *NewRouteRule(), false
+}
+
+// Authorize decides on the request and returns the decision along with the HTTP status to answer:
+// 200 when allowed, 401 when no subject could be resolved and 403 when denied.
+func (s *authzMiddlewareServiceImpl) Authorize(request *http.Request) (Decision, int) {
+	if !s.policy.IsEnabled {
+		return Decision{IsAllowed: true, Reason: ReasonDisabled}, http.StatusOK
+	}
+	route, ok := s.ResolveRoute(request)
+	if !ok {
+		d := Decision{IsAllowed: !s.policy.IsDenyUnmatched, Resource: charge.GetRequestPath(request), Action: request.Method}
+		if d.IsAllowed {
+			d.Reason = ReasonUnmatchedAllowed
+			return d, http.StatusOK
+		}
+		d.Reason = ReasonUnmatchedDenied
+		return d, http.StatusForbidden
+	}
+	action := route.ActionOf(request.Method)
+	if route.IsPublic {
+		return Decision{IsAllowed: true, Resource: route.Resource, Action: action, Reason: "public route"}, http.StatusOK
+	}
+	subject := s.subjectFunc(request)
+	if utils.IsEmpty(subject) {
+		return Decision{Resource: route.Resource, Action: action, Reason: "subject unauthenticated"}, http.StatusUnauthorized
+	}
+	d := s.rbac.Enforce(subject, route.Resource, action)
+	if !d.IsAllowed {
+		return d, http.StatusForbidden
+	}
+	return d, http.StatusOK
+}
+
+// AuthorizeMiddleware rejects unauthenticated requests with 401 and denied requests with 403,
+// otherwise injects the subject and decision into the request context.
+func (s *authzMiddlewareServiceImpl) AuthorizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, status := s.Authorize(r)
+		switch status {
+		case http.StatusOK:
+			ctx := context.WithValue(r.Context(), ContextKeyDecision, d)
+			if utils.IsNotEmpty(d.Subject) {
+				ctx = context.WithValue(ctx, ContextKeySubject, d.Subject)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		case http.StatusUnauthorized:
+			w.Header().Set(common.HeaderContentType, common.MediaTypeApplicationJSON)
+			w.WriteHeader(http.StatusUnauthorized)
+			e := entity.NewResponseEntity().Unauthorized("Unauthorized", nil)
+			w.Write([]byte(e.Json()))
+		default:
+			w.Header().Set(common.HeaderContentType, common.MediaTypeApplicationJSON)
+			w.WriteHeader(http.StatusForbidden)
+			e := entity.NewResponseEntity().Forbidden(fmt.Sprintf("Forbidden: %s on %s", d.Action, d.Resource), nil)
+			w.Write([]byte(e.Json()))
+		}
+	})
+}
+
+// SubjectFromContext returns the subject injected by the authz middleware.
+func SubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(ContextKeySubject).(string)
+	return subject
+}
+
+// DecisionFromContext returns the decision made by the authz middleware.
+func DecisionFromContext(ctx context.Context) (Decision, bool) {
+	d, ok := ctx.Value(ContextKeyDecision).(Decision)
+	return d, ok
+}
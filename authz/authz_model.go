@@ -1 +1,204 @@
 package authz
+
+import (
+	"crypto"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Role groups permissions expressed as "resource:action" patterns, where '*' and '?' are wildcards.
+// A role also owns the permissions of the roles it inherits.
+type Role struct {
+	Name        string   `json:"name" binding:"required" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Permissions []string `json:"permissions,omitempty" yaml:"permissions"`
+	Denies      []string `json:"denies,omitempty" yaml:"denies"`
+	Inherits    []string `json:"inherits,omitempty" yaml:"inherits"`
+}
+
+// RoleBinding assigns roles to a subject, e.g: a user id or a group.
+type RoleBinding struct {
+	Subject string   `json:"subject" binding:"required" yaml:"subject"`
+	Roles   []string `json:"roles" yaml:"roles"`
+}
+
+// RouteRule maps requests whose method and path match to the resource and action to authorize.
+// The pattern supports '*' and '?' wildcards; an empty action is derived from the method.
+type RouteRule struct {
+	Method   string `json:"method,omitempty" yaml:"method"`
+	Pattern  string `json:"pattern" binding:"required" yaml:"pattern"`
+	Resource string `json:"resource" binding:"required" yaml:"resource"`
+	Action   string `json:"action,omitempty" yaml:"action"`
+	IsPublic bool   `json:"public,omitempty" yaml:"public"`
+}
+
+// PolicyConfig is the RBAC policy, loadable from YAML or JSON, enabled unless "enabled" is false,
+// and denying the requests no route matches unless "deny_unmatched" is false.
+// A disabled policy allows everything, its decisions giving ReasonDisabled.
+type PolicyConfig struct {
+	IsEnabled       bool          `json:"enabled" yaml:"enabled"`
+	IsDenyUnmatched bool          `json:"deny_unmatched" yaml:"deny_unmatched"`
+	Roles           []Role        `json:"roles,omitempty" yaml:"roles"`
+	Bindings        []RoleBinding `json:"bindings,omitempty" yaml:"bindings"`
+	Routes          []RouteRule   `json:"routes,omitempty" yaml:"routes"`
+}
+
+// Decision explains the outcome of an authorization request.
+type Decision struct {
+	IsAllowed  bool     `json:"allowed"`
+	Subject    string   `json:"subject,omitempty"`
+	Resource   string   `json:"resource"`
+	Action     string   `json:"action"`
+	Role       string   `json:"role,omitempty"`
+	Permission string   `json:"permission,omitempty"`
+	Path       []string `json:"path,omitempty"`
+	Policy     string   `json:"policy,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+type rbacServiceImpl struct {
+	conf     PolicyConfig
+	mutex    sync.RWMutex
+	roles    map[string]Role
+	bindings map[string][]string
+}
+
+// SubjectExtractorFunc resolves the subject of a request, or returns an empty string when unauthenticated.
+type SubjectExtractorFunc func(request *http.Request) string
+
+// contextKey is the type of keys the authz package stores into a context.Context.
+type contextKey string
+
+type authzMiddlewareServiceImpl struct {
+	rbac        RbacService
+	policy      PolicyConfig
+	subjectFunc SubjectExtractorFunc
+}
+
+// JwtConfig configures issuing and verification of JSON Web Tokens.
+// HS256 uses Secret; RS256 and ES256 use PEM key files. A JWKS key set file, when set,
+// provides keys selected by the "kid" header and is reloaded when an unknown kid shows up.
+// Tokens without "exp" are rejected unless "require_expiration" is false.
+type JwtConfig struct {
+	IsEnabled           bool          `json:"enabled" yaml:"enabled"`
+	Algorithm           string        `json:"algorithm" yaml:"algorithm"`
+	Secret              string        `json:"-" yaml:"secret"`
+	PrivateKeyFile      string        `json:"private_key_file,omitempty" yaml:"private_key_file"`
+	PublicKeyFile       string        `json:"public_key_file,omitempty" yaml:"public_key_file"`
+	KeySetFile          string        `json:"key_set_file,omitempty" yaml:"key_set_file"`
+	Kid                 string        `json:"kid,omitempty" yaml:"kid"`
+	Issuer              string        `json:"issuer,omitempty" yaml:"issuer"`
+	Audience            []string      `json:"audience,omitempty" yaml:"audience"`
+	Expiration          time.Duration `json:"expiration" yaml:"expiration"`
+	Leeway              time.Duration `json:"leeway" yaml:"leeway"`
+	IsRequireExpiration bool          `json:"require_expiration" yaml:"require_expiration"`
+}
+
+// JwtClaims holds the claims of a token, registered claims included.
+type JwtClaims map[string]interface{}
+
+// JwtKey is a key able to verify, and sign when it holds private material, tokens of its algorithm.
+type JwtKey struct {
+	Kid        string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// JwtKeySet is a set of keys indexed by kid, as published by a JWKS document.
+type JwtKeySet struct {
+	mutex sync.RWMutex
+	keys  []JwtKey
+}
+
+// jsonWebKey is the JSON representation of a key within a JWKS document (RFC 7517).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	K   string `json:"k,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jsonWebKeySet is the JSON representation of a JWKS document.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type jwtServiceImpl struct {
+	conf   JwtConfig
+	keys   *JwtKeySet
+	mutex  sync.Mutex
+	reload time.Time
+}
+
+// AbacPolicy permits or denies actions on resources when its condition holds over the attributes.
+// An empty condition always holds.
+type AbacPolicy struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Effect      string   `json:"effect" yaml:"effect"`
+	Resources   []string `json:"resources" yaml:"resources"`
+	Actions     []string `json:"actions" yaml:"actions"`
+	Condition   string   `json:"condition,omitempty" yaml:"condition"`
+}
+
+// AbacConfig holds the attribute-based policies and the algorithm combining their effects.
+// It is enabled unless "enabled" is set to false, so that a config missing the flag fails closed.
+type AbacConfig struct {
+	IsEnabled bool         `json:"enabled" yaml:"enabled"`
+	Algorithm string       `json:"algorithm" yaml:"algorithm"`
+	Policies  []AbacPolicy `json:"policies" yaml:"policies"`
+}
+
+// AbacAttributes is the document conditions are evaluated against.
+type AbacAttributes struct {
+	Subject     map[string]interface{} `json:"subject"`
+	Resource    map[string]interface{} `json:"resource"`
+	Request     map[string]interface{} `json:"request"`
+	Environment map[string]interface{} `json:"environment"`
+}
+
+// Expression is a compiled ABAC condition.
+type Expression struct {
+	source string
+	root   expressionNode
+}
+
+// ExpressionContext carries the attributes document and the clock of an evaluation.
+type ExpressionContext struct {
+	Json string
+	Now  time.Time
+}
+
+// ExpressionFunc is a function callable from expressions; arguments are nil, bool, float64, string,
+// time.Time, []interface{} or map[string]interface{}.
+type ExpressionFunc func(ctx *ExpressionContext, args []interface{}) (interface{}, error)
+
+type abacPolicy struct {
+	AbacPolicy
+	condition *Expression
+}
+
+type abacServiceImpl struct {
+	mutex    sync.RWMutex
+	conf     AbacConfig
+	policies []abacPolicy
+}
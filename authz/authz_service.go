@@ -0,0 +1,317 @@
+package authz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sivaosorg/govm/match"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type RbacService interface {
+	Enforce(subject, resource, action string) Decision
+	EnforceRoles(roles []string, resource, action string) Decision
+	Can(subject, resource, action string) bool
+	AddRole(role Role) error
+	RemoveRole(name string) error
+	AssignRoles(subject string, roles ...string) error
+	RevokeRoles(subject string, roles ...string)
+	GetRoles(subject string) []string
+	GetEffectiveRoles(subject string) []string
+	GetPermissions(subject string) []string
+	Policy() PolicyConfig
+}
+
+// NewRbacService creates the RBAC engine of the policy, failing when the policy is invalid.
+func NewRbacService(conf PolicyConfig) (RbacService, error) {
+	if err := PolicyConfigValidator(conf); err != nil {
+		return nil, err
+	}
+	s := &rbacServiceImpl{
+		conf:     conf,
+		roles:    make(map[string]Role, len(conf.Roles)),
+		bindings: make(map[string][]string, len(conf.Bindings)),
+	}
+	for _, r := range conf.Roles {
+		s.roles[r.Name] = r
+	}
+	for _, b := range conf.Bindings {
+		s.bindings[b.Subject] = appendUnique(s.bindings[b.Subject], b.Roles...)
+	}
+	return s, nil
+}
+
+// Enforce decides whether the subject may perform the action on the resource.
+// Denies of any effective role take precedence over permissions.
+func (s *rbacServiceImpl) Enforce(subject, resource, action string) Decision {
+	s.mutex.RLock()
+	roles := s.bindings[subject]
+	s.mutex.RUnlock()
+	d := s.EnforceRoles(roles, resource, action)
+	d.Subject = subject
+	return d
+}
+
+// EnforceRoles decides whether the holder of the roles may perform the action on the resource,
+// e.g: roles carried by a token rather than bound in the policy.
+func (s *rbacServiceImpl) EnforceRoles(roles []string, resource, action string) Decision {
+	d := Decision{Resource: resource, Action: action}
+	if !s.conf.IsEnabled {
+		d.IsAllowed = true
+		d.Reason = ReasonDisabled
+		return d
+	}
+	if utils.IsEmpty(resource) || utils.IsEmpty(action) {
+		d.Reason = ReasonInvalidResource
+		return d
+	}
+	if len(roles) == 0 {
+		d.Reason = ReasonNoRole
+		return d
+	}
+	target := Permission(resource, action)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var granted *Decision
+	for _, path := range s.walk(roles) {
+		role := s.roles[path[len(path)-1]]
+		for _, pattern := range role.Denies {
+			if matchPermission(target, pattern) {
+				d.Role, d.Permission, d.Path = role.Name, pattern, path
+				d.Reason = fmt.Sprintf("%s '%s' of role '%s'%s", ReasonDenied, pattern, role.Name, describePath(path))
+				return d
+			}
+		}
+		if granted != nil {
+			continue
+		}
+		for _, pattern := range role.Permissions {
+			if matchPermission(target, pattern) {
+				g := d
+				g.IsAllowed = true
+				g.Role, g.Permission, g.Path = role.Name, pattern, path
+				g.Reason = fmt.Sprintf("%s '%s' of role '%s'%s", ReasonAllowed, pattern, role.Name, describePath(path))
+				granted = &g
+				break
+			}
+		}
+	}
+	if granted != nil {
+		return *granted
+	}
+	d.Reason = fmt.Sprintf("%s '%s' for roles %s", ReasonNoPermission, target, strings.Join(roles, ", "))
+	return d
+}
+
+// Can reports whether the subject may perform the action on the resource.
+func (s *rbacServiceImpl) Can(subject, resource, action string) bool {
+	return s.Enforce(subject, resource, action).IsAllowed
+}
+
+// AddRole adds or replaces a role at runtime.
+func (s *rbacServiceImpl) AddRole(role Role) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	conf := s.snapshot()
+	replaced := false
+	for i, r := range conf.Roles {
+		if r.Name == role.Name {
+			conf.Roles[i] = role
+			replaced = true
+		}
+	}
+	if !replaced {
+		conf.AppendRoles(role)
+	}
+	if err := PolicyConfigValidator(conf); err != nil {
+		return err
+	}
+	s.roles[role.Name] = role
+	return nil
+}
+
+// RemoveRole removes a role that is neither inherited nor bound.
+func (s *rbacServiceImpl) RemoveRole(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, r := range s.roles {
+		for _, parent := range r.Inherits {
+			if parent == name {
+				return fmt.Errorf("Role %s is inherited by role %s", name, r.Name)
+			}
+		}
+	}
+	for subject, roles := range s.bindings {
+		for _, v := range roles {
+			if v == name {
+				return fmt.Errorf("Role %s is bound to subject %s", name, subject)
+			}
+		}
+	}
+	delete(s.roles, name)
+	return nil
+}
+
+// AssignRoles binds existing roles to the subject.
+func (s *rbacServiceImpl) AssignRoles(subject string, roles ...string) error {
+	if utils.IsEmpty(subject) {
+		return fmt.Errorf("Subject is required")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, name := range roles {
+		if _, ok := s.roles[name]; !ok {
+			return fmt.Errorf("Role %s not found", name)
+		}
+	}
+	s.bindings[subject] = appendUnique(s.bindings[subject], roles...)
+	return nil
+}
+
+// RevokeRoles unbinds the roles from the subject.
+func (s *rbacServiceImpl) RevokeRoles(subject string, roles ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var kept []string
+	for _, v := range s.bindings[subject] {
+		if !contains(roles, v) {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.bindings, subject)
+		return
+	}
+	s.bindings[subject] = kept
+}
+
+// GetRoles returns the roles bound to the subject.
+func (s *rbacServiceImpl) GetRoles(subject string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]string(nil), s.bindings[subject]...)
+}
+
+// GetEffectiveRoles returns the roles bound to the subject along with every inherited role.
+func (s *rbacServiceImpl) GetEffectiveRoles(subject string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var roles []string
+	for _, path := range s.walk(s.bindings[subject]) {
+		roles = append(roles, path[len(path)-1])
+	}
+	return roles
+}
+
+// GetPermissions returns the sorted permissions granted to the subject through its effective roles.
+func (s *rbacServiceImpl) GetPermissions(subject string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var permissions []string
+	for _, path := range s.walk(s.bindings[subject]) {
+		permissions = appendUnique(permissions, s.roles[path[len(path)-1]].Permissions...)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Policy returns the current policy, including runtime changes.
+func (s *rbacServiceImpl) Policy() PolicyConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.snapshot()
+}
+
+// walk visits the roles and their ancestors breadth first, once each,
+// returning for every role the inheritance path that reached it.
+func (s *rbacServiceImpl) walk(roles []string) [][]string {
+	var paths [][]string
+	visited := make(map[string]bool)
+	queue := make([][]string, 0, len(roles))
+	for _, name := range roles {
+		queue = append(queue, []string{name})
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		name := path[len(path)-1]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		role, ok := s.roles[name]
+		if !ok {
+			continue
+		}
+		paths = append(paths, path)
+		for _, parent := range role.Inherits {
+			next := make([]string, len(path), len(path)+1)
+			copy(next, path)
+			queue = append(queue, append(next, parent))
+		}
+	}
+	return paths
+}
+
+// snapshot rebuilds the policy from the runtime state.
+func (s *rbacServiceImpl) snapshot() PolicyConfig {
+	conf := *NewPolicyConfig().
+		SetEnabled(s.conf.IsEnabled).
+		SetDenyUnmatched(s.conf.IsDenyUnmatched).
+		SetRoutes(append([]RouteRule(nil), s.conf.Routes...))
+	names := make([]string, 0, len(s.roles))
+	for name := range s.roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conf.AppendRoles(s.roles[name])
+	}
+	subjects := make([]string, 0, len(s.bindings))
+	for subject := range s.bindings {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		conf.AppendBindings(*NewRoleBinding().SetSubject(subject).SetRoles(append([]string(nil), s.bindings[subject]...)))
+	}
+	return conf
+}
+
+// matchPermission checks the "resource:action" target against a permission pattern.
+// The pattern "*" grants everything, and "resource" alone grants every action on it.
+func matchPermission(target, pattern string) bool {
+	if pattern == Wildcard {
+		return true
+	}
+	if !strings.Contains(pattern, PermissionSeparator) {
+		pattern = Permission(pattern, Wildcard)
+	}
+	return match.Match(target, pattern)
+}
+
+func describePath(path []string) string {
+	if len(path) <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (inherited via %s)", strings.Join(path, " -> "))
+}
+
+func appendUnique(values []string, items ...string) []string {
+	for _, v := range items {
+		if !contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func contains(values []string, item string) bool {
+	for _, v := range values {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -2721,6 +2721,15 @@ func init() {
 		"fromstr": modFromStr,
 		"group":   modGroup,
 		"dig":     modDig,
+		"sort":    modSort,
+		"unique":  modUnique,
+		"sum":     modSum,
+		"avg":     modAvg,
+		"min":     modMin,
+		"max":     modMax,
+		"count":   modCount,
+		"slice":   modSlice,
+		"filter":  modFilter,
 	}
 }
 
@@ -1,5 +1,12 @@
 package bjson
 
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
 const (
 	// Null is a null json value
 	Null Type = iota
@@ -21,3 +28,129 @@ var hexCharacters = [...]byte{
 }
 var DisableModifiers = false
 var modifiers map[string]func(json, arg string) string
+
+var (
+	ErrorPathEmpty         = errors.New("Path cannot be empty")
+	ErrorNotObjectOrArray  = errors.New("Json must be an object or array")
+	ErrorDeleteComplexPath = errors.New("Cannot delete value from a complex path")
+	errorNoChange          = errors.New("No change")
+)
+
+// JSON Patch operations, RFC 6902
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// Kinds of Token
+const (
+	TokenBeginObject TokenKind = iota
+	TokenEndObject
+	TokenBeginArray
+	TokenEndArray
+	TokenKey
+	TokenValue
+)
+
+// StreamBufferSize is the initial size of the buffer of streams, grown to hold the values captured.
+var StreamBufferSize = 32 * 1024
+
+// SchemaDefaultBase is the base uri of the schemas without $id, against which relative references are resolved.
+var SchemaDefaultBase = "bjson:///"
+
+// schemaFormats are the validators of the "format" keyword, by name.
+var schemaFormats map[string]func(value string) bool
+
+// schemaTypes are the values of the "type" keyword.
+var schemaTypes = map[string]bool{"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true}
+
+// Kinds of the selectors of JsonPath
+const (
+	jsonPathName = iota
+	jsonPathWildcard
+	jsonPathIndex
+	jsonPathSlice
+	jsonPathFilter
+)
+
+// Kinds of the filter expressions of JsonPath
+const (
+	jsonPathOr = iota
+	jsonPathAnd
+	jsonPathNot
+	jsonPathCompare
+	jsonPathTest // a query, testing whether it selects any node
+	jsonPathCall
+	jsonPathLiteral
+)
+
+// Types of the function extensions of JsonPath, RFC 9535 section 2.4.1
+const (
+	jsonPathValueType = iota
+	jsonPathLogicalType
+	jsonPathNodesType
+)
+
+// jsonPathFunctions are the function extensions of JsonPath, RFC 9535 section 2.4.
+var jsonPathFunctions = map[string]jsonPathFunction{
+	"length": {Params: []int{jsonPathValueType}, Result: jsonPathValueType},
+	"count":  {Params: []int{jsonPathNodesType}, Result: jsonPathValueType},
+	"match":  {Params: []int{jsonPathValueType, jsonPathValueType}, Result: jsonPathLogicalType},
+	"search": {Params: []int{jsonPathValueType, jsonPathValueType}, Result: jsonPathLogicalType},
+	"value":  {Params: []int{jsonPathNodesType}, Result: jsonPathValueType},
+}
+
+// jsonPathMaxInt is the largest integer of JSONPath indexes and slices, as of I-JSON.
+const jsonPathMaxInt = 1<<53 - 1
+
+// Struct tags of Unmarshal
+//
+// Example:
+// --------
+// Name		string		`bjson:"data.user.name" default:"anonymous"`
+// Tags		[]string	`bjson:"data.tags.#.name"`
+// Birthday	time.Time	`bjson:"data.user.birthday" layout:"2006-01-02"`
+const (
+	UnmarshalTagName    = "bjson"   // the path of the value of the field, relative to the value of the struct
+	UnmarshalDefaultTag = "default" // the value of the field when its path is missing or null
+	UnmarshalLayoutTag  = "layout"  // the layout of time.Time fields, RFC 3339 by default
+)
+
+var (
+	typeOfBJsonContext   = reflect.TypeOf(BJsonContext{})
+	typeOfTime           = reflect.TypeOf(time.Time{})
+	typeOfJsonUnmarshal  = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	ErrorUnmarshalTarget = errors.New("Unmarshal target must be a non-nil pointer to a struct")
+	ErrorUnmarshalType   = errors.New("Cannot unmarshal value into field")
+)
+
+// Kinds of DiffChange
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+var (
+	ErrorInvalidJson           = errors.New("Invalid json")
+	ErrorPatchInvalid          = errors.New("Patch must be a json array of operations")
+	ErrorPatchOperation        = errors.New("Unknown patch operation")
+	ErrorPatchValue            = errors.New("Patch operation is missing its value")
+	ErrorPatchDuplicateMember  = errors.New("Patch operation has a duplicated member")
+	ErrorPointerInvalid        = errors.New("Json pointer must be empty or start with '/'")
+	ErrorPointerNotFound       = errors.New("Json pointer not found")
+	ErrorPointerIndex          = errors.New("Json pointer has an invalid array index")
+	ErrorPatchTestFailed       = errors.New("Test operation failed, values are not equal")
+	ErrorPatchMoveIntoSelf     = errors.New("Cannot move a value into one of its children")
+	ErrorTokenNotValue         = errors.New("Next token is not a value")
+	errorStreamStop            = errors.New("Stream stopped")
+	ErrorStreamPathUnsupported = errors.New("Stream path supports keys, indexes, '#' and wildcards only")
+	ErrorSchemaInvalid         = errors.New("Schema must be an object or a boolean")
+	ErrorSchemaCycle           = errors.New("Schema references itself without going deeper into the instance")
+	ErrorJsonPathInvalid       = errors.New("Invalid JSONPath")
+	ErrorPathInvalid           = errors.New("Path has a malformed query, selector or escape")
+)
@@ -0,0 +1,335 @@
+package bjson
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sivaosorg/govm/pretty"
+)
+
+// Diff compares both json documents, regardless of the order of the object members,
+// returning the changes turning the original into the modified one, none when they are equal.
+//
+//	Diff(`{"name":"Tom","tags":["a"]}`, `{"tags":["a","b"],"name":"Jerry"}`)
+//	>> changed name: "Tom" => "Jerry"
+//	>> added tags.1: "b"
+func Diff(original, modified string) ([]DiffChange, error) {
+	return DiffWith(original, modified, nil)
+}
+
+// DiffWith compares both json documents per the options, e.g: ignoring the order of the array elements.
+func DiffWith(original, modified string, opts *DiffOptions) ([]DiffChange, error) {
+	c, err := newDiffContext(original, modified, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.changes, nil
+}
+
+// DiffPatch generates the JSON Patch turning the original json into the modified one.
+// When the order of the array elements is ignored, the unmatched elements are removed and the new ones appended.
+func DiffPatch(original, modified string, opts *DiffOptions) (Patch, error) {
+	c, err := newDiffContext(original, modified, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.patch, nil
+}
+
+// DiffSideBySide renders both json documents pretty printed side by side, their keys sorted, marking the lines
+// that differ as `diff -y` does: '|' changed, '<' removed and '>' added.
+// The lines are colorized by the style of the options, arrays being rendered in their order.
+func DiffSideBySide(original, modified string, opts *DiffOptions) (string, error) {
+	if !Valid(original) || !Valid(modified) {
+		return "", ErrorInvalidJson
+	}
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	conf := &pretty.OptionsConfig{Width: pretty.DefaultOptionsConfig.Width, Indent: pretty.DefaultOptionsConfig.Indent, SortKeys: true}
+	left := pretty.PrettyOptions([]byte(original), conf)
+	right := pretty.PrettyOptions([]byte(modified), conf)
+	lp, rp := diffLines(left), diffLines(right)
+	lc, rc := lp, rp
+	if opts.Style != nil {
+		lc, rc = diffLines(pretty.Color(left, opts.Style)), diffLines(pretty.Color(right, opts.Style))
+	}
+	width := opts.Width
+	if width <= 0 {
+		for _, line := range lp {
+			if n := utf8.RuneCountInString(line); n > width {
+				width = n
+			}
+		}
+	}
+	var sb strings.Builder
+	for _, row := range alignLines(lp, rp) {
+		n := 0
+		if row[0] >= 0 {
+			sb.WriteString(lc[row[0]])
+			n = utf8.RuneCountInString(lp[row[0]])
+		}
+		if n < width {
+			sb.WriteString(strings.Repeat(" ", width-n))
+		}
+		var marker string
+		var color [2]string
+		switch {
+		case row[0] < 0:
+			marker, color = ">", styleOf(opts.Style).Added
+		case row[1] < 0:
+			marker, color = "<", styleOf(opts.Style).Removed
+		case strings.TrimSuffix(lp[row[0]], ",") != strings.TrimSuffix(rp[row[1]], ","):
+			marker, color = "|", styleOf(opts.Style).Changed
+		default:
+			marker = " "
+		}
+		sb.WriteString(" " + color[0] + marker + color[1])
+		if row[1] >= 0 {
+			sb.WriteString(" " + rc[row[1]])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// FormatDiff returns the changes one per line, e.g: `~ name: "Tom" => "Jerry"`.
+func FormatDiff(changes []DiffChange) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// String returns the change prefixed by its kind, '+' added, '-' removed or '~' changed, e.g: `+ tags.1: "b"`.
+func (c DiffChange) String() string {
+	switch c.Kind {
+	case DiffAdded:
+		return "+ " + c.Path + ": " + compactJson(c.After.Raw)
+	case DiffRemoved:
+		return "- " + c.Path + ": " + compactJson(c.Before.Raw)
+	}
+	return "~ " + c.Path + ": " + compactJson(c.Before.Raw) + " => " + compactJson(c.After.Raw)
+}
+
+func newDiffContext(original, modified string, opts *DiffOptions) (*diffContext, error) {
+	if !Valid(original) || !Valid(modified) {
+		return nil, ErrorInvalidJson
+	}
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	c := &diffContext{opts: opts, changes: []DiffChange{}, patch: Patch{}}
+	c.compare("", "", rootOf(original), rootOf(modified))
+	return c, nil
+}
+
+func (c *diffContext) compare(path, pointer string, a, b BJsonContext) {
+	switch {
+	case a.IsObject() && b.IsObject():
+		x, y := members(a), members(b)
+		a.ForEach(func(key, value BJsonContext) bool {
+			p, ptr := childPath(path, escapeComp(key.Strings)), pointer+"/"+EscapePointer(key.Strings)
+			if v, ok := y[key.Strings]; ok {
+				c.compare(p, ptr, value, v)
+			} else {
+				c.record(DiffRemoved, p, ptr, value, BJsonContext{})
+				c.patch = append(c.patch, PatchOperation{Op: PatchRemove, Path: ptr})
+			}
+			return true
+		})
+		b.ForEach(func(key, value BJsonContext) bool {
+			if _, ok := x[key.Strings]; !ok {
+				p, ptr := childPath(path, escapeComp(key.Strings)), pointer+"/"+EscapePointer(key.Strings)
+				c.record(DiffAdded, p, ptr, BJsonContext{}, value)
+				c.patch = append(c.patch, PatchOperation{Op: PatchAdd, Path: ptr, Value: compactJson(value.Raw)})
+			}
+			return true
+		})
+	case a.IsArray() && b.IsArray():
+		x, y := elements(a), elements(b)
+		if c.opts.IgnoreArrayOrder {
+			c.compareUnordered(path, pointer, x, y)
+			return
+		}
+		i := 0
+		for ; i < len(x) && i < len(y); i++ {
+			c.compare(childPath(path, strconv.Itoa(i)), pointer+"/"+strconv.Itoa(i), x[i], y[i])
+		}
+		for j := len(x) - 1; j >= i; j-- {
+			ptr := pointer + "/" + strconv.Itoa(j)
+			c.record(DiffRemoved, childPath(path, strconv.Itoa(j)), ptr, x[j], BJsonContext{})
+			c.patch = append(c.patch, PatchOperation{Op: PatchRemove, Path: ptr})
+		}
+		for ; i < len(y); i++ {
+			ptr := pointer + "/" + strconv.Itoa(i)
+			c.record(DiffAdded, childPath(path, strconv.Itoa(i)), ptr, BJsonContext{}, y[i])
+			c.patch = append(c.patch, PatchOperation{Op: PatchAdd, Path: ptr, Value: compactJson(y[i].Raw)})
+		}
+	case !equalJsonWith(a, b, c.opts.IgnoreArrayOrder):
+		c.record(DiffChanged, path, pointer, a, b)
+		c.patch = append(c.patch, PatchOperation{Op: PatchReplace, Path: pointer, Value: compactJson(b.Raw)})
+	}
+}
+
+// compareUnordered reports the elements of the original array without an equal one in the modified array as removed,
+// and the elements left in the modified array as added.
+func (c *diffContext) compareUnordered(path, pointer string, x, y []BJsonContext) {
+	removed := matchElements(x, y, true)
+	added := make([]bool, len(y))
+	for _, j := range matchElements(y, x, true) {
+		added[j] = true
+	}
+	for _, i := range removed {
+		c.record(DiffRemoved, childPath(path, strconv.Itoa(i)), pointer+"/"+strconv.Itoa(i), x[i], BJsonContext{})
+	}
+	for k := len(removed) - 1; k >= 0; k-- {
+		c.patch = append(c.patch, PatchOperation{Op: PatchRemove, Path: pointer + "/" + strconv.Itoa(removed[k])})
+	}
+	for j := range y {
+		if added[j] {
+			c.record(DiffAdded, childPath(path, strconv.Itoa(j)), pointer+"/"+strconv.Itoa(j), BJsonContext{}, y[j])
+			c.patch = append(c.patch, PatchOperation{Op: PatchAdd, Path: pointer + "/-", Value: compactJson(y[j].Raw)})
+		}
+	}
+}
+
+func (c *diffContext) record(kind, path, pointer string, before, after BJsonContext) {
+	if path == "" {
+		path = "@this"
+	}
+	c.changes = append(c.changes, DiffChange{Kind: kind, Path: path, Pointer: pointer, Before: before, After: after})
+}
+
+// matchElements pairs each element of x with an equal element of y, returning the indexes of x left unmatched.
+func matchElements(x, y []BJsonContext, unordered bool) []int {
+	used := make([]bool, len(y))
+	var unmatched []int
+	for i := range x {
+		matched := false
+		for j := range y {
+			if !used[j] && equalJsonWith(x[i], y[j], unordered) {
+				used[j], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, i)
+		}
+	}
+	return unmatched
+}
+
+// elements returns the elements of the array, their Index the offset in the document.
+func elements(t BJsonContext) []BJsonContext {
+	var values []BJsonContext
+	t.ForEach(func(_, value BJsonContext) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// members returns the first member of the object for each key, their Index the offset in the document.
+func members(t BJsonContext) map[string]BJsonContext {
+	values := make(map[string]BJsonContext)
+	t.ForEach(func(key, value BJsonContext) bool {
+		if _, ok := values[key.Strings]; !ok {
+			values[key.Strings] = value
+		}
+		return true
+	})
+	return values
+}
+
+func childPath(path, component string) string {
+	if path == "" {
+		return component
+	}
+	return path + "." + component
+}
+
+func diffLines(json []byte) []string {
+	return strings.Split(strings.TrimRight(string(json), "\n"), "\n")
+}
+
+// alignLines pairs the lines of both sides along their longest common subsequence, -1 on the side missing,
+// the lines removed and added in between being paired as changed. Trailing commas are ignored.
+func alignLines(a, b []string) [][2]int {
+	key := func(line string) string {
+		return strings.TrimSuffix(line, ",")
+	}
+	// the common leading and trailing lines are left out of the table
+	p, q := 0, 0
+	for p < len(a) && p < len(b) && key(a[p]) == key(b[p]) {
+		p++
+	}
+	for q < len(a)-p && q < len(b)-p && key(a[len(a)-1-q]) == key(b[len(b)-1-q]) {
+		q++
+	}
+	n, m := len(a)-q, len(b)-q
+	// lcs[i-p][j-p] is the length of the longest common subsequence of a[i:n] and b[j:m]
+	lcs := make([][]int, n-p+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m-p+1)
+	}
+	for i := n - 1; i >= p; i-- {
+		for j := m - 1; j >= p; j-- {
+			if key(a[i]) == key(b[j]) {
+				lcs[i-p][j-p] = lcs[i-p+1][j-p+1] + 1
+			} else if lcs[i-p+1][j-p] >= lcs[i-p][j-p+1] {
+				lcs[i-p][j-p] = lcs[i-p+1][j-p]
+			} else {
+				lcs[i-p][j-p] = lcs[i-p][j-p+1]
+			}
+		}
+	}
+	var rows [][2]int
+	var removed, added []int
+	flush := func() {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			row := [2]int{-1, -1}
+			if k < len(removed) {
+				row[0] = removed[k]
+			}
+			if k < len(added) {
+				row[1] = added[k]
+			}
+			rows = append(rows, row)
+		}
+		removed, added = removed[:0], added[:0]
+	}
+	for i := 0; i < p; i++ {
+		rows = append(rows, [2]int{i, i})
+	}
+	i, j := p, p
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && key(a[i]) == key(b[j]):
+			flush()
+			rows = append(rows, [2]int{i, j})
+			i, j = i+1, j+1
+		case j == m || (i < n && lcs[i-p+1][j-p] >= lcs[i-p][j-p+1]):
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+	flush()
+	for k := q; k > 0; k-- {
+		rows = append(rows, [2]int{len(a) - k, len(b) - k})
+	}
+	return rows
+}
+
+func styleOf(style *pretty.Style) *pretty.Style {
+	if style == nil {
+		return &pretty.Style{}
+	}
+	return style
+}
@@ -0,0 +1,1039 @@
+package bjson
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// CompileJsonPath compiles a JSONPath query, RFC 9535, e.g: "$.store.book[?@.price < 10].title".
+func CompileJsonPath(expr string) (*JsonPath, error) {
+	p := &jsonPathParser{expr: expr}
+	if !p.peek('$') {
+		return nil, p.errorf("query must start with '$'")
+	}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(expr) {
+		return nil, p.unexpected()
+	}
+	return &JsonPath{expr: expr, query: q}, nil
+}
+
+// MustCompileJsonPath compiles a JSONPath query, panicking when it is invalid.
+func MustCompileJsonPath(expr string) *JsonPath {
+	path, err := CompileJsonPath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// QueryJsonPath evaluates the JSONPath query, RFC 9535, returning the values selected in order,
+// their Index the offset of the value in the json.
+func QueryJsonPath(json, expr string) ([]BJsonContext, error) {
+	path, err := CompileJsonPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.Query(json), nil
+}
+
+// Query returns the values selected in the json, in order, their Index the offset of the value in the json.
+func (p *JsonPath) Query(json string) []BJsonContext {
+	nodes := p.eval(json, false)
+	values := make([]BJsonContext, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.Value
+	}
+	return values
+}
+
+// QueryBytes returns the values selected in the json, see Query.
+func (p *JsonPath) QueryBytes(json []byte) []BJsonContext {
+	return p.Query(string(json))
+}
+
+// Nodes returns the values selected in the json, in order, along with their normalized paths, e.g: "$['store']['book'][0]".
+func (p *JsonPath) Nodes(json string) []JsonPathNode {
+	return p.eval(json, true)
+}
+
+// String returns the query as compiled.
+func (p *JsonPath) String() string {
+	return p.expr
+}
+
+// IsSingular checks whether the query selects at most one value, being made of names and indexes only.
+func (p *JsonPath) IsSingular() bool {
+	return p.query.isSingular()
+}
+
+// BJsonPath converts a singular query to a path of Get, e.g: "$.friends[1].last" >> "friends.1.last",
+// false when it cannot be, such as queries with wildcards, slices, filters, descendants or negative indexes.
+// Note that Get reads the names made of digits as indexes of arrays as well.
+func (p *JsonPath) BJsonPath() (string, bool) {
+	if !p.query.isSingular() {
+		return "", false
+	}
+	if len(p.query.Segments) == 0 {
+		return "@this", true
+	}
+	parts := make([]string, 0, len(p.query.Segments))
+	for _, seg := range p.query.Segments {
+		sel := seg.Selectors[0]
+		if sel.Kind == jsonPathIndex {
+			if sel.Index < 0 {
+				return "", false
+			}
+			parts = append(parts, strconv.Itoa(sel.Index))
+			continue
+		}
+		if sel.Name == "" {
+			return "", false
+		}
+		parts = append(parts, escapeComp(sel.Name))
+	}
+	return strings.Join(parts, "."), true
+}
+
+func (p *JsonPath) eval(json string, paths bool) []JsonPathNode {
+	root := rootOf(json)
+	if !root.Exists() {
+		return nil
+	}
+	start := JsonPathNode{Value: root}
+	if paths {
+		start.Path = "$"
+	}
+	return p.query.eval(root, start, paths)
+}
+
+func (q *jsonPathQuery) eval(root BJsonContext, start JsonPathNode, paths bool) []JsonPathNode {
+	nodes := []JsonPathNode{start}
+	for i := range q.Segments {
+		seg := &q.Segments[i]
+		var next []JsonPathNode
+		for _, n := range nodes {
+			if seg.Descendant {
+				next = seg.descend(root, n, paths, next)
+			} else {
+				next = seg.apply(root, n, paths, next)
+			}
+		}
+		if nodes = next; len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+// selectFrom evaluates the query of a filter from the root, or the current node when relative.
+func (q *jsonPathQuery) selectFrom(root, current BJsonContext) []JsonPathNode {
+	start := root
+	if q.Relative {
+		start = current
+	}
+	return q.eval(root, JsonPathNode{Value: start}, false)
+}
+
+func (q *jsonPathQuery) isSingular() bool {
+	for _, seg := range q.Segments {
+		if seg.Descendant || len(seg.Selectors) != 1 {
+			return false
+		}
+		if k := seg.Selectors[0].Kind; k != jsonPathName && k != jsonPathIndex {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *jsonPathSegment) apply(root BJsonContext, node JsonPathNode, paths bool, out []JsonPathNode) []JsonPathNode {
+	for i := range s.Selectors {
+		out = s.Selectors[i].apply(root, node, paths, out)
+	}
+	return out
+}
+
+// descend applies the selectors to the node, then to its descendants in document order.
+func (s *jsonPathSegment) descend(root BJsonContext, node JsonPathNode, paths bool, out []JsonPathNode) []JsonPathNode {
+	out = s.apply(root, node, paths, out)
+	for _, child := range jsonPathChildren(node, paths) {
+		out = s.descend(root, child, paths, out)
+	}
+	return out
+}
+
+func (s *jsonPathSelector) apply(root BJsonContext, node JsonPathNode, paths bool, out []JsonPathNode) []JsonPathNode {
+	v := node.Value
+	switch s.Kind {
+	case jsonPathName:
+		if v.IsObject() {
+			if m := objectMember(v, s.Name); m.Found {
+				n := JsonPathNode{Value: m.Value}
+				if paths {
+					n.Path = node.Path + "['" + escapeJsonPathName(s.Name) + "']"
+				}
+				out = append(out, n)
+			}
+		}
+	case jsonPathWildcard:
+		out = append(out, jsonPathChildren(node, paths)...)
+	case jsonPathIndex:
+		if v.IsArray() {
+			children := jsonPathChildren(node, paths)
+			if i := normalizeJsonPathIndex(s.Index, len(children)); i >= 0 && i < len(children) {
+				out = append(out, children[i])
+			}
+		}
+	case jsonPathSlice:
+		if v.IsArray() {
+			children := jsonPathChildren(node, paths)
+			for _, i := range s.sliceIndexes(len(children)) {
+				out = append(out, children[i])
+			}
+		}
+	case jsonPathFilter:
+		for _, child := range jsonPathChildren(node, paths) {
+			if s.Filter.test(root, child.Value) {
+				out = append(out, child)
+			}
+		}
+	}
+	return out
+}
+
+// sliceIndexes returns the indexes selected by the slice in an array of the length, RFC 9535 section 2.3.4.2.2.
+func (s *jsonPathSelector) sliceIndexes(length int) []int {
+	step := s.Step
+	if step == 0 {
+		return nil
+	}
+	start, end := 0, length
+	if step < 0 {
+		start, end = length-1, -length-1
+	}
+	if s.HasStart {
+		start = s.Index
+	}
+	if s.HasEnd {
+		end = s.End
+	}
+	start, end = normalizeJsonPathIndex(start, length), normalizeJsonPathIndex(end, length)
+	var indexes []int
+	if step > 0 {
+		lower, upper := clampJsonPathIndex(start, 0, length), clampJsonPathIndex(end, 0, length)
+		for i := lower; i < upper; i += step {
+			indexes = append(indexes, i)
+		}
+		return indexes
+	}
+	upper, lower := clampJsonPathIndex(start, -1, length-1), clampJsonPathIndex(end, -1, length-1)
+	for i := upper; lower < i; i += step {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// test evaluates a logical expression of a filter against the current node.
+func (e *jsonPathExpr) test(root, current BJsonContext) bool {
+	switch e.Kind {
+	case jsonPathOr:
+		return e.Args[0].test(root, current) || e.Args[1].test(root, current)
+	case jsonPathAnd:
+		return e.Args[0].test(root, current) && e.Args[1].test(root, current)
+	case jsonPathNot:
+		return !e.Args[0].test(root, current)
+	case jsonPathCompare:
+		a, aok := e.Args[0].value(root, current)
+		b, bok := e.Args[1].value(root, current)
+		return compareJsonPath(e.Op, a, aok, b, bok)
+	case jsonPathTest:
+		return len(e.Query.selectFrom(root, current)) > 0
+	case jsonPathCall:
+		switch e.Function {
+		case "match", "search":
+			s, ok := e.Args[0].value(root, current)
+			pattern, pok := e.Args[1].value(root, current)
+			if !ok || !pok || s.Type != String || pattern.Type != String {
+				return false
+			}
+			re := e.Pattern
+			if re == nil {
+				if re = compileIRegexp(pattern.Strings, e.Function == "match"); re == nil {
+					return false
+				}
+			}
+			return re.MatchString(s.Strings)
+		}
+	}
+	return false
+}
+
+// value evaluates a comparable of a filter against the current node, false when it is Nothing.
+func (e *jsonPathExpr) value(root, current BJsonContext) (BJsonContext, bool) {
+	switch e.Kind {
+	case jsonPathLiteral:
+		return e.Literal, true
+	case jsonPathTest:
+		if nodes := e.Query.selectFrom(root, current); len(nodes) == 1 {
+			return nodes[0].Value, true
+		}
+	case jsonPathCall:
+		switch e.Function {
+		case "length":
+			v, ok := e.Args[0].value(root, current)
+			if !ok {
+				break
+			}
+			switch {
+			case v.Type == String:
+				return Parse(strconv.Itoa(utf8.RuneCountInString(v.Strings))), true
+			case v.IsArray() || v.IsObject():
+				n := 0
+				v.ForEach(func(_, _ BJsonContext) bool {
+					n++
+					return true
+				})
+				return Parse(strconv.Itoa(n)), true
+			}
+		case "count":
+			return Parse(strconv.Itoa(len(e.Args[0].Query.selectFrom(root, current)))), true
+		case "value":
+			if nodes := e.Args[0].Query.selectFrom(root, current); len(nodes) == 1 {
+				return nodes[0].Value, true
+			}
+		}
+	}
+	return BJsonContext{}, false
+}
+
+// compareJsonPath compares the values, RFC 9535 section 2.3.5.2.2, Nothing being only equal to Nothing.
+func compareJsonPath(op string, a BJsonContext, aok bool, b BJsonContext, bok bool) bool {
+	switch op {
+	case "==":
+		return equalJsonPath(a, aok, b, bok)
+	case "!=":
+		return !equalJsonPath(a, aok, b, bok)
+	case "<":
+		return lessJsonPath(a, aok, b, bok)
+	case "<=":
+		return lessJsonPath(a, aok, b, bok) || equalJsonPath(a, aok, b, bok)
+	case ">":
+		return lessJsonPath(b, bok, a, aok)
+	case ">=":
+		return lessJsonPath(b, bok, a, aok) || equalJsonPath(a, aok, b, bok)
+	}
+	return false
+}
+
+func equalJsonPath(a BJsonContext, aok bool, b BJsonContext, bok bool) bool {
+	if !aok || !bok {
+		return !aok && !bok
+	}
+	return equalJson(a, b)
+}
+
+// lessJsonPath orders numbers, and strings by their code points, any other values being unordered.
+func lessJsonPath(a BJsonContext, aok bool, b BJsonContext, bok bool) bool {
+	switch {
+	case !aok || !bok:
+		return false
+	case a.Type == Number && b.Type == Number:
+		return a.Numeric < b.Numeric
+	case a.Type == String && b.Type == String:
+		return a.Strings < b.Strings
+	}
+	return false
+}
+
+// jsonPathChildren returns the member values of an object, or the elements of an array, in document order.
+func jsonPathChildren(node JsonPathNode, paths bool) []JsonPathNode {
+	v := node.Value
+	if !v.IsObject() && !v.IsArray() {
+		return nil
+	}
+	array := v.IsArray()
+	var children []JsonPathNode
+	v.ForEach(func(key, value BJsonContext) bool {
+		n := JsonPathNode{Value: value}
+		if paths {
+			if array {
+				n.Path = node.Path + "[" + strconv.Itoa(len(children)) + "]"
+			} else {
+				n.Path = node.Path + "['" + escapeJsonPathName(key.Strings) + "']"
+			}
+		}
+		children = append(children, n)
+		return true
+	})
+	return children
+}
+
+func normalizeJsonPathIndex(i, length int) int {
+	if i >= 0 {
+		return i
+	}
+	return length + i
+}
+
+func clampJsonPathIndex(i, lower, upper int) int {
+	if i < lower {
+		return lower
+	}
+	if i > upper {
+		return upper
+	}
+	return i
+}
+
+// escapeJsonPathName escapes a member name of a normalized path, RFC 9535 section 2.7, e.g: "it's" >> "it\'s".
+func escapeJsonPathName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 0x20 && c != '\'' && c != '\\' {
+			if b.Len() > 0 {
+				b.WriteByte(c)
+			}
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString(name[:i])
+		}
+		switch c {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteString(`\u00`)
+			b.WriteByte(hexCharacters[c>>4])
+			b.WriteByte(hexCharacters[c&0xf])
+		}
+	}
+	if b.Len() == 0 {
+		return name
+	}
+	return b.String()
+}
+
+// compileIRegexp compiles an I-Regexp, RFC 9485, matching the whole string when full, nil when it is invalid.
+func compileIRegexp(pattern string, full bool) *regexp.Regexp {
+	var b strings.Builder
+	class := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			c = pattern[i]
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '.' && !class:
+			// the dot of I-Regexp matches any character but line breaks
+			b.WriteString(`[^\n\r]`)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	expr := b.String()
+	if full {
+		expr = `\A(?:` + expr + `)\z`
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w, %s at offset %d", ErrorJsonPathInvalid, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *jsonPathParser) unexpected() error {
+	if p.pos >= len(p.expr) {
+		return p.errorf("unexpected end")
+	}
+	r, _ := utf8.DecodeRuneInString(p.expr[p.pos:])
+	return p.errorf("unexpected '%c'", r)
+}
+
+func (p *jsonPathParser) peek(c byte) bool {
+	return p.pos < len(p.expr) && p.expr[p.pos] == c
+}
+
+func (p *jsonPathParser) peekString(s string) bool {
+	return strings.HasPrefix(p.expr[p.pos:], s)
+}
+
+func (p *jsonPathParser) peekDigit() bool {
+	return p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9'
+}
+
+func (p *jsonPathParser) skipBlank() {
+	for p.pos < len(p.expr) {
+		switch p.expr[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonPathParser) skipDigits() {
+	for p.peekDigit() {
+		p.pos++
+	}
+}
+
+// parseQuery parses a query from its identifier, '$' or '@', followed by its segments.
+func (p *jsonPathParser) parseQuery() (*jsonPathQuery, error) {
+	q := &jsonPathQuery{Relative: p.expr[p.pos] == '@'}
+	p.pos++
+	for {
+		start := p.pos
+		p.skipBlank()
+		if !p.peek('[') && !p.peek('.') {
+			p.pos = start
+			return q, nil
+		}
+		seg, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		q.Segments = append(q.Segments, seg)
+	}
+}
+
+func (p *jsonPathParser) parseSegment() (jsonPathSegment, error) {
+	var seg jsonPathSegment
+	var err error
+	if p.peek('[') {
+		seg.Selectors, err = p.parseSelectors()
+		return seg, err
+	}
+	p.pos++
+	if p.peek('.') {
+		p.pos++
+		seg.Descendant = true
+		if p.peek('[') {
+			seg.Selectors, err = p.parseSelectors()
+			return seg, err
+		}
+	}
+	if p.peek('*') {
+		p.pos++
+		seg.Selectors = []jsonPathSelector{{Kind: jsonPathWildcard}}
+		return seg, nil
+	}
+	name, ok := p.parseMemberName()
+	if !ok {
+		return seg, p.unexpected()
+	}
+	seg.Selectors = []jsonPathSelector{{Kind: jsonPathName, Name: name}}
+	return seg, nil
+}
+
+// parseMemberName parses the name of a member in shorthand, e.g: ".store".
+func (p *jsonPathParser) parseMemberName() (string, bool) {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(r >= 0x80 && size > 1) || (p.pos > start && r >= '0' && r <= '9') {
+			p.pos += size
+			continue
+		}
+		break
+	}
+	return p.expr[start:p.pos], p.pos > start
+}
+
+// parseSelectors parses a bracketed selection, e.g: "['a', 0, 1:3, *, ?@.b]".
+func (p *jsonPathParser) parseSelectors() ([]jsonPathSelector, error) {
+	p.pos++
+	var selectors []jsonPathSelector
+	for {
+		p.skipBlank()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+		p.skipBlank()
+		switch {
+		case p.peek(','):
+			p.pos++
+		case p.peek(']'):
+			p.pos++
+			return selectors, nil
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *jsonPathParser) parseSelector() (jsonPathSelector, error) {
+	switch {
+	case p.peek('\'') || p.peek('"'):
+		name, err := p.parseString()
+		return jsonPathSelector{Kind: jsonPathName, Name: name}, err
+	case p.peek('*'):
+		p.pos++
+		return jsonPathSelector{Kind: jsonPathWildcard}, nil
+	case p.peek('?'):
+		p.pos++
+		p.skipBlank()
+		e, err := p.parseOr()
+		return jsonPathSelector{Kind: jsonPathFilter, Filter: e}, err
+	}
+	sel := jsonPathSelector{Kind: jsonPathIndex, Step: 1}
+	var err error
+	if !p.peek(':') {
+		if sel.Index, err = p.parseInt(); err != nil {
+			return sel, err
+		}
+		sel.HasStart = true
+		start := p.pos
+		if p.skipBlank(); !p.peek(':') {
+			p.pos = start
+			return sel, nil
+		}
+	}
+	sel.Kind = jsonPathSlice
+	p.pos++
+	p.skipBlank()
+	if p.peek('-') || p.peekDigit() {
+		if sel.End, err = p.parseInt(); err != nil {
+			return sel, err
+		}
+		sel.HasEnd = true
+		p.skipBlank()
+	}
+	if p.peek(':') {
+		p.pos++
+		p.skipBlank()
+		if p.peek('-') || p.peekDigit() {
+			sel.Step, err = p.parseInt()
+		}
+	}
+	return sel, err
+}
+
+// parseInt parses an integer of indexes and slices, without leading zeros, within the range of I-JSON.
+func (p *jsonPathParser) parseInt() (int, error) {
+	start := p.pos
+	if p.peek('-') {
+		p.pos++
+	}
+	digits := p.pos
+	p.skipDigits()
+	if p.pos == digits || (p.expr[digits] == '0' && (p.pos-digits > 1 || digits > start)) {
+		p.pos = start
+		return 0, p.errorf("invalid integer")
+	}
+	n, err := strconv.ParseInt(p.expr[start:p.pos], 10, 64)
+	if err != nil || n > jsonPathMaxInt || n < -jsonPathMaxInt {
+		p.pos = start
+		return 0, p.errorf("integer out of range")
+	}
+	return int(n), nil
+}
+
+// parseString parses a string literal, in single or double quotes.
+func (p *jsonPathParser) parseString() (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+	var b []byte
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return string(b), nil
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		case c != '\\':
+			b = append(b, c)
+			p.pos++
+			continue
+		}
+		if p.pos++; p.pos >= len(p.expr) {
+			break
+		}
+		switch c = p.expr[p.pos]; c {
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case '/', '\\', quote:
+			b = append(b, c)
+		case 'u':
+			r, err := p.parseUnicode()
+			if err != nil {
+				return "", err
+			}
+			b = utf8.AppendRune(b, r)
+			continue
+		default:
+			return "", p.errorf("invalid escape '\\%c'", c)
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseUnicode parses the escape of a character, e.g: "u00e9", or a surrogate pair, e.g: "uD83D\\uDE00".
+func (p *jsonPathParser) parseUnicode() (rune, error) {
+	r, ok := p.hex4(p.pos + 1)
+	if !ok {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 5
+	if utf16.IsSurrogate(r) {
+		if r >= 0xdc00 || !p.peekString(`\u`) {
+			return 0, p.errorf("lone surrogate")
+		}
+		low, ok := p.hex4(p.pos + 2)
+		if !ok || low < 0xdc00 || low > 0xdfff {
+			return 0, p.errorf("lone surrogate")
+		}
+		p.pos += 6
+		r = utf16.DecodeRune(r, low)
+	}
+	return r, nil
+}
+
+func (p *jsonPathParser) hex4(at int) (rune, bool) {
+	if at+4 > len(p.expr) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(p.expr[at:at+4], 16, 32)
+	return rune(n), err == nil
+}
+
+func (p *jsonPathParser) parseOr() (*jsonPathExpr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		start := p.pos
+		if p.skipBlank(); !p.peekString("||") {
+			p.pos = start
+			return e, nil
+		}
+		p.pos += 2
+		p.skipBlank()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		e = &jsonPathExpr{Kind: jsonPathOr, Args: []*jsonPathExpr{e, right}}
+	}
+}
+
+func (p *jsonPathParser) parseAnd() (*jsonPathExpr, error) {
+	e, err := p.parseBasic()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		start := p.pos
+		if p.skipBlank(); !p.peekString("&&") {
+			p.pos = start
+			return e, nil
+		}
+		p.pos += 2
+		p.skipBlank()
+		right, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		e = &jsonPathExpr{Kind: jsonPathAnd, Args: []*jsonPathExpr{e, right}}
+	}
+}
+
+// parseBasic parses a negation, an expression in parentheses, a comparison or a test.
+func (p *jsonPathParser) parseBasic() (*jsonPathExpr, error) {
+	if p.peek('!') {
+		p.pos++
+		p.skipBlank()
+		var e *jsonPathExpr
+		var err error
+		if p.peek('(') {
+			e, err = p.parseParen()
+		} else {
+			start := p.pos
+			if e, err = p.parseOperand(); err == nil {
+				err = p.checkTest(e, start)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathExpr{Kind: jsonPathNot, Args: []*jsonPathExpr{e}}, nil
+	}
+	if p.peek('(') {
+		return p.parseParen()
+	}
+	start := p.pos
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	end := p.pos
+	p.skipBlank()
+	op := p.parseComparisonOp()
+	if op == "" {
+		p.pos = end
+		return left, p.checkTest(left, start)
+	}
+	if err := p.checkComparable(left, start); err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	start = p.pos
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkComparable(right, start); err != nil {
+		return nil, err
+	}
+	return &jsonPathExpr{Kind: jsonPathCompare, Op: op, Args: []*jsonPathExpr{left, right}}, nil
+}
+
+func (p *jsonPathParser) parseParen() (*jsonPathExpr, error) {
+	p.pos++
+	p.skipBlank()
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); !p.peek(')') {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	return e, nil
+}
+
+func (p *jsonPathParser) parseComparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.peekString(op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// parseOperand parses a query, a function call or a literal.
+func (p *jsonPathParser) parseOperand() (*jsonPathExpr, error) {
+	if p.pos >= len(p.expr) {
+		return nil, p.unexpected()
+	}
+	switch c := p.expr[p.pos]; {
+	case c == '@' || c == '$':
+		q, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathExpr{Kind: jsonPathTest, Query: q}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		literal := BJsonContext{Type: String, Raw: string(AppendJsonString(nil, s)), Strings: s}
+		return &jsonPathExpr{Kind: jsonPathLiteral, Literal: literal}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.expr) {
+			c = p.expr[p.pos]
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+				break
+			}
+			p.pos++
+		}
+		name := p.expr[start:p.pos]
+		if p.peek('(') {
+			return p.parseCall(name, start)
+		}
+		switch name {
+		case "true", "false", "null":
+			return &jsonPathExpr{Kind: jsonPathLiteral, Literal: Parse(name)}, nil
+		}
+		p.pos = start
+		return nil, p.errorf("unknown name '%s'", name)
+	}
+	return nil, p.unexpected()
+}
+
+// parseNumber parses a number literal, e.g: "-1.5e3".
+func (p *jsonPathParser) parseNumber() (*jsonPathExpr, error) {
+	start := p.pos
+	if p.peek('-') {
+		p.pos++
+	}
+	digits := p.pos
+	p.skipDigits()
+	valid := p.pos > digits && (p.expr[digits] != '0' || p.pos-digits == 1)
+	if valid && p.peek('.') {
+		p.pos++
+		frac := p.pos
+		p.skipDigits()
+		valid = p.pos > frac
+	}
+	if valid && (p.peek('e') || p.peek('E')) {
+		if p.pos++; p.peek('+') || p.peek('-') {
+			p.pos++
+		}
+		exp := p.pos
+		p.skipDigits()
+		valid = p.pos > exp
+	}
+	if !valid {
+		p.pos = start
+		return nil, p.errorf("invalid number")
+	}
+	return &jsonPathExpr{Kind: jsonPathLiteral, Literal: Parse(p.expr[start:p.pos])}, nil
+}
+
+// parseCall parses the call of a function extension, checking the types of its arguments, RFC 9535 section 2.4.3.
+func (p *jsonPathParser) parseCall(name string, start int) (*jsonPathExpr, error) {
+	fn, ok := jsonPathFunctions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function '%s'", name)
+	}
+	p.pos++
+	e := &jsonPathExpr{Kind: jsonPathCall, Function: name}
+	if p.skipBlank(); !p.peek(')') {
+		for {
+			at := p.pos
+			arg, err := p.parseArgument()
+			if err != nil {
+				return nil, err
+			}
+			if len(e.Args) == len(fn.Params) {
+				p.pos = at
+				return nil, p.errorf("too many arguments to function '%s'", name)
+			}
+			if err := p.checkArgument(arg, fn.Params[len(e.Args)], at); err != nil {
+				return nil, err
+			}
+			e.Args = append(e.Args, arg)
+			if p.skipBlank(); !p.peek(',') {
+				break
+			}
+			p.pos++
+			p.skipBlank()
+		}
+	}
+	if !p.peek(')') {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	if len(e.Args) != len(fn.Params) {
+		p.pos = start
+		return nil, p.errorf("function '%s' expects %d arguments", name, len(fn.Params))
+	}
+	if arg := e.Args[len(e.Args)-1]; (name == "match" || name == "search") && arg.Kind == jsonPathLiteral && arg.Literal.Type == String {
+		e.Pattern = compileIRegexp(arg.Literal.Strings, name == "match")
+	}
+	return e, nil
+}
+
+// parseArgument parses an argument of a function: a literal, a query or a function call on its own,
+// or a logical expression.
+func (p *jsonPathParser) parseArgument() (*jsonPathExpr, error) {
+	start := p.pos
+	if arg, err := p.parseOperand(); err == nil {
+		end := p.pos
+		if p.skipBlank(); p.peek(',') || p.peek(')') {
+			p.pos = end
+			return arg, nil
+		}
+	}
+	p.pos = start
+	return p.parseOr()
+}
+
+// checkTest checks that the operand, not being compared, is a query or a function of LogicalType or NodesType.
+func (p *jsonPathParser) checkTest(e *jsonPathExpr, at int) error {
+	switch {
+	case e.Kind == jsonPathLiteral:
+		p.pos = at
+		return p.errorf("literal must be compared")
+	case e.Kind == jsonPathCall && jsonPathFunctions[e.Function].Result == jsonPathValueType:
+		p.pos = at
+		return p.errorf("result of function '%s' must be compared", e.Function)
+	}
+	return nil
+}
+
+// checkComparable checks that the operand of a comparison is a literal, a singular query or a function of ValueType.
+func (p *jsonPathParser) checkComparable(e *jsonPathExpr, at int) error {
+	switch {
+	case e.Kind == jsonPathTest && !e.Query.isSingular():
+		p.pos = at
+		return p.errorf("query compared must be singular")
+	case e.Kind == jsonPathCall && jsonPathFunctions[e.Function].Result != jsonPathValueType:
+		p.pos = at
+		return p.errorf("result of function '%s' cannot be compared", e.Function)
+	}
+	return nil
+}
+
+// checkArgument checks that the argument of a function is well-typed for its parameter.
+func (p *jsonPathParser) checkArgument(e *jsonPathExpr, param int, at int) error {
+	var ok bool
+	switch param {
+	case jsonPathValueType:
+		ok = e.Kind == jsonPathLiteral ||
+			(e.Kind == jsonPathTest && e.Query.isSingular()) ||
+			(e.Kind == jsonPathCall && jsonPathFunctions[e.Function].Result == jsonPathValueType)
+	case jsonPathNodesType:
+		ok = e.Kind == jsonPathTest ||
+			(e.Kind == jsonPathCall && jsonPathFunctions[e.Function].Result == jsonPathNodesType)
+	case jsonPathLogicalType:
+		ok = e.Kind != jsonPathLiteral &&
+			(e.Kind != jsonPathCall || jsonPathFunctions[e.Function].Result != jsonPathValueType)
+	}
+	if !ok {
+		p.pos = at
+		return p.errorf("argument is not well-typed")
+	}
+	return nil
+}
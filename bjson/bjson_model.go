@@ -1,6 +1,12 @@
 package bjson
 
-import "unsafe"
+import (
+	"io"
+	"regexp"
+	"unsafe"
+
+	"github.com/sivaosorg/govm/pretty"
+)
 
 type Type int
 
@@ -70,3 +76,304 @@ type sliceHeader struct {
 	length   int            `json:"-"`
 	capacity int            `json:"-"`
 }
+
+// SetOptions tunes Set and its variants.
+type SetOptions struct {
+	// Optimistic assumes that the value exists, setting it without building the path, e.g: when replacing in loops.
+	Optimistic bool `json:"optimistic"`
+	// ReplaceInPlace reuses the byte slice given when the new value fits in it; only the byte-slice variants honor it.
+	ReplaceInPlace bool `json:"replace_in_place"`
+}
+
+type setPathContext struct {
+	Part  string `json:"-"` // the key, unescaped
+	GPart string `json:"-"` // the key as a Get path
+	Path  string `json:"-"` // the remaining path
+	Force bool   `json:"-"` // the key is forced to an object key, e.g: ":1"
+	More  bool   `json:"-"`
+}
+
+// deleteValue marks a value to be deleted by Set.
+type deleteValue struct{}
+
+// PatchOperation is an operation of a JSON Patch document, RFC 6902.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`           // the json pointer of the target, RFC 6901
+	From  string `json:"from,omitempty"` // the json pointer of the source of move and copy
+	Value string `json:"value"`          // the raw json value of add, replace and test
+}
+
+// Patch is a JSON Patch document, the operations applied in sequence.
+type Patch []PatchOperation
+
+// PatchError reports the operation of a JSON Patch that failed.
+type PatchError struct {
+	Index int    `json:"index"` // the index of the operation in the patch, from 0
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Err   error  `json:"-"`
+}
+
+// pointerMember is a value located by a json pointer, along with its key when the parent is an object.
+type pointerMember struct {
+	Token  string       `json:"-"` // the last token of the pointer, unescaped
+	Parent BJsonContext `json:"-"`
+	Key    BJsonContext `json:"-"`
+	Value  BJsonContext `json:"-"`
+	Found  bool         `json:"-"`
+}
+
+// DiffChange is a change between two json documents.
+type DiffChange struct {
+	Kind    string       `json:"kind"`    // DiffAdded, DiffRemoved or DiffChanged
+	Path    string       `json:"path"`    // the path in BJsonContext.Path notation, e.g: "friends.1.last", "@this" for the root
+	Pointer string       `json:"pointer"` // the json pointer, RFC 6901, e.g: "/friends/1/last"
+	Before  BJsonContext `json:"before"`  // the value in the original document, its Index the offset of the value
+	After   BJsonContext `json:"after"`   // the value in the modified document, its Index the offset of the value
+}
+
+// DiffOptions tunes Diff and its renderings.
+type DiffOptions struct {
+	// IgnoreArrayOrder compares arrays as multisets, their elements matched regardless of their position.
+	IgnoreArrayOrder bool `json:"ignore_array_order"`
+	// Width is the width of the columns of side by side renderings, the widest line of the original document by default.
+	Width int `json:"width"`
+	// Style colorizes side by side renderings, plain text when nil, e.g: pretty.TerminalStyle.
+	Style *pretty.Style `json:"-"`
+}
+
+type diffContext struct {
+	opts    *DiffOptions `json:"-"`
+	changes []DiffChange `json:"-"`
+	patch   Patch        `json:"-"`
+}
+
+// TokenKind is the kind of a Token read by a Tokenizer.
+type TokenKind int
+
+// Token is a json token read from a stream.
+type Token struct {
+	Kind   TokenKind    `json:"kind"`
+	Value  BJsonContext `json:"value"`  // the key, as a String, or the scalar value, empty for the brackets
+	Offset int64        `json:"offset"` // the offset of the token in the stream
+}
+
+// Tokenizer reads json tokens from a stream, such as very large documents or json lines,
+// holding in memory no more than the token, or the value, being read.
+type Tokenizer struct {
+	scanner *streamScanner `json:"-"`
+	stack   []streamFrame  `json:"-"`
+}
+
+// streamScanner reads bytes from a stream through a buffer, keeping the bytes from the mark when capturing a value.
+type streamScanner struct {
+	reader io.Reader `json:"-"`
+	buf    []byte    `json:"-"`
+	pos    int       `json:"-"` // the read position in the buffer
+	base   int64     `json:"-"` // the offset of the buffer in the stream
+	mark   int       `json:"-"` // the start of the value being captured, -1 when none
+	err    error     `json:"-"` // the read error, io.EOF at the end of the stream
+}
+
+// streamFrame is an object or array opened by a Tokenizer.
+type streamFrame struct {
+	Object  bool   `json:"-"`
+	Index   int    `json:"-"` // the index of the current element, -1 before the first
+	Key     string `json:"-"`
+	HasKey  bool   `json:"-"` // the key of the current member has been read
+	Pending bool   `json:"-"` // the value of the current member, or the current element, is expected
+	HasItem bool   `json:"-"` // a member or element has been read, a comma being expected before the next one
+}
+
+// streamComponent is a component of a stream path: a key, possibly with wildcards, an index, or '#' for any element.
+type streamComponent struct {
+	Key      string `json:"-"`
+	Index    int    `json:"-"` // the index when numeric, -1 otherwise
+	Any      bool   `json:"-"` // '#', any element of an array
+	Wildcard bool   `json:"-"` // the key has wildcards '*' or '?'
+}
+
+// SchemaCompiler compiles JSON Schemas, draft 2020-12, resolving their references against the resources added.
+type SchemaCompiler struct {
+	docs      map[string]string                    `json:"-"` // the schema documents by uri
+	resources map[string]schemaLocation            `json:"-"` // the schema resources by absolute uri, e.g: their $id
+	anchors   map[string]schemaLocation            `json:"-"` // the $anchor and $dynamicAnchor by absolute uri, e.g: "https://x/a.json#item"
+	dynamics  map[string]map[string]schemaLocation `json:"-"` // the $dynamicAnchor by resource, then by name
+	nodes     map[string]*schemaNode               `json:"-"` // the schemas compiled by location
+	count     int                                  `json:"-"`
+}
+
+// Schema is a compiled JSON Schema, safe for concurrent use.
+type Schema struct {
+	root     *schemaNode                       `json:"-"`
+	dynamics map[string]map[string]*schemaNode `json:"-"`
+}
+
+// SchemaError is a violation of a JSON Schema, its locations being json pointers, RFC 6901.
+type SchemaError struct {
+	InstanceLocation string `json:"instance_location"` // the location of the value, e.g: "/items/0/id"
+	KeywordLocation  string `json:"keyword_location"`  // the location of the keyword, e.g: "/properties/items/items/$ref/type"
+	Message          string `json:"message"`
+}
+
+// SchemaErrors are the violations of a JSON Schema.
+type SchemaErrors []SchemaError
+
+type schemaLocation struct {
+	Doc     string `json:"-"` // the uri of the document
+	Pointer string `json:"-"` // the json pointer within the document
+}
+
+type schemaPattern struct {
+	Regexp *regexp.Regexp `json:"-"`
+	Node   *schemaNode    `json:"-"`
+}
+
+// schemaNode is a compiled schema, its limits nil when not set.
+type schemaNode struct {
+	Resource              string                 `json:"-"` // the base uri
+	Bool                  *bool                  `json:"-"` // the boolean schema
+	Ref                   *schemaNode            `json:"-"`
+	DynamicRef            *schemaNode            `json:"-"`
+	DynamicName           string                 `json:"-"` // the name of the $dynamicAnchor the $dynamicRef resolves dynamically
+	Types                 []string               `json:"-"`
+	Enum                  []BJsonContext         `json:"-"`
+	Const                 *BJsonContext          `json:"-"`
+	MultipleOf            *float64               `json:"-"`
+	Maximum               *float64               `json:"-"`
+	ExclusiveMaximum      *float64               `json:"-"`
+	Minimum               *float64               `json:"-"`
+	ExclusiveMinimum      *float64               `json:"-"`
+	MaxLength             *float64               `json:"-"`
+	MinLength             *float64               `json:"-"`
+	Pattern               *regexp.Regexp         `json:"-"`
+	Format                string                 `json:"-"`
+	MaxItems              *float64               `json:"-"`
+	MinItems              *float64               `json:"-"`
+	UniqueItems           bool                   `json:"-"`
+	MaxContains           *float64               `json:"-"`
+	MinContains           *float64               `json:"-"`
+	MaxProperties         *float64               `json:"-"`
+	MinProperties         *float64               `json:"-"`
+	Required              []string               `json:"-"`
+	DependentRequired     map[string][]string    `json:"-"`
+	AllOf                 []*schemaNode          `json:"-"`
+	AnyOf                 []*schemaNode          `json:"-"`
+	OneOf                 []*schemaNode          `json:"-"`
+	Not                   *schemaNode            `json:"-"`
+	If                    *schemaNode            `json:"-"`
+	Then                  *schemaNode            `json:"-"`
+	Else                  *schemaNode            `json:"-"`
+	DependentSchemas      map[string]*schemaNode `json:"-"`
+	PrefixItems           []*schemaNode          `json:"-"`
+	Items                 *schemaNode            `json:"-"`
+	Contains              *schemaNode            `json:"-"`
+	Properties            map[string]*schemaNode `json:"-"`
+	PatternProperties     []schemaPattern        `json:"-"`
+	AdditionalProperties  *schemaNode            `json:"-"`
+	PropertyNames         *schemaNode            `json:"-"`
+	UnevaluatedItems      *schemaNode            `json:"-"`
+	UnevaluatedProperties *schemaNode            `json:"-"`
+}
+
+// schemaAnnotations are the properties and items evaluated by a schema, for unevaluatedProperties and unevaluatedItems.
+type schemaAnnotations struct {
+	Properties map[string]bool `json:"-"`
+	Items      map[int]bool    `json:"-"`
+	AllItems   bool            `json:"-"`
+}
+
+// JsonPath is a compiled JSONPath query, RFC 9535, safe for concurrent use.
+type JsonPath struct {
+	expr  string         `json:"-"`
+	query *jsonPathQuery `json:"-"`
+}
+
+// JsonPathNode is a value selected by a JsonPath, along with its location.
+type JsonPathNode struct {
+	Path  string       `json:"path"`  // the normalized path, e.g: "$['store']['book'][0]"
+	Value BJsonContext `json:"value"` // the value, its Index the offset of the value in the json
+}
+
+type jsonPathQuery struct {
+	Relative bool              `json:"-"` // the query starts from the current node '@' rather than the root '$'
+	Segments []jsonPathSegment `json:"-"`
+}
+
+type jsonPathSegment struct {
+	Descendant bool               `json:"-"` // '..', the selectors applied to the node and all its descendants
+	Selectors  []jsonPathSelector `json:"-"`
+}
+
+// jsonPathSelector is a selector of a segment, its fields set per its kind.
+type jsonPathSelector struct {
+	Kind     int           `json:"-"`
+	Name     string        `json:"-"`
+	Index    int           `json:"-"` // the index, or the start of the slice
+	End      int           `json:"-"`
+	Step     int           `json:"-"`
+	HasStart bool          `json:"-"`
+	HasEnd   bool          `json:"-"`
+	Filter   *jsonPathExpr `json:"-"`
+}
+
+// jsonPathExpr is an expression of a filter selector, its fields set per its kind.
+type jsonPathExpr struct {
+	Kind     int             `json:"-"`
+	Op       string          `json:"-"` // the comparison operator
+	Args     []*jsonPathExpr `json:"-"` // the operands of the logical operators and comparisons, or the arguments of the function
+	Literal  BJsonContext    `json:"-"`
+	Query    *jsonPathQuery  `json:"-"`
+	Function string          `json:"-"`
+	Pattern  *regexp.Regexp  `json:"-"` // the regular expression of match and search, compiled when a literal
+}
+
+// jsonPathFunction is the signature of a function extension of JSONPath.
+type jsonPathFunction struct {
+	Params []int `json:"-"`
+	Result int   `json:"-"`
+}
+
+type jsonPathParser struct {
+	expr string `json:"-"`
+	pos  int    `json:"-"`
+}
+
+// unmarshalPlan is the plan of Unmarshal for a struct type: its fields tagged with paths, cached by type.
+type unmarshalPlan struct {
+	Fields []unmarshalField `json:"-"`
+}
+
+type unmarshalField struct {
+	Name    string       `json:"-"`
+	Index   int          `json:"-"`
+	Path    string       `json:"-"` // the path of the value, empty for the untagged embedded structs, decoded from the same value
+	Default BJsonContext `json:"-"` // the value when the path is missing or null, not existing when none
+	Layout  string       `json:"-"`
+}
+
+// Path is a compiled path of Get, reusable and safe for concurrent use, see Compile.
+type Path struct {
+	raw   string     `json:"-"`
+	parts []pathPart `json:"-"` // the components of a path of keys and indexes, nil when the path is evaluated by Get
+}
+
+type pathPart struct {
+	Key   string `json:"-"` // the key, unescaped
+	Index int    `json:"-"` // the index in arrays, -1 when the key is not numeric
+}
+
+// Extractor gets the values of many compiled paths, reading the document once, see CompileMany.
+type Extractor struct {
+	paths []*Path   `json:"-"`
+	root  *pathNode `json:"-"` // the trie of the paths of keys and indexes
+	count int       `json:"-"` // the number of paths in the trie
+}
+
+// pathNode is a node of the trie of an Extractor, its children by key and by index.
+type pathNode struct {
+	Keys    map[string]*pathNode `json:"-"`
+	Indexes map[int]*pathNode    `json:"-"`
+	Targets []int                `json:"-"` // the indexes of the paths ending at the node
+}
@@ -0,0 +1,312 @@
+package bjson
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// @sort sorts the array elements in ascending order, by the value of a path of the elements when given,
+// the values of different types ordered as: missing and null, false, numbers, strings, true, then arrays and objects.
+//
+//	[3,1,2] -> [1,2,3]
+//	friends|@sort:age
+//	friends|@sort:{"by":"age","desc":true}
+//
+// The {"caseSensitive":true} arg compares the strings case-sensitively.
+// The original json is returned when the json is not an array.
+func modSort(json, arg string) string {
+	res := Parse(json)
+	if !res.IsArray() {
+		return json
+	}
+	by := arg
+	var desc, caseSensitive bool
+	if arg != "" && arg[0] == '{' {
+		by = ""
+		Parse(arg).ForEach(func(key, value BJsonContext) bool {
+			switch key.String() {
+			case "by":
+				by = value.String()
+			case "desc":
+				desc = value.Bool()
+			case "caseSensitive":
+				caseSensitive = value.Bool()
+			}
+			return true
+		})
+	}
+	values := elements(res)
+	keys := make([]BJsonContext, len(values))
+	for i, value := range values {
+		keys[i] = value
+		if by != "" {
+			keys[i] = value.Get(by)
+		}
+	}
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := keys[indexes[i]], keys[indexes[j]]
+		if desc {
+			return b.Less(a, caseSensitive)
+		}
+		return a.Less(b, caseSensitive)
+	})
+	sorted := make([]BJsonContext, len(values))
+	for i, idx := range indexes {
+		sorted[i] = values[idx]
+	}
+	return appendElements(sorted, len(json))
+}
+
+// @unique removes the duplicate array elements, keeping the first ones, their duplicates being
+// the elements with the same value, or with the same value of a path of the elements when given.
+//
+//	[1,2,1,3,2] -> [1,2,3]
+//	friends|@unique:last
+//
+// The elements missing the path are kept.
+// The original json is returned when the json is not an array.
+func modUnique(json, arg string) string {
+	res := Parse(json)
+	if !res.IsArray() {
+		return json
+	}
+	seen := make(map[string]bool)
+	var values []BJsonContext
+	res.ForEach(func(_, value BJsonContext) bool {
+		key := value
+		if arg != "" {
+			if key = value.Get(arg); !key.Exists() {
+				values = append(values, value)
+				return true
+			}
+		}
+		k := compactJson(key.Raw)
+		if key.Type == Number {
+			k = strconv.FormatFloat(key.Numeric, 'g', -1, 64)
+		}
+		if !seen[k] {
+			seen[k] = true
+			values = append(values, value)
+		}
+		return true
+	})
+	return appendElements(values, len(json))
+}
+
+// @sum returns the sum of the numeric array elements, or of the numeric values of a path of the elements when given,
+// numeric strings included, e.g: "12.5".
+//
+//	[1,2,3] -> 6
+//	items|@sum:price
+//
+// An empty string is returned when the json is not an array, or when the sum overflows float64.
+func modSum(json, arg string) string {
+	values, ok := numericValues(json, arg)
+	if !ok {
+		return ""
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return formatNumeric(sum)
+}
+
+// @avg returns the average of the numeric array elements, or of the numeric values of a path of the elements when given.
+//
+//	[1,2,3,4] -> 2.5
+//	items|@avg:price
+//
+// An empty string is returned when the json is not an array, or when it has no numeric values.
+// The average is computed as a running mean, so that it does not overflow where the sum would.
+func modAvg(json, arg string) string {
+	values, ok := numericValues(json, arg)
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	var mean float64
+	for i, v := range values {
+		mean += (v - mean) / float64(i+1)
+	}
+	return formatNumeric(mean)
+}
+
+// @min returns the least of the numeric array elements, or of the numeric values of a path of the elements when given.
+//
+//	[3,1,2] -> 1
+//	items|@min:price
+//
+// An empty string is returned when the json is not an array, or when it has no numeric values.
+func modMin(json, arg string) string {
+	values, ok := numericValues(json, arg)
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	least := values[0]
+	for _, v := range values[1:] {
+		least = math.Min(least, v)
+	}
+	return formatNumeric(least)
+}
+
+// @max returns the greatest of the numeric array elements, or of the numeric values of a path of the elements when given.
+//
+//	[3,1,2] -> 3
+//	items|@max:price
+//
+// An empty string is returned when the json is not an array, or when it has no numeric values.
+func modMax(json, arg string) string {
+	values, ok := numericValues(json, arg)
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	greatest := values[0]
+	for _, v := range values[1:] {
+		greatest = math.Max(greatest, v)
+	}
+	return formatNumeric(greatest)
+}
+
+// @count returns the number of array elements, or of object members,
+// or the number of array elements matching a query when given, see @filter.
+//
+//	[1,2,3] -> 3
+//	{"a":1,"b":2} -> 2
+//	friends|@count:age>40
+//
+// An empty string is returned when the json is neither an array nor an object.
+func modCount(json, arg string) string {
+	res := Parse(json)
+	if arg != "" {
+		if !res.IsArray() {
+			return ""
+		}
+		res = Parse(modFilter(json, arg))
+	}
+	if !res.IsArray() && !res.IsObject() {
+		return ""
+	}
+	n := 0
+	res.ForEach(func(_, _ BJsonContext) bool {
+		n++
+		return true
+	})
+	return strconv.Itoa(n)
+}
+
+// @slice returns the array elements from start, included, to end, excluded,
+// the negative indexes counting from the end of the array.
+//
+//	[1,2,3,4,5]|@slice:{"start":1,"end":3} -> [2,3]
+//	[1,2,3,4,5]|@slice:{"start":-2} -> [4,5]
+//
+// The original json is returned when the json is not an array.
+func modSlice(json, arg string) string {
+	res := Parse(json)
+	if !res.IsArray() {
+		return json
+	}
+	values := elements(res)
+	start, end := 0, len(values)
+	Parse(arg).ForEach(func(key, value BJsonContext) bool {
+		switch key.String() {
+		case "start":
+			start = sliceBound(int(value.Int()), len(values))
+		case "end":
+			end = sliceBound(int(value.Int()), len(values))
+		}
+		return true
+	})
+	if start > end {
+		start = end
+	}
+	return appendElements(values[start:end], len(json))
+}
+
+// @filter returns the array elements matching a query, in the syntax of the queries of paths, e.g: "friends.#(age>40)#".
+//
+//	friends|@filter:age>40
+//	friends|@filter:last=="Murphy"
+//	friends|@filter:nets.#(=="fb")
+//
+// The original json is returned when the json is not an array.
+func modFilter(json, arg string) string {
+	res := Parse(json)
+	if !res.IsArray() {
+		return json
+	}
+	if arg == "" {
+		return json
+	}
+	filtered := Get(json, "#("+arg+")#")
+	if !filtered.Exists() {
+		return "[]"
+	}
+	return filtered.Raw
+}
+
+// numericValues returns the numbers of the array elements, or of a path of the elements, numeric strings included,
+// false when the json is not an array.
+func numericValues(json, path string) ([]float64, bool) {
+	res := Parse(json)
+	if !res.IsArray() {
+		return nil, false
+	}
+	var values []float64
+	res.ForEach(func(_, value BJsonContext) bool {
+		if path != "" {
+			value = value.Get(path)
+		}
+		switch value.Type {
+		case Number:
+			values = append(values, value.Numeric)
+		case String:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value.Strings), 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				values = append(values, f)
+			}
+		}
+		return true
+	})
+	return values, true
+}
+
+// formatNumeric returns the number as json, or an empty string when it is infinite or NaN, having no json equivalent.
+func formatNumeric(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sliceBound returns the index within [0, length], the negative indexes counting from the end.
+func sliceBound(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func appendElements(values []BJsonContext, size int) string {
+	out := make([]byte, 0, size)
+	out = append(out, '[')
+	for i, value := range values {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, value.Raw...)
+	}
+	out = append(out, ']')
+	return bytesString(out)
+}
@@ -0,0 +1,516 @@
+package bjson
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sivaosorg/govm/pretty"
+)
+
+// NewPatchOperation creates an operation of a JSON Patch, e.g: NewPatchOperation(PatchAdd, "/tags/-").
+func NewPatchOperation(op, path string) *PatchOperation {
+	return &PatchOperation{Op: op, Path: path}
+}
+
+func (p *PatchOperation) SetOp(value string) *PatchOperation {
+	p.Op = value
+	return p
+}
+
+func (p *PatchOperation) SetPath(value string) *PatchOperation {
+	p.Path = value
+	return p
+}
+
+func (p *PatchOperation) SetFrom(value string) *PatchOperation {
+	p.From = value
+	return p
+}
+
+// SetValue sets the value, marshalled to json; it is left unchanged when the value cannot be marshalled.
+func (p *PatchOperation) SetValue(value interface{}) *PatchOperation {
+	if b, err := marshalJson(value); err == nil {
+		p.Value = string(b)
+	}
+	return p
+}
+
+// SetRawValue sets the raw json value, e.g: `{"id":1}`.
+func (p *PatchOperation) SetRawValue(value string) *PatchOperation {
+	p.Value = value
+	return p
+}
+
+// PatchOperationValidator checks that the operation is known and carries the members it requires.
+func PatchOperationValidator(p PatchOperation) error {
+	switch p.Op {
+	default:
+		return ErrorPatchOperation
+	case PatchRemove:
+	case PatchMove, PatchCopy:
+		if err := validatePointer(p.From); err != nil {
+			return err
+		}
+	case PatchAdd, PatchReplace, PatchTest:
+		if p.Value == "" {
+			return ErrorPatchValue
+		}
+		if !Valid(p.Value) {
+			return ErrorInvalidJson
+		}
+	}
+	return validatePointer(p.Path)
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("Patch operation %d (%s '%s') failed: %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ParsePatch parses a JSON Patch document, RFC 6902, e.g: `[{"op":"add","path":"/a","value":1}]`.
+// The error is a *PatchError when an operation is not valid.
+func ParsePatch(patch string) (Patch, error) {
+	if !Valid(patch) {
+		return nil, ErrorInvalidJson
+	}
+	root := Parse(patch)
+	if !root.IsArray() {
+		return nil, ErrorPatchInvalid
+	}
+	p := Patch{}
+	var err error
+	root.ForEach(func(_, value BJsonContext) bool {
+		if !value.IsObject() {
+			err = &PatchError{Index: len(p), Err: ErrorPatchInvalid}
+			return false
+		}
+		// an operation with a duplicated member is ambiguous, e.g: two "op", RFC 6902 appendix A.13
+		members := make(map[string]bool)
+		value.ForEach(func(key, _ BJsonContext) bool {
+			if members[key.String()] {
+				err = &PatchError{Index: len(p), Op: value.Get("op").String(), Path: value.Get("path").String(), Err: ErrorPatchDuplicateMember}
+				return false
+			}
+			members[key.String()] = true
+			return true
+		})
+		if err != nil {
+			return false
+		}
+		op := PatchOperation{
+			Op:   value.Get("op").String(),
+			Path: value.Get("path").String(),
+			From: value.Get("from").String(),
+		}
+		if v := value.Get("value"); v.Exists() {
+			op.Value = v.Raw
+		}
+		if !value.Get("path").Exists() {
+			err = &PatchError{Index: len(p), Op: op.Op, Err: ErrorPointerInvalid}
+			return false
+		}
+		if e := PatchOperationValidator(op); e != nil {
+			err = &PatchError{Index: len(p), Op: op.Op, Path: op.Path, Err: e}
+			return false
+		}
+		p = append(p, op)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ParsePatchBytes parses a JSON Patch document, RFC 6902.
+func ParsePatchBytes(patch []byte) (Patch, error) {
+	return ParsePatch(bytesString(patch))
+}
+
+// ApplyPatch applies a JSON Patch document, RFC 6902, to the json, keeping the formatting of the parts left unchanged.
+// The patch is atomic: the json is returned unchanged along with a *PatchError when an operation fails.
+//
+//	ApplyPatch(`{"a":1}`, `[{"op":"add","path":"/b","value":[2]},{"op":"move","from":"/a","path":"/b/0"}]`)
+//	>> {"b":[1,2]}
+func ApplyPatch(json, patch string) (string, error) {
+	p, err := ParsePatch(patch)
+	if err != nil {
+		return json, err
+	}
+	return p.Apply(json)
+}
+
+// ApplyPatchBytes applies a JSON Patch document, RFC 6902, to the json.
+func ApplyPatchBytes(json, patch []byte) ([]byte, error) {
+	p, err := ParsePatchBytes(patch)
+	if err != nil {
+		return json, err
+	}
+	return p.ApplyBytes(json)
+}
+
+// Apply applies the operations in sequence, the json being returned unchanged along with a *PatchError when one fails.
+func (p Patch) Apply(json string) (string, error) {
+	if !Valid(json) {
+		return json, ErrorInvalidJson
+	}
+	doc := json
+	for i, op := range p {
+		next, err := applyOperation(doc, op)
+		if err != nil {
+			return json, &PatchError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+		doc = next
+	}
+	return doc, nil
+}
+
+// ApplyBytes applies the operations in sequence to the json.
+func (p Patch) ApplyBytes(json []byte) ([]byte, error) {
+	res, err := p.Apply(bytesString(json))
+	if err != nil {
+		return json, err
+	}
+	return []byte(res), nil
+}
+
+// String returns the patch as a JSON Patch document.
+func (p Patch) String() string {
+	buf := []byte{'['}
+	for i, op := range p {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"op":`...)
+		buf = AppendJsonString(buf, op.Op)
+		if op.Op == PatchMove || op.Op == PatchCopy {
+			buf = append(buf, `,"from":`...)
+			buf = AppendJsonString(buf, op.From)
+		}
+		buf = append(buf, `,"path":`...)
+		buf = AppendJsonString(buf, op.Path)
+		if op.Value != "" {
+			buf = append(buf, `,"value":`...)
+			buf = append(buf, op.Value...)
+		}
+		buf = append(buf, '}')
+	}
+	return string(append(buf, ']'))
+}
+
+// MergePatch applies a JSON Merge Patch, RFC 7396, to the json, keeping the formatting of the members left unchanged.
+// Members of the patch set to null are removed, objects are merged recursively, and any other value replaces the target.
+//
+//	MergePatch(`{"a":"b","c":{"d":"e","f":"g"}}`, `{"a":"z","c":{"f":null}}`)
+//	>> {"a":"z","c":{"d":"e"}}
+func MergePatch(json, patch string) (string, error) {
+	if trim(json) != "" && !Valid(json) {
+		return json, ErrorInvalidJson
+	}
+	if !Valid(patch) {
+		return json, ErrorInvalidJson
+	}
+	return mergePatch(json, rootOf(patch)), nil
+}
+
+// MergePatchBytes applies a JSON Merge Patch, RFC 7396, to the json.
+func MergePatchBytes(json, patch []byte) ([]byte, error) {
+	res, err := MergePatch(bytesString(json), bytesString(patch))
+	if err != nil {
+		return json, err
+	}
+	return []byte(res), nil
+}
+
+// CreatePatch generates the JSON Patch turning the original json into the modified one.
+// Arrays are compared index by index, the extra elements removed or added at their end.
+func CreatePatch(original, modified string) (Patch, error) {
+	return DiffPatch(original, modified, nil)
+}
+
+// CreateMergePatch generates the JSON Merge Patch turning the original json into the modified one, `{}` when they are equal.
+// Null values of the modified json cannot be expressed by a merge patch, their members are removed instead.
+func CreateMergePatch(original, modified string) (string, error) {
+	if !Valid(original) || !Valid(modified) {
+		return "", ErrorInvalidJson
+	}
+	a, b := rootOf(original), rootOf(modified)
+	if !a.IsObject() || !b.IsObject() {
+		if equalJson(a, b) {
+			return "{}", nil
+		}
+		return compactJson(b.Raw), nil
+	}
+	return string(appendMergeDiff(nil, a, b)), nil
+}
+
+func applyOperation(doc string, op PatchOperation) (string, error) {
+	if err := PatchOperationValidator(op); err != nil {
+		return doc, err
+	}
+	switch op.Op {
+	case PatchAdd:
+		return patchAdd(doc, op.Path, trim(op.Value))
+	case PatchRemove:
+		return patchRemove(doc, op.Path)
+	case PatchReplace:
+		m, err := lookupPointer(doc, op.Path)
+		if err != nil {
+			return doc, err
+		}
+		if !m.Found {
+			return doc, ErrorPointerNotFound
+		}
+		return replaceValue(doc, m.Value, trim(op.Value)), nil
+	case PatchMove:
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return doc, ErrorPatchMoveIntoSelf
+		}
+		m, err := lookupPointer(doc, op.From)
+		if err != nil {
+			return doc, err
+		}
+		if !m.Found {
+			return doc, ErrorPointerNotFound
+		}
+		if op.Path == op.From {
+			return doc, nil
+		}
+		doc, err = patchRemove(doc, op.From)
+		if err != nil {
+			return doc, err
+		}
+		return patchAdd(doc, op.Path, m.Value.Raw)
+	case PatchCopy:
+		m, err := lookupPointer(doc, op.From)
+		if err != nil {
+			return doc, err
+		}
+		if !m.Found {
+			return doc, ErrorPointerNotFound
+		}
+		return patchAdd(doc, op.Path, m.Value.Raw)
+	default: // PatchTest
+		m, err := lookupPointer(doc, op.Path)
+		if err != nil {
+			return doc, err
+		}
+		if !m.Found || !equalJson(m.Value, rootOf(op.Value)) {
+			return doc, ErrorPatchTestFailed
+		}
+		return doc, nil
+	}
+}
+
+// patchAdd adds the value at the pointer: the member is created or replaced, and the element inserted before the one
+// at the index, or appended when the index is "-" or the length of the array.
+func patchAdd(doc, pointer, value string) (string, error) {
+	m, err := lookupPointer(doc, pointer)
+	if err != nil {
+		return doc, err
+	}
+	if pointer == "" || (m.Found && m.Parent.IsObject()) {
+		return replaceValue(doc, m.Value, value), nil
+	}
+	if m.Parent.IsObject() {
+		return insertMember(doc, m.Parent, string(AppendJsonString(nil, m.Token))+":"+value), nil
+	}
+	if m.Found {
+		at := m.Value.Index
+		ws := at
+		for ws > 0 && doc[ws-1] <= ' ' {
+			ws--
+		}
+		return doc[:at] + value + "," + doc[ws:at] + doc[at:], nil
+	}
+	if m.Token != "-" {
+		n, ok := arrayIndex(m.Token)
+		if !ok || n != len(m.Parent.Array()) {
+			return doc, ErrorPointerIndex
+		}
+	}
+	return insertMember(doc, m.Parent, value), nil
+}
+
+func patchRemove(doc, pointer string) (string, error) {
+	m, err := lookupPointer(doc, pointer)
+	if err != nil {
+		return doc, err
+	}
+	if pointer == "" || !m.Found {
+		return doc, ErrorPointerNotFound
+	}
+	return removeMember(doc, m), nil
+}
+
+// rootOf parses the json, its raw value trimmed, so that its bounds are those of the value.
+func rootOf(json string) BJsonContext {
+	res := Parse(json)
+	if res.Type == JSON {
+		end := len(res.Raw)
+		for end > 0 && res.Raw[end-1] <= ' ' {
+			end--
+		}
+		res.Raw = res.Raw[:end]
+	}
+	return res
+}
+
+func replaceValue(doc string, v BJsonContext, value string) string {
+	if !v.Exists() {
+		return value
+	}
+	return doc[:v.Index] + value + doc[v.Index+len(v.Raw):]
+}
+
+// insertMember appends the raw member, `"key":value` or a value, to the object or array.
+func insertMember(doc string, container BJsonContext, member string) string {
+	tail, sep, comma := insertionPoint(container.Raw)
+	at := container.Index + tail
+	if comma {
+		return doc[:at] + sep + member + doc[at:]
+	}
+	return doc[:at] + member + doc[at:]
+}
+
+// removeMember removes the member, or element, along with the comma separating it from its siblings.
+func removeMember(doc string, m pointerMember) string {
+	start, end := m.Value.Index, m.Value.Index+len(m.Value.Raw)
+	if m.Parent.IsObject() {
+		start = m.Key.Index
+	}
+	i := end
+	for i < len(doc) && doc[i] <= ' ' {
+		i++
+	}
+	if i < len(doc) && doc[i] == ',' {
+		for i++; i < len(doc) && doc[i] <= ' '; i++ {
+		}
+		return doc[:start] + doc[i:]
+	}
+	j := start
+	for j > 0 && doc[j-1] <= ' ' {
+		j--
+	}
+	if j > 0 && doc[j-1] == ',' {
+		return doc[:j-1] + doc[end:]
+	}
+	return doc[:j] + doc[i:] // the only member
+}
+
+func mergePatch(json string, patch BJsonContext) string {
+	if !patch.IsObject() {
+		return patch.Raw
+	}
+	target := rootOf(json)
+	if !target.IsObject() {
+		json = "{}"
+		target = rootOf(json)
+	}
+	patch.ForEach(func(key, value BJsonContext) bool {
+		m := objectMember(target, key.Strings)
+		switch {
+		case value.Type == Null:
+			if m.Found {
+				json = removeMember(json, m)
+			}
+		case m.Found:
+			json = replaceValue(json, m.Value, mergePatch(m.Value.Raw, value))
+		default:
+			json = insertMember(json, target, key.Raw+":"+mergePatch("", value))
+		}
+		target = rootOf(json)
+		return true
+	})
+	return json
+}
+
+func appendMergeDiff(buf []byte, a, b BJsonContext) []byte {
+	buf = append(buf, '{')
+	n := len(buf)
+	x, y := a.Map(), b.Map()
+	a.ForEach(func(key, _ BJsonContext) bool {
+		if _, ok := y[key.Strings]; !ok {
+			if len(buf) > n {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, key.Raw...)
+			buf = append(buf, ":null"...)
+		}
+		return true
+	})
+	b.ForEach(func(key, value BJsonContext) bool {
+		v, ok := x[key.Strings]
+		if ok && equalJson(v, value) {
+			return true
+		}
+		if len(buf) > n {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, key.Raw...)
+		buf = append(buf, ':')
+		if ok && v.IsObject() && value.IsObject() {
+			buf = appendMergeDiff(buf, v, value)
+		} else {
+			buf = append(buf, compactJson(value.Raw)...)
+		}
+		return true
+	})
+	return append(buf, '}')
+}
+
+// equalJson checks whether both values are equal, regardless of the order of the object members and of the formatting.
+func equalJson(a, b BJsonContext) bool {
+	return equalJsonWith(a, b, false)
+}
+
+// equalJsonWith checks whether both values are equal, the arrays compared as multisets when unordered.
+func equalJsonWith(a, b BJsonContext, unordered bool) bool {
+	switch {
+	case a.Type != b.Type:
+		return false
+	case a.Type == Number:
+		return a.Raw == b.Raw || a.Numeric == b.Numeric
+	case a.Type == String:
+		return a.Strings == b.Strings
+	case a.Type != JSON:
+		return true
+	case a.IsArray() != b.IsArray():
+		return false
+	case a.IsArray():
+		x, y := a.Array(), b.Array()
+		if len(x) != len(y) {
+			return false
+		}
+		if unordered {
+			return len(matchElements(x, y, true)) == 0
+		}
+		for i := range x {
+			if !equalJsonWith(x[i], y[i], false) {
+				return false
+			}
+		}
+		return true
+	}
+	x, y := a.Map(), b.Map()
+	if len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		if w, ok := y[k]; !ok || !equalJsonWith(v, w, unordered) {
+			return false
+		}
+	}
+	return true
+}
+
+// compactJson removes the whitespace of the json value.
+func compactJson(json string) string {
+	if json == "" || (json[0] != '{' && json[0] != '[') {
+		return json
+	}
+	return string(pretty.Ugly([]byte(json)))
+}
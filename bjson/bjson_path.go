@@ -0,0 +1,383 @@
+package bjson
+
+import (
+	"strings"
+)
+
+// Compile compiles a path of Get, e.g: "data.user.name", to be applied to many documents.
+// The paths of keys and indexes are evaluated by a dedicated scanner, with no parsing of the path per call,
+// the others, such as queries, wildcards and modifiers, by Get.
+// It returns ErrorPathEmpty, or ErrorPathInvalid when a query, a selector or an escape is malformed.
+// Parsing a path costs little next to scanning the document, so a single compiled path performs as Get does,
+// the gain comes from CompileMany, which reads the document once for all the paths.
+func Compile(path string) (*Path, error) {
+	if err := validatePath(path); err != nil {
+		return nil, err
+	}
+	return &Path{raw: path, parts: compilePathParts(path)}, nil
+}
+
+// MustCompile compiles a path of Get, panicking when it is invalid.
+func MustCompile(path string) *Path {
+	p, err := Compile(path)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Get searches the json for the value of the path, as Get does, and at about the same cost.
+func (p *Path) Get(json string) BJsonContext {
+	if p.parts == nil {
+		return Get(json, p.raw)
+	}
+	i := openingOf(json, 0)
+	for n, part := range p.parts {
+		if i < 0 {
+			break
+		}
+		start := lookupPart(json, i, part)
+		if start < 0 {
+			break
+		}
+		if n == len(p.parts)-1 {
+			res := Parse(json[start:skipValue(json, start)])
+			res.Index = start
+			return res
+		}
+		if json[start] != '{' && json[start] != '[' {
+			break
+		}
+		i = start
+	}
+	return BJsonContext{}
+}
+
+// GetBytes searches the json for the value of the path, the value being copied out of the json.
+func (p *Path) GetBytes(json []byte) BJsonContext {
+	return cloneContext(p.Get(bytesString(json)))
+}
+
+// String returns the path as compiled.
+func (p *Path) String() string {
+	return p.raw
+}
+
+// CompileMany compiles the paths of Get, e.g: "id", "data.user.name", "data.items.0.price",
+// to be extracted from many documents, each document being read once for all the paths of keys and indexes.
+func CompileMany(paths ...string) (*Extractor, error) {
+	e := &Extractor{paths: make([]*Path, len(paths)), root: &pathNode{}}
+	for i, path := range paths {
+		p, err := Compile(path)
+		if err != nil {
+			return nil, err
+		}
+		e.paths[i] = p
+		if p.parts == nil {
+			continue
+		}
+		node := e.root
+		for _, part := range p.parts {
+			node = node.child(part)
+		}
+		node.Targets = append(node.Targets, i)
+		e.count++
+	}
+	return e, nil
+}
+
+// MustCompileMany compiles the paths of Get, panicking when one is invalid.
+func MustCompileMany(paths ...string) *Extractor {
+	e, err := CompileMany(paths...)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Get returns the values of the paths in the json, as GetMany does.
+func (e *Extractor) Get(json string) []BJsonContext {
+	res := make([]BJsonContext, len(e.paths))
+	if e.count > 0 {
+		found := make([]bool, len(e.paths))
+		remaining := e.count
+		if i := openingOf(json, 0); i >= 0 {
+			e.root.walk(json, i, res, found, &remaining)
+		}
+	}
+	for i, p := range e.paths {
+		if p.parts == nil {
+			res[i] = Get(json, p.raw)
+		}
+	}
+	return res
+}
+
+// GetBytes returns the values of the paths in the json, the values being copied out of the json.
+func (e *Extractor) GetBytes(json []byte) []BJsonContext {
+	res := e.Get(bytesString(json))
+	for i := range res {
+		res[i] = cloneContext(res[i])
+	}
+	return res
+}
+
+// Paths returns the paths as compiled.
+func (e *Extractor) Paths() []string {
+	paths := make([]string, len(e.paths))
+	for i, p := range e.paths {
+		paths[i] = p.raw
+	}
+	return paths
+}
+
+func (n *pathNode) child(part pathPart) *pathNode {
+	if n.Keys == nil {
+		n.Keys = make(map[string]*pathNode)
+		n.Indexes = make(map[int]*pathNode)
+	}
+	c, ok := n.Keys[part.Key]
+	if !ok {
+		c = &pathNode{}
+		n.Keys[part.Key] = c
+		if part.Index >= 0 {
+			n.Indexes[part.Index] = c
+		}
+	}
+	return c
+}
+
+// walk scans the object or array at i, filling the values of the paths of its children, the first ones found,
+// returning the end of the container, or -1 once all the values are found.
+func (n *pathNode) walk(json string, i int, res []BJsonContext, found []bool, remaining *int) int {
+	object := json[i] == '{'
+	idx := 0
+	for i++; i < len(json); {
+		switch json[i] {
+		case ' ', '\t', '\n', '\r', ',':
+			i++
+			continue
+		case '}', ']':
+			return i + 1
+		}
+		var child *pathNode
+		if object {
+			if json[i] != '"' {
+				return len(json)
+			}
+			end, key, escaped, ok := parseString(json, i+1)
+			if !ok {
+				return len(json)
+			}
+			key = key[1 : len(key)-1]
+			if escaped {
+				key = unescape(key)
+			}
+			child = n.Keys[key]
+			if i = skipColon(json, end); i < 0 {
+				return len(json)
+			}
+		} else {
+			child = n.Indexes[idx]
+			idx++
+		}
+		start := i
+		if child != nil && len(child.Keys) > 0 && (json[i] == '{' || json[i] == '[') {
+			if i = child.walk(json, i, res, found, remaining); i < 0 {
+				return -1
+			}
+		} else {
+			i = skipValue(json, i)
+		}
+		if child == nil || len(child.Targets) == 0 {
+			continue
+		}
+		var value BJsonContext
+		for _, t := range child.Targets {
+			if found[t] {
+				continue
+			}
+			if !value.Exists() {
+				value = Parse(json[start:i])
+				value.Index = start
+			}
+			res[t], found[t] = value, true
+			if *remaining--; *remaining == 0 {
+				return -1
+			}
+		}
+	}
+	return len(json)
+}
+
+// lookupPart locates the value of the key, or of the index, in the object or array at i, returning its position, -1 when missing.
+func lookupPart(json string, i int, part pathPart) int {
+	object := json[i] == '{'
+	if !object && part.Index < 0 {
+		return -1
+	}
+	idx := 0
+	for i++; i < len(json); {
+		switch json[i] {
+		case ' ', '\t', '\n', '\r', ',':
+			i++
+			continue
+		case '}', ']':
+			return -1
+		}
+		match := false
+		if object {
+			if json[i] != '"' {
+				return -1
+			}
+			end, key, escaped, ok := parseString(json, i+1)
+			if !ok {
+				return -1
+			}
+			key = key[1 : len(key)-1]
+			if escaped {
+				key = unescape(key)
+			}
+			match = key == part.Key
+			if i = skipColon(json, end); i < 0 {
+				return -1
+			}
+		} else {
+			match = idx == part.Index
+			idx++
+		}
+		if match {
+			return i
+		}
+		i = skipValue(json, i)
+	}
+	return -1
+}
+
+// openingOf returns the position of the first '{' or '[' from i, -1 when none, as Get looks for the document.
+func openingOf(json string, i int) int {
+	for ; i < len(json); i++ {
+		if json[i] == '{' || json[i] == '[' {
+			return i
+		}
+	}
+	return -1
+}
+
+// skipColon returns the position of the value following the colon of a member, -1 when none.
+func skipColon(json string, i int) int {
+	for ; i < len(json); i++ {
+		switch json[i] {
+		case ' ', '\t', '\n', '\r':
+		case ':':
+			for i++; i < len(json) && json[i] <= ' '; i++ {
+			}
+			if i == len(json) {
+				return -1
+			}
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// skipValue returns the end of the value at i.
+func skipValue(json string, i int) int {
+	switch json[i] {
+	case '"':
+		i, _, _, _ = parseString(json, i+1)
+	case '{', '[':
+		i, _ = parseSquash(json, i)
+	case 't', 'f', 'n':
+		i, _ = parseLiteral(json, i)
+	default:
+		i, _ = parseNumber(json, i)
+	}
+	return i
+}
+
+// compilePathParts returns the components of a path made of keys and indexes only, nil for any other path.
+func compilePathParts(path string) []pathPart {
+	var parts []pathPart
+	var key []byte
+	start := true
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if start {
+			switch c {
+			case '@', '!', '[', '{', '.':
+				return nil
+			}
+			start = false
+		}
+		switch c {
+		case '\\':
+			i++
+			key = append(key, path[i])
+		case '.':
+			parts = append(parts, newPathPart(string(key)))
+			key = key[:0]
+			start = true
+		case '*', '?', '|', '#':
+			return nil
+		default:
+			key = append(key, c)
+		}
+	}
+	if start {
+		return nil
+	}
+	return append(parts, newPathPart(string(key)))
+}
+
+func newPathPart(key string) pathPart {
+	part := pathPart{Key: key, Index: -1}
+	if n, ok := parseUint(key); ok && n <= uint64(^uint(0)>>1) {
+		part.Index = int(n)
+	}
+	return part
+}
+
+// validatePath checks the escapes, the queries, e.g: "#(age>40)", and the leading selectors, e.g: "{name,age}", of a path.
+func validatePath(path string) error {
+	if path == "" {
+		return ErrorPathEmpty
+	}
+	if path[0] == '[' || path[0] == '{' {
+		if _, _, ok := parseSubSelectors(path); !ok {
+			return ErrorPathInvalid
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			if i++; i == len(path) {
+				return ErrorPathInvalid
+			}
+		case '#':
+			if i+1 < len(path) && (path[i+1] == '(' || path[i+1] == '[') {
+				_, _, _, _, end, _, ok := parseQuery(path[i:])
+				if !ok {
+					return ErrorPathInvalid
+				}
+				i += end - 1
+			}
+		}
+	}
+	return nil
+}
+
+// cloneContext copies the strings of the value, for the values found in byte slices.
+func cloneContext(t BJsonContext) BJsonContext {
+	raw := strings.Clone(t.Raw)
+	if n := len(t.Strings); n > 0 && len(t.Raw) == n+2 && t.Raw[1:n+1] == t.Strings {
+		// the string is not escaped, sharing the copy of the raw value
+		t.Strings = raw[1 : n+1]
+	} else {
+		t.Strings = strings.Clone(t.Strings)
+	}
+	t.Raw = raw
+	return t
+}
@@ -0,0 +1,173 @@
+package bjson
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// EscapePointer escapes a key as a token of a json pointer, RFC 6901, e.g: "a/b" >> "a~1b".
+func EscapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+// GetPointer returns the value of the json pointer, RFC 6901, e.g: "/friends/1/last", or its uri fragment "#/friends/1/last".
+// Its Index is the offset of the value in the json, and it does not exist when the pointer is invalid or not found.
+func GetPointer(json, pointer string) BJsonContext {
+	values, err := QueryPointer(json, pointer)
+	if err != nil {
+		return BJsonContext{}
+	}
+	return values[0]
+}
+
+// GetPointerBytes returns the value of the json pointer, RFC 6901, see GetPointer.
+func GetPointerBytes(json []byte, pointer string) BJsonContext {
+	return GetPointer(bytesString(json), pointer)
+}
+
+// QueryPointer evaluates the json pointer, RFC 6901, or its uri fragment, e.g: "#/a%20b", returning the value located,
+// its Index being the offset of the value in the json, or ErrorPointerInvalid, ErrorPointerIndex, ErrorPointerNotFound.
+func QueryPointer(json, pointer string) ([]BJsonContext, error) {
+	pointer, err := unfragmentPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	m, err := lookupPointer(json, pointer)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Found {
+		return nil, ErrorPointerNotFound
+	}
+	return []BJsonContext{m.Value}, nil
+}
+
+// PointerToPath converts the json pointer, RFC 6901, or its uri fragment, to a path of Get, e.g: "/a.b/0" >> "a\.b.0",
+// "@this" for the whole document.
+func PointerToPath(pointer string) (string, error) {
+	pointer, err := unfragmentPointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) == 0 {
+		return "@this", nil
+	}
+	for i, token := range tokens {
+		tokens[i] = escapeComp(token)
+	}
+	return strings.Join(tokens, "."), nil
+}
+
+// unfragmentPointer decodes the json pointer of a uri fragment, e.g: "#/c%25d" >> "/c%d", the others being left as they are.
+func unfragmentPointer(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "#") {
+		return pointer, nil
+	}
+	unescaped, err := url.PathUnescape(pointer[1:])
+	if err != nil {
+		return "", ErrorPointerInvalid
+	}
+	return unescaped, nil
+}
+
+// lookupPointer locates the value of the json pointer, RFC 6901, reporting it not found when only its last token is missing,
+// along with its parent so that it can be added.
+func lookupPointer(doc, pointer string) (pointerMember, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return pointerMember{}, err
+	}
+	m := pointerMember{Value: rootOf(doc)}
+	m.Found = m.Value.Exists()
+	for i, token := range tokens {
+		if !m.Found || m.Value.Type != JSON {
+			return m, ErrorPointerNotFound
+		}
+		parent := m.Value
+		if parent.IsObject() {
+			m = objectMember(parent, token)
+		} else {
+			m = pointerMember{Token: token, Parent: parent}
+			if n, ok := arrayIndex(token); ok {
+				parent.ForEach(func(_, value BJsonContext) bool {
+					if n == 0 {
+						m.Value, m.Found = value, true
+						return false
+					}
+					n--
+					return true
+				})
+			} else if token != "-" {
+				return m, ErrorPointerIndex
+			}
+		}
+		if !m.Found && i < len(tokens)-1 {
+			return m, ErrorPointerNotFound
+		}
+	}
+	return m, nil
+}
+
+// objectMember locates the first member of the object with the key.
+func objectMember(obj BJsonContext, key string) pointerMember {
+	m := pointerMember{Token: key, Parent: obj}
+	obj.ForEach(func(k, value BJsonContext) bool {
+		if k.Strings == key {
+			m.Key, m.Value, m.Found = k, value, true
+			return false
+		}
+		return true
+	})
+	return m
+}
+
+// splitPointer returns the unescaped tokens of a json pointer, RFC 6901, e.g: "/a~1b/0" >> ["a/b", "0"].
+func splitPointer(pointer string) ([]string, error) {
+	if err := validatePointer(pointer); err != nil {
+		return nil, err
+	}
+	if pointer == "" {
+		return nil, nil
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		if strings.IndexByte(token, '~') >= 0 {
+			tokens[i] = pointerUnescaper.Replace(token)
+		}
+	}
+	return tokens, nil
+}
+
+func validatePointer(pointer string) error {
+	if pointer != "" && pointer[0] != '/' {
+		return ErrorPointerInvalid
+	}
+	for i := 0; i < len(pointer); i++ {
+		if pointer[i] == '~' && (i+1 == len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1')) {
+			return ErrorPointerInvalid
+		}
+	}
+	return nil
+}
+
+// arrayIndex parses the token as an index of array, without leading zeros.
+func arrayIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(token)
+	return n, err == nil
+}
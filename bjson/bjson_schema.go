@@ -0,0 +1,965 @@
+package bjson
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sivaosorg/govm/utils"
+)
+
+var schemaFormatsMutex sync.RWMutex
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+var hostnameRegexp = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+func init() {
+	schemaFormats = map[string]func(value string) bool{
+		"email": func(value string) bool {
+			ok, err := utils.VerifyEmail(value)
+			return ok && err == nil
+		},
+		"date-time": func(value string) bool {
+			return isSchemaTime(time.RFC3339Nano, value, 17)
+		},
+		"date": func(value string) bool {
+			_, err := time.Parse("2006-01-02", value)
+			return err == nil
+		},
+		"time": func(value string) bool {
+			return isSchemaTime("15:04:05.999999999Z07:00", value, 6)
+		},
+		"uuid": uuidRegexp.MatchString,
+		"ipv4": func(value string) bool {
+			return net.ParseIP(value) != nil && strings.IndexByte(value, ':') < 0
+		},
+		"ipv6": func(value string) bool {
+			return net.ParseIP(value) != nil && strings.IndexByte(value, ':') >= 0
+		},
+		"hostname": func(value string) bool {
+			return len(value) <= 253 && hostnameRegexp.MatchString(value)
+		},
+		"uri": func(value string) bool {
+			u, err := url.Parse(value)
+			return err == nil && u.IsAbs()
+		},
+		"uri-reference": func(value string) bool {
+			_, err := url.Parse(value)
+			return err == nil
+		},
+		"regex": func(value string) bool {
+			_, err := regexp.Compile(value)
+			return err == nil
+		},
+	}
+}
+
+// isSchemaTime checks the value against the layout of RFC 3339, the seconds at the index,
+// accepting the leap second 60 on the last minute of a UTC day, e.g: "1990-12-31T23:59:60Z", "15:59:60-08:00".
+func isSchemaTime(layout, value string, second int) bool {
+	value = strings.ToUpper(value)
+	if len(value) >= second+2 && value[second-1] == ':' && value[second:second+2] == "60" {
+		t, err := time.Parse(layout, value[:second]+"59"+value[second+2:])
+		if err != nil {
+			return false
+		}
+		t = t.UTC()
+		return t.Hour() == 23 && t.Minute() == 59
+	}
+	_, err := time.Parse(layout, value)
+	return err == nil
+}
+
+// AddSchemaFormat registers, or replaces, the validator of a value of the "format" keyword, e.g: "phone".
+// Formats without validator are not asserted.
+func AddSchemaFormat(name string, fn func(value string) bool) {
+	schemaFormatsMutex.Lock()
+	defer schemaFormatsMutex.Unlock()
+	schemaFormats[name] = fn
+}
+
+// CompileSchema compiles a JSON Schema, draft 2020-12, once for validating many values.
+//
+//	schema, err := CompileSchema(`{"type":"object","required":["email"],"properties":{"email":{"format":"email"}}}`)
+//	err = schema.Validate(`{"email":"nope"}`)
+//	>> #/email: String is not a valid 'email'
+func CompileSchema(schema string) (*Schema, error) {
+	return NewSchemaCompiler().Compile(schema)
+}
+
+// MustCompileSchema compiles a JSON Schema, panicking when it is not valid.
+func MustCompileSchema(schema string) *Schema {
+	s, err := CompileSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewSchemaCompiler creates a compiler of JSON Schemas, draft 2020-12.
+func NewSchemaCompiler() *SchemaCompiler {
+	return &SchemaCompiler{
+		docs:      make(map[string]string),
+		resources: make(map[string]schemaLocation),
+		anchors:   make(map[string]schemaLocation),
+		dynamics:  make(map[string]map[string]schemaLocation),
+		nodes:     make(map[string]*schemaNode),
+	}
+}
+
+// AddResource registers a schema that the schemas compiled may reference by its uri, or by its $id,
+// e.g: AddResource("https://example.com/address.json", `{"type":"object"}`).
+func (c *SchemaCompiler) AddResource(uri, schema string) error {
+	if !Valid(schema) {
+		return ErrorInvalidJson
+	}
+	base, err := url.Parse(SchemaDefaultBase)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	u = base.ResolveReference(u)
+	u.Fragment, u.RawFragment = "", ""
+	return c.addDocument(u.String(), schema)
+}
+
+// Compile compiles the JSON Schema, resolving its references against itself and the resources added.
+func (c *SchemaCompiler) Compile(schema string) (*Schema, error) {
+	if !Valid(schema) {
+		return nil, ErrorInvalidJson
+	}
+	c.count++
+	uri := SchemaDefaultBase + "schema" + strconv.Itoa(c.count) + ".json"
+	if err := c.addDocument(uri, schema); err != nil {
+		return nil, err
+	}
+	root, err := c.compileAt(schemaLocation{Doc: uri})
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{root: root, dynamics: make(map[string]map[string]*schemaNode)}
+	for resource, anchors := range c.dynamics {
+		s.dynamics[resource] = make(map[string]*schemaNode)
+		for name, loc := range anchors {
+			if s.dynamics[resource][name], err = c.compileAt(loc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.checkCycles(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate validates the json against the schema, the error being SchemaErrors when the json violates it.
+func (s *Schema) Validate(json string) error {
+	if !Valid(json) {
+		return ErrorInvalidJson
+	}
+	return s.ValidateContext(rootOf(json))
+}
+
+// ValidateBytes validates the json against the schema.
+func (s *Schema) ValidateBytes(json []byte) error {
+	return s.Validate(bytesString(json))
+}
+
+// ValidateContext validates a value, e.g: returned by Get, against the schema.
+func (s *Schema) ValidateContext(value BJsonContext) error {
+	errs, _ := s.validate(s.root, value, "", "", nil)
+	if len(errs) > 0 {
+		return SchemaErrors(errs)
+	}
+	return nil
+}
+
+// IsValid checks whether the json is valid against the schema.
+func (s *Schema) IsValid(json string) bool {
+	return s.Validate(json) == nil
+}
+
+func (e SchemaError) Error() string {
+	return "#" + e.InstanceLocation + ": " + e.Message
+}
+
+func (e SchemaErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// addDocument registers the document, along with the resources and anchors it embeds.
+func (c *SchemaCompiler) addDocument(uri, schema string) error {
+	base, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	c.docs[uri] = schema
+	c.resources[uri] = schemaLocation{Doc: uri}
+	return c.register(uri, base, "", rootOf(schema))
+}
+
+func (c *SchemaCompiler) register(doc string, base *url.URL, pointer string, value BJsonContext) error {
+	if value.IsArray() {
+		for i, v := range elements(value) {
+			if err := c.register(doc, base, pointer+"/"+strconv.Itoa(i), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !value.IsObject() {
+		return nil
+	}
+	loc := schemaLocation{Doc: doc, Pointer: pointer}
+	if id := value.Get("$id"); id.Type == String {
+		u, err := url.Parse(id.String())
+		if err != nil {
+			return fmt.Errorf("Invalid $id '%s' at '%s': %v", id.String(), pointer, err)
+		}
+		base = base.ResolveReference(u)
+		base.Fragment, base.RawFragment = "", ""
+		c.resources[base.String()] = loc
+	}
+	if anchor := value.Get("$anchor"); anchor.Type == String {
+		c.anchors[base.String()+"#"+anchor.String()] = loc
+	}
+	if anchor := value.Get("$dynamicAnchor"); anchor.Type == String {
+		c.anchors[base.String()+"#"+anchor.String()] = loc
+		if c.dynamics[base.String()] == nil {
+			c.dynamics[base.String()] = make(map[string]schemaLocation)
+		}
+		c.dynamics[base.String()][anchor.String()] = loc
+	}
+	var err error
+	value.ForEach(func(key, v BJsonContext) bool {
+		switch key.Strings {
+		case "enum", "const", "examples", "default":
+			return true
+		}
+		err = c.register(doc, base, pointer+"/"+EscapePointer(key.Strings), v)
+		return err == nil
+	})
+	return err
+}
+
+// baseOf returns the base uri at the location, applying the $id of the schemas from the root of the document.
+func (c *SchemaCompiler) baseOf(loc schemaLocation) *url.URL {
+	base, _ := url.Parse(loc.Doc)
+	doc := c.docs[loc.Doc]
+	tokens, _ := splitPointer(loc.Pointer)
+	value := rootOf(doc)
+	for i := 0; ; i++ {
+		if id := value.Get("$id"); value.IsObject() && id.Type == String {
+			if u, err := url.Parse(id.String()); err == nil {
+				base = base.ResolveReference(u)
+			}
+		}
+		if i == len(tokens) {
+			break
+		}
+		if value.IsObject() {
+			value = objectMember(value, tokens[i]).Value
+		} else if n, ok := arrayIndex(tokens[i]); ok && value.IsArray() {
+			value = value.Get(strconv.Itoa(n))
+		} else {
+			break
+		}
+	}
+	base.Fragment, base.RawFragment = "", ""
+	return base
+}
+
+// compileAt compiles the schema at the location once, its node being cached before its keywords are compiled
+// so that recursive references resolve to it.
+func (c *SchemaCompiler) compileAt(loc schemaLocation) (*schemaNode, error) {
+	key := loc.Doc + "#" + loc.Pointer
+	if n, ok := c.nodes[key]; ok {
+		return n, nil
+	}
+	m, err := lookupPointer(c.docs[loc.Doc], loc.Pointer)
+	if err == nil && !m.Found {
+		err = ErrorPointerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot locate schema '%s': %w", key, err)
+	}
+	n := &schemaNode{}
+	c.nodes[key] = n
+	if err := c.fill(n, loc, m.Value); err != nil {
+		delete(c.nodes, key)
+		return nil, err
+	}
+	return n, nil
+}
+
+func (c *SchemaCompiler) fill(n *schemaNode, loc schemaLocation, value BJsonContext) error {
+	switch {
+	case value.Type == True || value.Type == False:
+		b := value.Type == True
+		n.Bool = &b
+		return nil
+	case !value.IsObject():
+		return fmt.Errorf("%w, at '%s'", ErrorSchemaInvalid, loc.Doc+"#"+loc.Pointer)
+	}
+	base := c.baseOf(loc)
+	n.Resource = base.String()
+	sub := func(keyword string) (*schemaNode, error) {
+		return c.compileAt(schemaLocation{Doc: loc.Doc, Pointer: loc.Pointer + "/" + keyword})
+	}
+	list := func(keyword string, v BJsonContext) ([]*schemaNode, error) {
+		var nodes []*schemaNode
+		for i := range elements(v) {
+			node, err := sub(keyword + "/" + strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+	dict := func(keyword string, v BJsonContext) (map[string]*schemaNode, error) {
+		nodes := make(map[string]*schemaNode)
+		var err error
+		v.ForEach(func(key, _ BJsonContext) bool {
+			nodes[key.Strings], err = sub(keyword + "/" + EscapePointer(key.Strings))
+			return err == nil
+		})
+		return nodes, err
+	}
+	number := func(v BJsonContext) *float64 {
+		f := v.Float()
+		return &f
+	}
+	var err error
+	value.ForEach(func(key, v BJsonContext) bool {
+		switch key.Strings {
+		case "$ref":
+			n.Ref, _, err = c.resolveRef(base, v.String())
+		case "$dynamicRef":
+			var target schemaLocation
+			n.DynamicRef, target, err = c.resolveRef(base, v.String())
+			if err == nil {
+				name := v.String()[strings.IndexByte(v.String(), '#')+1:]
+				if m, e := lookupPointer(c.docs[target.Doc], target.Pointer); e == nil && m.Value.Get("$dynamicAnchor").String() == name {
+					n.DynamicName = name
+				}
+			}
+		case "type":
+			types := []BJsonContext{v}
+			if v.IsArray() {
+				types = elements(v)
+			}
+			for _, t := range types {
+				if t.Type != String || !schemaTypes[t.Strings] {
+					err = fmt.Errorf("Invalid type %s at '%s'", compactJson(t.Raw), loc.Pointer)
+					return false
+				}
+				n.Types = append(n.Types, t.Strings)
+			}
+		case "enum":
+			n.Enum = elements(v)
+		case "const":
+			value := v
+			n.Const = &value
+		case "multipleOf":
+			n.MultipleOf = number(v)
+		case "maximum":
+			n.Maximum = number(v)
+		case "exclusiveMaximum":
+			n.ExclusiveMaximum = number(v)
+		case "minimum":
+			n.Minimum = number(v)
+		case "exclusiveMinimum":
+			n.ExclusiveMinimum = number(v)
+		case "maxLength":
+			n.MaxLength = number(v)
+		case "minLength":
+			n.MinLength = number(v)
+		case "pattern":
+			if n.Pattern, err = regexp.Compile(v.String()); err != nil {
+				err = fmt.Errorf("Invalid pattern '%s' at '%s': %v", v.String(), loc.Pointer, err)
+			}
+		case "format":
+			n.Format = v.String()
+		case "maxItems":
+			n.MaxItems = number(v)
+		case "minItems":
+			n.MinItems = number(v)
+		case "uniqueItems":
+			n.UniqueItems = v.Bool()
+		case "maxContains":
+			n.MaxContains = number(v)
+		case "minContains":
+			n.MinContains = number(v)
+		case "maxProperties":
+			n.MaxProperties = number(v)
+		case "minProperties":
+			n.MinProperties = number(v)
+		case "required":
+			for _, r := range elements(v) {
+				n.Required = append(n.Required, r.String())
+			}
+		case "dependentRequired":
+			n.DependentRequired = make(map[string][]string)
+			v.ForEach(func(k, names BJsonContext) bool {
+				for _, r := range elements(names) {
+					n.DependentRequired[k.Strings] = append(n.DependentRequired[k.Strings], r.String())
+				}
+				return true
+			})
+		case "allOf":
+			n.AllOf, err = list(key.Strings, v)
+		case "anyOf":
+			n.AnyOf, err = list(key.Strings, v)
+		case "oneOf":
+			n.OneOf, err = list(key.Strings, v)
+		case "prefixItems":
+			n.PrefixItems, err = list(key.Strings, v)
+		case "not":
+			n.Not, err = sub(key.Strings)
+		case "if":
+			n.If, err = sub(key.Strings)
+		case "then":
+			n.Then, err = sub(key.Strings)
+		case "else":
+			n.Else, err = sub(key.Strings)
+		case "items":
+			n.Items, err = sub(key.Strings)
+		case "contains":
+			n.Contains, err = sub(key.Strings)
+		case "additionalProperties":
+			n.AdditionalProperties, err = sub(key.Strings)
+		case "propertyNames":
+			n.PropertyNames, err = sub(key.Strings)
+		case "unevaluatedItems":
+			n.UnevaluatedItems, err = sub(key.Strings)
+		case "unevaluatedProperties":
+			n.UnevaluatedProperties, err = sub(key.Strings)
+		case "properties":
+			n.Properties, err = dict(key.Strings, v)
+		case "dependentSchemas":
+			n.DependentSchemas, err = dict(key.Strings, v)
+		case "patternProperties":
+			v.ForEach(func(k, _ BJsonContext) bool {
+				p := schemaPattern{}
+				if p.Regexp, err = regexp.Compile(k.Strings); err != nil {
+					err = fmt.Errorf("Invalid pattern '%s' at '%s': %v", k.Strings, loc.Pointer, err)
+					return false
+				}
+				if p.Node, err = sub(key.Strings + "/" + EscapePointer(k.Strings)); err != nil {
+					return false
+				}
+				n.PatternProperties = append(n.PatternProperties, p)
+				return true
+			})
+		}
+		return err == nil
+	})
+	return err
+}
+
+// resolveRef compiles the schema referenced, by a json pointer or an anchor, relative to the base uri.
+func (c *SchemaCompiler) resolveRef(base *url.URL, ref string) (*schemaNode, schemaLocation, error) {
+	r, err := url.Parse(ref)
+	if err != nil {
+		return nil, schemaLocation{}, fmt.Errorf("Invalid $ref '%s': %v", ref, err)
+	}
+	u := base.ResolveReference(r)
+	fragment := u.Fragment
+	u.Fragment, u.RawFragment = "", ""
+	var loc schemaLocation
+	var ok bool
+	if fragment == "" || fragment[0] == '/' {
+		if loc, ok = c.resources[u.String()]; ok {
+			loc.Pointer += fragment
+		}
+	} else {
+		loc, ok = c.anchors[u.String()+"#"+fragment]
+	}
+	if !ok {
+		return nil, loc, fmt.Errorf("Cannot resolve $ref '%s' against '%s'", ref, base.String())
+	}
+	node, err := c.compileAt(loc)
+	return node, loc, err
+}
+
+// checkCycles rejects the schemas whose in-place applicators, e.g: $ref, allOf, not, lead back to themselves,
+// e.g: {"$ref":"#"}, as their validation would never end, the instance location staying the same.
+func (s *Schema) checkCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*schemaNode]int)
+	var visit func(n *schemaNode) error
+	visit = func(n *schemaNode) error {
+		switch state[n] {
+		case visiting:
+			return ErrorSchemaCycle
+		case done:
+			return nil
+		}
+		state[n] = visiting
+		inPlace, _ := s.children(n)
+		for _, c := range inPlace {
+			if err := visit(c); err != nil {
+				return err
+			}
+		}
+		state[n] = done
+		return nil
+	}
+	// every schema reachable from the root, through any applicator
+	reached := map[*schemaNode]bool{s.root: true}
+	queue := []*schemaNode{s.root}
+	for _, anchors := range s.dynamics {
+		for _, n := range anchors {
+			if !reached[n] {
+				reached[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if err := visit(n); err != nil {
+			return err
+		}
+		inPlace, deeper := s.children(n)
+		for _, c := range append(inPlace, deeper...) {
+			if !reached[c] {
+				reached[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+	return nil
+}
+
+// children returns the subschemas of the schema applied to the same instance, and those applied to its items, properties or names.
+// The $dynamicRef leads to its static target and to every $dynamicAnchor of the same name it may resolve to.
+func (s *Schema) children(n *schemaNode) (inPlace, deeper []*schemaNode) {
+	add := func(nodes *[]*schemaNode, c ...*schemaNode) {
+		for _, node := range c {
+			if node != nil {
+				*nodes = append(*nodes, node)
+			}
+		}
+	}
+	add(&inPlace, n.Ref, n.DynamicRef, n.Not, n.If, n.Then, n.Else)
+	add(&inPlace, n.AllOf...)
+	add(&inPlace, n.AnyOf...)
+	add(&inPlace, n.OneOf...)
+	for _, c := range n.DependentSchemas {
+		add(&inPlace, c)
+	}
+	if n.DynamicName != "" {
+		for _, anchors := range s.dynamics {
+			add(&inPlace, anchors[n.DynamicName])
+		}
+	}
+	add(&deeper, n.Items, n.Contains, n.AdditionalProperties, n.PropertyNames, n.UnevaluatedItems, n.UnevaluatedProperties)
+	add(&deeper, n.PrefixItems...)
+	for _, c := range n.Properties {
+		add(&deeper, c)
+	}
+	for _, p := range n.PatternProperties {
+		add(&deeper, p.Node)
+	}
+	return inPlace, deeper
+}
+
+// validate validates the value at the instance location against the schema reached by the keyword location,
+// the dynamic scope being the resources entered, returning the properties and items it evaluated when valid.
+func (s *Schema) validate(n *schemaNode, v BJsonContext, instance, keyword string, scope []string) ([]SchemaError, schemaAnnotations) {
+	var errs []SchemaError
+	ann := schemaAnnotations{}
+	fail := func(at, message string, args ...interface{}) {
+		errs = append(errs, SchemaError{InstanceLocation: instance, KeywordLocation: keyword + at, Message: fmt.Sprintf(message, args...)})
+	}
+	if n.Bool != nil {
+		if !*n.Bool {
+			fail("", "Value is not allowed by the false schema")
+		}
+		return errs, ann
+	}
+	if len(scope) == 0 || scope[len(scope)-1] != n.Resource {
+		scope = append(scope[:len(scope):len(scope)], n.Resource)
+	}
+	// in place applicators, their annotations kept when valid
+	apply := func(node *schemaNode, at string) bool {
+		e, a := s.validate(node, v, instance, keyword+at, scope)
+		if len(e) > 0 {
+			return false
+		}
+		ann.merge(a)
+		return true
+	}
+	collect := func(node *schemaNode, at string) {
+		e, a := s.validate(node, v, instance, keyword+at, scope)
+		if len(e) > 0 {
+			errs = append(errs, e...)
+			return
+		}
+		ann.merge(a)
+	}
+	if n.Ref != nil {
+		collect(n.Ref, "/$ref")
+	}
+	if n.DynamicRef != nil {
+		target := n.DynamicRef
+		if n.DynamicName != "" {
+			for _, resource := range scope {
+				if node, ok := s.dynamics[resource][n.DynamicName]; ok {
+					target = node
+					break
+				}
+			}
+		}
+		collect(target, "/$dynamicRef")
+	}
+	if len(n.Types) > 0 && !schemaTypeOf(v, n.Types) {
+		fail("/type", "Value must be of type %s", strings.Join(n.Types, " or "))
+	}
+	if n.Enum != nil {
+		found := false
+		for _, e := range n.Enum {
+			if equalJson(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("/enum", "Value must be one of the enum values")
+		}
+	}
+	if n.Const != nil && !equalJson(v, *n.Const) {
+		fail("/const", "Value must be equal to %s", compactJson(n.Const.Raw))
+	}
+	switch {
+	case v.Type == Number:
+		s.validateNumber(n, v.Numeric, fail)
+	case v.Type == String:
+		length := float64(utf8.RuneCountInString(v.Strings))
+		if n.MaxLength != nil && length > *n.MaxLength {
+			fail("/maxLength", "String must be at most %v characters long", *n.MaxLength)
+		}
+		if n.MinLength != nil && length < *n.MinLength {
+			fail("/minLength", "String must be at least %v characters long", *n.MinLength)
+		}
+		if n.Pattern != nil && !n.Pattern.MatchString(v.Strings) {
+			fail("/pattern", "String must match the pattern '%s'", n.Pattern.String())
+		}
+		if n.Format != "" {
+			schemaFormatsMutex.RLock()
+			fn := schemaFormats[n.Format]
+			schemaFormatsMutex.RUnlock()
+			if fn != nil && !fn(v.Strings) {
+				fail("/format", "String is not a valid '%s'", n.Format)
+			}
+		}
+	case v.IsArray():
+		errs = append(errs, s.validateArray(n, v, instance, keyword, scope, &ann)...)
+	case v.IsObject():
+		errs = append(errs, s.validateObject(n, v, instance, keyword, scope, &ann)...)
+	}
+	for i, node := range n.AllOf {
+		collect(node, "/allOf/"+strconv.Itoa(i))
+	}
+	if n.AnyOf != nil {
+		valid := false
+		for i, node := range n.AnyOf {
+			if apply(node, "/anyOf/"+strconv.Itoa(i)) {
+				valid = true
+			}
+		}
+		if !valid {
+			fail("/anyOf", "Value must match at least one schema of anyOf")
+		}
+	}
+	if n.OneOf != nil {
+		var matched []string
+		for i, node := range n.OneOf {
+			if e, a := s.validate(node, v, instance, keyword+"/oneOf/"+strconv.Itoa(i), scope); len(e) == 0 {
+				matched = append(matched, strconv.Itoa(i))
+				if len(matched) == 1 {
+					ann.merge(a)
+				}
+			}
+		}
+		if len(matched) != 1 {
+			fail("/oneOf", "Value must match exactly one schema of oneOf, matched [%s]", strings.Join(matched, ", "))
+		}
+	}
+	if n.Not != nil {
+		if e, _ := s.validate(n.Not, v, instance, keyword+"/not", scope); len(e) == 0 {
+			fail("/not", "Value must not match the schema of not")
+		}
+	}
+	if n.If != nil {
+		if apply(n.If, "/if") {
+			if n.Then != nil {
+				collect(n.Then, "/then")
+			}
+		} else if n.Else != nil {
+			collect(n.Else, "/else")
+		}
+	}
+	if v.IsObject() && n.DependentSchemas != nil {
+		v.ForEach(func(key, _ BJsonContext) bool {
+			if node, ok := n.DependentSchemas[key.Strings]; ok {
+				collect(node, "/dependentSchemas/"+EscapePointer(key.Strings))
+			}
+			return true
+		})
+	}
+	// unevaluated applicators, once the annotations of all the other keywords are known
+	if n.UnevaluatedItems != nil && v.IsArray() && !ann.AllItems {
+		for i, item := range elements(v) {
+			if ann.Items[i] {
+				continue
+			}
+			e, _ := s.validate(n.UnevaluatedItems, item, instance+"/"+strconv.Itoa(i), keyword+"/unevaluatedItems", scope)
+			errs = append(errs, e...)
+		}
+		ann.AllItems = true
+	}
+	if n.UnevaluatedProperties != nil && v.IsObject() {
+		v.ForEach(func(key, value BJsonContext) bool {
+			if ann.Properties[key.Strings] {
+				return true
+			}
+			errs = append(errs, s.validateProperty(n.UnevaluatedProperties, key.Strings, value, instance, keyword+"/unevaluatedProperties", scope)...)
+			ann.evaluate(key.Strings)
+			return true
+		})
+	}
+	if len(errs) > 0 {
+		return errs, schemaAnnotations{}
+	}
+	return errs, ann
+}
+
+func (s *Schema) validateNumber(n *schemaNode, f float64, fail func(at, message string, args ...interface{})) {
+	if n.MultipleOf != nil && *n.MultipleOf > 0 {
+		q := f / *n.MultipleOf
+		if math.IsInf(q, 0) || math.Abs(q-math.Round(q)) > 1e-9*math.Max(1, math.Abs(q)) {
+			fail("/multipleOf", "Value must be a multiple of %v", *n.MultipleOf)
+		}
+	}
+	if n.Maximum != nil && f > *n.Maximum {
+		fail("/maximum", "Value must be less than or equal to %v", *n.Maximum)
+	}
+	if n.ExclusiveMaximum != nil && f >= *n.ExclusiveMaximum {
+		fail("/exclusiveMaximum", "Value must be less than %v", *n.ExclusiveMaximum)
+	}
+	if n.Minimum != nil && f < *n.Minimum {
+		fail("/minimum", "Value must be greater than or equal to %v", *n.Minimum)
+	}
+	if n.ExclusiveMinimum != nil && f <= *n.ExclusiveMinimum {
+		fail("/exclusiveMinimum", "Value must be greater than %v", *n.ExclusiveMinimum)
+	}
+}
+
+func (s *Schema) validateArray(n *schemaNode, v BJsonContext, instance, keyword string, scope []string, ann *schemaAnnotations) []SchemaError {
+	var errs []SchemaError
+	fail := func(at, message string, args ...interface{}) {
+		errs = append(errs, SchemaError{InstanceLocation: instance, KeywordLocation: keyword + at, Message: fmt.Sprintf(message, args...)})
+	}
+	items := elements(v)
+	count := float64(len(items))
+	if n.MaxItems != nil && count > *n.MaxItems {
+		fail("/maxItems", "Array must have at most %v items", *n.MaxItems)
+	}
+	if n.MinItems != nil && count < *n.MinItems {
+		fail("/minItems", "Array must have at least %v items", *n.MinItems)
+	}
+	if n.UniqueItems {
+	unique:
+		for i := range items {
+			for j := 0; j < i; j++ {
+				if equalJson(items[i], items[j]) {
+					fail("/uniqueItems", "Array items must be unique, items %d and %d are equal", j, i)
+					break unique
+				}
+			}
+		}
+	}
+	for i, node := range n.PrefixItems {
+		if i >= len(items) {
+			break
+		}
+		e, _ := s.validate(node, items[i], instance+"/"+strconv.Itoa(i), keyword+"/prefixItems/"+strconv.Itoa(i), scope)
+		errs = append(errs, e...)
+		ann.item(i)
+	}
+	if n.Items != nil {
+		for i := len(n.PrefixItems); i < len(items); i++ {
+			e, _ := s.validate(n.Items, items[i], instance+"/"+strconv.Itoa(i), keyword+"/items", scope)
+			errs = append(errs, e...)
+		}
+		ann.AllItems = true
+	}
+	if n.Contains != nil {
+		matched := 0
+		for i, item := range items {
+			if e, _ := s.validate(n.Contains, item, instance+"/"+strconv.Itoa(i), keyword+"/contains", scope); len(e) == 0 {
+				matched++
+				ann.item(i)
+			}
+		}
+		min := 1.0
+		if n.MinContains != nil {
+			min = *n.MinContains
+		}
+		if float64(matched) < min {
+			fail("/contains", "Array must contain at least %v items matching the schema of contains", min)
+		}
+		if n.MaxContains != nil && float64(matched) > *n.MaxContains {
+			fail("/maxContains", "Array must contain at most %v items matching the schema of contains", *n.MaxContains)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(n *schemaNode, v BJsonContext, instance, keyword string, scope []string, ann *schemaAnnotations) []SchemaError {
+	var errs []SchemaError
+	fail := func(at, message string, args ...interface{}) {
+		errs = append(errs, SchemaError{InstanceLocation: instance, KeywordLocation: keyword + at, Message: fmt.Sprintf(message, args...)})
+	}
+	props := members(v)
+	count := float64(len(props))
+	if n.MaxProperties != nil && count > *n.MaxProperties {
+		fail("/maxProperties", "Object must have at most %v properties", *n.MaxProperties)
+	}
+	if n.MinProperties != nil && count < *n.MinProperties {
+		fail("/minProperties", "Object must have at least %v properties", *n.MinProperties)
+	}
+	for _, name := range n.Required {
+		if _, ok := props[name]; !ok {
+			fail("/required", "Property '%s' is required", name)
+		}
+	}
+	if n.DependentRequired != nil {
+		v.ForEach(func(key, _ BJsonContext) bool {
+			for _, name := range n.DependentRequired[key.Strings] {
+				if _, ok := props[name]; !ok {
+					fail("/dependentRequired/"+EscapePointer(key.Strings), "Property '%s' is required when '%s' is present", name, key.Strings)
+				}
+			}
+			return true
+		})
+	}
+	v.ForEach(func(key, value BJsonContext) bool {
+		name := key.Strings
+		evaluated := false
+		if node, ok := n.Properties[name]; ok {
+			errs = append(errs, s.validateProperty(node, name, value, instance, keyword+"/properties/"+EscapePointer(name), scope)...)
+			evaluated = true
+		}
+		for _, p := range n.PatternProperties {
+			if p.Regexp.MatchString(name) {
+				errs = append(errs, s.validateProperty(p.Node, name, value, instance, keyword+"/patternProperties/"+EscapePointer(p.Regexp.String()), scope)...)
+				evaluated = true
+			}
+		}
+		if !evaluated && n.AdditionalProperties != nil {
+			errs = append(errs, s.validateProperty(n.AdditionalProperties, name, value, instance, keyword+"/additionalProperties", scope)...)
+			evaluated = true
+		}
+		if evaluated {
+			ann.evaluate(name)
+		}
+		if n.PropertyNames != nil {
+			if e, _ := s.validate(n.PropertyNames, Parse(key.Raw), instance+"/"+EscapePointer(name), keyword+"/propertyNames", scope); len(e) > 0 {
+				fail("/propertyNames", "Property name '%s' is not valid", name)
+			}
+		}
+		return true
+	})
+	return errs
+}
+
+// validateProperty validates the value of the property, its violation of a false schema reported as a property not allowed.
+func (s *Schema) validateProperty(n *schemaNode, name string, value BJsonContext, instance, keyword string, scope []string) []SchemaError {
+	if n.Bool != nil && !*n.Bool {
+		return []SchemaError{{InstanceLocation: instance + "/" + EscapePointer(name), KeywordLocation: keyword, Message: fmt.Sprintf("Property '%s' is not allowed", name)}}
+	}
+	errs, _ := s.validate(n, value, instance+"/"+EscapePointer(name), keyword, scope)
+	return errs
+}
+
+func (a *schemaAnnotations) merge(o schemaAnnotations) {
+	for name := range o.Properties {
+		a.evaluate(name)
+	}
+	for i := range o.Items {
+		a.item(i)
+	}
+	a.AllItems = a.AllItems || o.AllItems
+}
+
+func (a *schemaAnnotations) evaluate(name string) {
+	if a.Properties == nil {
+		a.Properties = make(map[string]bool)
+	}
+	a.Properties[name] = true
+}
+
+func (a *schemaAnnotations) item(i int) {
+	if a.Items == nil {
+		a.Items = make(map[int]bool)
+	}
+	a.Items[i] = true
+}
+
+// schemaTypeOf checks whether the value is of any of the types, an integer being a number without fraction, e.g: 1.0.
+func schemaTypeOf(v BJsonContext, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v.Type == Null {
+				return true
+			}
+		case "boolean":
+			if v.Type == True || v.Type == False {
+				return true
+			}
+		case "number":
+			if v.Type == Number {
+				return true
+			}
+		case "integer":
+			if v.Type == Number && v.Numeric == math.Trunc(v.Numeric) && !math.IsInf(v.Numeric, 0) {
+				return true
+			}
+		case "string":
+			if v.Type == String {
+				return true
+			}
+		case "array":
+			if v.IsArray() {
+				return true
+			}
+		case "object":
+			if v.IsObject() {
+				return true
+			}
+		}
+	}
+	return false
+}
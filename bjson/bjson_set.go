@@ -0,0 +1,606 @@
+package bjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Set sets a json value for the specified path.
+// A path is in dot syntax, such as "name.last" or "age", the same as Get.
+// This function expects that the json is well-formed, and does not validate.
+// Invalid json will not panic, but it may return back unexpected results.
+// An error is returned if the path is not valid.
+//
+// A path is a series of keys separated by a dot.
+//
+//	{
+//	  "name": {"first": "Tom", "last": "Anderson"},
+//	  "age":37,
+//	  "children": ["Sara","Alex","Jack"],
+//	  "friends": [
+//	    {"first": "James", "last": "Murphy"},
+//	    {"first": "Roger", "last": "Craig"}
+//	  ]
+//	}
+//	"name.last"          >> "Anderson"
+//	"age"                >> 37
+//	"children.1"         >> "Alex"
+//	"children.-1"        >> appends a new value to the end of the children array
+//	"friends.#(last=Murphy).first" >> replaces "James", paths with queries only replace existing values
+//
+// Missing objects and arrays along the path are created, numeric keys creating arrays unless forced
+// to object keys with a ":" prefix, e.g: "ids.:3". The formatting and the key order of the json are preserved.
+func Set(json, path string, value interface{}) (string, error) {
+	return SetWith(json, path, value, nil)
+}
+
+// SetWith sets a json value for the specified path with options.
+// ReplaceInPlace is ignored, the memory of a string being immutable.
+func SetWith(json, path string, value interface{}, opts *SetOptions) (string, error) {
+	if opts != nil && opts.ReplaceInPlace {
+		copied := *opts
+		copied.ReplaceInPlace = false
+		opts = &copied
+	}
+	res, err := SetBytesWith(stringBytes(json), path, value, opts)
+	return string(res), err
+}
+
+// SetBytes sets a json value for the specified path.
+// If working with bytes, this method preferred over Set(string(data), path, value)
+func SetBytes(json []byte, path string, value interface{}) ([]byte, error) {
+	return SetBytesWith(json, path, value, nil)
+}
+
+// SetBytesWith sets a json value for the specified path with options.
+// With ReplaceInPlace, the json given is overwritten when the new value fits in it, avoiding allocations;
+// the json given must not be used afterwards.
+func SetBytesWith(json []byte, path string, value interface{}, opts *SetOptions) ([]byte, error) {
+	var optimistic, inplace bool
+	if opts != nil {
+		optimistic = opts.Optimistic
+		inplace = opts.ReplaceInPlace
+	}
+	str := bytesString(json)
+	var res []byte
+	var err error
+	switch v := value.(type) {
+	default:
+		b, e := marshalJson(value)
+		if e != nil {
+			return nil, e
+		}
+		res, err = set(str, path, bytesString(b), false, false, optimistic, inplace)
+	case deleteValue:
+		res, err = set(str, path, "", false, true, optimistic, inplace)
+	case string:
+		res, err = set(str, path, v, true, false, optimistic, inplace)
+	case []byte:
+		res, err = set(str, path, bytesString(v), true, false, optimistic, inplace)
+	case bool:
+		if v {
+			res, err = set(str, path, "true", false, false, optimistic, inplace)
+		} else {
+			res, err = set(str, path, "false", false, false, optimistic, inplace)
+		}
+	case int8:
+		res, err = set(str, path, strconv.FormatInt(int64(v), 10), false, false, optimistic, inplace)
+	case int16:
+		res, err = set(str, path, strconv.FormatInt(int64(v), 10), false, false, optimistic, inplace)
+	case int32:
+		res, err = set(str, path, strconv.FormatInt(int64(v), 10), false, false, optimistic, inplace)
+	case int64:
+		res, err = set(str, path, strconv.FormatInt(v, 10), false, false, optimistic, inplace)
+	case int:
+		res, err = set(str, path, strconv.FormatInt(int64(v), 10), false, false, optimistic, inplace)
+	case uint8:
+		res, err = set(str, path, strconv.FormatUint(uint64(v), 10), false, false, optimistic, inplace)
+	case uint16:
+		res, err = set(str, path, strconv.FormatUint(uint64(v), 10), false, false, optimistic, inplace)
+	case uint32:
+		res, err = set(str, path, strconv.FormatUint(uint64(v), 10), false, false, optimistic, inplace)
+	case uint64:
+		res, err = set(str, path, strconv.FormatUint(v, 10), false, false, optimistic, inplace)
+	case uint:
+		res, err = set(str, path, strconv.FormatUint(uint64(v), 10), false, false, optimistic, inplace)
+	case float32:
+		res, err = set(str, path, strconv.FormatFloat(float64(v), 'f', -1, 32), false, false, optimistic, inplace)
+	case float64:
+		res, err = set(str, path, strconv.FormatFloat(v, 'f', -1, 64), false, false, optimistic, inplace)
+	}
+	if err == errorNoChange {
+		return json, nil
+	}
+	return res, err
+}
+
+// SetRaw sets a raw json value for the specified path, e.g: `{"id":1}`, inserted as is.
+func SetRaw(json, path, value string) (string, error) {
+	return SetRawWith(json, path, value, nil)
+}
+
+// SetRawWith sets a raw json value for the specified path with options.
+func SetRawWith(json, path, value string, opts *SetOptions) (string, error) {
+	var optimistic bool
+	if opts != nil {
+		optimistic = opts.Optimistic
+	}
+	res, err := set(json, path, value, false, false, optimistic, false)
+	if err == errorNoChange {
+		return json, nil
+	}
+	return string(res), err
+}
+
+// SetRawBytes sets a raw json value for the specified path.
+// If working with bytes, this method preferred over SetRaw(string(data), path, value)
+func SetRawBytes(json []byte, path string, value []byte) ([]byte, error) {
+	return SetRawBytesWith(json, path, value, nil)
+}
+
+// SetRawBytesWith sets a raw json value for the specified path with options.
+func SetRawBytesWith(json []byte, path string, value []byte, opts *SetOptions) ([]byte, error) {
+	var optimistic, inplace bool
+	if opts != nil {
+		optimistic = opts.Optimistic
+		inplace = opts.ReplaceInPlace
+	}
+	res, err := set(bytesString(json), path, bytesString(value), false, false, optimistic, inplace)
+	if err == errorNoChange {
+		return json, nil
+	}
+	return res, err
+}
+
+// Delete deletes a value from json for the specified path, "-1" deleting the last element of an array.
+// The json is returned unchanged when the value does not exist.
+func Delete(json, path string) (string, error) {
+	return Set(json, path, deleteValue{})
+}
+
+// DeleteBytes deletes a value from json for the specified path.
+func DeleteBytes(json []byte, path string) ([]byte, error) {
+	return SetBytes(json, path, deleteValue{})
+}
+
+func set(json, path, raw string, stringify, del, optimistic, inplace bool) ([]byte, error) {
+	if path == "" {
+		return []byte(json), ErrorPathEmpty
+	}
+	if !del && optimistic && isOptimisticPath(path) {
+		res := Get(json, path)
+		if res.Exists() && res.Index > 0 {
+			size := len(json) - len(res.Raw) + len(raw)
+			if stringify {
+				size += 2
+			}
+			if inplace && size <= len(json) {
+				if !stringify || !mustMarshalString(raw) {
+					b := stringBytes(json)
+					if stringify {
+						b[res.Index] = '"'
+						copy(b[res.Index+1:], raw)
+						b[res.Index+1+len(raw)] = '"'
+						copy(b[res.Index+1+len(raw)+1:], b[res.Index+len(res.Raw):])
+					} else {
+						copy(b[res.Index:], raw)
+						copy(b[res.Index+len(raw):], b[res.Index+len(res.Raw):])
+					}
+					return b[:size], nil
+				}
+			}
+			buf := make([]byte, 0, size)
+			buf = append(buf, json[:res.Index]...)
+			if stringify {
+				buf = appendStringify(buf, raw)
+			} else {
+				buf = append(buf, raw...)
+			}
+			buf = append(buf, json[res.Index+len(res.Raw):]...)
+			return buf, nil
+		}
+	}
+	var paths []setPathContext
+	r, simple := parseSetPath(path)
+	if simple {
+		paths = append(paths, r)
+		for r.More {
+			r, simple = parseSetPath(r.Path)
+			if !simple {
+				break
+			}
+			paths = append(paths, r)
+		}
+	}
+	if !simple {
+		if del {
+			return []byte(json), ErrorDeleteComplexPath
+		}
+		return setComplexPath(json, path, raw, stringify)
+	}
+	res, err := appendRawPaths(nil, json, paths, raw, stringify, del)
+	if err != nil {
+		return []byte(json), err
+	}
+	return res, nil
+}
+
+// setComplexPath replaces the existing values of a path with wildcards, queries or modifiers.
+func setComplexPath(json, path, raw string, stringify bool) ([]byte, error) {
+	res := Get(json, path)
+	if !res.Exists() || !(res.Index != 0 || len(res.Indexes) != 0) {
+		return []byte(json), errorNoChange
+	}
+	if res.Index != 0 {
+		buf := []byte(json[:res.Index])
+		if stringify {
+			buf = appendStringify(buf, raw)
+		} else {
+			buf = append(buf, raw...)
+		}
+		buf = append(buf, json[res.Index+len(res.Raw):]...)
+		return buf, nil
+	}
+	type located struct {
+		index int
+		value BJsonContext
+	}
+	values := make([]located, 0, len(res.Indexes))
+	res.ForEach(func(_, value BJsonContext) bool {
+		values = append(values, located{value: value})
+		return true
+	})
+	if len(res.Indexes) != len(values) {
+		return []byte(json), errorNoChange
+	}
+	for i := 0; i < len(res.Indexes); i++ {
+		values[i].index = res.Indexes[i]
+	}
+	// replace from the end, so that the indexes of the values before remain valid
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].index > values[j].index
+	})
+	for _, v := range values {
+		buf := []byte(json[:v.index])
+		if stringify {
+			buf = appendStringify(buf, raw)
+		} else {
+			buf = append(buf, raw...)
+		}
+		buf = append(buf, json[v.index+len(v.value.Raw):]...)
+		json = string(buf)
+	}
+	return []byte(json), nil
+}
+
+func appendRawPaths(buf []byte, json string, paths []setPathContext, raw string, stringify, del bool) ([]byte, error) {
+	var err error
+	var res BJsonContext
+	var found bool
+	if del && paths[0].Part == "-1" && !paths[0].Force {
+		res = Get(json, "#")
+		if res.Int() > 0 {
+			res = Get(json, strconv.FormatInt(res.Int()-1, 10))
+			found = true
+		}
+	}
+	if !found {
+		res = Get(json, paths[0].GPart)
+	}
+	if res.Index > 0 {
+		if len(paths) > 1 {
+			buf = append(buf, json[:res.Index]...)
+			buf, err = appendRawPaths(buf, res.Raw, paths[1:], raw, stringify, del)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, json[res.Index+len(res.Raw):]...)
+			return buf, nil
+		}
+		buf = append(buf, json[:res.Index]...)
+		var skip int // the comma following the value deleted
+		if del {
+			var nextComma bool
+			buf, nextComma = deleteTailItem(buf)
+			if nextComma {
+				i, j := res.Index+len(res.Raw), 0
+				for ; i < len(json); i, j = i+1, j+1 {
+					if json[i] <= ' ' {
+						continue
+					}
+					if json[i] == ',' {
+						// as well as the whitespace following it, the one leading the container being kept
+						for skip = j + 1; i+1 < len(json) && json[i+1] <= ' '; i++ {
+							skip++
+						}
+					}
+					break
+				}
+			}
+		} else if stringify {
+			buf = appendStringify(buf, raw)
+		} else {
+			buf = append(buf, raw...)
+		}
+		buf = append(buf, json[res.Index+len(res.Raw)+skip:]...)
+		return buf, nil
+	}
+	if del {
+		return nil, errorNoChange
+	}
+	n, numeric := atoui(paths[0])
+	if trim(json) == "" {
+		if numeric {
+			json = "[]"
+		} else {
+			json = "{}"
+		}
+	}
+	container := Parse(json)
+	if container.Type != JSON {
+		if numeric {
+			json = "[]"
+		} else {
+			json = "{}"
+		}
+		container = Parse(json)
+	}
+	tail, sep, comma := insertionPoint(container.Raw)
+	switch container.Raw[0] {
+	default:
+		return nil, ErrorNotObjectOrArray
+	case '{':
+		buf = append(buf, container.Raw[:tail]...)
+		if comma {
+			buf = append(buf, sep...)
+		}
+		buf = appendBuild(buf, false, paths, raw, stringify)
+		buf = append(buf, container.Raw[tail:]...)
+		return buf, nil
+	case '[':
+		items := len(container.Array())
+		if !numeric {
+			if paths[0].Part != "-1" || paths[0].Force {
+				return nil, fmt.Errorf("Cannot set array element for non-numeric key '%s'", paths[0].Part)
+			}
+			n = items
+		}
+		buf = append(buf, container.Raw[:tail]...)
+		for i := items; i < n; i++ {
+			if i > 0 {
+				buf = append(buf, sep...)
+			}
+			buf = append(buf, "null"...)
+		}
+		if n > 0 {
+			buf = append(buf, sep...)
+		}
+		buf = appendBuild(buf, true, paths, raw, stringify)
+		buf = append(buf, container.Raw[tail:]...)
+		return buf, nil
+	}
+}
+
+// insertionPoint returns where a member is appended to the container, right after its last one,
+// the trailing whitespace kept before the closing bracket, as well as the separator preceding it,
+// on its own line when the container spans several lines, and whether the container has members.
+func insertionPoint(container string) (tail int, sep string, comma bool) {
+	lead := 1
+	for lead < len(container) && container[lead] <= ' ' {
+		lead++
+	}
+	comma = lead < len(container) && container[lead] != '}' && container[lead] != ']'
+	tail = len(container) - 1
+	for tail > 0 && container[tail] != '}' && container[tail] != ']' {
+		tail--
+	}
+	for tail > 0 && container[tail-1] <= ' ' {
+		tail--
+	}
+	sep = ","
+	if comma && strings.IndexByte(container[1:lead], '\n') >= 0 {
+		sep += container[1:lead]
+	}
+	return tail, sep, comma
+}
+
+// appendBuild builds the json of the missing path, e.g: `"a":{"b":[null,1]}`.
+func appendBuild(buf []byte, array bool, paths []setPathContext, raw string, stringify bool) []byte {
+	if !array {
+		buf = appendStringify(buf, paths[0].Part)
+		buf = append(buf, ':')
+	}
+	if len(paths) > 1 {
+		n, numeric := atoui(paths[1])
+		if numeric || (!paths[1].Force && paths[1].Part == "-1") {
+			buf = append(buf, '[')
+			buf = appendRepeat(buf, "null,", n)
+			buf = appendBuild(buf, true, paths[1:], raw, stringify)
+			buf = append(buf, ']')
+		} else {
+			buf = append(buf, '{')
+			buf = appendBuild(buf, false, paths[1:], raw, stringify)
+			buf = append(buf, '}')
+		}
+		return buf
+	}
+	if stringify {
+		return appendStringify(buf, raw)
+	}
+	return append(buf, raw...)
+}
+
+// deleteTailItem deletes the key, or the comma, preceding the value deleted,
+// reporting whether the comma following the value must be deleted instead.
+func deleteTailItem(buf []byte) ([]byte, bool) {
+loop:
+	for i := len(buf) - 1; i >= 0; i-- {
+		switch buf[i] {
+		case '[':
+			return buf, true
+		case ',':
+			return buf[:i], false
+		case ':':
+			// delete the key
+			i--
+			for ; i >= 0; i-- {
+				if buf[i] == '"' {
+					i--
+					for ; i >= 0; i-- {
+						if buf[i] == '"' {
+							i--
+							if i >= 0 && buf[i] == '\\' {
+								i--
+								continue
+							}
+							for ; i >= 0; i-- {
+								switch buf[i] {
+								case '{':
+									// keep the whitespace following the brace
+									j := i + 1
+									for j < len(buf) && buf[j] <= ' ' {
+										j++
+									}
+									return buf[:j], true
+								case ',':
+									return buf[:i], false
+								}
+							}
+						}
+					}
+					break
+				}
+			}
+			break loop
+		}
+	}
+	return buf, false
+}
+
+// parseSetPath parses the first key of a simple path, reporting false when the path
+// has wildcards, queries, pipes or modifiers.
+func parseSetPath(path string) (setPathContext, bool) {
+	var r setPathContext
+	if len(path) > 0 && path[0] == ':' {
+		r.Force = true
+		path = path[1:]
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '.' {
+			r.Part = path[:i]
+			r.GPart = path[:i]
+			r.Path = path[i+1:]
+			r.More = true
+			return r, true
+		}
+		if !isSimpleSetChar(path[i]) {
+			return r, false
+		}
+		if path[i] == '\\' {
+			// escaped keys, stripped of their escape characters
+			part := []byte(path[:i])
+			gpart := []byte(path[:i+1])
+			i++
+			if i < len(path) {
+				part = append(part, path[i])
+				gpart = append(gpart, path[i])
+				i++
+				for ; i < len(path); i++ {
+					if path[i] == '\\' {
+						gpart = append(gpart, '\\')
+						i++
+						if i < len(path) {
+							part = append(part, path[i])
+							gpart = append(gpart, path[i])
+						}
+						continue
+					} else if path[i] == '.' {
+						r.Part = string(part)
+						r.GPart = string(gpart)
+						r.Path = path[i+1:]
+						r.More = true
+						return r, true
+					} else if !isSimpleSetChar(path[i]) {
+						return r, false
+					}
+					part = append(part, path[i])
+					gpart = append(gpart, path[i])
+				}
+			}
+			r.Part = string(part)
+			r.GPart = string(gpart)
+			return r, true
+		}
+	}
+	r.Part = path
+	r.GPart = path
+	return r, true
+}
+
+func isSimpleSetChar(c byte) bool {
+	switch c {
+	case '|', '#', '@', '*', '?':
+		return false
+	default:
+		return true
+	}
+}
+
+func isOptimisticPath(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] < '.' || path[i] > 'z' {
+			return false
+		}
+		if path[i] > '9' && path[i] < 'A' {
+			return false
+		}
+	}
+	return true
+}
+
+// atoui converts the key to an array index, false when it is not numeric or forced to an object key.
+func atoui(r setPathContext) (n int, ok bool) {
+	if r.Force || r.Part == "" {
+		return 0, false
+	}
+	for i := 0; i < len(r.Part); i++ {
+		if r.Part[i] < '0' || r.Part[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(r.Part[i]-'0')
+	}
+	return n, true
+}
+
+func appendRepeat(buf []byte, s string, n int) []byte {
+	for i := 0; i < n; i++ {
+		buf = append(buf, s...)
+	}
+	return buf
+}
+
+func mustMarshalString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > 0x7f || s[i] == '"' || s[i] == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+// appendStringify appends the string as a json string.
+func appendStringify(buf []byte, s string) []byte {
+	if mustMarshalString(s) {
+		return AppendJsonString(buf, s)
+	}
+	buf = append(buf, '"')
+	buf = append(buf, s...)
+	return append(buf, '"')
+}
+
+func marshalJson(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
@@ -0,0 +1,659 @@
+package bjson
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Stream reads the json documents of the stream, one or many such as json lines, iterating through the values
+// matching the path, e.g: "items.#.id", "items.0", "meta.*", each value read into memory on its own.
+// The path is a series of keys separated by a dot, a key possibly having wildcards '*' and '?',
+// an index matching the element of an array, and '#' any element; an empty path matches the documents.
+// The other syntax of Get, i.e: the queries, e.g: "items.#(id==1)", the modifiers, e.g: "@pretty", the pipes '|',
+// the multipaths and the literals, is not supported, the path then failing with ErrorStreamPathUnsupported.
+// Return false from the iterator to stop reading.
+//
+//	f, _ := os.Open("export.json")
+//	err := Stream(f, "items.#.id", func(value BJsonContext) bool {
+//		fmt.Println(value.Int(), value.Index) // the id and its offset in the file
+//		return true
+//	})
+func Stream(r io.Reader, path string, iterator func(value BJsonContext) bool) error {
+	return StreamMany(r, []string{path}, func(_ string, value BJsonContext) bool {
+		return iterator(value)
+	})
+}
+
+// StreamMany reads the json documents of the stream in a single pass, iterating through the values matching
+// any of the paths, along with their path in BJsonContext.Path notation, e.g: "items.3.id".
+// The paths are of the syntax of Stream, failing with ErrorStreamPathUnsupported otherwise.
+func StreamMany(r io.Reader, paths []string, iterator func(path string, value BJsonContext) bool) error {
+	patterns := make([][]streamComponent, len(paths))
+	for i, path := range paths {
+		pattern, err := parseStreamPath(path)
+		if err != nil {
+			return err
+		}
+		patterns[i] = pattern
+	}
+	s := newStreamScanner(r)
+	for {
+		if _, ok := s.peek(); !ok {
+			return s.final()
+		}
+		if err := s.walk("", patterns, 0, iterator); err != nil {
+			if err == errorStreamStop {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// ForEachLineReader iterates through the json values of the stream, as ForEachLine does with the json lines
+// of a string, holding in memory no more than the value being read.
+// Return false from the iterator to stop reading.
+func ForEachLineReader(r io.Reader, iterator func(line BJsonContext) bool) error {
+	s := newStreamScanner(r)
+	for {
+		if _, ok := s.peek(); !ok {
+			return s.final()
+		}
+		value, err := s.capture()
+		if err != nil {
+			return err
+		}
+		if !iterator(value) {
+			return nil
+		}
+	}
+}
+
+// NewTokenizer creates a tokenizer reading the json documents of the stream, one or many such as json lines.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{scanner: newStreamScanner(r)}
+}
+
+// Next reads the next token, io.EOF at the end of the stream.
+// Keys are returned as String values, and scalars as values of their type, their Index the offset in the stream.
+func (t *Tokenizer) Next() (Token, error) {
+	c, err := t.prepare()
+	if err != nil {
+		return Token{}, err
+	}
+	offset := t.scanner.offset()
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		if c == '}' || c == ']' {
+			if (c == '}') != f.Object || f.Pending {
+				return Token{}, t.scanner.syntax(c)
+			}
+			t.scanner.pos++
+			t.stack = t.stack[:n-1]
+			t.done()
+			if c == '}' {
+				return Token{Kind: TokenEndObject, Offset: offset}, nil
+			}
+			return Token{Kind: TokenEndArray, Offset: offset}, nil
+		}
+		if f.Object && !f.Pending {
+			if c != '"' {
+				return Token{}, t.scanner.syntax(c)
+			}
+			key, err := t.scanner.capture()
+			if err != nil {
+				return Token{}, err
+			}
+			if err := t.scanner.expect(':'); err != nil {
+				return Token{}, err
+			}
+			f.Key, f.HasKey, f.Pending = key.Strings, true, true
+			return Token{Kind: TokenKey, Value: key, Offset: offset}, nil
+		}
+		if !f.Pending {
+			f.Index++
+			f.Pending = true
+		}
+	}
+	switch c {
+	case '{':
+		t.scanner.pos++
+		t.stack = append(t.stack, streamFrame{Object: true, Index: -1})
+		return Token{Kind: TokenBeginObject, Offset: offset}, nil
+	case '[':
+		t.scanner.pos++
+		t.stack = append(t.stack, streamFrame{Index: -1})
+		return Token{Kind: TokenBeginArray, Offset: offset}, nil
+	case '}', ']', ',', ':':
+		return Token{}, t.scanner.syntax(c)
+	}
+	value, err := t.scanner.capture()
+	if err != nil {
+		return Token{}, err
+	}
+	t.done()
+	return Token{Kind: TokenValue, Value: value, Offset: offset}, nil
+}
+
+// ReadValue reads the next value as a whole, e.g: the object following a key, ErrorTokenNotValue when the next token is not a value.
+func (t *Tokenizer) ReadValue() (BJsonContext, error) {
+	if err := t.beginValue(); err != nil {
+		return BJsonContext{}, err
+	}
+	value, err := t.scanner.capture()
+	if err != nil {
+		return BJsonContext{}, err
+	}
+	t.done()
+	return value, nil
+}
+
+// SkipValue skips the next value as a whole, without holding it in memory.
+func (t *Tokenizer) SkipValue() error {
+	if err := t.beginValue(); err != nil {
+		return err
+	}
+	if err := t.scanner.skipValue(); err != nil {
+		return err
+	}
+	t.done()
+	return nil
+}
+
+// Path returns the path of the last token in BJsonContext.Path notation, e.g: "items.3.id", "@this" for the documents.
+func (t *Tokenizer) Path() string {
+	var path string
+	for _, f := range t.stack {
+		if f.Object && f.HasKey {
+			path = childPath(path, escapeComp(f.Key))
+		} else if !f.Object && f.Index >= 0 {
+			path = childPath(path, strconv.Itoa(f.Index))
+		}
+	}
+	if path == "" {
+		return "@this"
+	}
+	return path
+}
+
+// Depth returns the number of objects and arrays opened.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Offset returns the number of bytes read from the stream.
+func (t *Tokenizer) Offset() int64 {
+	return t.scanner.offset()
+}
+
+// prepare skips the comma separating the members, or elements, returning the first byte of the next token.
+func (t *Tokenizer) prepare() (byte, error) {
+	c, ok := t.scanner.peek()
+	if !ok {
+		return 0, t.end()
+	}
+	n := len(t.stack)
+	if n == 0 || t.stack[n-1].Pending || !t.stack[n-1].HasItem || c == '}' || c == ']' {
+		return c, nil
+	}
+	if c != ',' {
+		return 0, t.scanner.syntax(c)
+	}
+	t.scanner.pos++
+	if c, ok = t.scanner.peek(); !ok {
+		return 0, t.end()
+	}
+	if c == '}' || c == ']' {
+		return 0, t.scanner.syntax(c)
+	}
+	return c, nil
+}
+
+func (t *Tokenizer) beginValue() error {
+	c, err := t.prepare()
+	if err != nil {
+		return err
+	}
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		if c == '}' || c == ']' || (f.Object && !f.Pending) {
+			return ErrorTokenNotValue
+		}
+		if !f.Pending {
+			f.Index++
+			f.Pending = true
+		}
+	}
+	return nil
+}
+
+// done marks the member, or element, being read as complete.
+func (t *Tokenizer) done() {
+	if n := len(t.stack); n > 0 {
+		t.stack[n-1].Pending = false
+		t.stack[n-1].HasItem = true
+	}
+}
+
+func (t *Tokenizer) end() error {
+	if len(t.stack) > 0 {
+		return t.scanner.unexpected()
+	}
+	if err := t.scanner.final(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func newStreamScanner(r io.Reader) *streamScanner {
+	size := StreamBufferSize
+	if size <= 0 {
+		size = 4096
+	}
+	return &streamScanner{reader: r, buf: make([]byte, 0, size), mark: -1}
+}
+
+// fill reads from the stream, discarding the bytes consumed unless captured and growing the buffer when full,
+// returning false at the end of the stream.
+func (s *streamScanner) fill() bool {
+	if s.err != nil {
+		return false
+	}
+	keep := s.pos
+	if s.mark >= 0 {
+		keep = s.mark
+	}
+	if keep > 0 {
+		n := copy(s.buf, s.buf[keep:])
+		s.buf = s.buf[:n]
+		s.pos -= keep
+		s.base += int64(keep)
+		if s.mark >= 0 {
+			s.mark -= keep
+		}
+	}
+	if len(s.buf) == cap(s.buf) {
+		buf := make([]byte, len(s.buf), 2*cap(s.buf))
+		copy(buf, s.buf)
+		s.buf = buf
+	}
+	n, err := s.reader.Read(s.buf[len(s.buf):cap(s.buf)])
+	s.buf = s.buf[:len(s.buf)+n]
+	if err != nil {
+		s.err = err
+	}
+	return n > 0 || err == nil
+}
+
+// peek returns the next byte other than whitespace, without consuming it.
+func (s *streamScanner) peek() (byte, bool) {
+	for {
+		for s.pos < len(s.buf) {
+			if c := s.buf[s.pos]; c > ' ' {
+				return c, true
+			}
+			s.pos++
+		}
+		if !s.fill() {
+			return 0, false
+		}
+	}
+}
+
+func (s *streamScanner) offset() int64 {
+	return s.base + int64(s.pos)
+}
+
+func (s *streamScanner) expect(c byte) error {
+	next, ok := s.peek()
+	if !ok {
+		return s.unexpected()
+	}
+	if next != c {
+		return s.syntax(next)
+	}
+	s.pos++
+	return nil
+}
+
+// capture reads the next value into memory, its Index the offset in the stream.
+func (s *streamScanner) capture() (BJsonContext, error) {
+	if _, ok := s.peek(); !ok {
+		return BJsonContext{}, s.unexpected()
+	}
+	s.mark = s.pos
+	offset := s.offset()
+	err := s.skipValue()
+	raw := string(s.buf[s.mark:s.pos])
+	s.mark = -1
+	if err != nil {
+		return BJsonContext{}, err
+	}
+	value := Parse(raw)
+	value.Index = int(offset)
+	return value, nil
+}
+
+// skipValue consumes the next value, the objects and arrays by balancing their brackets.
+func (s *streamScanner) skipValue() error {
+	c, ok := s.peek()
+	if !ok {
+		return s.unexpected()
+	}
+	switch c {
+	case '"':
+		return s.skipString()
+	case '}', ']', ',', ':':
+		return s.syntax(c)
+	case '{', '[':
+		depth := 0
+		for {
+			for s.pos < len(s.buf) {
+				switch s.buf[s.pos] {
+				case '"':
+					if err := s.skipString(); err != nil {
+						return err
+					}
+					continue
+				case '{', '[':
+					depth++
+				case '}', ']':
+					depth--
+					if depth == 0 {
+						s.pos++
+						return nil
+					}
+				}
+				s.pos++
+			}
+			if !s.fill() {
+				return s.unexpected()
+			}
+		}
+	}
+	for {
+		for s.pos < len(s.buf) {
+			if c := s.buf[s.pos]; c <= ' ' || c == ',' || c == ':' || c == '}' || c == ']' {
+				return nil
+			}
+			s.pos++
+		}
+		if !s.fill() {
+			return s.final()
+		}
+	}
+}
+
+func (s *streamScanner) skipString() error {
+	s.pos++
+	esc := false
+	for {
+		for s.pos < len(s.buf) {
+			c := s.buf[s.pos]
+			s.pos++
+			if esc {
+				esc = false
+			} else if c == '\\' {
+				esc = true
+			} else if c == '"' {
+				return nil
+			}
+		}
+		if !s.fill() {
+			return s.unexpected()
+		}
+	}
+}
+
+// walk matches the value at the read position against the patterns whose first components matched its path,
+// reading into memory the values matched in full and skipping those none of the patterns lead to.
+func (s *streamScanner) walk(path string, patterns [][]streamComponent, depth int, iterator func(path string, value BJsonContext) bool) error {
+	if len(patterns) == 0 {
+		return s.skipValue()
+	}
+	for _, pattern := range patterns {
+		if len(pattern) == depth {
+			value, err := s.capture()
+			if err != nil {
+				return err
+			}
+			if !walkValue(path, value, patterns, depth, iterator) {
+				return errorStreamStop
+			}
+			return nil
+		}
+	}
+	c, ok := s.peek()
+	if !ok {
+		return s.unexpected()
+	}
+	switch c {
+	case '{':
+		s.pos++
+		return s.members(func(key string) error {
+			next := matchKey(patterns, depth, key)
+			if len(next) == 0 {
+				return s.skipValue()
+			}
+			return s.walk(childPath(path, escapeComp(key)), next, depth+1, iterator)
+		})
+	case '[':
+		s.pos++
+		return s.elements(func(index int) error {
+			next := matchIndex(patterns, depth, index)
+			if len(next) == 0 {
+				return s.skipValue()
+			}
+			return s.walk(childPath(path, strconv.Itoa(index)), next, depth+1, iterator)
+		})
+	}
+	return s.skipValue()
+}
+
+// members reads the members of the object whose brace has been consumed, the iterator consuming their value.
+func (s *streamScanner) members(iterator func(key string) error) error {
+	for i := 0; ; i++ {
+		c, ok := s.peek()
+		if !ok {
+			return s.unexpected()
+		}
+		if c == '}' {
+			s.pos++
+			return nil
+		}
+		if i > 0 {
+			if c != ',' {
+				return s.syntax(c)
+			}
+			s.pos++
+			if c, ok = s.peek(); !ok {
+				return s.unexpected()
+			}
+		}
+		if c != '"' {
+			return s.syntax(c)
+		}
+		key, err := s.capture()
+		if err != nil {
+			return err
+		}
+		if err := s.expect(':'); err != nil {
+			return err
+		}
+		if err := iterator(key.Strings); err != nil {
+			return err
+		}
+	}
+}
+
+// elements reads the elements of the array whose bracket has been consumed, the iterator consuming them.
+func (s *streamScanner) elements(iterator func(index int) error) error {
+	for i := 0; ; i++ {
+		c, ok := s.peek()
+		if !ok {
+			return s.unexpected()
+		}
+		if c == ']' {
+			s.pos++
+			return nil
+		}
+		if i > 0 {
+			if c != ',' {
+				return s.syntax(c)
+			}
+			s.pos++
+		}
+		if err := iterator(i); err != nil {
+			return err
+		}
+	}
+}
+
+// final returns the read error of the stream, nil at its end.
+func (s *streamScanner) final() error {
+	if s.err == nil || s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+func (s *streamScanner) unexpected() error {
+	if err := s.final(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (s *streamScanner) syntax(c byte) error {
+	return fmt.Errorf("%w, unexpected '%c' at offset %d", ErrorInvalidJson, c, s.offset())
+}
+
+// walkValue matches the value read into memory against the patterns, returning false when the iterator stops.
+func walkValue(path string, value BJsonContext, patterns [][]streamComponent, depth int, iterator func(path string, value BJsonContext) bool) bool {
+	for _, pattern := range patterns {
+		if len(pattern) == depth {
+			at := path
+			if at == "" {
+				at = "@this"
+			}
+			if !iterator(at, value) {
+				return false
+			}
+			break
+		}
+	}
+	if value.Type != JSON {
+		return true
+	}
+	proceed := true
+	if value.IsObject() {
+		value.ForEach(func(key, v BJsonContext) bool {
+			if next := matchKey(patterns, depth, key.Strings); len(next) > 0 {
+				proceed = walkValue(childPath(path, escapeComp(key.Strings)), v, next, depth+1, iterator)
+			}
+			return proceed
+		})
+		return proceed
+	}
+	index := 0
+	value.ForEach(func(_, v BJsonContext) bool {
+		if next := matchIndex(patterns, depth, index); len(next) > 0 {
+			proceed = walkValue(childPath(path, strconv.Itoa(index)), v, next, depth+1, iterator)
+		}
+		index++
+		return proceed
+	})
+	return proceed
+}
+
+// matchKey returns the patterns whose component at the depth matches the key.
+func matchKey(patterns [][]streamComponent, depth int, key string) [][]streamComponent {
+	var next [][]streamComponent
+	for _, pattern := range patterns {
+		if len(pattern) <= depth {
+			continue
+		}
+		c := pattern[depth]
+		if !c.Any && ((!c.Wildcard && key == c.Key) || (c.Wildcard && matchLimit(key, c.Key))) {
+			next = append(next, pattern)
+		}
+	}
+	return next
+}
+
+// matchIndex returns the patterns whose component at the depth matches the index.
+func matchIndex(patterns [][]streamComponent, depth int, index int) [][]streamComponent {
+	var next [][]streamComponent
+	for _, pattern := range patterns {
+		if len(pattern) > depth && (pattern[depth].Any || pattern[depth].Index == index) {
+			next = append(next, pattern)
+		}
+	}
+	return next
+}
+
+// parseStreamPath splits the path into its components, e.g: "items.#.id", the dots and wildcards escaped by '\',
+// failing on the syntax of Get that is not streamed: queries, modifiers, pipes, multipaths and literals.
+func parseStreamPath(path string) ([]streamComponent, error) {
+	if path == "" || path == "@this" {
+		return nil, nil
+	}
+	unsupported := func(at int) error {
+		return fmt.Errorf("%w, '%s' at position %d of '%s'", ErrorStreamPathUnsupported, path[at:at+1], at, path)
+	}
+	var components []streamComponent
+	var key, pattern strings.Builder // the key unescaped, and as a pattern when it has wildcards
+	var wildcard, escaped bool
+	flush := func() {
+		c := streamComponent{Key: key.String(), Index: -1, Wildcard: wildcard}
+		if wildcard {
+			c.Key = pattern.String()
+		} else if c.Key == "#" && !escaped {
+			c.Any = true
+		} else if n, ok := arrayIndex(c.Key); ok {
+			c.Index = n
+		}
+		components = append(components, c)
+		key.Reset()
+		pattern.Reset()
+		wildcard, escaped = false, false
+	}
+	for i := 0; i < len(path); i++ {
+		start := key.Len() == 0 && !escaped
+		switch path[i] {
+		case '\\':
+			if i+1 < len(path) {
+				i++
+				escaped = true
+				key.WriteByte(path[i])
+				pattern.WriteByte('\\')
+				pattern.WriteByte(path[i])
+			}
+		case '.':
+			flush()
+		case '|':
+			return nil, unsupported(i)
+		case '@', '!', '{', '[', '(':
+			if start && path[i] != '(' {
+				return nil, unsupported(i)
+			}
+			if (path[i] == '(' || path[i] == '[') && key.String() == "#" && !escaped {
+				// a query, e.g: "#(id==1)"
+				return nil, unsupported(i - 1)
+			}
+			key.WriteByte(path[i])
+			pattern.WriteByte(path[i])
+		case '*', '?':
+			wildcard = true
+			key.WriteByte(path[i])
+			pattern.WriteByte(path[i])
+		default:
+			key.WriteByte(path[i])
+			pattern.WriteByte(path[i])
+		}
+	}
+	flush()
+	return components, nil
+}
@@ -0,0 +1,276 @@
+package bjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// unmarshalPlans caches the plans of Unmarshal by struct type.
+var unmarshalPlans sync.Map
+
+// Unmarshal fills the fields of the struct dst points to with the values of their paths, e.g: `bjson:"data.user.name"`,
+// see UnmarshalTagName. The fields without tag are left as they are, but for the embedded structs, filled from the same value.
+//
+// Nested structs take the paths of their fields relative to their own value, slices take the elements of arrays,
+// e.g: `bjson:"friends.#.first"`, time.Time parses strings in RFC 3339, or per the layout tag,
+// and the types implementing json.Unmarshaler, as well as BJsonContext, take the raw value.
+// The missing and null values leave the fields as they are, unless a default tag is set.
+// The values are not coerced: strings, booleans and numbers only fill fields of their kind, integers take numbers
+// without fraction within their range, e.g: 1e3 but not 1.5 or "12", and unsigned integers no negative numbers.
+// It returns ErrorUnmarshalType otherwise.
+func Unmarshal(json string, dst interface{}) error {
+	return Parse(json).Unmarshal(dst)
+}
+
+// UnmarshalBytes fills the fields of the struct dst points to, see Unmarshal.
+func UnmarshalBytes(json []byte, dst interface{}) error {
+	return Unmarshal(string(json), dst)
+}
+
+// Unmarshal fills the fields of the struct dst points to with the values of their paths, relative to this value, see Unmarshal.
+func (t BJsonContext) Unmarshal(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrorUnmarshalTarget
+	}
+	return unmarshalStruct(t, v.Elem())
+}
+
+func unmarshalStruct(t BJsonContext, v reflect.Value) error {
+	plan := unmarshalPlanOf(v.Type())
+	for i := range plan.Fields {
+		f := &plan.Fields[i]
+		value := t
+		if f.Path != "" {
+			value = t.Get(f.Path)
+		}
+		if (!value.Exists() || value.Type == Null) && f.Default.Exists() {
+			value = f.Default
+		}
+		if err := unmarshalValue(value, v.Field(f.Index), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalValue(t BJsonContext, v reflect.Value, f *unmarshalField) error {
+	if !t.Exists() || t.Type == Null {
+		return nil
+	}
+	switch v.Type() {
+	case typeOfBJsonContext:
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case typeOfTime:
+		if t.Type != String {
+			return unmarshalTypeError(f, "expected a string")
+		}
+		layout := f.Layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		at, err := time.Parse(layout, t.Strings)
+		if err != nil {
+			return unmarshalTypeError(f, err.Error())
+		}
+		v.Set(reflect.ValueOf(at))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(t, v.Elem(), f)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(typeOfJsonUnmarshal) {
+		if err := v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON([]byte(t.Raw)); err != nil {
+			return unmarshalTypeError(f, err.Error())
+		}
+		return nil
+	}
+	if t.Type == JSON {
+		switch v.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			return unmarshalTypeError(f, v.Type().String()+" cannot hold an object or array")
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if t.Type != String {
+			return unmarshalTypeError(f, "expected a string")
+		}
+		v.SetString(t.Strings)
+	case reflect.Bool:
+		if t.Type != True && t.Type != False {
+			return unmarshalTypeError(f, "expected a boolean")
+		}
+		v.SetBool(t.Type == True)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := checkInteger(t, v.Type(), false); err != "" {
+			return unmarshalTypeError(f, err)
+		}
+		n, err := strconv.ParseInt(t.Raw, 10, 64)
+		if err != nil {
+			n = int64(t.Numeric)
+		}
+		if v.OverflowInt(n) {
+			return unmarshalTypeError(f, fmt.Sprintf("%s overflows %s", t.Raw, v.Type()))
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if err := checkInteger(t, v.Type(), true); err != "" {
+			return unmarshalTypeError(f, err)
+		}
+		n, err := strconv.ParseUint(t.Raw, 10, 64)
+		if err != nil {
+			n = uint64(t.Numeric)
+		}
+		if v.OverflowUint(n) {
+			return unmarshalTypeError(f, fmt.Sprintf("%s overflows %s", t.Raw, v.Type()))
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if t.Type != Number {
+			return unmarshalTypeError(f, "expected a number")
+		}
+		if math.IsInf(t.Numeric, 0) || v.OverflowFloat(t.Numeric) {
+			return unmarshalTypeError(f, fmt.Sprintf("%s overflows %s", t.Raw, v.Type()))
+		}
+		v.SetFloat(t.Numeric)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return unmarshalTypeError(f, "unsupported type "+v.Type().String())
+		}
+		v.Set(reflect.ValueOf(t.Value()))
+	case reflect.Struct:
+		if !t.IsObject() {
+			return unmarshalTypeError(f, "expected an object")
+		}
+		return unmarshalStruct(t, v)
+	case reflect.Slice:
+		elements := t.Array()
+		s := reflect.MakeSlice(v.Type(), len(elements), len(elements))
+		for i, e := range elements {
+			if err := unmarshalValue(e, s.Index(i), f); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i, e := range t.Array() {
+			if i == v.Len() {
+				break
+			}
+			if err := unmarshalValue(e, v.Index(i), f); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return unmarshalTypeError(f, "unsupported type "+v.Type().String())
+		}
+		if !t.IsObject() {
+			return unmarshalTypeError(f, "expected an object")
+		}
+		m := reflect.MakeMap(v.Type())
+		var err error
+		t.ForEach(func(key, value BJsonContext) bool {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err = unmarshalValue(value, e, f); err != nil {
+				return false
+			}
+			m.SetMapIndex(reflect.ValueOf(key.Strings).Convert(v.Type().Key()), e)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(m)
+	default:
+		return unmarshalTypeError(f, "unsupported type "+v.Type().String())
+	}
+	return nil
+}
+
+// checkInteger returns why the value cannot be held by the integer type, or an empty string when it can:
+// the value must be a number with no fraction, within int64, or within uint64 and not negative when unsigned.
+func checkInteger(t BJsonContext, typ reflect.Type, unsigned bool) string {
+	if t.Type != Number {
+		return "expected a number"
+	}
+	if t.Numeric != math.Trunc(t.Numeric) {
+		return fmt.Sprintf("%s is not an integer", t.Raw)
+	}
+	if unsigned {
+		if t.Numeric < 0 {
+			return fmt.Sprintf("%s is negative, %s is unsigned", t.Raw, typ)
+		}
+		if t.Numeric >= math.MaxUint64 {
+			if _, err := strconv.ParseUint(t.Raw, 10, 64); err != nil {
+				return fmt.Sprintf("%s overflows %s", t.Raw, typ)
+			}
+		}
+		return ""
+	}
+	if t.Numeric < math.MinInt64 || t.Numeric >= math.MaxInt64 {
+		if _, err := strconv.ParseInt(t.Raw, 10, 64); err != nil {
+			return fmt.Sprintf("%s overflows %s", t.Raw, typ)
+		}
+	}
+	return ""
+}
+
+func unmarshalTypeError(f *unmarshalField, reason string) error {
+	return fmt.Errorf("%w '%s' from '%s', %s", ErrorUnmarshalType, f.Name, f.Path, reason)
+}
+
+// unmarshalPlanOf returns the plan of Unmarshal for the struct type, built once.
+func unmarshalPlanOf(t reflect.Type) *unmarshalPlan {
+	if plan, ok := unmarshalPlans.Load(t); ok {
+		return plan.(*unmarshalPlan)
+	}
+	plan := &unmarshalPlan{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path, tagged := sf.Tag.Lookup(UnmarshalTagName)
+		if path == "-" {
+			continue
+		}
+		if !tagged || path == "" {
+			if !sf.Anonymous || sf.Type.Kind() != reflect.Struct && (sf.Type.Kind() != reflect.Ptr || sf.Type.Elem().Kind() != reflect.Struct) {
+				continue
+			}
+			path = ""
+		}
+		if !sf.IsExported() && (!sf.Anonymous || sf.Type.Kind() != reflect.Struct) {
+			continue
+		}
+		f := unmarshalField{Name: sf.Name, Index: i, Path: path, Layout: sf.Tag.Get(UnmarshalLayoutTag)}
+		if value, ok := sf.Tag.Lookup(UnmarshalDefaultTag); ok {
+			f.Default = defaultContext(sf.Type, value)
+		}
+		plan.Fields = append(plan.Fields, f)
+	}
+	actual, _ := unmarshalPlans.LoadOrStore(t, plan)
+	return actual.(*unmarshalPlan)
+}
+
+// defaultContext returns the value of a default tag: a string for the fields of strings and times, json otherwise.
+func defaultContext(t reflect.Type, value string) BJsonContext {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String || t == typeOfTime {
+		return BJsonContext{Type: String, Raw: string(AppendJsonString(nil, value)), Strings: value}
+	}
+	return Parse(value)
+}
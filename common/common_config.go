@@ -74,6 +74,9 @@ const (
 	HeaderExpectCt                      = "Expect-CT"
 	HeaderStrictTransportSecurity       = "Strict-Transport-Security"
 	HeaderUpgradeInsecureRequests       = "Upgrade-Insecure-Requests"
+	HeaderXRateLimitLimit               = "X-RateLimit-Limit"
+	HeaderXRateLimitRemaining           = "X-RateLimit-Remaining"
+	HeaderXRateLimitReset               = "X-RateLimit-Reset"
 )
 
 // Define constants for media types
@@ -182,6 +182,7 @@ func GetKeysDefaultConfig() *KeysConfig {
 	k.SetCookie(*cookies.GetCookieConfigSample().SetEnabled(false))
 	k.SetLogger(*logger.GetLoggerSample().SetEnabled(false))
 	k.SetKafka(*queues.GetKafkaSample().SetEnabled(false))
+	k.SetScheduler(*timex.GetSchedulerConfigSample().SetEnabled(false))
 	k.AppendTelegramSeekers(*telegram.GetMultiTenantTelegramConfigSample())
 	k.AppendSlackSeekers(*slack.GetMultiTenantSlackConfigSample())
 	k.AppendAsteriskSeekers(*asterisk.GetMultiTenantAsteriskConfigSample())
@@ -209,6 +210,7 @@ func (KeysConfig) WriteDefaultConfig() {
 		"cookie":           fmt.Sprintf("################################\n%s\n%s\n################################", "Cookie Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
 		"logger":           fmt.Sprintf("################################\n%s\n%s\n################################", "Logger Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
 		"kafka":            fmt.Sprintf("################################\n%s\n%s\n################################", "Kafka Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
+		"scheduler":        fmt.Sprintf("################################\n%s\n%s\n################################", "Scheduler Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
 		"asterisk":         fmt.Sprintf("################################\n%s\n%s\n################################", "Asterisk Server Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
 		"mongodb":          fmt.Sprintf("################################\n%s\n%s\n################################", "Mongodb Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
 		"mysql":            fmt.Sprintf("################################\n%s\n%s\n################################", "MySQL Config", timex.With(time.Now()).Format(timex.TimeFormat20060102150405)),
@@ -340,11 +342,16 @@ func ReadConfig[T any](path string) (*T, error) {
 }
 
 func CreateConfig[T any](path string, data *T) error {
+	return CreateConfigPerm(path, data, os.ModePerm)
+}
+
+// CreateConfigPerm writes the config as YAML, the file being created with the permissions, e.g: 0644.
+func CreateConfigPerm[T any](path string, data *T, perm os.FileMode) error {
 	config, err := yaml.Marshal(data)
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(path, config, os.ModePerm)
+	err = ioutil.WriteFile(path, config, perm)
 	if err != nil {
 		return err
 	}
@@ -464,6 +471,16 @@ func (k *KeysConfig) SetKafkaCursor(value *queues.KafkaConfig) *KeysConfig {
 	return k
 }
 
+func (k *KeysConfig) SetScheduler(value timex.SchedulerConfig) *KeysConfig {
+	k.Scheduler = value
+	return k
+}
+
+func (k *KeysConfig) SetSchedulerCursor(value *timex.SchedulerConfig) *KeysConfig {
+	k.Scheduler = *value
+	return k
+}
+
 func (k *KeysConfig) SetTelegramSeekers(values []telegram.MultiTenantTelegramConfig) *KeysConfig {
 	k.TelegramSeekers = values
 	return k
@@ -14,6 +14,7 @@ import (
 	"github.com/sivaosorg/govm/rabbitmqx"
 	"github.com/sivaosorg/govm/redisx"
 	"github.com/sivaosorg/govm/server"
+	"github.com/sivaosorg/govm/timex"
 )
 
 type FieldCommentConfig map[string]string
@@ -27,19 +28,20 @@ type CommentedConfig struct {
 
 type KeysConfig struct {
 	// Basic
-	Asterisk asterisk.AsteriskConfig  `json:"asterisk,omitempty" yaml:"asterisk"`
-	Mongodb  mongodb.MongodbConfig    `json:"mongodb,omitempty" yaml:"mongodb"`
-	MySql    mysql.MysqlConfig        `json:"mysql,omitempty" yaml:"mysql"`
-	Postgres postgres.PostgresConfig  `json:"postgres,omitempty" yaml:"postgres"`
-	RabbitMq rabbitmqx.RabbitMqConfig `json:"rabbitmq,omitempty" yaml:"rabbitmq"`
-	Redis    redisx.RedisConfig       `json:"redis,omitempty" yaml:"redis"`
-	Telegram telegram.TelegramConfig  `json:"telegram,omitempty" yaml:"telegram"`
-	Slack    slack.SlackConfig        `json:"slack,omitempty" yaml:"slack"`
-	Cors     corsx.CorsConfig         `json:"cors,omitempty" yaml:"cors"`
-	Server   server.Server            `json:"server,omitempty" yaml:"server"`
-	Cookie   cookies.CookieConfig     `json:"cookie,omitempty" yaml:"cookie"`
-	Logger   logger.Logger            `json:"logger,omitempty" yaml:"logger"`
-	Kafka    queues.KafkaConfig       `json:"kafka,omitempty" yaml:"kafka"`
+	Asterisk  asterisk.AsteriskConfig  `json:"asterisk,omitempty" yaml:"asterisk"`
+	Mongodb   mongodb.MongodbConfig    `json:"mongodb,omitempty" yaml:"mongodb"`
+	MySql     mysql.MysqlConfig        `json:"mysql,omitempty" yaml:"mysql"`
+	Postgres  postgres.PostgresConfig  `json:"postgres,omitempty" yaml:"postgres"`
+	RabbitMq  rabbitmqx.RabbitMqConfig `json:"rabbitmq,omitempty" yaml:"rabbitmq"`
+	Redis     redisx.RedisConfig       `json:"redis,omitempty" yaml:"redis"`
+	Telegram  telegram.TelegramConfig  `json:"telegram,omitempty" yaml:"telegram"`
+	Slack     slack.SlackConfig        `json:"slack,omitempty" yaml:"slack"`
+	Cors      corsx.CorsConfig         `json:"cors,omitempty" yaml:"cors"`
+	Server    server.Server            `json:"server,omitempty" yaml:"server"`
+	Cookie    cookies.CookieConfig     `json:"cookie,omitempty" yaml:"cookie"`
+	Logger    logger.Logger            `json:"logger,omitempty" yaml:"logger"`
+	Kafka     queues.KafkaConfig       `json:"kafka,omitempty" yaml:"kafka"`
+	Scheduler timex.SchedulerConfig    `json:"scheduler,omitempty" yaml:"scheduler"`
 
 	// Seekers
 	TelegramSeekers []telegram.MultiTenantTelegramConfig  `json:"telegram_seekers,omitempty" yaml:"telegram-seekers"`
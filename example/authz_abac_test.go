@@ -0,0 +1,74 @@
+package example
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sivaosorg/govm/authz"
+)
+
+func TestAbacMissingAttributes(t *testing.T) {
+	svc, err := authz.NewAbacService(*authz.GetAbacConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := svc.Evaluate("documents", "read", `{"subject":{},"resource":{}}`); d.IsAllowed {
+		t.Fatalf("empty attributes allowed: %s", d.Reason)
+	}
+	if d := svc.Evaluate("documents", "read", `{"subject":{"id":7},"resource":{"owner":7}}`); !d.IsAllowed {
+		t.Fatalf("owner denied: %s", d.Reason)
+	}
+	tests := []struct {
+		expr string
+		json string
+		want bool
+	}{
+		{"subject.tenant == resource.tenant", `{}`, false},
+		{"subject.tenant != resource.tenant", `{}`, false},
+		{"subject.tenant == null", `{}`, false},
+		{"subject.level >= resource.level", `{}`, false},
+		{"subject.tenant in ['a', 'b']", `{}`, false},
+		{"not exists(subject.tenant)", `{}`, true},
+		{"subject.tenant == resource.tenant", `{"subject":{"tenant":"a"},"resource":{"tenant":"a"}}`, true},
+		{"subject.tenant == null", `{"subject":{"tenant":null}}`, true},
+	}
+	for _, tt := range tests {
+		got, err := authz.MustCompileExpression(tt.expr).Evaluate(tt.json)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s over %s = %v, want %v", tt.expr, tt.json, got, tt.want)
+		}
+	}
+}
+
+func TestAbacConfigEnabledByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abac.yaml")
+	policies := "algorithm: deny-overrides\npolicies:\n  - name: owner\n    effect: permit\n    condition: subject.id == resource.owner\n"
+	if err := os.WriteFile(path, []byte(policies), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := authz.LoadAbacConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJson authz.AbacConfig
+	if err := json.Unmarshal([]byte(`{"policies":[]}`), &fromJson); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []authz.AbacConfig{*conf, fromJson, *authz.NewAbacConfig()} {
+		if !c.IsEnabled {
+			t.Fatalf("config without enabled is disabled: %s", c.Json())
+		}
+		svc, err := authz.NewAbacService(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := svc.Evaluate("documents", "read", `{"subject":{"id":1},"resource":{"owner":2}}`); d.IsAllowed {
+			t.Fatalf("config without enabled allowed: %s", d.Reason)
+		}
+	}
+}
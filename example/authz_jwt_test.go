@@ -0,0 +1,244 @@
+package example
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/authz"
+)
+
+func TestJwtRequiresExpiration(t *testing.T) {
+	svc, err := authz.NewJwtService(*authz.GetJwtConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := svc.Sign(authz.JwtClaims{"sub": "alice", "iss": "govm", "aud": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Verify(token); err != authz.ErrorJwtExpirationAbsent {
+		t.Fatalf("expected %v, got %v", authz.ErrorJwtExpirationAbsent, err)
+	}
+	lax, err := authz.NewJwtService(*authz.GetJwtConfigSample().SetRequireExpiration(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lax.Verify(token); err != nil {
+		t.Fatalf("expected no error without required expiration, got %v", err)
+	}
+	var conf authz.JwtConfig
+	if err := json.Unmarshal([]byte(`{"algorithm":"HS256","expiration":60000000000}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !conf.IsRequireExpiration || conf.Expiration != time.Minute {
+		t.Fatalf("expected required expiration by default, got %s", conf.Json())
+	}
+}
+
+func TestJwtIssueAndVerify(t *testing.T) {
+	svc, err := authz.NewJwtService(*authz.GetJwtConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := svc.Issue("alice", map[string]interface{}{"roles": []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject() != "alice" || claims.Issuer() != "govm" || len(claims.Roles()) != 1 || claims.Roles()[0] != "admin" {
+		t.Fatalf("unexpected claims %s", claims.Json())
+	}
+	if exp, ok := claims.ExpiresAt(); !ok || exp.Sub(time.Now()) > 15*time.Minute {
+		t.Fatalf("unexpected expiration %v", exp)
+	}
+}
+
+func TestJwtRejectsTamperedTokens(t *testing.T) {
+	svc, err := authz.NewJwtService(*authz.GetJwtConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := svc.Issue("bob", nil)
+	parts := strings.Split(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice","iss":"govm","aud":"api","exp":4102444800}`))
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	rs256 := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	other, _ := authz.NewJwtService(*authz.GetJwtConfigSample().SetSecret("another secret"))
+	otherToken, _ := other.Issue("bob", nil)
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"forged payload", parts[0] + "." + forged + "." + parts[2], authz.ErrorJwtSignature},
+		{"alg none", none + "." + parts[1] + ".", authz.ErrorJwtAlgorithm},
+		{"alg confusion", rs256 + "." + parts[1] + "." + parts[2], authz.ErrorJwtAlgorithm},
+		{"other secret", otherToken, authz.ErrorJwtSignature},
+		{"two segments", parts[0] + "." + parts[1], authz.ErrorJwtMalformed},
+		{"garbage header", "!." + parts[1] + "." + parts[2], authz.ErrorJwtMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Verify(tt.token); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestJwtClaimsValidation(t *testing.T) {
+	svc, err := authz.NewJwtService(*authz.GetJwtConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	tests := []struct {
+		name   string
+		claims authz.JwtClaims
+		err    error
+	}{
+		{"valid", authz.JwtClaims{"iss": "govm", "aud": "api", "exp": now.Add(time.Minute).Unix()}, nil},
+		{"expired", authz.JwtClaims{"iss": "govm", "aud": "api", "exp": now.Add(-time.Minute).Unix()}, authz.ErrorJwtExpired},
+		{"expired within leeway", authz.JwtClaims{"iss": "govm", "aud": "api", "exp": now.Add(-10 * time.Second).Unix()}, nil},
+		{"not valid yet", authz.JwtClaims{"iss": "govm", "aud": "api", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}, authz.ErrorJwtNotValidYet},
+		{"issued in the future", authz.JwtClaims{"iss": "govm", "aud": "api", "exp": now.Add(time.Hour).Unix(), "iat": now.Add(time.Minute).Unix()}, authz.ErrorJwtIssuedInFuture},
+		{"other issuer", authz.JwtClaims{"iss": "other", "aud": "api", "exp": now.Add(time.Minute).Unix()}, authz.ErrorJwtInvalidIssuer},
+		{"no issuer", authz.JwtClaims{"aud": "api", "exp": now.Add(time.Minute).Unix()}, authz.ErrorJwtInvalidIssuer},
+		{"other audience", authz.JwtClaims{"iss": "govm", "aud": "web", "exp": now.Add(time.Minute).Unix()}, authz.ErrorJwtInvalidAudience},
+		{"audience list", authz.JwtClaims{"iss": "govm", "aud": []string{"web", "api"}, "exp": now.Add(time.Minute).Unix()}, nil},
+	}
+	for _, tt := range tests {
+		token, err := svc.Sign(tt.claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Verify(token); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestJwtAsymmetricAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writePEM := func(name, kind string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ecPrivate, _ := x509.MarshalECPrivateKey(ecKey)
+	rsaPublic, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	ecPublic, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	tests := []struct {
+		algorithm string
+		private   string
+		public    string
+	}{
+		{authz.AlgorithmRS256, writePEM("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), writePEM("rsa.pub", "PUBLIC KEY", rsaPublic)},
+		{authz.AlgorithmES256, writePEM("ec.pem", "EC PRIVATE KEY", ecPrivate), writePEM("ec.pub", "PUBLIC KEY", ecPublic)},
+	}
+	for _, tt := range tests {
+		issuer, err := authz.NewJwtService(*authz.GetJwtConfigSample().SetAlgorithm(tt.algorithm).SetSecret("").SetPrivateKeyFile(tt.private))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.algorithm, err)
+		}
+		verifier, err := authz.NewJwtService(*authz.GetJwtConfigSample().SetAlgorithm(tt.algorithm).SetSecret("").SetPublicKeyFile(tt.public))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.algorithm, err)
+		}
+		token, err := issuer.Issue("alice", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.algorithm, err)
+		}
+		if claims, err := verifier.Verify(token); err != nil || claims.Subject() != "alice" {
+			t.Fatalf("%s: expected alice, got %v", tt.algorithm, err)
+		}
+		if _, err := verifier.Issue("alice", nil); err != authz.ErrorJwtSigningKeyAbsent {
+			t.Fatalf("%s: expected %v, got %v", tt.algorithm, authz.ErrorJwtSigningKeyAbsent, err)
+		}
+		parts := strings.Split(token, ".")
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		signature[0] ^= 0xff
+		if _, err := verifier.Verify(parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)); err != authz.ErrorJwtSignature {
+			t.Fatalf("%s: expected %v, got %v", tt.algorithm, authz.ErrorJwtSignature, err)
+		}
+	}
+	if _, err := authz.NewJwtService(*authz.GetJwtConfigSample().SetAlgorithm(authz.AlgorithmES256).SetSecret("").SetPublicKeyFile(tests[0].public)); err == nil {
+		t.Fatal("expected an error for an RSA key with ES256")
+	}
+}
+
+func TestJwtKeySetRFC7515(t *testing.T) {
+	// RFC 7515 appendix A.1: the HS256 example, signed with the key of the JWKS below and expired since 2011
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := `{"keys":[{"kty":"oct","k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"}]}`
+	if err := os.WriteFile(path, []byte(jwks), 0600); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := authz.NewJwtService(*authz.NewJwtConfig().SetKeySetFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	claims, err := svc.Verify(token)
+	if err != authz.ErrorJwtExpired {
+		t.Fatalf("expected %v once the signature is verified, got %v", authz.ErrorJwtExpired, err)
+	}
+	if claims.Issuer() != "joe" {
+		t.Fatalf("unexpected claims %s", claims.Json())
+	}
+	if _, err := svc.Verify(token[:len(token)-1] + "Y"); err != authz.ErrorJwtSignature {
+		t.Fatalf("expected %v, got %v", authz.ErrorJwtSignature, err)
+	}
+}
+
+func TestSubjectByJwt(t *testing.T) {
+	svc, err := authz.NewJwtService(*authz.GetJwtConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := authz.SubjectByJwt(svc)
+	token, _ := svc.Issue("alice", nil)
+	tests := []struct {
+		authorization string
+		subject       string
+	}{
+		{"Bearer " + token, "alice"},
+		{"Bearer alice", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		if got := extract(req); got != tt.subject {
+			t.Errorf("%q: expected %q, got %q", tt.authorization, tt.subject, got)
+		}
+	}
+}
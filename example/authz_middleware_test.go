@@ -0,0 +1,116 @@
+package example
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/authz"
+	"github.com/sivaosorg/govm/common"
+)
+
+func newAuthzHandler(t *testing.T, policy *authz.PolicyConfig, subjectFunc authz.SubjectExtractorFunc) http.Handler {
+	rbac, err := authz.NewRbacService(*policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := authz.NewAuthzMiddlewareService(rbac, subjectFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.AuthorizeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(authz.SubjectFromContext(r.Context())))
+	}))
+}
+
+func serveAuthz(h http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthzMiddlewareDefaultsDoNotTrustBearerToken(t *testing.T) {
+	rbac, err := authz.NewRbacService(*authz.GetPolicyConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authz.NewAuthzMiddlewareService(rbac, nil); err != authz.ErrorSubjectExtractorRequired {
+		t.Fatalf("expected %v, got %v", authz.ErrorSubjectExtractorRequired, err)
+	}
+	h := newAuthzHandler(t, authz.GetPolicyConfigSample(), authz.SubjectByBearerToken(nil))
+	if rec := serveAuthz(h, http.MethodDelete, "/api/v1/articles/1", "Bearer alice"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("raw bearer token accepted as subject: %d", rec.Code)
+	}
+}
+
+func TestAuthzMiddleware(t *testing.T) {
+	resolver := func(token string) (string, error) {
+		if token == "token-of-bob" {
+			return "bob", nil
+		}
+		return "", errors.New("unknown token")
+	}
+	h := newAuthzHandler(t, authz.GetPolicyConfigSample(), authz.SubjectByBearerToken(resolver))
+	tests := []struct {
+		method        string
+		path          string
+		authorization string
+		status        int
+	}{
+		{http.MethodGet, "/health", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/articles", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/articles", "Bearer forged", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/articles", "Bearer token-of-bob", http.StatusOK},
+		{http.MethodPost, "/api/v1/articles/1/publish", "Bearer token-of-bob", http.StatusOK},
+		{http.MethodGet, "/api/v1/users", "Bearer token-of-bob", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := serveAuthz(h, tt.method, tt.path, tt.authorization)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+	}
+	if rec := serveAuthz(h, http.MethodGet, "/api/v1/articles", "Bearer token-of-bob"); rec.Body.String() != "bob" {
+		t.Fatalf("subject not injected into the context, got %q", rec.Body.String())
+	}
+}
+
+func TestAuthzMiddlewareDenials(t *testing.T) {
+	viewer := authz.NewRole().SetName("viewer").AppendPermissions("*:read")
+	policy := authz.NewPolicyConfig().
+		AppendRoles(*viewer).
+		AppendRoutes(*authz.NewRouteRule().SetPattern("/orders*").SetResource("orders")).
+		AppendBindings(*authz.NewRoleBinding().SetSubject("carol").AppendRoles("viewer"))
+	h := newAuthzHandler(t, policy, authz.SubjectByHeader("X-Subject"))
+	req := httptest.NewRequest(http.MethodDelete, "/orders/1", nil)
+	req.Header.Set("X-Subject", "carol")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "delete on orders") {
+		t.Fatalf("expected 403 with a JSON reason, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(common.HeaderContentType) != common.MediaTypeApplicationJSON {
+		t.Fatalf("expected a JSON denial, got %q", rec.Header().Get(common.HeaderContentType))
+	}
+	req = httptest.NewRequest(http.MethodGet, "/unmatched", nil)
+	req.Header.Set("X-Subject", "carol")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unmatched route not denied by default, got %d", rec.Code)
+	}
+	h = newAuthzHandler(t, policy.SetDenyUnmatched(false), authz.SubjectByHeader("X-Subject"))
+	if rec := serveAuthz(h, http.MethodGet, "/unmatched", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unmatched route denied with deny_unmatched off, got %d", rec.Code)
+	}
+	h = newAuthzHandler(t, policy.SetEnabled(false), authz.SubjectByHeader("X-Subject"))
+	if rec := serveAuthz(h, http.MethodDelete, "/orders/1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("disabled policy denied a request, got %d", rec.Code)
+	}
+}
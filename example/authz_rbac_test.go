@@ -0,0 +1,154 @@
+package example
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/authz"
+)
+
+func TestRbacEnforce(t *testing.T) {
+	svc, err := authz.NewRbacService(*authz.GetPolicyConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		subject  string
+		resource string
+		action   string
+		allowed  bool
+		role     string
+	}{
+		{"bob", "articles", "publish", true, "editor"},
+		{"bob", "comments", "read", true, "viewer"},
+		{"bob", "comments", "delete", false, ""},
+		{"alice", "billing", "delete", true, "admin"},
+		{"alice", "audits", "read", true, "admin"},
+		{"alice", "audits", "delete", false, "admin"},
+		{"carol", "articles", "read", false, ""},
+		{"bob", "", "read", false, ""},
+		{"bob", "articles", "", false, ""},
+	}
+	for _, tt := range tests {
+		d := svc.Enforce(tt.subject, tt.resource, tt.action)
+		if d.IsAllowed != tt.allowed || d.Role != tt.role {
+			t.Errorf("%s %s:%s: expected %v by %q, got %v by %q (%s)", tt.subject, tt.resource, tt.action, tt.allowed, tt.role, d.IsAllowed, d.Role, d.Reason)
+		}
+	}
+	d := svc.Enforce("bob", "comments", "read")
+	if !reflect.DeepEqual(d.Path, []string{"editor", "viewer"}) || !strings.Contains(d.Reason, "inherited via editor -> viewer") {
+		t.Fatalf("unexpected inheritance path %v: %s", d.Path, d.Reason)
+	}
+	if d := svc.Enforce("carol", "articles", "read"); d.Reason != authz.ReasonNoRole {
+		t.Fatalf("expected %q, got %q", authz.ReasonNoRole, d.Reason)
+	}
+	if d := svc.EnforceRoles([]string{"viewer"}, "articles", "list"); !d.IsAllowed || d.Permission != "*:list" {
+		t.Fatalf("roles of a token not enforced: %s", d.Reason)
+	}
+}
+
+func TestRbacWildcardPermissions(t *testing.T) {
+	role := authz.NewRole().
+		SetName("ops").
+		AppendPermissions("reports", "orders:re*", "*:list").
+		AppendDenies("orders:refund")
+	svc, err := authz.NewRbacService(*authz.NewPolicyConfig().AppendRoles(*role))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resource string
+		action   string
+		allowed  bool
+	}{
+		{"reports", "export", true},
+		{"reports", "read", true},
+		{"reportsx", "read", false},
+		{"orders", "read", true},
+		{"orders", "refund", false},
+		{"orders", "create", false},
+		{"users", "list", true},
+		{"users", "read", false},
+	}
+	for _, tt := range tests {
+		if d := svc.EnforceRoles([]string{"ops"}, tt.resource, tt.action); d.IsAllowed != tt.allowed {
+			t.Errorf("%s:%s: expected %v, got %v (%s)", tt.resource, tt.action, tt.allowed, d.IsAllowed, d.Reason)
+		}
+	}
+}
+
+func TestRbacRuntimeChanges(t *testing.T) {
+	svc, err := authz.NewRbacService(*authz.GetPolicyConfigSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AssignRoles("carol", "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown role")
+	}
+	if err := svc.AssignRoles("carol", "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if !svc.Can("carol", "articles", "read") || svc.Can("carol", "articles", "update") {
+		t.Fatal("assigned role not enforced")
+	}
+	if err := svc.RemoveRole("viewer"); err == nil {
+		t.Fatal("expected an error when removing an inherited role")
+	}
+	if err := svc.AddRole(*authz.NewRole().SetName("viewer").AppendInherits("admin")); err == nil {
+		t.Fatal("expected an error for an inheritance cycle")
+	}
+	if !reflect.DeepEqual(svc.GetEffectiveRoles("bob"), []string{"editor", "viewer"}) {
+		t.Fatalf("unexpected effective roles %v", svc.GetEffectiveRoles("bob"))
+	}
+	svc.RevokeRoles("carol", "viewer")
+	if svc.Can("carol", "articles", "read") || len(svc.GetRoles("carol")) != 0 {
+		t.Fatal("revoked role still enforced")
+	}
+	permissions := svc.GetPermissions("bob")
+	if !reflect.DeepEqual(permissions, []string{"*:list", "*:read", "articles:*"}) {
+		t.Fatalf("unexpected permissions %v", permissions)
+	}
+}
+
+func TestPolicyConfigValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *authz.PolicyConfig
+	}{
+		{"unnamed role", authz.NewPolicyConfig().AppendRoles(*authz.NewRole())},
+		{"duplicated role", authz.NewPolicyConfig().AppendRoles(*authz.NewRole().SetName("a"), *authz.NewRole().SetName("a"))},
+		{"unknown parent", authz.NewPolicyConfig().AppendRoles(*authz.NewRole().SetName("a").AppendInherits("b"))},
+		{"cycle", authz.NewPolicyConfig().AppendRoles(*authz.NewRole().SetName("a").AppendInherits("b"), *authz.NewRole().SetName("b").AppendInherits("a"))},
+		{"unknown binding", authz.NewPolicyConfig().AppendBindings(*authz.NewRoleBinding().SetSubject("alice").AppendRoles("a"))},
+		{"route without resource", authz.NewPolicyConfig().AppendRoutes(*authz.NewRouteRule().SetPattern("/a"))},
+	}
+	for _, tt := range tests {
+		if _, err := authz.NewRbacService(*tt.policy); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestPolicyConfigDefaults(t *testing.T) {
+	var p authz.PolicyConfig
+	if err := json.Unmarshal([]byte(`{"roles":[]}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsEnabled || !p.IsDenyUnmatched {
+		t.Fatal("policy omitting enabled and deny_unmatched is not fail-closed")
+	}
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := authz.WritePolicyConfig(path, authz.GetPolicyConfigSample()); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := authz.LoadPolicyConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Roles) != 3 || len(loaded.Bindings) != 2 || loaded.IsDenyUnmatched {
+		t.Fatalf("policy not round-tripped: %s", loaded.Json())
+	}
+}
@@ -0,0 +1,133 @@
+package example
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+	"github.com/sivaosorg/govm/pretty"
+)
+
+func TestDiff(t *testing.T) {
+	original := `{"name":"Tom","age":37,"tags":["a","b","c"],"meta":{"a.b":1,"keep":true},"gone":null}`
+	modified := "{\n  \"meta\": {\"keep\": true, \"a.b\": 2},\n  \"tags\": [\"a\", \"x\"],\n  \"age\": 37.0,\n  \"name\": \"Jerry\",\n  \"new\": [1]\n}"
+	changes, err := bjson.Diff(original, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		`~ name: "Tom" => "Jerry"`,
+		`~ tags.1: "b" => "x"`,
+		`- tags.2: "c"`,
+		`~ meta.a\.b: 1 => 2`,
+		`- gone: null`,
+		`+ new: [1]`,
+	}, "\n") + "\n"
+	if got := bjson.FormatDiff(changes); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+	// the paths are those of Get, and the pointers those of JSON Patch
+	for _, c := range changes {
+		if c.Kind == bjson.DiffRemoved {
+			if bjson.Get(original, c.Path).Raw != c.Before.Raw {
+				t.Errorf("path %s does not locate %s", c.Path, c.Before.Raw)
+			}
+			continue
+		}
+		if bjson.Get(modified, c.Path).Raw != c.After.Raw {
+			t.Errorf("path %s does not locate %s", c.Path, c.After.Raw)
+		}
+	}
+	if changes[3].Pointer != "/meta/a.b" || changes[3].After.Index != strings.Index(modified, "2}") {
+		t.Fatalf("unexpected pointer %s or offset %d", changes[3].Pointer, changes[3].After.Index)
+	}
+	if changes, err := bjson.Diff(`{"a":1,"b":[1,{"c":2}]}`, `{"b":[1,{"c":2.0}],"a":1e0}`); err != nil || len(changes) != 0 {
+		t.Fatalf("expected equal documents, got %v: %v", changes, err)
+	}
+	if changes, _ := bjson.Diff(`1`, `"1"`); len(changes) != 1 || changes[0].Path != "@this" || changes[0].Pointer != "" {
+		t.Fatalf("expected the root changed, got %v", changes)
+	}
+	if _, err := bjson.Diff(`{"a":`, `{}`); err != bjson.ErrorInvalidJson {
+		t.Fatalf("expected %v, got %v", bjson.ErrorInvalidJson, err)
+	}
+}
+
+func TestDiffIgnoreArrayOrder(t *testing.T) {
+	original := `{"ids":[1,2,2,3],"items":[{"id":1,"tags":["x","y"]},{"id":2}]}`
+	modified := `{"ids":[3,2,1,4],"items":[{"id":2},{"tags":["y","x"],"id":1}]}`
+	opts := &bjson.DiffOptions{IgnoreArrayOrder: true}
+	changes, err := bjson.DiffWith(original, modified, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one of the 2 is removed, and 4 added
+	expected := "- ids.2: 2\n+ ids.3: 4\n"
+	if got := bjson.FormatDiff(changes); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+	p, err := bjson.DiffPatch(original, modified, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Apply(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes, _ := bjson.DiffWith(got, modified, opts); len(changes) != 0 {
+		t.Fatalf("patch %s gave %s, still differing by %v", p, got, changes)
+	}
+	if changes, _ := bjson.Diff(original, modified); len(changes) == 0 {
+		t.Fatal("expected the order of the arrays to matter by default")
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	original := `{"a":[1,2,3,4],"b":{"c":1},"k~/":0}`
+	modified := `{"a":[1],"b":{"d":1},"k~/":1}`
+	p, err := bjson.DiffPatch(original, modified, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the extra elements are removed from the end, so that the indexes remain valid
+	expected := `[{"op":"remove","path":"/a/3"},{"op":"remove","path":"/a/2"},{"op":"remove","path":"/a/1"},` +
+		`{"op":"remove","path":"/b/c"},{"op":"add","path":"/b/d","value":1},{"op":"replace","path":"/k~0~1","value":1}]`
+	if p.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, p.String())
+	}
+	got, err := p.Apply(original)
+	if err != nil || !sameJson(got, modified) {
+		t.Fatalf("patch gave %s: %v", got, err)
+	}
+}
+
+func TestDiffSideBySide(t *testing.T) {
+	original := `{"name":"Tom","age":37}`
+	modified := `{"age":37,"name":"Jerry","zip":"700000"}`
+	got, err := bjson.DiffSideBySide(original, modified, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the keys are sorted, and the columns aligned on the widest line of the original document
+	expected := strings.Join([]string{
+		`{                 {`,
+		`  "age": 37,        "age": 37,`,
+		`  "name": "Tom" |   "name": "Jerry",`,
+		`                >   "zip": "700000"`,
+		`}                 }`,
+	}, "\n") + "\n"
+	if got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+	style := *pretty.TerminalStyle
+	style.Changed = [2]string{"<changed>", "</changed>"}
+	colored, err := bjson.DiffSideBySide(original, modified, &bjson.DiffOptions{Style: &style, Width: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(colored, "<changed>|</changed>") || !strings.Contains(colored, "\x1b[") {
+		t.Fatalf("expected the lines colorized by the style, got\n%s", colored)
+	}
+	if _, err := bjson.DiffSideBySide(original, `{`, nil); err != bjson.ErrorInvalidJson {
+		t.Fatalf("expected %v, got %v", bjson.ErrorInvalidJson, err)
+	}
+}
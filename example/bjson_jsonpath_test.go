@@ -0,0 +1,338 @@
+package example
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+	"github.com/sivaosorg/govm/pretty"
+)
+
+// RFC 9535, section 1.5
+const jsonPathStore = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+// RFC 9535, section 2.3.5.3
+const jsonPathFilter = `{
+  "a": [3, 5, 1, 2, 4, 6,
+        {"b": "j"},
+        {"b": "k"},
+        {"b": {}},
+        {"b": "kilo"}
+       ],
+  "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+  "e": "f"
+}`
+
+// RFC 6901, section 5
+const jsonPointerDoc = `{
+  "foo": ["bar", "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "e^f": 3,
+  "g|h": 4,
+  "i\\j": 5,
+  "k\"l": 6,
+  " ": 7,
+  "m~n": 8
+}`
+
+func compactValues(values []bjson.BJsonContext) string {
+	raws := make([]string, len(values))
+	for i, v := range values {
+		raws[i] = string(pretty.Ugly([]byte(v.Raw)))
+	}
+	return "[" + strings.Join(raws, ",") + "]"
+}
+
+func checkOffsets(t *testing.T, json string, values []bjson.BJsonContext) {
+	t.Helper()
+	for _, v := range values {
+		if v.Index < 0 || v.Index+len(v.Raw) > len(json) || json[v.Index:v.Index+len(v.Raw)] != v.Raw {
+			t.Errorf("Value %s is not at offset %d", v.Raw, v.Index)
+		}
+	}
+}
+
+func TestJsonPathConformance(t *testing.T) {
+	cases := []struct {
+		json     string
+		query    string
+		expected string
+	}{
+		// section 1.5, table 2
+		{jsonPathStore, `$.store.book[*].author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{jsonPathStore, `$..author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{jsonPathStore, `$.store..price`, `[8.95,12.99,8.99,22.99,399]`},
+		{jsonPathStore, `$..book[2]`, `[{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99}]`},
+		{jsonPathStore, `$..book[2].author`, `["Herman Melville"]`},
+		{jsonPathStore, `$..book[2].publisher`, `[]`},
+		{jsonPathStore, `$..book[-1].title`, `["The Lord of the Rings"]`},
+		{jsonPathStore, `$..book[0,1].title`, `["Sayings of the Century","Sword of Honour"]`},
+		{jsonPathStore, `$..book[:2].title`, `["Sayings of the Century","Sword of Honour"]`},
+		{jsonPathStore, `$..book[?@.isbn].title`, `["Moby Dick","The Lord of the Rings"]`},
+		{jsonPathStore, `$..book[?@.price<10].title`, `["Sayings of the Century","Moby Dick"]`},
+		// section 2.3.1.3
+		{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o['j j']`, `[{"k.k":3}]`},
+		{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o['j j']['k.k']`, `[3]`},
+		{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o["j j"]["k.k"]`, `[3]`},
+		{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$["'"]["@"]`, `[2]`},
+		// section 2.3.2.3
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$[*]`, `[{"j":1,"k":2},[5,3]]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.o[*]`, `[1,2]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.o[*, *]`, `[1,2,1,2]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.a[*]`, `[5,3]`},
+		// section 2.3.3.3
+		{`["a","b"]`, `$[1]`, `["b"]`},
+		{`["a","b"]`, `$[-2]`, `["a"]`},
+		// section 2.3.4.3
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[1:3]`, `["b","c"]`},
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[5:]`, `["f","g"]`},
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[1:5:2]`, `["b","d"]`},
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[5:1:-2]`, `["f","d"]`},
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[::-1]`, `["g","f","e","d","c","b","a"]`},
+		{`["a", "b", "c", "d", "e", "f", "g"]`, `$[1:5:0]`, `[]`},
+		// section 2.3.5.3
+		{jsonPathFilter, `$.a[?@.b == 'kilo']`, `[{"b":"kilo"}]`},
+		{jsonPathFilter, `$.a[?(@.b == 'kilo')]`, `[{"b":"kilo"}]`},
+		{jsonPathFilter, `$.a[?@>3.5]`, `[5,4,6]`},
+		{jsonPathFilter, `$.a[?@.b]`, `[{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`},
+		{jsonPathFilter, `$[?@.*]`, `[[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}],{"p":1,"q":2,"r":3,"s":5,"t":{"u":6}}]`},
+		{jsonPathFilter, `$[?@[?@.b]]`, `[[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]]`},
+		{jsonPathFilter, `$.o[?@<3, ?@<3]`, `[1,2,1,2]`},
+		{jsonPathFilter, `$.a[?@<2 || @.b == "k"]`, `[1,{"b":"k"}]`},
+		{jsonPathFilter, `$.a[?match(@.b, "[jk]")]`, `[{"b":"j"},{"b":"k"}]`},
+		{jsonPathFilter, `$.a[?search(@.b, "[jk]")]`, `[{"b":"j"},{"b":"k"},{"b":"kilo"}]`},
+		{jsonPathFilter, `$.o[?@>1 && @<4]`, `[2,3]`},
+		{jsonPathFilter, `$.o[?@.u || @.x]`, `[{"u":6}]`},
+		{jsonPathFilter, `$.a[?@.b == $.x]`, `[3,5,1,2,4,6]`},
+		{jsonPathFilter, `$.a[?@ == @]`, `[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`},
+		// section 2.4.4 to 2.4.8
+		{jsonPathFilter, `$.a[?length(@.b) == 4]`, `[{"b":"kilo"}]`},
+		{jsonPathFilter, `$[?count(@.*) == 5]`, `[{"p":1,"q":2,"r":3,"s":5,"t":{"u":6}}]`},
+		{jsonPathFilter, `$.a[?value(@..b) == "k"]`, `[{"b":"k"}]`},
+		{jsonPathFilter, `$.a[?!match(@.b, 'k.*')].b`, `["j",{}]`},
+		// section 2.5.2.3
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$..j`, `[1,4]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$..[0]`, `[5,{"j":4}]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$..*`, `[{"j":1,"k":2},[5,3,[{"j":4},{"k":6}]],1,2,5,3,[{"j":4},{"k":6}],{"j":4},{"k":6},4,6]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$..o`, `[{"j":1,"k":2}]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$.o..[*, *]`, `[1,2,1,2]`},
+		{`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, `$.a..[0, 1]`, `[5,3,{"j":4},{"k":6}]`},
+		// section 2.6.1
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.a`, `[null]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.a[0]`, `[]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.a.d`, `[]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.b[0]`, `[null]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.b[*]`, `[null]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.b[?@]`, `[null]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.b[?@==null]`, `[null]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.c[?@.d==null]`, `[]`},
+		{`{"a": null, "b": [null], "c": [{}], "null": 1}`, `$.null`, `[1]`},
+	}
+	for _, c := range cases {
+		values, err := bjson.QueryJsonPath(c.json, c.query)
+		if err != nil {
+			t.Errorf("%s: %v", c.query, err)
+			continue
+		}
+		if actual := compactValues(values); actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.query, c.expected, actual)
+		}
+		checkOffsets(t, c.json, values)
+	}
+	if n := len(bjson.MustCompileJsonPath(`$..*`).Query(jsonPathStore)); n != 27 {
+		t.Errorf("$..*: expected 27 values, got %d", n)
+	}
+}
+
+func TestJsonPathNormalizedPaths(t *testing.T) {
+	cases := []struct {
+		json     string
+		query    string
+		expected string
+	}{
+		// section 2.7.1
+		{`{"a": 1}`, `$.a`, `$['a']`},
+		{`[0, 1]`, `$[1]`, `$[1]`},
+		{`[0, 1, 2]`, `$[-1]`, `$[2]`},
+		{`{"\u000b": 1}`, `$['\u000b']`, `$['\u000b']`},
+		{`{"\u000B": 1}`, `$["\u000B"]`, `$['\u000b']`},
+		{`{"it's": 1}`, `$["it's"]`, `$['it\'s']`},
+		{`{"a": [0, 1, {"b": 2}]}`, `$.a[2].b`, `$['a'][2]['b']`},
+		{`{"a": [0, 1, {"b": 2}]}`, `$..b`, `$['a'][2]['b']`},
+	}
+	for _, c := range cases {
+		nodes := bjson.MustCompileJsonPath(c.query).Nodes(c.json)
+		if len(nodes) != 1 || nodes[0].Path != c.expected {
+			t.Errorf("%s: expected %s, got %v", c.query, c.expected, nodes)
+		}
+	}
+}
+
+func TestJsonPathWellFormedness(t *testing.T) {
+	valid := []string{
+		// section 2.4.9
+		`$[?length(@) < 3]`,
+		`$[?count(@.*) == 1]`,
+		`$[?match(@.timezone, 'Europe/.*')]`,
+		`$[?value(@..color) == "red"]`,
+		`$[?@.a == -0.5e+2]`,
+		`$[ 1 , 2:4 ]`,
+		`$.café`,
+		`$['𝄞']`,
+	}
+	for _, query := range valid {
+		if _, err := bjson.CompileJsonPath(query); err != nil {
+			t.Errorf("%s: %v", query, err)
+		}
+	}
+	invalid := []string{
+		// section 2.4.9
+		`$[?length(@.*) < 3]`,
+		`$[?count(1) == 1]`,
+		`$[?count(foo(@.*)) == 1]`,
+		`$[?match(@.timezone,'Europe/.*') == true]`,
+		`$[?value(@..color)]`,
+		`$[?@.* == 1]`,
+		`$[?1]`,
+		``,
+		` $`,
+		`$ `,
+		`$.`,
+		`$..`,
+		`$[`,
+		`$['a'`,
+		`$[01]`,
+		`$[-0]`,
+		`$[9007199254740992]`,
+		`$['\uD834']`,
+		`$["\'"]`,
+		`$.1a`,
+		`$[?@.a == 01]`,
+		`$[?@.a === 1]`,
+	}
+	for _, query := range invalid {
+		if _, err := bjson.CompileJsonPath(query); !errors.Is(err, bjson.ErrorJsonPathInvalid) {
+			t.Errorf("%q: expected an error, got %v", query, err)
+		}
+	}
+}
+
+func TestJsonPathBJsonPath(t *testing.T) {
+	cases := map[string]string{
+		`$`:                   `@this`,
+		`$.store.book[1]`:     `store.book.1`,
+		`$['a.b']['@c']`:      `a\.b.\@c`,
+		`$.store.book[-1]`:    ``,
+		`$.store.book[*]`:     ``,
+		`$.store..price`:      ``,
+		`$.store.book[0,1]`:   ``,
+		`$.store.book[?@.id]`: ``,
+	}
+	for query, expected := range cases {
+		path, ok := bjson.MustCompileJsonPath(query).BJsonPath()
+		if path != expected || ok != (expected != "") {
+			t.Errorf("%s: expected %q, got %q", query, expected, path)
+		}
+	}
+	path, _ := bjson.MustCompileJsonPath(`$.store.book[2].title`).BJsonPath()
+	if title := bjson.Get(jsonPathStore, path).String(); title != "Moby Dick" {
+		t.Errorf("%s: expected Moby Dick, got %s", path, title)
+	}
+}
+
+func TestJsonPointerConformance(t *testing.T) {
+	cases := []struct {
+		pointer  string
+		expected string
+	}{
+		// section 5
+		{``, string(pretty.Ugly([]byte(jsonPointerDoc)))},
+		{`/foo`, `["bar","baz"]`},
+		{`/foo/0`, `"bar"`},
+		{`/`, `0`},
+		{`/a~1b`, `1`},
+		{`/c%d`, `2`},
+		{`/e^f`, `3`},
+		{`/g|h`, `4`},
+		{`/i\j`, `5`},
+		{`/k"l`, `6`},
+		{`/ `, `7`},
+		{`/m~0n`, `8`},
+		// section 6
+		{`#`, string(pretty.Ugly([]byte(jsonPointerDoc)))},
+		{`#/foo`, `["bar","baz"]`},
+		{`#/foo/0`, `"bar"`},
+		{`#/`, `0`},
+		{`#/a~1b`, `1`},
+		{`#/c%25d`, `2`},
+		{`#/e%5Ef`, `3`},
+		{`#/g%7Ch`, `4`},
+		{`#/i%5Cj`, `5`},
+		{`#/k%22l`, `6`},
+		{`#/%20`, `7`},
+		{`#/m~0n`, `8`},
+	}
+	for _, c := range cases {
+		values, err := bjson.QueryPointer(jsonPointerDoc, c.pointer)
+		if err != nil {
+			t.Errorf("%q: %v", c.pointer, err)
+			continue
+		}
+		if actual := compactValues(values); actual != "["+c.expected+"]" {
+			t.Errorf("%q: expected %s, got %s", c.pointer, c.expected, actual)
+		}
+		checkOffsets(t, jsonPointerDoc, values)
+	}
+	errs := map[string]error{
+		`foo`:     bjson.ErrorPointerInvalid,
+		`/m~2n`:   bjson.ErrorPointerInvalid,
+		`/foo/01`: bjson.ErrorPointerIndex,
+		`/foo/2`:  bjson.ErrorPointerNotFound,
+		`/foo/-`:  bjson.ErrorPointerNotFound,
+		`/x/y`:    bjson.ErrorPointerNotFound,
+	}
+	for pointer, expected := range errs {
+		if _, err := bjson.QueryPointer(jsonPointerDoc, pointer); !errors.Is(err, expected) {
+			t.Errorf("%q: expected %v, got %v", pointer, expected, err)
+		}
+	}
+	if v := bjson.GetPointer(jsonPointerDoc, "/foo/1"); v.String() != "baz" {
+		t.Errorf("/foo/1: expected baz, got %s", v.String())
+	}
+	if path, _ := bjson.PointerToPath("/a~1b/c.d/0"); path != `a\/b.c\.d.0` {
+		t.Errorf("expected a\\/b.c\\.d.0, got %s", path)
+	}
+}
@@ -0,0 +1,79 @@
+package example
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+var modifierFriends = `{"friends":[
+	{"first":"Dale","last":"Murphy","age":44,"nets":["ig","fb","tw"]},
+	{"first":"Roger","last":"Craig","age":68,"nets":["fb","tw"]},
+	{"first":"Jane","last":"Murphy","age":47,"nets":["ig","tw"]},
+	{"first":"Ann","age":21}
+]}`
+
+func TestModifiers(t *testing.T) {
+	tests := []struct {
+		json string
+		path string
+		want string
+	}{
+		// @sort
+		{`[3,1,2]`, `@sort`, `[1,2,3]`},
+		{`[]`, `@sort`, `[]`},
+		{`[true,"b",null,2,false,"A",[1],{"a":1}]`, `@sort`, `[null,false,2,"A","b",true,[1],{"a":1}]`},
+		{`["b","B","a"]`, `@sort:{"caseSensitive":true}`, `["B","a","b"]`},
+		{modifierFriends, `friends|@sort:age|#.first`, `["Ann","Dale","Jane","Roger"]`},
+		{modifierFriends, `friends|@sort:{"by":"age","desc":true}|#.first`, `["Roger","Jane","Dale","Ann"]`},
+		{modifierFriends, `friends|@sort:{"by":"last"}|#.first`, `["Ann","Roger","Dale","Jane"]`},
+		{`{"a":1}`, `@sort`, `{"a":1}`},
+		// @unique
+		{`[1,2,1,3,2,1.0]`, `@unique`, `[1,2,3]`},
+		{`[]`, `@unique`, `[]`},
+		{modifierFriends, `friends|@unique:last|#.first`, `["Dale","Roger","Ann"]`},
+		// @sum, @avg, @min, @max
+		{`[1,2,3,"4.5","x",null]`, `@sum`, `10.5`},
+		{`[]`, `@sum`, `0`},
+		{`[]`, `@avg`, ``},
+		{`{"a":1}`, `@sum`, ``},
+		{`[1e308,1e308]`, `@sum`, ``},
+		{`[-1e308,-1e308]`, `@sum`, ``},
+		{`[1e308,1e308]`, `@avg`, "1" + strings.Repeat("0", 308)},
+		{`[1e400]`, `@max`, ``},
+		{`[1,2,3,4]`, `@avg`, `2.5`},
+		{modifierFriends, `friends|@avg:age`, `45`},
+		{modifierFriends, `friends|@min:age`, `21`},
+		{modifierFriends, `friends|@max:age`, `68`},
+		// @count
+		{`[1,2,3]`, `@count`, `3`},
+		{`[]`, `@count`, `0`},
+		{`{"a":1,"b":2}`, `@count`, `2`},
+		{`"a"`, `@count`, ``},
+		{modifierFriends, `friends|@count:age>40`, `3`},
+		{modifierFriends, `friends|@count:last=="Murphy"`, `2`},
+		{modifierFriends, `friends|@count:age>100`, `0`},
+		{`[]`, `@count:age>40`, `0`},
+		// @filter
+		{modifierFriends, `friends|@filter:age>45|#.first`, `["Roger","Jane"]`},
+		{modifierFriends, `friends|@filter:nets.#(=="fb")|#.first`, `["Dale","Roger"]`},
+		{modifierFriends, `friends|@filter:age>100`, `[]`},
+		{`[]`, `@filter:age>40`, `[]`},
+		{`[1,2]`, `@filter`, `[1,2]`},
+		// @slice
+		{`[1,2,3,4,5]`, `@slice:{"start":1,"end":3}`, `[2,3]`},
+		{`[1,2,3,4,5]`, `@slice:{"start":-2}`, `[4,5]`},
+		{`[1,2,3,4,5]`, `@slice:{"end":-4}`, `[1]`},
+		{`[1,2,3,4,5]`, `@slice:{"start":-10,"end":10}`, `[1,2,3,4,5]`},
+		{`[1,2,3,4,5]`, `@slice:{"start":4,"end":1}`, `[]`},
+		{`[1,2,3,4,5]`, `@slice:{"start":5}`, `[]`},
+		{`[]`, `@slice:{"start":1}`, `[]`},
+		{`{"a":1}`, `@slice:{"start":1}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if got := bjson.Get(tt.json, tt.path).Raw; got != tt.want {
+			t.Errorf("%s over %s: expected %s, got %s", tt.path, tt.json, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package example
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+// sameJson checks whether both documents hold the same values, regardless of the order of the members and of the formatting.
+func sameJson(a, b string) bool {
+	var x, y interface{}
+	if json.Unmarshal([]byte(a), &x) != nil || json.Unmarshal([]byte(b), &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// RFC 6902 appendix A
+func TestApplyPatchRFC6902(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      error
+	}{
+		{"A.1 adding an object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"A.2 adding an array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"A.3 removing an object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"A.4 removing an array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"A.5 replacing a value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, nil},
+		{"A.6 moving a value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"A.7 moving an array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+		{"A.8 testing a value: success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"A.9 testing a value: error", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", bjson.ErrorPatchTestFailed},
+		{"A.10 adding a nested member object", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`, nil},
+		{"A.11 ignoring unrecognized elements", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`, nil},
+		{"A.12 adding to a nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", bjson.ErrorPointerNotFound},
+		{"A.13 invalid JSON Patch document", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`, "", bjson.ErrorPatchDuplicateMember},
+		{"A.14 ~ escape ordering", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, nil},
+		{"A.15 comparing strings and numbers", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, "", bjson.ErrorPatchTestFailed},
+		{"A.16 adding an array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, nil},
+	}
+	for _, tt := range tests {
+		got, err := bjson.ApplyPatch(tt.doc, tt.patch)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+			}
+			if got != tt.doc {
+				t.Errorf("%s: expected the json unchanged, got %s", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !sameJson(got, tt.expected) {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	doc := `{"a":1,"b":[1,2]}`
+	patch := `[{"op":"replace","path":"/a","value":2},{"op":"add","path":"/b/-","value":3},{"op":"remove","path":"/c"}]`
+	got, err := bjson.ApplyPatch(doc, patch)
+	var pe *bjson.PatchError
+	if !errors.As(err, &pe) || pe.Index != 2 || pe.Op != bjson.PatchRemove || pe.Path != "/c" || !errors.Is(err, bjson.ErrorPointerNotFound) {
+		t.Fatalf("expected the failing operation 2 to be reported, got %v", err)
+	}
+	if got != doc {
+		t.Fatalf("expected an atomic patch, got %s", got)
+	}
+	tests := []struct {
+		patch string
+		index int
+		err   error
+	}{
+		{`[{"op":"add","path":"/x","value":1},{"op":"jump","path":"/a"}]`, 1, bjson.ErrorPatchOperation},
+		{`[{"op":"add","path":"/x"}]`, 0, bjson.ErrorPatchValue},
+		{`[{"op":"remove","path":"a"}]`, 0, bjson.ErrorPointerInvalid},
+		{`[{"op":"remove"}]`, 0, bjson.ErrorPointerInvalid},
+		{`[1]`, 0, bjson.ErrorPatchInvalid},
+		{`[{"op":"add","path":"/b/5","value":1}]`, 0, bjson.ErrorPointerIndex},
+		{`[{"op":"add","path":"/b/01","value":1}]`, 0, bjson.ErrorPointerIndex},
+		{`[{"op":"replace","path":"/missing","value":1}]`, 0, bjson.ErrorPointerNotFound},
+		{`[{"op":"move","from":"/b","path":"/b/0"}]`, 0, bjson.ErrorPatchMoveIntoSelf},
+		{`[{"op":"copy","from":"/missing","path":"/c"}]`, 0, bjson.ErrorPointerNotFound},
+	}
+	for _, tt := range tests {
+		_, err := bjson.ApplyPatch(doc, tt.patch)
+		if !errors.As(err, &pe) || pe.Index != tt.index || !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v at operation %d, got %v", tt.patch, tt.err, tt.index, err)
+		}
+	}
+	if _, err := bjson.ApplyPatch(doc, `{"op":"add"}`); err != bjson.ErrorPatchInvalid {
+		t.Fatalf("expected %v, got %v", bjson.ErrorPatchInvalid, err)
+	}
+	if _, err := bjson.ApplyPatch(`{"a":`, `[]`); err != bjson.ErrorInvalidJson {
+		t.Fatalf("expected %v, got %v", bjson.ErrorInvalidJson, err)
+	}
+}
+
+func TestApplyPatchOperations(t *testing.T) {
+	doc := "{\n  \"name\": \"Tom\",\n  \"tags\": [\"a\"]\n}"
+	p := bjson.Patch{
+		*bjson.NewPatchOperation(bjson.PatchCopy, "/alias").SetFrom("/name"),
+		*bjson.NewPatchOperation(bjson.PatchAdd, "/tags/0").SetValue("first"),
+		*bjson.NewPatchOperation(bjson.PatchReplace, "").SetRawValue(`{"root":true}`),
+	}
+	got, err := p[:2].Apply(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameJson(got, `{"name":"Tom","tags":["first","a"],"alias":"Tom"}`) {
+		t.Fatalf("unexpected patched json %s", got)
+	}
+	if got, err := p.Apply(doc); err != nil || got != `{"root":true}` {
+		t.Fatalf("expected the whole document replaced, got %s: %v", got, err)
+	}
+	parsed, err := bjson.ParsePatch(p.String())
+	if err != nil || !reflect.DeepEqual(parsed, p) {
+		t.Fatalf("patch not round-tripped: %s, %v", p.String(), err)
+	}
+	b, err := bjson.ApplyPatchBytes([]byte(`{"a":1}`), []byte(`[{"op":"remove","path":"/a"}]`))
+	if err != nil || string(b) != `{}` {
+		t.Fatalf("unexpected bytes %s: %v", b, err)
+	}
+}
+
+// RFC 7396 appendix A
+func TestMergePatchRFC7396(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := bjson.MergePatch(tt.doc, tt.patch)
+		if err != nil {
+			t.Fatalf("%s with %s: %v", tt.doc, tt.patch, err)
+		}
+		if !sameJson(got, tt.expected) {
+			t.Errorf("%s with %s: expected %s, got %s", tt.doc, tt.patch, tt.expected, got)
+		}
+	}
+	doc := "{\n  \"title\": \"Goodbye!\",\n  \"author\": {\"givenName\": \"John\", \"familyName\": \"Doe\"}\n}"
+	got, err := bjson.MergePatch(doc, `{"title":"Hello!"}`)
+	if err != nil || got != "{\n  \"title\": \"Hello!\",\n  \"author\": {\"givenName\": \"John\", \"familyName\": \"Doe\"}\n}" {
+		t.Fatalf("expected the formatting of the members left unchanged, got %s: %v", got, err)
+	}
+	if _, err := bjson.MergePatch(doc, `{"a":`); err != bjson.ErrorInvalidJson {
+		t.Fatalf("expected %v, got %v", bjson.ErrorInvalidJson, err)
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	pairs := []struct {
+		original string
+		modified string
+	}{
+		{`{"a":1,"b":{"c":[1,2,3]},"d":"x"}`, `{"a":2,"b":{"c":[1,5]},"e":false}`},
+		{`{"list":[1]}`, `{"list":[1,2,3]}`},
+		{`{"a":{"b":1}}`, `{"a":[1]}`},
+		{`[1,{"a":1}]`, `[1,{"a":2},"x"]`},
+		{`{"k~/":1}`, `{"k~/":2}`},
+	}
+	for _, tt := range pairs {
+		p, err := bjson.CreatePatch(tt.original, tt.modified)
+		if err != nil {
+			t.Fatalf("%s to %s: %v", tt.original, tt.modified, err)
+		}
+		got, err := p.Apply(tt.original)
+		if err != nil || !sameJson(got, tt.modified) {
+			t.Errorf("%s to %s: patch %s gave %s: %v", tt.original, tt.modified, p, got, err)
+		}
+		merge, err := bjson.CreateMergePatch(tt.original, tt.modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := bjson.MergePatch(tt.original, merge); !sameJson(got, tt.modified) {
+			t.Errorf("%s to %s: merge patch %s gave %s", tt.original, tt.modified, merge, got)
+		}
+	}
+	if p, err := bjson.CreatePatch(`{"a":1,"b":2}`, "{\n  \"b\": 2,\n  \"a\": 1\n}"); err != nil || len(p) != 0 {
+		t.Fatalf("expected no operation for equal documents, got %s: %v", p, err)
+	}
+	if merge, err := bjson.CreateMergePatch(`{"a":1}`, `{"a":1}`); err != nil || merge != `{}` {
+		t.Fatalf("expected an empty merge patch, got %s: %v", merge, err)
+	}
+	p, err := bjson.CreatePatch(`{"a":1}`, `{"a":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.Apply(`{"a":1}`); err != nil || got != `{"a":null}` {
+		t.Fatalf("expected a null value set by the patch %s, got %s: %v", p, got, err)
+	}
+	// a null value cannot be expressed by a merge patch, the member is removed
+	if merge, err := bjson.CreateMergePatch(`{"a":1}`, `{"a":null}`); err != nil || merge != `{"a":null}` {
+		t.Fatalf("unexpected merge patch %s: %v", merge, err)
+	}
+}
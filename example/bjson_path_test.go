@@ -0,0 +1,123 @@
+package example
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+var pathEvent = `{
+  "id": "evt_01HV3K9X",
+  "type": "order.created",
+  "created_at": "2024-05-06T07:08:09Z",
+  "source": {"service": "checkout", "region": "ap-southeast-1", "version": "2.14.0"},
+  "data": {
+    "order": {
+      "id": 918273, "currency": "VND", "total": 1250000.5, "discount": 50000, "status": "pending",
+      "items": [
+        {"sku": "A-100", "name": "Keyboard", "qty": 1, "price": 750000},
+        {"sku": "B-200", "name": "Mouse", "qty": 2, "price": 250000.25}
+      ],
+      "shipping": {"method": "express", "fee": 30000, "address": {"city": "Ho Chi Minh", "district": "1", "zip": "700000"}}
+    },
+    "customer": {
+      "id": 5521, "name": "Nguyen Van A", "email": "a@example.com", "tier": "gold",
+      "tags": ["vip", "returning"], "consent": {"email": true, "sms": false}
+    },
+    "payment": {"method": "card", "brand": "visa", "last4": "4242", "captured": false}
+  },
+  "meta": {"trace_id": "0af7651916cd43dd8448eb211c80319c", "retries": 0, "tenant": "acme"}
+}`
+
+var pathEventPaths = []string{
+	"id", "type", "created_at", "source.service", "source.region", "source.version",
+	"data.order.id", "data.order.currency", "data.order.total", "data.order.discount", "data.order.status",
+	"data.order.items.0.sku", "data.order.items.0.price", "data.order.items.1.sku", "data.order.items.1.qty",
+	"data.order.shipping.method", "data.order.shipping.fee", "data.order.shipping.address.city", "data.order.shipping.address.zip",
+	"data.customer.id", "data.customer.name", "data.customer.email", "data.customer.tier", "data.customer.tags.0",
+	"data.customer.consent.email", "data.payment.method", "data.payment.brand", "data.payment.last4",
+	"meta.trace_id", "meta.tenant",
+}
+
+func TestCompiledPaths(t *testing.T) {
+	paths := append(append([]string{}, pathEventPaths...), "data.order.items.#.sku", "missing.path", `data.customer|@keys`)
+	expected := bjson.GetMany(pathEvent, paths...)
+	actual := bjson.MustCompileMany(paths...).Get(pathEvent)
+	for i, path := range paths {
+		single := bjson.MustCompile(path).Get(pathEvent)
+		for _, res := range []bjson.BJsonContext{actual[i], single} {
+			if res.Raw != expected[i].Raw || res.Index != expected[i].Index || res.String() != expected[i].String() {
+				t.Errorf("%s: expected %s at %d, got %s at %d", path, expected[i].Raw, expected[i].Index, res.Raw, res.Index)
+			}
+		}
+	}
+	bytes := bjson.MustCompileMany(paths...).GetBytes([]byte(pathEvent))
+	if strings.Join(resultRaws(bytes), ",") != strings.Join(resultRaws(expected), ",") {
+		t.Errorf("GetBytes: expected %v, got %v", resultRaws(expected), resultRaws(bytes))
+	}
+	if _, err := bjson.Compile(""); err != bjson.ErrorPathEmpty {
+		t.Errorf("expected %v, got %v", bjson.ErrorPathEmpty, err)
+	}
+	if _, err := bjson.Compile(`friends.#(last=="Murphy"`); err != bjson.ErrorPathInvalid {
+		t.Errorf("expected %v, got %v", bjson.ErrorPathInvalid, err)
+	}
+}
+
+func resultRaws(values []bjson.BJsonContext) []string {
+	raws := make([]string, len(values))
+	for i, v := range values {
+		raws[i] = v.Raw
+	}
+	return raws
+}
+
+func BenchmarkGet(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bjson.Get(pathEvent, "data.order.shipping.address.city")
+	}
+}
+
+func BenchmarkPathGet(b *testing.B) {
+	path := bjson.MustCompile("data.order.shipping.address.city")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path.Get(pathEvent)
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bjson.GetMany(pathEvent, pathEventPaths...)
+	}
+}
+
+func BenchmarkExtractorGet(b *testing.B) {
+	extractor := bjson.MustCompileMany(pathEventPaths...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		extractor.Get(pathEvent)
+	}
+}
+
+func BenchmarkGetManyBytes(b *testing.B) {
+	event := []byte(pathEvent)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bjson.GetManyBytes(event, pathEventPaths...)
+	}
+}
+
+func BenchmarkExtractorGetBytes(b *testing.B) {
+	extractor := bjson.MustCompileMany(pathEventPaths...)
+	event := []byte(pathEvent)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		extractor.GetBytes(event)
+	}
+}
@@ -0,0 +1,61 @@
+package example
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+func TestSchemaTimeFormats(t *testing.T) {
+	dateTime := bjson.MustCompileSchema(`{"format":"date-time"}`)
+	clock := bjson.MustCompileSchema(`{"format":"time"}`)
+	tests := []struct {
+		schema *bjson.Schema
+		value  string
+		valid  bool
+	}{
+		{dateTime, `"1963-06-19T08:30:06.283185Z"`, true},
+		{dateTime, `"1963-06-19t08:30:06z"`, true},
+		{dateTime, `"1990-12-31T23:59:60Z"`, true},
+		{dateTime, `"1990-12-31T15:59:60-08:00"`, true},
+		{dateTime, `"1990-12-31T15:59:60.123-08:00"`, true},
+		{dateTime, `"1998-12-31T23:59:61Z"`, false},
+		{dateTime, `"1998-12-31T23:58:60Z"`, false},
+		{dateTime, `"1998-12-31T22:59:60Z"`, false},
+		{dateTime, `"1990-12-31T23:59:60+01:00"`, false},
+		{dateTime, `"1963-06-19 08:30:06Z"`, false},
+		{dateTime, `"1963-06-19T08:30:06"`, false},
+		{clock, `"08:30:06Z"`, true},
+		{clock, `"23:59:60Z"`, true},
+		{clock, `"15:59:60-08:00"`, true},
+		{clock, `"22:59:60Z"`, false},
+		{clock, `"08:30:06"`, false},
+	}
+	for _, tt := range tests {
+		if got := tt.schema.IsValid(tt.value); got != tt.valid {
+			t.Errorf("%s: expected valid %v, got %v", tt.value, tt.valid, got)
+		}
+	}
+}
+
+func TestSchemaCompileErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		fails  bool
+		err    error
+	}{
+		{`{"$ref":"#"}`, true, bjson.ErrorSchemaCycle},
+		{`{"allOf":[{"$ref":"#"}]}`, true, bjson.ErrorSchemaCycle},
+		{`{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`, true, bjson.ErrorSchemaCycle},
+		{`{"type":"bogus"}`, true, nil},
+		{`{"items":{"$ref":"#"}}`, false, nil},
+		{`{"properties":{"next":{"$ref":"#"}}}`, false, nil},
+	}
+	for _, tt := range tests {
+		_, err := bjson.CompileSchema(tt.schema)
+		if (err != nil) != tt.fails || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v (%v), got %v", tt.schema, tt.fails, tt.err, err)
+		}
+	}
+}
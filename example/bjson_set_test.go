@@ -0,0 +1,151 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+func TestSet(t *testing.T) {
+	const doc = `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex"]}`
+	tests := []struct {
+		path     string
+		value    interface{}
+		expected string
+	}{
+		{"name.last", "Smith", `{"name":{"first":"Tom","last":"Smith"},"age":37,"children":["Sara","Alex"]}`},
+		{"age", 38, `{"name":{"first":"Tom","last":"Anderson"},"age":38,"children":["Sara","Alex"]}`},
+		{"children.1", "Jack", `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Jack"]}`},
+		{"children.-1", "Jack", `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex","Jack"]}`},
+		{"children.3", "Jack", `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex",null,"Jack"]}`},
+		{"name.middle", "J", `{"name":{"first":"Tom","last":"Anderson","middle":"J"},"age":37,"children":["Sara","Alex"]}`},
+		{"address.geo.0", 10.5, `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex"],"address":{"geo":[10.5]}}`},
+		{"ids.:3", true, `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex"],"ids":{"3":true}}`},
+		{`key\.with\.dots`, nil, `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Alex"],"key.with.dots":null}`},
+		{"name", map[string]int{"id": 1}, `{"name":{"id":1},"age":37,"children":["Sara","Alex"]}`},
+		{"children.#(==Alex)", "Jack", `{"name":{"first":"Tom","last":"Anderson"},"age":37,"children":["Sara","Jack"]}`},
+		// paths with queries only replace existing values
+		{"children.#(==Nobody)", "Jack", doc},
+		{"name.first", `quote "and" \`, `{"name":{"first":"quote \"and\" \\","last":"Anderson"},"age":37,"children":["Sara","Alex"]}`},
+	}
+	for _, tt := range tests {
+		got, err := bjson.Set(doc, tt.path, tt.value)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.expected, got)
+		}
+	}
+	if got, err := bjson.Set("", "a.b", 1); err != nil || got != `{"a":{"b":1}}` {
+		t.Fatalf("unexpected document built from nothing %s: %v", got, err)
+	}
+	if got, err := bjson.Set("", "0.a", 1); err != nil || got != `[{"a":1}]` {
+		t.Fatalf("unexpected array built from nothing %s: %v", got, err)
+	}
+	if _, err := bjson.Set(doc, "", 1); err != bjson.ErrorPathEmpty {
+		t.Fatalf("expected %v, got %v", bjson.ErrorPathEmpty, err)
+	}
+	if _, err := bjson.Set(doc, "children.name", 1); err == nil {
+		t.Fatal("expected an error for a non-numeric key of an array")
+	}
+}
+
+func TestSetPreservesFormatting(t *testing.T) {
+	doc := "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}"
+	got, err := bjson.Set(doc, "c", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the member appended takes the indentation of the others, written compactly
+	if expected := "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ],\n  \"c\":\"x\"\n}"; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	got, err = bjson.Set(doc, "a.-1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2,\n    3\n  ]\n}"; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	got, err = bjson.Set(`{ "b" : 1 , "a" : 2 }`, "b", 10)
+	if err != nil || got != `{ "b" : 10 , "a" : 2 }` {
+		t.Fatalf("expected the whitespace and key order kept, got %s: %v", got, err)
+	}
+}
+
+func TestSetRaw(t *testing.T) {
+	got, err := bjson.SetRaw(`{"a":1}`, "b", `{"id":[1,2]}`)
+	if err != nil || got != `{"a":1,"b":{"id":[1,2]}}` {
+		t.Fatalf("unexpected raw value %s: %v", got, err)
+	}
+	got, err = bjson.SetRaw(`{"a":1}`, "a", `"1"`)
+	if err != nil || got != `{"a":"1"}` {
+		t.Fatalf("expected a raw value inserted as is, got %s: %v", got, err)
+	}
+	b, err := bjson.SetRawBytes([]byte(`{"list":[]}`), "list.-1", []byte(`{"x":1}`))
+	if err != nil || string(b) != `{"list":[{"x":1}]}` {
+		t.Fatalf("unexpected raw bytes %s: %v", b, err)
+	}
+}
+
+func TestSetBytesInPlace(t *testing.T) {
+	doc := []byte(`{"name":"Anderson","age":37}`)
+	b, err := bjson.SetBytes(doc, "age", 38)
+	if err != nil || string(b) != `{"name":"Anderson","age":38}` {
+		t.Fatalf("unexpected bytes %s: %v", b, err)
+	}
+	if string(doc) != `{"name":"Anderson","age":37}` {
+		t.Fatalf("SetBytes modified the json given: %s", doc)
+	}
+	opts := &bjson.SetOptions{Optimistic: true, ReplaceInPlace: true}
+	inplace := []byte(`{"name":"Anderson","age":37}`)
+	b, err = bjson.SetBytesWith(inplace, "name", "Smith", opts)
+	if err != nil || string(b) != `{"name":"Smith","age":37}` {
+		t.Fatalf("unexpected bytes %s: %v", b, err)
+	}
+	if &b[0] != &inplace[0] {
+		t.Fatal("expected the json given to be reused when the value fits")
+	}
+	// the string variant ignores ReplaceInPlace, strings being immutable
+	s := `{"name":"Anderson","age":37}`
+	got, err := bjson.SetWith(s, "name", "Smith", opts)
+	if err != nil || got != `{"name":"Smith","age":37}` || s != `{"name":"Anderson","age":37}` {
+		t.Fatalf("unexpected string %s from %s: %v", got, s, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		json     string
+		path     string
+		expected string
+	}{
+		{`{"a":1,"b":2,"c":3}`, "a", `{"b":2,"c":3}`},
+		{`{"a":1,"b":2,"c":3}`, "b", `{"a":1,"c":3}`},
+		{`{"a":1,"b":2,"c":3}`, "c", `{"a":1,"b":2}`},
+		{`{"a":[1,2,3]}`, "a.0", `{"a":[2,3]}`},
+		{`{"a":[1,2,3]}`, "a.-1", `{"a":[1,2]}`},
+		{`{"a":{"b":{"c":1,"d":2}}}`, "a.b.c", `{"a":{"b":{"d":2}}}`},
+		{`{"a":1}`, "missing", `{"a":1}`},
+		{`{"a":[]}`, "a.-1", `{"a":[]}`},
+		{"{\n  \"a\": 1,\n  \"b\": 2\n}", "a", "{\n  \"b\": 2\n}"},
+		{"{\n  \"a\": 1,\n  \"b\": 2\n}", "b", "{\n  \"a\": 1\n}"},
+	}
+	for _, tt := range tests {
+		got, err := bjson.Delete(tt.json, tt.path)
+		if err != nil {
+			t.Fatalf("%s from %s: %v", tt.path, tt.json, err)
+		}
+		if got != tt.expected {
+			t.Errorf("%s from %s: expected %q, got %q", tt.path, tt.json, tt.expected, got)
+		}
+	}
+	if _, err := bjson.Delete(`{"a":[1,2]}`, "a.#(==1)"); err != bjson.ErrorDeleteComplexPath {
+		t.Fatalf("expected %v, got %v", bjson.ErrorDeleteComplexPath, err)
+	}
+	b, err := bjson.DeleteBytes([]byte(`{"a":1,"b":2}`), "a")
+	if err != nil || string(b) != `{"b":2}` {
+		t.Fatalf("unexpected bytes %s: %v", b, err)
+	}
+}
@@ -0,0 +1,243 @@
+package example
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+var streamExport = `{
+  "name": "export",
+  "items": [
+    {"id": 1, "name": "Keyboard", "tags": ["a", "b"]},
+    {"id": 2, "name": "Mouse \"M2\"", "nested": {"id": 99}},
+    {"id": 3, "nome": "Screen"}
+  ],
+  "meta": {"count": 3, "a.b": true}
+}`
+
+func TestStream(t *testing.T) {
+	var ids []int64
+	err := bjson.Stream(iotest.OneByteReader(strings.NewReader(streamExport)), "items.#.id", func(value bjson.BJsonContext) bool {
+		ids = append(ids, value.Int())
+		if streamExport[value.Index:value.Index+len(value.Raw)] != value.Raw {
+			t.Errorf("value %s not at its offset %d", value.Raw, value.Index)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Fatalf("expected the ids of the items only, got %v", ids)
+	}
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"items.1.name", []string{`"Mouse \"M2\""`}},
+		{"items.#.n?me", []string{`"Keyboard"`, `"Mouse \"M2\""`, `"Screen"`}},
+		{"items.#.tags.#", []string{`"a"`, `"b"`}},
+		{"meta.*", []string{`3`, `true`}},
+		{`meta.a\.b`, []string{`true`}},
+		{"items.5", nil},
+		{"missing.#", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := bjson.Stream(strings.NewReader(streamExport), tt.path, func(value bjson.BJsonContext) bool {
+			got = append(got, value.Raw)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.expected, got)
+		}
+		// the values are those of Get
+		if len(tt.expected) == 1 && bjson.Get(streamExport, tt.path).Raw != got[0] {
+			t.Errorf("%s: expected the value of Get %s", tt.path, bjson.Get(streamExport, tt.path).Raw)
+		}
+	}
+}
+
+func TestStreamMany(t *testing.T) {
+	var got []string
+	err := bjson.StreamMany(strings.NewReader(streamExport), []string{"name", "items.#.id", "meta.count"}, func(path string, value bjson.BJsonContext) bool {
+		got = append(got, path+"="+value.Raw)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`name="export"`, "items.0.id=1", "items.1.id=2", "items.2.id=3", "meta.count=3"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	// the documents of json lines are streamed one after the other, an empty path matching each of them
+	lines := "{\"id\":1}\n\n{\"id\":2}\n[3]\n"
+	var ids []string
+	if err := bjson.Stream(strings.NewReader(lines), "id", func(value bjson.BJsonContext) bool {
+		ids = append(ids, value.Raw)
+		return true
+	}); err != nil || !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Fatalf("unexpected ids %v: %v", ids, err)
+	}
+	var documents int
+	if err := bjson.Stream(strings.NewReader(lines), "", func(value bjson.BJsonContext) bool {
+		documents++
+		return true
+	}); err != nil || documents != 3 {
+		t.Fatalf("expected 3 documents, got %d: %v", documents, err)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	unsupported := []string{"items.#(id==1)", "items.#[id==1]", "@pretty", "items|0", "{id,name}", "!true", "items.#.id|@reverse"}
+	for _, path := range unsupported {
+		err := bjson.Stream(strings.NewReader(streamExport), path, func(value bjson.BJsonContext) bool { return true })
+		if !errors.Is(err, bjson.ErrorStreamPathUnsupported) {
+			t.Errorf("%s: expected %v, got %v", path, bjson.ErrorStreamPathUnsupported, err)
+		}
+	}
+	// the values skipped are balanced, not validated, as Get does
+	malformed := []string{`{"items":[1,2`, `{"items":[1,2,]}`, `{"a" 1}`, `{"a":1}}`, `{"a":"open}`}
+	for _, json := range malformed {
+		err := bjson.Stream(strings.NewReader(json), "items.#", func(value bjson.BJsonContext) bool { return true })
+		if err == nil {
+			t.Errorf("%s: expected an error", json)
+		}
+	}
+	reader := iotest.TimeoutReader(strings.NewReader(`{"items":[1,2,3]}`))
+	if err := bjson.Stream(reader, "items.#", func(value bjson.BJsonContext) bool { return true }); err == nil {
+		t.Fatal("expected the error of the reader")
+	}
+}
+
+// endlessItems is the stream `{"items":[{"id":0},{"id":1},...` that never ends.
+type endlessItems struct {
+	next    int
+	pending []byte
+}
+
+func (e *endlessItems) Read(p []byte) (int, error) {
+	if e.pending == nil {
+		e.pending = []byte(`{"items":[`)
+	}
+	for len(e.pending) < len(p) {
+		e.pending = append(e.pending, `{"id":`+strconv.Itoa(e.next)+`},`...)
+		e.next++
+	}
+	n := copy(p, e.pending)
+	e.pending = append(e.pending[:0], e.pending[n:]...)
+	return n, nil
+}
+
+func TestStreamStopsEarly(t *testing.T) {
+	// the stream never ends, so that values can only be read one at a time
+	var last int64
+	err := bjson.Stream(&endlessItems{}, "items.#.id", func(value bjson.BJsonContext) bool {
+		last = value.Int()
+		return last < 100000
+	})
+	if err != nil || last != 100000 {
+		t.Fatalf("expected to stop at 100000, got %d: %v", last, err)
+	}
+}
+
+func TestForEachLineReader(t *testing.T) {
+	lines := "{\"name\":\"Gilbert\",\"age\":61}\n\n  {\"name\":\"Alexa\",\"age\":34}\r\n[1,2]\n\"text\"\n"
+	var expected, got []string
+	bjson.ForEachLine(lines, func(line bjson.BJsonContext) bool {
+		expected = append(expected, line.Raw)
+		return true
+	})
+	if err := bjson.ForEachLineReader(iotest.HalfReader(strings.NewReader(lines)), func(line bjson.BJsonContext) bool {
+		got = append(got, line.Raw)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the lines of ForEachLine %v, got %v", expected, got)
+	}
+	var count int
+	if err := bjson.ForEachLineReader(strings.NewReader(lines), func(line bjson.BJsonContext) bool {
+		count++
+		return false
+	}); err != nil || count != 1 {
+		t.Fatalf("expected to stop after the first line, got %d: %v", count, err)
+	}
+	if err := bjson.ForEachLineReader(strings.NewReader("{\"a\":1}\n{\"a\":\n"), func(line bjson.BJsonContext) bool { return true }); err == nil {
+		t.Fatal("expected an error for a truncated line")
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	json := `{"a":[1,{"b":null}],"c":"x"}`
+	tk := bjson.NewTokenizer(iotest.OneByteReader(strings.NewReader(json)))
+	expected := []struct {
+		kind bjson.TokenKind
+		raw  string
+		path string
+	}{
+		{bjson.TokenBeginObject, "", "@this"},
+		{bjson.TokenKey, `"a"`, "a"},
+		{bjson.TokenBeginArray, "", "a"},
+		{bjson.TokenValue, "1", "a.0"},
+		{bjson.TokenBeginObject, "", "a.1"},
+		{bjson.TokenKey, `"b"`, "a.1.b"},
+		{bjson.TokenValue, "null", "a.1.b"},
+		{bjson.TokenEndObject, "", "a.1"},
+		{bjson.TokenEndArray, "", "a"},
+		{bjson.TokenKey, `"c"`, "c"},
+		{bjson.TokenValue, `"x"`, "c"},
+		{bjson.TokenEndObject, "", "@this"},
+	}
+	for i, e := range expected {
+		tok, err := tk.Next()
+		if err != nil {
+			t.Fatalf("token %d: %v", i, err)
+		}
+		if tok.Kind != e.kind || tok.Value.Raw != e.raw || tk.Path() != e.path {
+			t.Errorf("token %d: expected %v %s at %s, got %v %s at %s", i, e.kind, e.raw, e.path, tok.Kind, tok.Value.Raw, tk.Path())
+		}
+		if at := json[tok.Offset:]; !strings.HasPrefix(at, e.raw) || (e.raw == "" && !strings.ContainsAny(at[:1], "{}[]")) {
+			t.Errorf("token %d: unexpected offset %d", i, tok.Offset)
+		}
+	}
+	if _, err := tk.Next(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	tk = bjson.NewTokenizer(strings.NewReader(json))
+	tk.Next()
+	if _, err := tk.ReadValue(); err != bjson.ErrorTokenNotValue {
+		t.Fatalf("expected %v before a key, got %v", bjson.ErrorTokenNotValue, err)
+	}
+	tk.Next()
+	if err := tk.SkipValue(); err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := tk.Next(); tok.Kind != bjson.TokenKey || tok.Value.String() != "c" {
+		t.Fatalf("expected the key following the value skipped, got %v", tok)
+	}
+	if v, err := tk.ReadValue(); err != nil || v.String() != "x" || tk.Depth() != 1 {
+		t.Fatalf("unexpected value %s at depth %d: %v", v.Raw, tk.Depth(), err)
+	}
+	if tk.Offset() != int64(len(json)-1) {
+		t.Fatalf("expected %d bytes read, got %d", len(json)-1, tk.Offset())
+	}
+	tk = bjson.NewTokenizer(strings.NewReader(`[1 2]`))
+	tk.Next()
+	tk.Next()
+	if _, err := tk.Next(); err == nil {
+		t.Fatal("expected an error for a missing comma")
+	}
+}
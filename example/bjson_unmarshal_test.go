@@ -0,0 +1,148 @@
+package example
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/bjson"
+)
+
+type unmarshalAddress struct {
+	City string `bjson:"city"`
+	Zip  string `bjson:"zip" default:"000000"`
+}
+
+type unmarshalOrder struct {
+	Id        int64              `bjson:"data.order.id"`
+	Total     float64            `bjson:"data.order.total"`
+	Status    *string            `bjson:"data.order.status"`
+	Skus      []string           `bjson:"data.order.items.#.sku"`
+	Qty       [2]uint8           `bjson:"data.order.items.#.qty"`
+	Address   unmarshalAddress   `bjson:"data.order.shipping.address"`
+	Consent   map[string]bool    `bjson:"data.customer.consent"`
+	Created   time.Time          `bjson:"created_at"`
+	Day       time.Time          `bjson:"day" layout:"2006-01-02"`
+	Retries   int                `bjson:"meta.retries" default:"3"`
+	Tenant    string             `bjson:"meta.missing" default:"acme"`
+	Raw       bjson.BJsonContext `bjson:"source"`
+	Untouched string
+}
+
+func TestUnmarshal(t *testing.T) {
+	doc := `{"created_at":"2024-05-06T07:08:09Z","day":"2024-05-06","source":{"service":"checkout"},
+		"data":{"order":{"id":918273,"total":1250000.5,"status":"pending",
+			"items":[{"sku":"A-100","qty":1},{"sku":"B-200","qty":2}],
+			"shipping":{"address":{"city":"Ho Chi Minh","zip":null}}},
+			"customer":{"consent":{"email":true,"sms":false}}},
+		"meta":{"retries":null}}`
+	actual := unmarshalOrder{Untouched: "kept"}
+	if err := bjson.Unmarshal(doc, &actual); err != nil {
+		t.Fatal(err)
+	}
+	status := "pending"
+	expected := unmarshalOrder{
+		Id: 918273, Total: 1250000.5, Status: &status, Skus: []string{"A-100", "B-200"}, Qty: [2]uint8{1, 2},
+		Address: unmarshalAddress{City: "Ho Chi Minh", Zip: "000000"},
+		Consent: map[string]bool{"email": true, "sms": false},
+		Created: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), Day: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+		Retries: 3, Tenant: "acme", Untouched: "kept",
+	}
+	expected.Raw = actual.Raw
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if actual.Raw.Get("service").String() != "checkout" {
+		t.Errorf("expected the raw source, got %s", actual.Raw.Raw)
+	}
+	if err := bjson.Unmarshal(doc, actual); !errors.Is(err, bjson.ErrorUnmarshalTarget) {
+		t.Errorf("expected %v, got %v", bjson.ErrorUnmarshalTarget, err)
+	}
+}
+
+func TestUnmarshalTypeMismatches(t *testing.T) {
+	var v struct {
+		S   string    `bjson:"v"`
+		B   bool      `bjson:"v"`
+		I   int       `bjson:"v"`
+		I8  int8      `bjson:"v"`
+		I64 int64     `bjson:"v"`
+		U8  uint8     `bjson:"v"`
+		U64 uint64    `bjson:"v"`
+		F32 float32   `bjson:"v"`
+		T   time.Time `bjson:"v"`
+	}
+	fields := map[string]interface{}{
+		"S": &v.S, "B": &v.B, "I": &v.I, "I8": &v.I8, "I64": &v.I64, "U8": &v.U8, "U64": &v.U64, "F32": &v.F32, "T": &v.T,
+	}
+	tests := []struct {
+		field string
+		json  string
+		err   string
+	}{
+		{"I", `"abc"`, "expected a number"},
+		{"I", `"12"`, "expected a number"},
+		{"I", `1.9`, "1.9 is not an integer"},
+		{"I", `true`, "expected a number"},
+		{"I", `[1]`, "cannot hold an object or array"},
+		{"I8", `128`, "128 overflows int8"},
+		{"I64", `9223372036854775808`, "9223372036854775808 overflows int64"},
+		{"U8", `300`, "300 overflows uint8"},
+		{"U8", `-1`, "-1 is negative, uint8 is unsigned"},
+		{"U64", `18446744073709551616`, "18446744073709551616 overflows uint64"},
+		{"F32", `1e39`, "1e39 overflows float32"},
+		{"F32", `"1.5"`, "expected a number"},
+		{"B", `1`, "expected a boolean"},
+		{"B", `"true"`, "expected a boolean"},
+		{"S", `true`, "expected a string"},
+		{"S", `12`, "expected a string"},
+		{"S", `{"a":1}`, "cannot hold an object or array"},
+		{"T", `"2024-05-06"`, "cannot parse"},
+		{"T", `1714979289`, "expected a string"},
+	}
+	for _, tt := range tests {
+		doc := `{"v":` + tt.json + `}`
+		err := unmarshalField(doc, tt.field, fields)
+		if !errors.Is(err, bjson.ErrorUnmarshalType) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s from %s: expected %q, got %v", tt.field, tt.json, tt.err, err)
+		}
+	}
+	valid := []struct {
+		field string
+		json  string
+		want  interface{}
+	}{
+		{"I", `1e3`, 1000},
+		{"I", `-2.0`, -2},
+		{"I64", `-9223372036854775808`, int64(-9223372036854775808)},
+		{"I64", `9223372036854775807`, int64(9223372036854775807)},
+		{"U64", `18446744073709551615`, uint64(18446744073709551615)},
+		{"U8", `255`, uint8(255)},
+		{"F32", `1.5`, float32(1.5)},
+		{"S", `"12"`, "12"},
+		{"B", `false`, false},
+	}
+	for _, tt := range valid {
+		if err := unmarshalField(`{"v":`+tt.json+`}`, tt.field, fields); err != nil {
+			t.Errorf("%s from %s: %v", tt.field, tt.json, err)
+			continue
+		}
+		if got := reflect.ValueOf(fields[tt.field]).Elem().Interface(); got != tt.want {
+			t.Errorf("%s from %s: expected %v, got %v", tt.field, tt.json, tt.want, got)
+		}
+	}
+}
+
+// unmarshalField unmarshals the json into the single field of a struct built of the field pointed to.
+func unmarshalField(json, name string, fields map[string]interface{}) error {
+	ptr := reflect.ValueOf(fields[name])
+	typ := reflect.StructOf([]reflect.StructField{{Name: name, Type: ptr.Type().Elem(), Tag: `bjson:"v"`}})
+	dst := reflect.New(typ)
+	if err := bjson.Unmarshal(json, dst.Interface()); err != nil {
+		return err
+	}
+	ptr.Elem().Set(dst.Elem().Field(0))
+	return nil
+}
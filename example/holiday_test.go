@@ -0,0 +1,307 @@
+package example
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/holiday"
+)
+
+func TestAddBusinessDaysLongSpan(t *testing.T) {
+	svc, err := holiday.NewCalendarService(*holiday.GetCalendarSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, svc.Location())
+	for _, days := range []int{3000, -3000} {
+		end := svc.AddBusinessDays(start, days)
+		if n := svc.BusinessDaysBetween(start, end); n != days {
+			t.Errorf("AddBusinessDays(%d) = %s, %d business days away", days, end.Format(holiday.DateLayout), n)
+		}
+	}
+}
+
+func TestParseICSSkipsUnsupportedRecurrences(t *testing.T) {
+	doc := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"X-WR-CALNAME:Mixed",
+		"BEGIN:VEVENT",
+		"SUMMARY:Labor Day",
+		"DTSTART;VALUE=DATE:20240902",
+		"RRULE:FREQ=YEARLY;BYMONTH=9;BYDAY=1MO",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Standup",
+		"DTSTART:20240902T090000Z",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Workshop",
+		"DTSTART;VALUE=DATE:20240101",
+		"RRULE:FREQ=YEARLY;BYDAY=MO,TU",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	c, warnings, err := holiday.ParseICSWithWarnings(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Rules) != 1 || c.Rules[0].Name != "Labor Day" || c.Rules[0].Kind != holiday.RuleNthWeekday {
+		t.Fatalf("expected the Labor Day rule only, got %s", c.Json())
+	}
+	if len(warnings) != 2 || !errors.Is(warnings[0], holiday.ErrorICSUnsupported) || !errors.Is(warnings[1], holiday.ErrorICSUnsupported) {
+		t.Fatalf("expected 2 unsupported warnings, got %v", warnings)
+	}
+	if _, err := holiday.ParseICS(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	malformed := strings.Replace(doc, "DTSTART;VALUE=DATE:20240902", "DTSTART;VALUE=DATE:2024-09-02", 1)
+	if _, err := holiday.ParseICS(strings.NewReader(malformed)); err == nil {
+		t.Fatal("expected an error for a malformed date")
+	}
+}
+
+func TestHolidayRuleDates(t *testing.T) {
+	hcm, _ := time.LoadLocation("Asia/Ho_Chi_Minh")
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	tests := []struct {
+		name     string
+		date     time.Time
+		expected string
+	}{
+		{"Easter 2024", holiday.Easter(2024, time.UTC), "2024-03-31"},
+		{"Easter 2025", holiday.Easter(2025, time.UTC), "2025-04-20"},
+		{"Easter 2038", holiday.Easter(2038, time.UTC), "2038-04-25"},
+		{"Orthodox Easter 2024", holiday.OrthodoxEaster(2024, time.UTC), "2024-05-05"},
+		{"Orthodox Easter 2025", holiday.OrthodoxEaster(2025, time.UTC), "2025-04-20"},
+		{"Lunar New Year 2024", holiday.LunarNewYearOf(2024, hcm), "2024-02-10"},
+		{"Lunar New Year 2025", holiday.LunarNewYearOf(2025, hcm), "2025-01-29"},
+		// the new moon fell on different dates at UTC+7 and UTC+8
+		{"Tet 2007", holiday.LunarNewYearOf(2007, hcm), "2007-02-17"},
+		{"Chinese New Year 2007", holiday.LunarNewYearOf(2007, shanghai), "2007-02-18"},
+	}
+	for _, tt := range tests {
+		if got := tt.date.Format(holiday.DateLayout); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+	nth := []struct {
+		rule     *holiday.Rule
+		year     int
+		expected []string
+	}{
+		{holiday.NthWeekday("Thanksgiving", time.November, time.Thursday, 4), 2024, []string{"2024-11-28"}},
+		{holiday.NthWeekday("Memorial Day", time.May, time.Monday, -1), 2024, []string{"2024-05-27"}},
+		{holiday.NthWeekday("Fifth Friday", time.February, time.Friday, 5), 2024, nil},
+		{holiday.Fixed("Leap Day", time.February, 29), 2023, nil},
+		{holiday.Fixed("Leap Day", time.February, 29), 2024, []string{"2024-02-29"}},
+		{holiday.EasterRelative("Good Friday", -2), 2024, []string{"2024-03-29"}},
+		{holiday.LunarNewYear("Tet", -1, 3), 2024, []string{"2024-02-09", "2024-02-10", "2024-02-11"}},
+		{holiday.Fixed("Once", time.April, 18).SetStartYear(2024).SetEndYear(2024), 2025, nil},
+		{holiday.LunarNewYear("Tet", 0, 3).AppendExcludes("2024-02-11"), 2024, []string{"2024-02-10", "2024-02-12"}},
+	}
+	for _, tt := range nth {
+		if err := holiday.RuleValidator(tt.rule); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, d := range tt.rule.Dates(tt.year, hcm) {
+			got = append(got, d.Format(holiday.DateLayout))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s %d: expected %v, got %v", tt.rule.Name, tt.year, tt.expected, got)
+		}
+	}
+}
+
+func TestBusinessDays(t *testing.T) {
+	svc, err := holiday.NewCalendarService(*holiday.GetCalendarSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := svc.Location()
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 9, 30, 0, 0, loc)
+	}
+	tests := []struct {
+		name     string
+		got      time.Time
+		expected time.Time
+	}{
+		{"over the holidays", svc.AddBusinessDays(day(time.April, 29), 1), day(time.May, 2)},
+		{"over the weekend", svc.NextBusinessDay(day(time.April, 26)), day(time.April, 29)},
+		{"backwards", svc.PreviousBusinessDay(day(time.May, 2)), day(time.April, 29)},
+		{"zero days on a holiday", svc.AddBusinessDays(day(time.April, 30), 0), day(time.April, 30)},
+		{"from a weekend day", svc.AddBusinessDays(day(time.April, 27), 1), day(time.April, 29)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+	if n := svc.BusinessDaysBetween(day(time.April, 26), day(time.May, 2)); n != 2 {
+		t.Fatalf("expected 2 business days, got %d", n)
+	}
+	if n := svc.BusinessDaysBetween(day(time.May, 2), day(time.April, 26)); n != -2 {
+		t.Fatalf("expected -2 business days, got %d", n)
+	}
+	// 18:00 UTC on April 29 is already April 30 in Ho Chi Minh City
+	if !svc.IsHoliday(time.Date(2024, 4, 29, 18, 0, 0, 0, time.UTC)) {
+		t.Fatal("date not taken in the timezone of the calendar")
+	}
+	var names []string
+	for _, h := range svc.HolidaysBetween(day(time.April, 1), day(time.May, 31)) {
+		names = append(names, h.Name)
+	}
+	if strings.Join(names, ",") != "Hung Kings Commemoration,Reunification Day,Labour Day" {
+		t.Fatalf("unexpected holidays %v", names)
+	}
+}
+
+func TestBusinessDaysWithoutBusinessDay(t *testing.T) {
+	c := holiday.NewCalendar().SetWeekends([]string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"})
+	svc, err := holiday.NewCalendarService(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if end := svc.AddBusinessDays(start, 1); end.Sub(start) != holiday.MaxBusinessDaysScan*24*time.Hour {
+		t.Fatalf("expected the scan to stop after %d days, got %v", holiday.MaxBusinessDaysScan, end)
+	}
+}
+
+func TestCalendarValidator(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar *holiday.Calendar
+	}{
+		{"timezone", holiday.NewCalendar().SetTimezone("Mars/Olympus")},
+		{"weekend", holiday.NewCalendar().AppendWeekends("caturday")},
+		{"observed", holiday.NewCalendar().SetObserved("later")},
+		{"kind", holiday.NewCalendar().AppendRules(*holiday.NewRule().SetName("x").SetKind("lunar"))},
+		{"month", holiday.NewCalendar().AppendRules(*holiday.Fixed("x", 13, 1))},
+		{"nth", holiday.NewCalendar().AppendRules(*holiday.NthWeekday("x", time.May, time.Monday, 6))},
+		{"exclude", holiday.NewCalendar().AppendRules(*holiday.Fixed("x", 1, 1).AppendExcludes("01/01/2024"))},
+		{"years", holiday.NewCalendar().AppendRules(*holiday.Fixed("x", 1, 1).SetStartYear(2025).SetEndYear(2024))},
+	}
+	for _, tt := range tests {
+		if _, err := holiday.NewCalendarService(*tt.calendar); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestObservedHolidays(t *testing.T) {
+	tests := []struct {
+		policy   string
+		date     time.Time
+		expected string
+	}{
+		{holiday.ObservedNearest, time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC), "2020-07-03"},
+		{holiday.ObservedNearest, time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), "2021-07-05"},
+		{holiday.ObservedFollowing, time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC), "2020-07-06"},
+		{holiday.ObservedPreceding, time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), "2021-07-02"},
+		{holiday.ObservedSundayToMonday, time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC), ""},
+		{holiday.ObservedSundayToMonday, time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), "2021-07-05"},
+		{holiday.ObservedNone, time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), ""},
+		{holiday.ObservedFollowing, time.Date(2022, 7, 4, 0, 0, 0, 0, time.UTC), ""},
+	}
+	for _, tt := range tests {
+		c := holiday.NewCalendar().SetObserved(tt.policy).AppendRules(*holiday.Fixed("Independence Day", time.July, 4))
+		svc, err := holiday.NewCalendarService(*c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var observed []string
+		for _, h := range svc.Holidays(tt.date.Year()) {
+			if h.IsObserved {
+				observed = append(observed, h.Date.Format(holiday.DateLayout))
+				if !h.Original.Equal(tt.date) {
+					t.Errorf("%s %d: expected original %v, got %v", tt.policy, tt.date.Year(), tt.date, h.Original)
+				}
+			}
+		}
+		if strings.Join(observed, ",") != tt.expected {
+			t.Errorf("%s %d: expected %q, got %v", tt.policy, tt.date.Year(), tt.expected, observed)
+		}
+	}
+}
+
+func TestObservedHolidaysCollisions(t *testing.T) {
+	c := holiday.NewCalendar().
+		SetObserved(holiday.ObservedFollowing).
+		AppendRules(*holiday.Fixed("Christmas Day", time.December, 25),
+			*holiday.Fixed("Boxing Day", time.December, 26),
+			*holiday.Fixed("New Year's Day", time.January, 1).SetObserved(holiday.ObservedPreceding))
+	svc, err := holiday.NewCalendarService(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Christmas 2022 is on Sunday, and the Monday after is Boxing Day
+	h, ok := svc.GetHoliday(time.Date(2022, 12, 27, 12, 0, 0, 0, time.UTC))
+	if !ok || h.Name != "Christmas Day" || !h.IsObserved {
+		t.Fatalf("expected Christmas Day observed on Tuesday, got %+v", h)
+	}
+	// New Year's Day 2022 is on Saturday, observed on the Friday before, within 2021
+	h, ok = svc.GetHoliday(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC))
+	if !ok || h.Name != "New Year's Day" || h.Original.Year() != 2022 {
+		t.Fatalf("expected New Year's Day 2022 observed in 2021, got %+v", h)
+	}
+	if svc.IsBusinessDay(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("observed holiday counted as a business day")
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	c := holiday.NewCalendar().
+		SetName("Test; holidays, with a name long enough to be folded over several content lines of the document").
+		SetObserved(holiday.ObservedFollowing).
+		AppendWeekends("friday", "saturday").
+		AppendRules(*holiday.Fixed("Christmas Day", time.December, 25).AppendExcludes("2025-12-25"),
+			*holiday.NthWeekday("Thanksgiving", time.November, time.Thursday, 4).SetObserved(holiday.ObservedNone),
+			*holiday.EasterRelative("Good Friday", -2),
+			*holiday.LunarNewYear("Lunar New Year", 0, 3),
+			*holiday.Fixed("Jubilee", time.June, 3).SetStartYear(2025).SetEndYear(2025))
+	doc, err := holiday.ExportICS(*c, 2024, 2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(doc, "\r\n") {
+		if len(line) > holiday.ICSMaxLineOctets {
+			t.Fatalf("line longer than %d octets: %q", holiday.ICSMaxLineOctets, line)
+		}
+	}
+	parsed, err := holiday.ParseICS(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Name != c.Name || parsed.Observed != c.Observed || strings.Join(parsed.Weekends, ",") != "friday,saturday" {
+		t.Fatalf("calendar properties not restored: %s", parsed.Json())
+	}
+	original, err := holiday.NewCalendarService(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := holiday.NewCalendarService(*parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe := func(holidays []holiday.Holiday) string {
+		var values []string
+		for _, h := range holidays {
+			values = append(values, fmt.Sprintf("%s %s %v", h.Date.Format(holiday.DateLayout), h.Name, h.IsObserved))
+		}
+		return strings.Join(values, "\n")
+	}
+	for year := 2024; year <= 2026; year++ {
+		if len(original.Holidays(year)) < 5 {
+			t.Fatalf("%d: expected every rule to date a holiday, got %d", year, len(original.Holidays(year)))
+		}
+		if want, got := describe(original.Holidays(year)), describe(restored.Holidays(year)); want != got {
+			t.Errorf("%d: expected\n%s\ngot\n%s", year, want, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package example
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/mem"
+)
+
+func TestLRUCacheCleanupEvictsOnlyExpiredEntries(t *testing.T) {
+	// the cleanup runs every 200ms, entries expire after 400ms
+	c := mem.NewLRUCacheExpiration(10, 400*time.Millisecond)
+	defer c.StopCleanup()
+	c.Set("a", 1)
+	time.Sleep(300 * time.Millisecond)
+	if !c.Contains("a") {
+		t.Fatal("entry evicted by the cleanup before it expired")
+	}
+	time.Sleep(600 * time.Millisecond)
+	if c.Contains("a") {
+		t.Fatal("expired entry kept by the cleanup")
+	}
+	plain := mem.NewLRUCache(2)
+	plain.Set("a", 1)
+	plain.Set("b", 2)
+	if _, ok := plain.Get("a"); !ok {
+		t.Fatal("entry without expiration not found")
+	}
+	plain.Set("c", 3)
+	if !plain.Contains("a") || plain.Contains("b") {
+		t.Fatal("Get did not make the entry the most recently used")
+	}
+}
+
+func TestLRUCacheConcurrentGet(t *testing.T) {
+	c := mem.NewLRUCache(100)
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if v, ok := c.Get(strconv.Itoa((i + g) % 100)); !ok || v != (i+g)%100 {
+					t.Errorf("expected %d, got %v", (i+g)%100, v)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package example
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sivaosorg/govm/pretty"
+	"gopkg.in/yaml.v2"
+)
+
+var renderDocument = []byte(`{"name":"svc","port":8080,"ratio":1.5,"tags":["a","b"],"empty":{},"nil":null,` +
+	`"server":{"tls":{"enabled":true}},"servers":[{"host":"a"},{"host":"b"}],"odd key":"x: y"}`)
+
+func TestRenderTOML(t *testing.T) {
+	out, err := pretty.TOML(renderDocument, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[string]interface{}
+	if _, err := toml.Decode(string(out), &actual); err != nil {
+		t.Fatalf("invalid TOML: %v\n%s", err, out)
+	}
+	expected := map[string]interface{}{
+		"name": "svc", "port": int64(8080), "ratio": 1.5, "tags": []interface{}{"a", "b"}, "empty": map[string]interface{}{},
+		"server":  map[string]interface{}{"tls": map[string]interface{}{"enabled": true}},
+		"servers": []map[string]interface{}{{"host": "a"}, {"host": "b"}}, "odd key": "x: y",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	tests := []struct {
+		json string
+		err  error
+	}{
+		{`[1]`, pretty.ErrorTOMLRootObject},
+		{`{"big":12345678901234567890}`, pretty.ErrorTOMLNumberRange},
+		{`{"a":{"b":[-9223372036854775809]}}`, pretty.ErrorTOMLNumberRange},
+		{`{"f":1e400}`, pretty.ErrorTOMLNumberRange},
+		{`{"a":1,"a":2}`, pretty.ErrorTOMLDuplicateKey},
+		{`{"t":[{"k":1,"k":null}]}`, pretty.ErrorTOMLDuplicateKey},
+		{`{"min":-9223372036854775808,"f":1E5,"small":1e-400}`, nil},
+	}
+	for _, tt := range tests {
+		out, err := pretty.TOML([]byte(tt.json), nil)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.json, tt.err, err)
+			continue
+		}
+		if err == nil {
+			if _, err := toml.Decode(string(out), &actual); err != nil {
+				t.Errorf("%s: invalid TOML: %v\n%s", tt.json, err, out)
+			}
+		}
+	}
+}
+
+func TestRenderYAML(t *testing.T) {
+	out, err := pretty.YAML(renderDocument, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual, expected interface{}
+	if err := yaml.Unmarshal(out, &actual); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, out)
+	}
+	yaml.Unmarshal(renderDocument, &expected)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	for _, s := range []string{"true", "1.5", "a: b", "- a", "null", "2024-01-02"} {
+		doc, _ := json.Marshal(map[string]string{"k": s})
+		out, _ := pretty.YAML(doc, nil)
+		var v map[string]interface{}
+		if err := yaml.Unmarshal(out, &v); err != nil || v["k"] != s {
+			t.Errorf("%q: read back as %v (%v)\n%s", s, v["k"], err, out)
+		}
+	}
+}
+
+func TestRenderCSVAndTable(t *testing.T) {
+	out, err := pretty.CSV([]byte(`[{"a":1,"b":{"c":"x"}},{"a":2,"d":[true]}]`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a,b.c,d.0\n1,x,\n2,,true\n"; string(out) != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	out, err = pretty.Table([]byte(`{"name":"svc","server":{"port":8080}}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "name          \"svc\"\nserver.port   8080"; strings.TrimSpace(string(out)) != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	if _, err := pretty.Render(renderDocument, "ini", nil); !errors.Is(err, pretty.ErrorUnknownFormat) {
+		t.Errorf("expected %v, got %v", pretty.ErrorUnknownFormat, err)
+	}
+	if _, err := pretty.Render([]byte(`{"a":`), pretty.FormatYAML, nil); !errors.Is(err, pretty.ErrorInvalidJson) {
+		t.Errorf("expected %v, got %v", pretty.ErrorInvalidJson, err)
+	}
+}
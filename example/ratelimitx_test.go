@@ -0,0 +1,230 @@
+package example
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/common"
+	"github.com/sivaosorg/govm/ratelimitx"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().
+		SetEnabled(true).
+		SetRate(2).
+		SetMaxBurst(3).
+		SetPeriod(200 * time.Millisecond).
+		SetMode(ratelimitx.ModeTokenBucket)
+	l := ratelimitx.NewRateLimiterService(*conf)
+	defer l.Stop()
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a") {
+			t.Fatalf("event %d within the burst denied", i)
+		}
+	}
+	if l.Allow("a") {
+		t.Fatal("event beyond the burst allowed")
+	}
+	if !l.Allow("b") {
+		t.Fatal("keys are not limited independently")
+	}
+	if l.Remaining("a") != 0 {
+		t.Fatalf("expected 0 remaining, got %d", l.Remaining("a"))
+	}
+	// a denied AllowN consumes nothing
+	if l.AllowN("a", 1) || l.Remaining("a") != 0 {
+		t.Fatal("denied event consumed tokens")
+	}
+	time.Sleep(110 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Fatal("refilled token denied")
+	}
+	l.Reset("a")
+	if l.Remaining("a") != 3 {
+		t.Fatalf("expected the full burst after Reset, got %d", l.Remaining("a"))
+	}
+	if !l.AllowN("a", 3) {
+		t.Fatal("AllowN of the whole burst denied")
+	}
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().
+		SetEnabled(true).
+		SetRate(2).
+		SetPeriod(200 * time.Millisecond).
+		SetMode(ratelimitx.ModeSlidingWindow)
+	l := ratelimitx.NewRateLimiterService(*conf)
+	defer l.Stop()
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("events within the window denied")
+	}
+	if l.Allow("a") {
+		t.Fatal("event beyond the window allowed")
+	}
+	r := l.Reserve("a")
+	if !r.OK() || r.Delay() <= 0 || r.Delay() > 200*time.Millisecond {
+		t.Fatalf("expected a delay within the period, got %v", r.Delay())
+	}
+	r.Cancel()
+	time.Sleep(220 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Fatal("event after the window slid denied")
+	}
+	if l.Remaining("a") != 1 {
+		t.Fatalf("expected 1 remaining, got %d", l.Remaining("a"))
+	}
+}
+
+func TestRateLimiterReserveN(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().SetEnabled(true).SetRate(10).SetMaxBurst(2)
+	l := ratelimitx.NewRateLimiterService(*conf)
+	defer l.Stop()
+	for _, n := range []int{0, -1, 3} {
+		if r := l.ReserveN("a", n); r.OK() {
+			t.Errorf("ReserveN(%d) expected not OK", n)
+		}
+		if l.AllowN("a", n) {
+			t.Errorf("AllowN(%d) expected false", n)
+		}
+	}
+	r := l.ReserveN("a", 2)
+	if !r.OK() || r.Delay() != 0 || r.Limit() != 2 || r.Remaining() != 0 {
+		t.Fatalf("unexpected reservation: ok %v, delay %v, limit %d, remaining %d", r.OK(), r.Delay(), r.Limit(), r.Remaining())
+	}
+	if !r.ResetAt().After(time.Now()) {
+		t.Fatal("expected ResetAt in the future")
+	}
+	r.Cancel()
+	if l.Remaining("a") != 2 {
+		t.Fatalf("expected Cancel to give the tokens back, got %d remaining", l.Remaining("a"))
+	}
+}
+
+func TestRateLimiterWaitN(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().SetEnabled(true).SetRate(1).SetPeriod(100 * time.Millisecond)
+	l := ratelimitx.NewRateLimiterService(*conf)
+	defer l.Stop()
+	ctx := context.Background()
+	if err := l.WaitN(ctx, "a", 0); err == nil {
+		t.Fatal("expected an error for 0 events")
+	}
+	if err := l.WaitN(ctx, "a", 2); err == nil {
+		t.Fatal("expected an error for events beyond the burst")
+	}
+	start := time.Now()
+	if err := l.Wait(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("expected Wait to block for the refill, took %v", elapsed)
+	}
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := l.Wait(short, "a"); err == nil {
+		t.Fatal("expected an error when the delay exceeds the deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("expected Wait to fail fast, took %v", elapsed)
+	}
+	done, cancelDone := context.WithCancel(ctx)
+	cancelDone()
+	if err := l.Wait(done, "b"); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := ratelimitx.NewRateLimiterService(*ratelimitx.NewRateLimitConfig().SetEnabled(false))
+	defer l.Stop()
+	for i := 0; i < 100; i++ {
+		if !l.Allow("a") {
+			t.Fatal("disabled limiter denied an event")
+		}
+	}
+	if l.Len() != 0 {
+		t.Fatalf("disabled limiter tracked %d keys", l.Len())
+	}
+}
+
+func TestRateLimitMiddlewareDefaultKeyIgnoresForwardedFor(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().SetEnabled(true).SetRate(2).SetPeriod(time.Minute)
+	m := ratelimitx.NewRateLimitMiddlewareService(*conf, nil)
+	defer m.Stop()
+	h := m.RateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:" + strconv.Itoa(40000+i)
+		req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+		if i < 2 {
+			continue
+		}
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("rotating X-Forwarded-For bypassed the limit: %v", codes)
+		}
+		if rec.Header().Get(common.HeaderRetryAfter) == "" {
+			t.Fatal("expected a Retry-After header")
+		}
+		if rec.Header().Get(common.HeaderXRateLimitLimit) != "2" || rec.Header().Get(common.HeaderXRateLimitRemaining) != "0" {
+			t.Fatalf("unexpected X-RateLimit headers: %v", rec.Header())
+		}
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK {
+		t.Fatalf("requests within the limit rejected: %v", codes)
+	}
+}
+
+func TestRateLimitMiddlewareEmptyKeyFallsBackToRemoteAddr(t *testing.T) {
+	conf := ratelimitx.NewRateLimitConfig().SetEnabled(true).SetRate(1).SetPeriod(time.Minute)
+	m := ratelimitx.NewRateLimitMiddlewareService(*conf, ratelimitx.KeyByBearerToken())
+	defer m.Stop()
+	h := m.RateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("request %d without token: expected %d, got %d", i, want, rec.Code)
+		}
+	}
+}
+
+func TestKeyByTrustedClientIP(t *testing.T) {
+	key := ratelimitx.KeyByTrustedClientIP("10.0.0.0/8", "192.168.1.10", "invalid")
+	tests := []struct {
+		remote    string
+		forwarded string
+		expected  string
+	}{
+		{"203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"10.0.0.1:1234", "1.1.1.1, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"192.168.1.10:1234", "", "192.168.1.10"},
+		{"192.168.1.11:1234", "198.51.100.1", "192.168.1.11"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := key(req); got != tt.expected {
+			t.Errorf("remote %s, forwarded %q: expected %s, got %s", tt.remote, tt.forwarded, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package example
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/cookies"
+	"github.com/sivaosorg/govm/session"
+)
+
+func TestSessionCookieRequiresSecretKey(t *testing.T) {
+	conf := session.NewSessionManager().SetCookie(*cookies.GetCookieConfigSample())
+	if _, err := conf.SignValue("id"); err != session.ErrorSecretKeyRequired {
+		t.Fatalf("expected %v, got %v", session.ErrorSecretKeyRequired, err)
+	}
+	if _, ok := conf.VerifyValue("id"); ok {
+		t.Fatal("unsigned value accepted without secret key")
+	}
+	svc := session.NewSessionManagerService(*conf)
+	if s := svc.CreateSession(httptest.NewRecorder(), nil); s != nil {
+		t.Fatal("session created without secret key")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: conf.Cookie.Name, Value: "id"})
+	if s := svc.GetSession(r); s != nil {
+		t.Fatal("unsigned cookie accepted without secret key")
+	}
+}
+
+func TestCreateSessionDiscardsUnissuedSession(t *testing.T) {
+	dir := t.TempDir()
+	store, err := session.NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := session.NewSessionManager().
+		SetSecretKey("secret").
+		SetCookie(*cookies.GetCookieConfigSample().SetEnabled(false))
+	svc := session.NewSessionManagerServiceWith(*conf, store)
+	if s := svc.CreateSession(httptest.NewRecorder(), map[string]interface{}{"user": "alice"}); s != nil {
+		t.Fatal("session created without cookie")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no stored session, got %d", len(entries))
+	}
+}
+
+func TestSessionSignValue(t *testing.T) {
+	conf := session.NewSessionManager().SetSecretKey("k1")
+	signed, err := conf.SignValue("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := conf.VerifyValue(signed); !ok || value != "id" {
+		t.Fatalf("expected id, got %q, %v", value, ok)
+	}
+	tampered := []string{
+		"other" + signed[2:],
+		signed[:len(signed)-1] + "x",
+		"id",
+		"id.",
+		"." + signed,
+	}
+	for _, v := range tampered {
+		if _, ok := conf.VerifyValue(v); ok {
+			t.Errorf("tampered value %q accepted", v)
+		}
+	}
+	if _, ok := session.NewSessionManager().SetSecretKey("k2").VerifyValue(signed); ok {
+		t.Fatal("value accepted under another key")
+	}
+	conf.RotateSecretKey("k2")
+	if value, ok := conf.VerifyValue(signed); !ok || value != "id" {
+		t.Fatal("value signed before the rotation rejected")
+	}
+	rotated, _ := conf.SignValue("id")
+	if _, ok := session.NewSessionManager().SetSecretKey("k1").VerifyValue(rotated); ok {
+		t.Fatal("value signed with the previous key after the rotation")
+	}
+}
+
+func TestSessionEncrypt(t *testing.T) {
+	conf := session.NewSessionManager().SetSecretKey("k1")
+	s := session.NewSession().SetId("id").SetExpiresAt(time.Now().Add(time.Hour)).AppendData("user", "alice")
+	sealed, err := conf.Encrypt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "alice") {
+		t.Fatal("session data readable in the sealed value")
+	}
+	opened, err := conf.Decrypt(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := opened.GetString("user"); opened.Id != "id" || user != "alice" {
+		t.Fatalf("unexpected session: %s", opened.Json())
+	}
+	again, _ := conf.Encrypt(s)
+	if again == sealed {
+		t.Fatal("expected a fresh nonce per encryption")
+	}
+	flipped := []byte(sealed)
+	if flipped[len(flipped)/2] == 'A' {
+		flipped[len(flipped)/2] = 'B'
+	} else {
+		flipped[len(flipped)/2] = 'A'
+	}
+	for _, v := range []string{string(flipped), sealed[:10], "!!", ""} {
+		if _, err := conf.Decrypt(v); err == nil {
+			t.Errorf("tampered value %q opened", v)
+		}
+	}
+	if _, err := session.NewSessionManager().SetSecretKey("k2").Decrypt(sealed); err == nil {
+		t.Fatal("value opened under another key")
+	}
+	conf.RotateSecretKey("k2")
+	if _, err := conf.Decrypt(sealed); err != nil {
+		t.Fatalf("value sealed before the rotation rejected: %v", err)
+	}
+	big := session.NewSession().SetId("id").AppendData("blob", strings.Repeat("x", session.MaxCookieSize))
+	if _, err := conf.Encrypt(big); err == nil {
+		t.Fatal("expected an error for a session too large for a cookie")
+	}
+}
+
+func TestClientSideSessionRoundTrip(t *testing.T) {
+	conf := session.NewSessionManager().
+		SetSecretKey("k1").
+		SetClientSide(true).
+		SetExpiration(time.Hour).
+		SetCookie(*cookies.GetCookieConfigSample())
+	svc := session.NewSessionManagerService(*conf)
+	rec := httptest.NewRecorder()
+	if s := svc.CreateSession(rec, map[string]interface{}{"user": "alice"}); s == nil {
+		t.Fatal("client-side session not created")
+	}
+	cookie := rec.Result().Cookies()[0]
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	s := svc.GetSession(r)
+	if s == nil {
+		t.Fatal("client-side session not read back")
+	}
+	if user, _ := s.GetString("user"); user != "alice" {
+		t.Fatalf("expected alice, got %q", user)
+	}
+	if svc.VerifySessionId(s.Id) {
+		t.Fatal("client-side session kept in the store")
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value[:len(cookie.Value)-2]})
+	if svc.GetSession(r) != nil {
+		t.Fatal("truncated client-side cookie accepted")
+	}
+}
+
+func newLifecycleSessionService(store session.SessionStore, sliding bool) session.SessionManagerService {
+	conf := session.NewSessionManager().
+		SetSecretKey("k1").
+		SetSliding(sliding).
+		SetExpiration(time.Hour).
+		SetCookie(*cookies.GetCookieConfigSample())
+	return session.NewSessionManagerServiceWith(*conf, store)
+}
+
+func requestWithCookies(rec *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	svc := newLifecycleSessionService(session.NewMemorySessionStore(), false)
+	rec := httptest.NewRecorder()
+	old := svc.CreateSession(rec, map[string]interface{}{"user": "alice"})
+	oldRequest := requestWithCookies(rec)
+	rec = httptest.NewRecorder()
+	regenerated, err := svc.Regenerate(rec, oldRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regenerated.Id == old.Id {
+		t.Fatal("session id not regenerated")
+	}
+	if svc.VerifySessionId(old.Id) || svc.GetSession(oldRequest) != nil {
+		t.Fatal("old session id still valid")
+	}
+	s := svc.GetSession(requestWithCookies(rec))
+	if s == nil {
+		t.Fatal("regenerated session not found")
+	}
+	if user, _ := s.GetString("user"); user != "alice" {
+		t.Fatalf("data not carried over, got %q", user)
+	}
+	if _, err := svc.Regenerate(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Fatal("expected an error without session")
+	}
+}
+
+func TestSessionDestroy(t *testing.T) {
+	svc := newLifecycleSessionService(session.NewMemorySessionStore(), false)
+	rec := httptest.NewRecorder()
+	s := svc.CreateSession(rec, nil)
+	r := requestWithCookies(rec)
+	rec = httptest.NewRecorder()
+	if err := svc.Destroy(rec, r); err != nil {
+		t.Fatal(err)
+	}
+	if svc.VerifySessionId(s.Id) || svc.GetSession(r) != nil {
+		t.Fatal("destroyed session still valid")
+	}
+	issued := rec.Result().Cookies()
+	if len(issued) != 1 || issued[0].MaxAge >= 0 || issued[0].Value != "" {
+		t.Fatalf("expected the session cookie to be deleted, got %v", issued)
+	}
+}
+
+func TestSessionSlidingExpiration(t *testing.T) {
+	store, err := session.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newLifecycleSessionService(store, true)
+	rec := httptest.NewRecorder()
+	created := svc.CreateSession(rec, nil)
+	r := requestWithCookies(rec)
+	time.Sleep(20 * time.Millisecond)
+	rec = httptest.NewRecorder()
+	var seen time.Time
+	svc.SessionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s := session.FromContext(r.Context()); s != nil {
+			seen = s.ExpiresAt
+		}
+	})).ServeHTTP(rec, r)
+	if !seen.After(created.ExpiresAt) {
+		t.Fatalf("expiration not renewed: %v, was %v", seen, created.ExpiresAt)
+	}
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatal("expected the session cookie to be re-issued")
+	}
+	if stored := svc.GetSession(r); stored == nil || stored.ExpiresAt.Before(seen) {
+		t.Fatal("renewed expiration not persisted by the store")
+	}
+}
+
+func TestSessionFlashes(t *testing.T) {
+	store, err := session.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newLifecycleSessionService(store, false)
+	rec := httptest.NewRecorder()
+	svc.CreateSession(rec, nil)
+	r := requestWithCookies(rec)
+	if err := svc.AddFlash(httptest.NewRecorder(), r, "notice", "saved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddFlash(httptest.NewRecorder(), r, "notice", "sent"); err != nil {
+		t.Fatal(err)
+	}
+	values := svc.Flashes(httptest.NewRecorder(), r, "notice")
+	if len(values) != 2 || values[0] != "saved" || values[1] != "sent" {
+		t.Fatalf("unexpected flashes: %v", values)
+	}
+	if values := svc.Flashes(httptest.NewRecorder(), r, "notice"); len(values) != 0 {
+		t.Fatalf("flashes shown twice: %v", values)
+	}
+	if err := svc.AddFlash(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), "notice", "x"); err == nil {
+		t.Fatal("expected an error without session")
+	}
+}
+
+func TestRequireSessionMiddleware(t *testing.T) {
+	svc := newLifecycleSessionService(session.NewMemorySessionStore(), false)
+	h := svc.RequireSessionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if session.FromContext(r.Context()) == nil {
+			t.Error("session not injected into the context")
+		}
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without session, got %d", rec.Code)
+	}
+	created := httptest.NewRecorder()
+	svc.CreateSession(created, nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, requestWithCookies(created))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with session, got %d", rec.Code)
+	}
+}
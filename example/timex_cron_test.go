@@ -0,0 +1,183 @@
+package example
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+)
+
+func TestCronNext(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"*/15 9-17 * * MON-FRI", utc(2024, 2, 2, 17, 50, 0), utc(2024, 2, 5, 9, 0, 0)},
+		{"30 * * * * *", utc(2024, 2, 2, 10, 0, 0), utc(2024, 2, 2, 10, 0, 30)},
+		{"0 0 L * *", utc(2024, 2, 10, 0, 0, 0), utc(2024, 2, 29, 0, 0, 0)},
+		{"0 0 L-3 * *", utc(2024, 2, 10, 0, 0, 0), utc(2024, 2, 26, 0, 0, 0)},
+		// June 15, 2024 is a Saturday
+		{"0 0 15W * *", utc(2024, 6, 1, 0, 0, 0), utc(2024, 6, 14, 0, 0, 0)},
+		// August 31, 2024 is a Saturday
+		{"0 0 LW * *", utc(2024, 8, 1, 0, 0, 0), utc(2024, 8, 30, 0, 0, 0)},
+		// June 1, 2024 is a Saturday, its nearest weekday within the month is Monday the 3rd
+		{"0 0 1W * *", utc(2024, 5, 31, 0, 0, 0), utc(2024, 6, 3, 0, 0, 0)},
+		{"0 0 * * 5L", utc(2024, 2, 1, 0, 0, 0), utc(2024, 2, 23, 0, 0, 0)},
+		{"0 0 * * 1#2", utc(2024, 2, 1, 0, 0, 0), utc(2024, 2, 12, 0, 0, 0)},
+		{"0 0 * * 7", utc(2024, 2, 1, 0, 0, 0), utc(2024, 2, 4, 0, 0, 0)},
+		// both day fields restricted, either matches
+		{"0 0 1 * MON", utc(2024, 2, 1, 0, 0, 0), utc(2024, 2, 5, 0, 0, 0)},
+		{"0 0 29 2 *", utc(2024, 3, 1, 0, 0, 0), utc(2028, 2, 29, 0, 0, 0)},
+		{"@monthly", utc(2024, 1, 31, 23, 0, 0), utc(2024, 2, 1, 0, 0, 0)},
+		{"@every 90s", utc(2024, 1, 1, 0, 0, 0), utc(2024, 1, 1, 0, 1, 30)},
+		{"TZ=Asia/Tokyo 0 9 * * *", utc(2023, 12, 31, 12, 0, 0), utc(2024, 1, 1, 0, 0, 0)},
+		{"0 0 30 2 *", utc(2024, 1, 1, 0, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := timex.ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.expected) {
+			t.Errorf("%s from %v: expected %v, got %v", tt.spec, tt.from, tt.expected, got)
+		}
+	}
+}
+
+func TestCronNextAcrossDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 02:30 does not exist on March 10, 2024, when the clocks spring forward
+	s := timex.MustParseCron("30 2 * * *")
+	if got := s.Next(time.Date(2024, 3, 9, 3, 0, 0, 0, ny)); !got.Equal(time.Date(2024, 3, 11, 2, 30, 0, 0, ny)) {
+		t.Fatalf("expected the skipped time not to match, got %v", got)
+	}
+	// 01:30 happens twice on November 3, 2024, when the clocks fall back, and matches once
+	s = timex.MustParseCron("30 1 * * *")
+	first := s.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, ny))
+	if _, offset := first.Zone(); first.Hour() != 1 || first.Minute() != 30 || offset != -4*3600 {
+		t.Fatalf("expected 01:30 EDT, got %v", first)
+	}
+	if second := s.Next(first); !second.Equal(time.Date(2024, 11, 4, 1, 30, 0, 0, ny)) {
+		t.Fatalf("expected the repeated time to match once, got %v", second)
+	}
+	// the result keeps the location of the time given
+	tokyo := timex.MustParseCron("TZ=Asia/Tokyo 0 9 * * *").Next(time.Date(2024, 1, 1, 0, 0, 0, 0, ny))
+	if tokyo.Location() != ny {
+		t.Fatalf("expected the location of the time given, got %v", tokyo.Location())
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"* * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * L-31 * *",
+		"* * 32W * *",
+		"* * * * 1#6",
+		"* * * FOO *",
+		"@fortnightly",
+		"@every 100ms",
+		"@every soon",
+		"TZ=Mars/Olympus * * * * *",
+		"TZ=UTC",
+	}
+	for _, spec := range specs {
+		if _, err := timex.ParseCron(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestSchedulerOverlapSkip(t *testing.T) {
+	svc, err := timex.NewSchedulerService(*timex.NewSchedulerConfig().SetEnabled(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	var runs int32
+	var cancelled int32
+	err = svc.AddJob(*timex.NewCronJobConfig().SetName("slow").SetSpec("@every 1s").SetOverlap(timex.OverlapSkip), func(ctx context.Context) {
+		atomic.AddInt32(&runs, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			atomic.AddInt32(&cancelled, 1)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddFunc("slow", "@every 1s", func(ctx context.Context) {}); err == nil {
+		t.Fatal("expected an error for a duplicated job")
+	}
+	svc.Start()
+	time.Sleep(2300 * time.Millisecond)
+	e, _ := svc.Entry("slow")
+	if atomic.LoadInt32(&runs) != 1 || e.Running != 1 || e.Skipped < 1 {
+		t.Fatalf("expected a single run and skipped activations, got %d runs, %+v", runs, e)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := svc.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Fatal("expected the context of the running job to be cancelled")
+	}
+	if svc.IsRunning() {
+		t.Fatal("scheduler still running after Stop")
+	}
+	close(release)
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	if _, err := timex.NewSchedulerService(*timex.GetSchedulerConfigSample(), map[string]timex.JobFunc{}); err == nil {
+		t.Fatal("expected an error for a job without function")
+	}
+	funcs := map[string]timex.JobFunc{
+		"daily_report":   func(ctx context.Context) {},
+		"monthly_report": func(ctx context.Context) {},
+	}
+	svc, err := timex.NewSchedulerService(*timex.GetSchedulerConfigSample(), funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.Entries()) != 2 {
+		t.Fatalf("expected the enabled jobs only, got %v", svc.Entries())
+	}
+	svc.Start()
+	defer svc.Stop(context.Background())
+	entries := svc.Entries()
+	if entries[0].Next.IsZero() || entries[1].Next.Before(entries[0].Next) {
+		t.Fatalf("expected entries sorted by next activation, got %v", entries)
+	}
+	invalid := []*timex.SchedulerConfig{
+		timex.NewSchedulerConfig().SetOverlap("sometimes"),
+		timex.NewSchedulerConfig().SetTimezone("Mars/Olympus"),
+		timex.NewSchedulerConfig().AppendJobs(*timex.NewCronJobConfig().SetSpec("@daily")),
+		timex.NewSchedulerConfig().AppendJobs(*timex.NewCronJobConfig().SetName("a").SetSpec("@daily"), *timex.NewCronJobConfig().SetName("a").SetSpec("@hourly")),
+	}
+	for i, conf := range invalid {
+		if _, err := timex.NewSchedulerService(*conf, nil); err == nil {
+			t.Errorf("config %d: expected an error", i)
+		}
+	}
+}
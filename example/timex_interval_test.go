@@ -0,0 +1,141 @@
+package example
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+)
+
+func hours(from, to int) timex.Interval {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return timex.NewInterval(base.Add(time.Duration(from)*time.Hour), base.Add(time.Duration(to)*time.Hour))
+}
+
+func TestIntervalAlgebra(t *testing.T) {
+	if i := timex.NewInterval(hours(5, 5).Start, hours(2, 2).Start); i != hours(2, 5) {
+		t.Fatalf("expected the bounds to be swapped, got %v", i)
+	}
+	a := hours(1, 5)
+	if !a.Contains(hours(1, 1).Start) || a.Contains(hours(5, 5).Start) {
+		t.Fatal("expected a half-open interval")
+	}
+	if a.Overlaps(hours(5, 8)) || !a.Abuts(hours(5, 8)) {
+		t.Fatal("abutting intervals must not overlap")
+	}
+	tests := []struct {
+		name     string
+		got      []timex.Interval
+		expected []timex.Interval
+	}{
+		{"union overlapping", a.Union(hours(3, 8)), []timex.Interval{hours(1, 8)}},
+		{"union abutting", a.Union(hours(5, 8)), []timex.Interval{hours(1, 8)}},
+		{"union disjoint", a.Union(hours(6, 8)), []timex.Interval{hours(1, 5), hours(6, 8)}},
+		{"difference inside", a.Difference(hours(2, 3)), []timex.Interval{hours(1, 2), hours(3, 5)}},
+		{"difference covering", a.Difference(hours(0, 6)), nil},
+		{"difference disjoint", a.Difference(hours(6, 8)), []timex.Interval{hours(1, 5)}},
+		{"gaps", hours(8, 18).Gaps(hours(12, 13), hours(9, 10), hours(9, 11), hours(17, 20)), []timex.Interval{hours(8, 9), hours(11, 12), hours(13, 17)}},
+		{"merge", timex.MergeIntervals(hours(6, 7), hours(1, 3), hours(2, 4), hours(4, 5), hours(9, 9)), []timex.Interval{hours(1, 5), hours(6, 7)}},
+		{"intersect lists", timex.IntersectIntervals([]timex.Interval{hours(0, 4), hours(6, 10)}, []timex.Interval{hours(2, 7), hours(9, 12)}), []timex.Interval{hours(2, 4), hours(6, 7), hours(9, 10)}},
+		{"subtract lists", timex.SubtractIntervals([]timex.Interval{hours(0, 4), hours(6, 10)}, []timex.Interval{hours(2, 7)}), []timex.Interval{hours(0, 2), hours(7, 10)}},
+		{"union lists", timex.UnionIntervals([]timex.Interval{hours(0, 2)}, []timex.Interval{hours(1, 3), hours(5, 6)}), []timex.Interval{hours(0, 3), hours(5, 6)}},
+		{"gaps between", timex.GapsBetween(hours(5, 6), hours(0, 2), hours(1, 3)), []timex.Interval{hours(3, 5)}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+	if v, ok := a.Intersection(hours(3, 8)); !ok || v != hours(3, 5) {
+		t.Fatalf("unexpected intersection %v", v)
+	}
+	if _, ok := a.Intersection(hours(5, 8)); ok {
+		t.Fatal("abutting intervals must not intersect")
+	}
+	if v, ok := hours(6, 8).Gap(a); !ok || v != hours(5, 6) {
+		t.Fatalf("unexpected gap %v", v)
+	}
+	if _, ok := a.Gap(hours(5, 8)); ok {
+		t.Fatal("abutting intervals must not have a gap")
+	}
+	if a.String() != "2024-01-01T01:00:00Z/2024-01-01T05:00:00Z" {
+		t.Fatalf("unexpected ISO 8601 interval %s", a.String())
+	}
+}
+
+func TestIntervalSplit(t *testing.T) {
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2024, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	days := timex.NewInterval(utc(1, 1, 18), utc(1, 3, 6)).SplitByDay()
+	expected := []timex.Interval{
+		timex.NewInterval(utc(1, 1, 18), utc(1, 2, 0)),
+		timex.NewInterval(utc(1, 2, 0), utc(1, 3, 0)),
+		timex.NewInterval(utc(1, 3, 0), utc(1, 3, 6)),
+	}
+	if !reflect.DeepEqual(days, expected) {
+		t.Fatalf("expected %v, got %v", expected, days)
+	}
+	// weeks start on Sunday by default; January 7, 2024 is a Sunday
+	weeks := timex.NewInterval(utc(1, 1, 0), utc(1, 15, 0)).SplitByWeek()
+	if len(weeks) != 3 || !weeks[1].Start.Equal(utc(1, 7, 0)) || !weeks[2].Start.Equal(utc(1, 14, 0)) {
+		t.Fatalf("unexpected weeks %v", weeks)
+	}
+	months := timex.NewInterval(utc(1, 15, 0), utc(3, 10, 0)).SplitByMonth()
+	if len(months) != 3 || !months[1].Start.Equal(utc(2, 1, 0)) || months[1].Duration() != 29*24*time.Hour {
+		t.Fatalf("unexpected months %v", months)
+	}
+	if parts := timex.NewInterval(utc(1, 1, 0), utc(1, 1, 0)).SplitByDay(); len(parts) != 0 {
+		t.Fatalf("expected no part for an empty interval, got %v", parts)
+	}
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// the day DST starts lasts 23 hours
+	dst := timex.NewInterval(time.Date(2024, 3, 9, 0, 0, 0, 0, ny), time.Date(2024, 3, 12, 0, 0, 0, 0, ny)).SplitByDay()
+	if len(dst) != 3 || dst[1].Duration() != 23*time.Hour || dst[2].Start.Hour() != 0 {
+		t.Fatalf("unexpected days across daylight saving %v", dst)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	ref := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		opts     timex.HumanizeOptions
+		expected string
+	}{
+		{ref.Add(-(2*time.Hour + 5*time.Minute)), timex.HumanizeOptions{Precision: 2, Short: true}, "2h 5m ago"},
+		{ref.Add(-(2*time.Hour + 5*time.Minute)), timex.HumanizeOptions{}, "2 hours ago"},
+		{ref.Add(72 * time.Hour), timex.HumanizeOptions{}, "in 3 days"},
+		{ref.Add(time.Minute), timex.HumanizeOptions{}, "in 1 minute"},
+		{ref.Add(500 * time.Millisecond), timex.HumanizeOptions{}, "just now"},
+		// units stop at the first zero unit after the largest one
+		{ref.Add(24*time.Hour + 30*time.Second), timex.HumanizeOptions{Precision: 3}, "in 1 day"},
+		{ref.Add(-400 * 24 * time.Hour), timex.HumanizeOptions{Precision: 2}, "1 year 1 month ago"},
+		{ref.Add(-3 * time.Hour), timex.HumanizeOptions{Locale: "vi"}, "3 giờ trước"},
+		{ref.Add(3 * time.Hour), timex.HumanizeOptions{Locale: "unknown"}, "in 3 hours"},
+	}
+	for _, tt := range tests {
+		if got := timex.HumanizeWith(tt.at, ref, tt.opts); got != tt.expected {
+			t.Errorf("%v with %+v: expected %q, got %q", tt.at.Sub(ref), tt.opts, tt.expected, got)
+		}
+	}
+	if got := timex.Humanize(ref.Add(-time.Hour), ref); got != "1 hour ago" {
+		t.Fatalf("expected %q, got %q", "1 hour ago", got)
+	}
+	if got := timex.HumanizeDuration(0, timex.HumanizeOptions{}); got != "0 seconds" {
+		t.Fatalf("expected %q, got %q", "0 seconds", got)
+	}
+	if got := hours(1, 3).Humanize(timex.HumanizeOptions{Short: true}); got != "2h" {
+		t.Fatalf("expected %q, got %q", "2h", got)
+	}
+	locale := timex.GetHumanizeLocale("en")
+	locale.Future, locale.Past = "dans %s", "il y a %s"
+	timex.AddHumanizeLocale("example", locale)
+	if got := timex.HumanizeWith(ref.Add(-time.Hour), ref, timex.HumanizeOptions{Locale: "example"}); got != "il y a 1 hour" {
+		t.Fatalf("expected the registered locale, got %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+)
+
+func TestParseRelative(t *testing.T) {
+	loc := time.UTC
+	base := time.Date(2024, 5, 15, 10, 30, 0, 0, loc) // a Wednesday
+	now := timex.With(base)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"now", base},
+		{"now-15m", base.Add(-15 * time.Minute)},
+		{"now+1d-2h", base.Add(22 * time.Hour)},
+		{"now/d", time.Date(2024, 5, 15, 0, 0, 0, 0, loc)},
+		{"now-1M/M", time.Date(2024, 4, 1, 0, 0, 0, 0, loc)},
+		{"-PT15M", base.Add(-15 * time.Minute)},
+		{"P1DT2H", base.Add(26 * time.Hour)},
+		{"3 days ago", base.AddDate(0, 0, -3)},
+		{"in 2 weeks", base.AddDate(0, 0, 14)},
+		{"yesterday at 17:00", time.Date(2024, 5, 14, 17, 0, 0, 0, loc)},
+		{"next monday 9am", time.Date(2024, 5, 20, 9, 0, 0, 0, loc)},
+		{"last friday", time.Date(2024, 5, 10, 0, 0, 0, 0, loc)},
+		{"end of quarter", time.Date(2024, 6, 30, 23, 59, 59, 999999999, loc)},
+		{"beginning of next month", time.Date(2024, 6, 1, 0, 0, 0, 0, loc)},
+		{"2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, loc)},
+	}
+	for _, tt := range tests {
+		for name, parse := range map[string]func(string) (time.Time, error){
+			"ParseRelative": now.ParseRelative,
+			"Parse":         func(s string) (time.Time, error) { return now.Parse(s) },
+		} {
+			got, err := parse(tt.expr)
+			if err != nil {
+				t.Errorf("%s(%q): %v", name, tt.expr, err)
+				continue
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("%s(%q) = %s, want %s", name, tt.expr, got, tt.want)
+			}
+		}
+	}
+	for _, expr := range []string{"", "someday", "3 parsecs ago", "P1X"} {
+		if _, err := now.ParseRelative(expr); err == nil {
+			t.Errorf("ParseRelative(%q): expected an error", expr)
+		}
+		if _, err := now.Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected an error", expr)
+		}
+	}
+	if _, err := timex.Parse("3 days ago"); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+)
+
+func loadNewYork(t *testing.T) *time.Location {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	return ny
+}
+
+func TestRRuleAll(t *testing.T) {
+	ny := loadNewYork(t)
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, ny)
+	}
+	// examples of RFC 5545 section 3.8.5.3
+	tests := []struct {
+		rule     string
+		dtstart  time.Time
+		expected []time.Time
+	}{
+		{"FREQ=DAILY;COUNT=3", at(1997, 9, 2), []time.Time{at(1997, 9, 2), at(1997, 9, 3), at(1997, 9, 4)}},
+		{"FREQ=DAILY;INTERVAL=10;COUNT=5", at(1997, 9, 2), []time.Time{at(1997, 9, 2), at(1997, 9, 12), at(1997, 9, 22), at(1997, 10, 2), at(1997, 10, 12)}},
+		{"FREQ=DAILY;UNTIL=19970904", at(1997, 9, 2), []time.Time{at(1997, 9, 2), at(1997, 9, 3), at(1997, 9, 4)}},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU,TH;COUNT=8", at(1997, 9, 2), []time.Time{
+			at(1997, 9, 2), at(1997, 9, 4), at(1997, 9, 16), at(1997, 9, 18), at(1997, 9, 30), at(1997, 10, 2), at(1997, 10, 14), at(1997, 10, 16),
+		}},
+		// WKST changes the weeks of a WEEKLY rule with an interval
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", at(1997, 8, 5), []time.Time{at(1997, 8, 5), at(1997, 8, 10), at(1997, 8, 19), at(1997, 8, 24)}},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", at(1997, 8, 5), []time.Time{at(1997, 8, 5), at(1997, 8, 17), at(1997, 8, 19), at(1997, 8, 31)}},
+		{"FREQ=MONTHLY;COUNT=4;BYDAY=1FR", at(1997, 9, 5), []time.Time{at(1997, 9, 5), at(1997, 10, 3), at(1997, 11, 7), at(1997, 12, 5)}},
+		{"FREQ=MONTHLY;COUNT=3;BYDAY=-1FR", at(2024, 1, 1), []time.Time{at(2024, 1, 26), at(2024, 2, 23), at(2024, 3, 29)}},
+		{"FREQ=MONTHLY;COUNT=3;BYMONTHDAY=-3", at(1997, 9, 28), []time.Time{at(1997, 9, 28), at(1997, 10, 29), at(1997, 11, 28)}},
+		{"FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3", at(1997, 9, 4), []time.Time{at(1997, 9, 4), at(1997, 10, 7), at(1997, 11, 6)}},
+		{"FREQ=MONTHLY;COUNT=4;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2", at(1997, 9, 29), []time.Time{at(1997, 9, 29), at(1997, 10, 30), at(1997, 11, 27), at(1997, 12, 30)}},
+		// the last weekday of the month
+		{"FREQ=MONTHLY;COUNT=3;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", at(2024, 8, 1), []time.Time{at(2024, 8, 30), at(2024, 9, 30), at(2024, 10, 31)}},
+		// months without a 31st are skipped, not clamped
+		{"FREQ=MONTHLY;COUNT=3;BYMONTHDAY=31", at(2024, 1, 31), []time.Time{at(2024, 1, 31), at(2024, 3, 31), at(2024, 5, 31)}},
+		{"FREQ=YEARLY;COUNT=3;BYMONTH=1;BYDAY=-1SU", at(2024, 1, 1), []time.Time{at(2024, 1, 28), at(2025, 1, 26), at(2026, 1, 25)}},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", at(2024, 1, 1), nil},
+	}
+	for _, tt := range tests {
+		r, err := timex.ParseRRule(tt.rule, tt.dtstart)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.rule, err)
+		}
+		got := r.All(10)
+		if len(got) != len(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.rule, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.expected[i]) {
+				t.Errorf("%s: occurrence %d: expected %v, got %v", tt.rule, i, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestRRuleAcrossDaylightSaving(t *testing.T) {
+	ny := loadNewYork(t)
+	// DST ends on October 26, 1997: occurrences keep 09:00 while the offset changes
+	r := timex.MustParseRRule("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=DAILY;UNTIL=19971224T000000Z", time.Time{})
+	all := r.All(200)
+	if len(all) != 113 {
+		t.Fatalf("expected 113 occurrences, got %d", len(all))
+	}
+	first, last := all[0], all[len(all)-1]
+	if !last.Equal(time.Date(1997, 12, 23, 9, 0, 0, 0, ny)) {
+		t.Fatalf("expected the last occurrence on December 23, got %v", last)
+	}
+	_, before := first.Zone()
+	_, after := last.Zone()
+	if first.Hour() != 9 || last.Hour() != 9 || before != -4*3600 || after != -5*3600 {
+		t.Fatalf("expected 09:00 EDT then 09:00 EST, got %v and %v", first, last)
+	}
+	// DST starts on March 10, 2024
+	daily := timex.MustParseRRule("FREQ=DAILY", time.Date(2024, 3, 9, 9, 0, 0, 0, ny))
+	got := daily.Between(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC))
+	if len(got) != 3 {
+		t.Fatalf("expected 3 occurrences, got %v", got)
+	}
+	for _, v := range got {
+		if v.Hour() != 9 || v.Location() != ny {
+			t.Errorf("expected 09:00 in %v, got %v", ny, v)
+		}
+	}
+	if got[1].Sub(got[0]) != 23*time.Hour {
+		t.Fatalf("expected a 23 hours day across the transition, got %v", got[1].Sub(got[0]))
+	}
+}
+
+func TestRRuleBetweenAndNext(t *testing.T) {
+	dtstart := time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC)
+	r := timex.MustParseRRule("FREQ=DAILY", dtstart)
+	got := r.Between(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC))
+	if len(got) != 3 || !got[0].Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected both bounds to be included, got %v", got)
+	}
+	if got := r.Next(got[0]); !got.Equal(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the occurrence strictly after, got %v", got)
+	}
+	if got := r.Next(time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)); !got.Equal(dtstart) {
+		t.Fatalf("expected DTSTART, got %v", got)
+	}
+	counted := timex.MustParseRRule("FREQ=WEEKLY;COUNT=2", dtstart)
+	if got := counted.Next(dtstart.AddDate(0, 0, 7)); !got.IsZero() {
+		t.Fatalf("expected no occurrence after COUNT, got %v", got)
+	}
+	if got := counted.Between(dtstart.AddDate(0, 0, 1), dtstart.AddDate(1, 0, 0)); len(got) != 1 {
+		t.Fatalf("expected COUNT to include the occurrences before the range, got %v", got)
+	}
+	it := counted.Iterator()
+	for i := 0; i < 2; i++ {
+		if _, ok := it.Next(); !ok {
+			t.Fatalf("occurrence %d missing", i)
+		}
+	}
+	if _, ok := it.Next(); ok {
+		t.Fatal("iterator not exhausted after COUNT")
+	}
+	if _, ok := timex.NewRRule().SetFreq("DAILY").Iterator().Next(); ok {
+		t.Fatal("expected the iterator of an invalid rule to yield nothing")
+	}
+}
+
+func TestRRuleString(t *testing.T) {
+	dtstart := time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC)
+	rules := []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;COUNT=8;BYDAY=TU,TH;WKST=SU",
+		"FREQ=DAILY;UNTIL=19971224T000000Z",
+		"FREQ=YEARLY;BYMONTH=1,2;BYMONTHDAY=-1;BYDAY=2MO,-1FR;BYSETPOS=1,-1",
+	}
+	for _, rule := range rules {
+		r, err := timex.ParseRRule("RRULE:"+rule, dtstart)
+		if err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+		if r.String() != rule {
+			t.Errorf("expected %s, got %s", rule, r.String())
+		}
+	}
+	r := timex.NewRRule().
+		SetFreq("monthly").
+		AppendByDay(timex.RRuleWeekday{Weekday: time.Tuesday, Nth: 2}).
+		SetCount(3).
+		SetDtstart(dtstart)
+	if err := timex.RRuleValidator(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "FREQ=MONTHLY;COUNT=3;BYDAY=2TU" {
+		t.Fatalf("unexpected rule %s", r.String())
+	}
+	if d, err := timex.ParseRRuleWeekday("-1fr"); err != nil || d.Weekday != time.Friday || d.Nth != -1 {
+		t.Fatalf("unexpected weekday %v: %v", d, err)
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule     string
+		dtstart  time.Time
+		expected error
+	}{
+		{"", dtstart, timex.ErrorRRuleFreq},
+		{"INTERVAL=2", dtstart, timex.ErrorRRuleFreq},
+		{"FREQ=FORTNIGHTLY", dtstart, timex.ErrorRRuleFreq},
+		{"FREQ=DAILY", time.Time{}, timex.ErrorRRuleDtstart},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20240110", dtstart, timex.ErrorRRuleCountUntil},
+	}
+	for _, tt := range tests {
+		if _, err := timex.ParseRRule(tt.rule, tt.dtstart); err != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.rule, tt.expected, err)
+		}
+	}
+	rules := []string{
+		"FREQ",
+		"FREQ=DAILY;INTERVAL=-1",
+		"FREQ=DAILY;INTERVAL=two",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;FOO=1",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=0MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=54MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYDAY=MO;BYSETPOS=0",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=WEEKLY;WKST=XX",
+		"DTSTART;TZID=Mars/Olympus:20240101T090000\nRRULE:FREQ=DAILY",
+	}
+	for _, rule := range rules {
+		if _, err := timex.ParseRRule(rule, dtstart); err == nil {
+			t.Errorf("%q: expected an error", rule)
+		}
+	}
+}
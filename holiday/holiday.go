@@ -1 +1,436 @@
 package holiday
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/configx"
+	"github.com/sivaosorg/govm/timex"
+	"github.com/sivaosorg/govm/utils"
+)
+
+func NewRule() *Rule {
+	return &Rule{}
+}
+
+func (r *Rule) SetName(value string) *Rule {
+	r.Name = utils.TrimSpaces(value)
+	return r
+}
+
+func (r *Rule) SetKind(value string) *Rule {
+	r.Kind = strings.ToLower(utils.TrimSpaces(value))
+	return r
+}
+
+func (r *Rule) SetMonth(value int) *Rule {
+	r.Month = value
+	return r
+}
+
+func (r *Rule) SetDay(value int) *Rule {
+	r.Day = value
+	return r
+}
+
+func (r *Rule) SetWeekday(value string) *Rule {
+	r.Weekday = strings.ToLower(utils.TrimSpaces(value))
+	return r
+}
+
+func (r *Rule) SetNth(value int) *Rule {
+	r.Nth = value
+	return r
+}
+
+func (r *Rule) SetOffset(value int) *Rule {
+	r.Offset = value
+	return r
+}
+
+func (r *Rule) SetDuration(value int) *Rule {
+	r.Duration = value
+	return r
+}
+
+func (r *Rule) SetStartYear(value int) *Rule {
+	r.StartYear = value
+	return r
+}
+
+func (r *Rule) SetEndYear(value int) *Rule {
+	r.EndYear = value
+	return r
+}
+
+func (r *Rule) SetExcludes(values []string) *Rule {
+	r.Excludes = values
+	return r
+}
+
+func (r *Rule) AppendExcludes(values ...string) *Rule {
+	r.Excludes = append(r.Excludes, values...)
+	return r
+}
+
+// AppendExcludeDates excludes the dates of the times.
+func (r *Rule) AppendExcludeDates(values ...time.Time) *Rule {
+	for _, v := range values {
+		r.Excludes = append(r.Excludes, v.Format(DateLayout))
+	}
+	return r
+}
+
+func (r *Rule) SetObserved(value string) *Rule {
+	r.Observed = strings.ToLower(utils.TrimSpaces(value))
+	return r
+}
+
+func (r *Rule) Json() string {
+	return utils.ToJson(r)
+}
+
+// Fixed creates a rule on the month and day of every year.
+func Fixed(name string, month time.Month, day int) *Rule {
+	return NewRule().SetName(name).SetKind(RuleFixed).SetMonth(int(month)).SetDay(day)
+}
+
+// NthWeekday creates a rule on the nth weekday of the month, the last one when nth is -1.
+func NthWeekday(name string, month time.Month, weekday time.Weekday, nth int) *Rule {
+	return NewRule().SetName(name).SetKind(RuleNthWeekday).SetMonth(int(month)).SetWeekday(weekday.String()).SetNth(nth)
+}
+
+// EasterRelative creates a rule offset by days from Western Easter Sunday.
+func EasterRelative(name string, offset int) *Rule {
+	return NewRule().SetName(name).SetKind(RuleEaster).SetOffset(offset)
+}
+
+// LunarNewYear creates a rule on the first day of the lunisolar year, spanning the days given.
+func LunarNewYear(name string, offset, duration int) *Rule {
+	return NewRule().SetName(name).SetKind(RuleLunarNewYear).SetOffset(offset).SetDuration(duration)
+}
+
+// RuleValidator fills the default duration and checks the fields required by the kind of the rule.
+func RuleValidator(r *Rule) error {
+	if utils.IsEmpty(r.Name) {
+		return fmt.Errorf("Rule name is required")
+	}
+	if !RuleKinds[r.Kind] {
+		return fmt.Errorf("Rule %s has unsupported kind '%s'", r.Name, r.Kind)
+	}
+	if r.Duration <= 0 {
+		r.SetDuration(1)
+	}
+	if r.StartYear > 0 && r.EndYear > 0 && r.EndYear < r.StartYear {
+		return fmt.Errorf("Rule %s ends before it starts", r.Name)
+	}
+	if utils.IsNotEmpty(r.Observed) && !ObservedPolicies[r.Observed] {
+		return fmt.Errorf("Rule %s has unsupported observed policy '%s'", r.Name, r.Observed)
+	}
+	for _, v := range r.Excludes {
+		if _, err := time.Parse(DateLayout, v); err != nil {
+			return fmt.Errorf("Rule %s has invalid exclude date '%s'", r.Name, v)
+		}
+	}
+	switch r.Kind {
+	case RuleFixed:
+		if r.Month < 1 || r.Month > 12 || r.Day < 1 || r.Day > 31 {
+			return fmt.Errorf("Rule %s has invalid month or day", r.Name)
+		}
+	case RuleNthWeekday:
+		if r.Month < 1 || r.Month > 12 {
+			return fmt.Errorf("Rule %s has invalid month", r.Name)
+		}
+		if _, ok := Weekdays[r.Weekday]; !ok {
+			return fmt.Errorf("Rule %s has invalid weekday '%s'", r.Name, r.Weekday)
+		}
+		if r.Nth == 0 || r.Nth < -5 || r.Nth > 5 {
+			return fmt.Errorf("Rule %s has invalid nth %d", r.Name, r.Nth)
+		}
+	}
+	return nil
+}
+
+// Dates returns the dates of the rule within the year, at midnight in the location.
+// A fixed date that does not exist in the year, e.g: February 29, has none.
+func (r *Rule) Dates(year int, loc *time.Location) []time.Time {
+	if r.StartYear > 0 && year < r.StartYear || r.EndYear > 0 && year > r.EndYear {
+		return nil
+	}
+	var anchor time.Time
+	switch r.Kind {
+	case RuleFixed:
+		anchor = time.Date(year, time.Month(r.Month), r.Day, 0, 0, 0, 0, loc)
+		if anchor.Day() != r.Day {
+			return nil
+		}
+	case RuleNthWeekday:
+		var ok bool
+		anchor, ok = NthWeekdayOf(year, time.Month(r.Month), Weekdays[r.Weekday], r.Nth, loc)
+		if !ok {
+			return nil
+		}
+	case RuleEaster:
+		anchor = Easter(year, loc)
+	case RuleOrthodoxEaster:
+		anchor = OrthodoxEaster(year, loc)
+	case RuleLunarNewYear:
+		anchor = LunarNewYearOf(year, loc)
+	default:
+		return nil
+	}
+	duration := r.Duration
+	if duration <= 0 {
+		duration = 1
+	}
+	dates := make([]time.Time, 0, duration)
+	for i := 0; i < duration; i++ {
+		date := anchor.AddDate(0, 0, r.Offset+i)
+		if r.IsExcluded(date) {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// IsExcluded checks whether the date of the time is excluded from the rule.
+func (r *Rule) IsExcluded(date time.Time) bool {
+	if len(r.Excludes) == 0 {
+		return false
+	}
+	key := date.Format(DateLayout)
+	for _, v := range r.Excludes {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+func NewCalendar() *Calendar {
+	return &Calendar{}
+}
+
+func (c *Calendar) SetName(value string) *Calendar {
+	c.Name = utils.TrimSpaces(value)
+	return c
+}
+
+func (c *Calendar) SetTimezone(value string) *Calendar {
+	c.Timezone = utils.TrimSpaces(value)
+	return c
+}
+
+func (c *Calendar) SetWeekends(values []string) *Calendar {
+	c.Weekends = values
+	return c
+}
+
+func (c *Calendar) AppendWeekends(values ...string) *Calendar {
+	c.Weekends = append(c.Weekends, values...)
+	return c
+}
+
+func (c *Calendar) SetObserved(value string) *Calendar {
+	c.Observed = strings.ToLower(utils.TrimSpaces(value))
+	return c
+}
+
+func (c *Calendar) SetRules(values []Rule) *Calendar {
+	c.Rules = values
+	return c
+}
+
+func (c *Calendar) AppendRules(values ...Rule) *Calendar {
+	c.Rules = append(c.Rules, values...)
+	return c
+}
+
+func (c *Calendar) Json() string {
+	return utils.ToJson(c)
+}
+
+// CalendarValidator fills the default timezone, weekends and observed policy, and validates the timezone and every rule.
+func CalendarValidator(c *Calendar) error {
+	if utils.IsEmpty(c.Timezone) {
+		c.SetTimezone(DefaultTimezone)
+	}
+	if _, err := timex.ApplyTimezone(time.Now(), c.Timezone); err != nil {
+		return fmt.Errorf("Calendar %s has invalid timezone: %v", c.Name, err)
+	}
+	if c.Weekends == nil {
+		c.SetWeekends(append([]string(nil), DefaultWeekends...))
+	}
+	if utils.IsEmpty(c.Observed) {
+		c.SetObserved(ObservedNone)
+	}
+	if !ObservedPolicies[c.Observed] {
+		return fmt.Errorf("Calendar %s has unsupported observed policy '%s'", c.Name, c.Observed)
+	}
+	for i, v := range c.Weekends {
+		c.Weekends[i] = strings.ToLower(utils.TrimSpaces(v))
+		if _, ok := Weekdays[c.Weekends[i]]; !ok {
+			return fmt.Errorf("Calendar %s has invalid weekend '%s'", c.Name, v)
+		}
+	}
+	for i := range c.Rules {
+		if err := RuleValidator(&c.Rules[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCalendar reads a YAML calendar file through configx.ReadConfig and validates it.
+func LoadCalendar(path string) (*Calendar, error) {
+	c, err := configx.ReadConfig[Calendar](path)
+	if err != nil {
+		return nil, err
+	}
+	if err := CalendarValidator(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// WriteCalendar writes the calendar as YAML through configx.CreateConfig.
+func WriteCalendar(path string, c *Calendar) error {
+	return configx.CreateConfig[Calendar](path, c)
+}
+
+func GetCalendarSample() *Calendar {
+	c := NewCalendar().
+		SetName("VN").
+		SetTimezone("Asia/Ho_Chi_Minh").
+		AppendWeekends(DefaultWeekends...).
+		AppendRules(*Fixed("New Year's Day", time.January, 1),
+			*LunarNewYear("Lunar New Year", -1, 5),
+			*Fixed("Reunification Day", time.April, 30),
+			*Fixed("Labour Day", time.May, 1),
+			*Fixed("National Day", time.September, 2),
+			*NewRule().SetName("Hung Kings Commemoration").SetKind(RuleFixed).SetMonth(4).SetDay(18).SetStartYear(2024).SetEndYear(2024))
+	return c
+}
+
+// NthWeekdayOf returns the nth weekday of the month, counted from the end of the month when nth is negative.
+func NthWeekdayOf(year int, month time.Month, weekday time.Weekday, nth int, loc *time.Location) (time.Time, bool) {
+	if nth > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		day := first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+(nth-1)*7)
+		return day, day.Month() == month
+	}
+	if nth < 0 {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+		day := last.AddDate(0, 0, -((int(last.Weekday())-int(weekday)+7)%7)+(nth+1)*7)
+		return day, day.Month() == month
+	}
+	return time.Time{}, false
+}
+
+// Easter returns Western Easter Sunday of the year (Gregorian computus, Meeus/Jones/Butcher).
+func Easter(year int, loc *time.Location) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+}
+
+// OrthodoxEaster returns Orthodox Easter Sunday of the year (Julian computus), as a Gregorian date.
+func OrthodoxEaster(year int, loc *time.Location) time.Time {
+	a := year % 4
+	b := year % 7
+	c := year % 19
+	d := (19*c + 15) % 30
+	e := (2*a + 4*b - d + 34) % 7
+	month := (d + e + 114) / 31
+	day := (d+e+114)%31 + 1
+	julian := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	// the gap between the Julian and Gregorian calendars grows by a day every century not divisible by 400
+	return julian.AddDate(0, 0, year/100-year/400-2)
+}
+
+// LunarNewYearOf returns the first day of the lunisolar year starting within the Gregorian year,
+// the date of the first new moon on or after January 21 in the location.
+// Chinese calendars use UTC+8 (Asia/Shanghai) and Vietnamese ones UTC+7 (Asia/Ho_Chi_Minh).
+func LunarNewYearOf(year int, loc *time.Location) time.Time {
+	from := time.Date(year, time.January, 21, 0, 0, 0, 0, loc)
+	k := math.Floor((float64(year) + 20.0/365.25 - 2000) * 12.3685)
+	for {
+		moon := NewMoon(k).In(loc)
+		day := time.Date(moon.Year(), moon.Month(), moon.Day(), 0, 0, 0, 0, loc)
+		if !day.Before(from) {
+			return day
+		}
+		k++
+	}
+}
+
+// NewMoon returns the instant of the kth new moon since January 6, 2000,
+// per Meeus, Astronomical Algorithms, chapter 49, accurate to about a minute.
+func NewMoon(k float64) time.Time {
+	const rad = math.Pi / 180
+	t := k / 1236.85
+	jde := 2451550.09766 + 29.530588861*k + 0.00015437*t*t - 0.000000150*t*t*t + 0.00000000073*t*t*t*t
+	e := 1 - 0.002516*t - 0.0000074*t*t
+	m := (2.5534 + 29.10535670*k - 0.0000014*t*t - 0.00000011*t*t*t) * rad
+	mp := (201.5643 + 385.81693528*k + 0.0107582*t*t + 0.00001238*t*t*t - 0.000000058*t*t*t*t) * rad
+	f := (160.7108 + 390.67050284*k - 0.0016118*t*t - 0.00000227*t*t*t + 0.000000011*t*t*t*t) * rad
+	omega := (124.7746 - 1.56375588*k + 0.0020672*t*t + 0.00000215*t*t*t) * rad
+	jde += -0.40720*math.Sin(mp) +
+		0.17241*e*math.Sin(m) +
+		0.01608*math.Sin(2*mp) +
+		0.01039*math.Sin(2*f) +
+		0.00739*e*math.Sin(mp-m) -
+		0.00514*e*math.Sin(mp+m) +
+		0.00208*e*e*math.Sin(2*m) -
+		0.00111*math.Sin(mp-2*f) -
+		0.00057*math.Sin(mp+2*f) +
+		0.00056*e*math.Sin(2*mp+m) -
+		0.00042*math.Sin(3*mp) +
+		0.00042*e*math.Sin(m+2*f) +
+		0.00038*e*math.Sin(m-2*f) -
+		0.00024*e*math.Sin(2*mp-m) -
+		0.00017*math.Sin(omega) -
+		0.00007*math.Sin(mp+2*m) +
+		0.00004*math.Sin(2*mp-2*f) +
+		0.00004*math.Sin(3*m) +
+		0.00003*math.Sin(mp+m-2*f) +
+		0.00003*math.Sin(2*mp+2*f) -
+		0.00003*math.Sin(mp+m+2*f) +
+		0.00003*math.Sin(mp-m+2*f) -
+		0.00002*math.Sin(mp-m-2*f) -
+		0.00002*math.Sin(3*mp+m) +
+		0.00002*math.Sin(4*mp)
+	planetary := [][3]float64{
+		{299.77, 0.107408, 0.000325}, {251.88, 0.016321, 0.000165}, {251.83, 26.651886, 0.000164},
+		{349.42, 36.412478, 0.000126}, {84.66, 18.206239, 0.000110}, {141.74, 53.303771, 0.000062},
+		{207.14, 2.453732, 0.000060}, {154.84, 7.306860, 0.000056}, {34.52, 27.261239, 0.000047},
+		{207.19, 0.121824, 0.000042}, {291.34, 1.844379, 0.000040}, {161.72, 24.198154, 0.000037},
+		{239.56, 25.513099, 0.000035}, {331.55, 3.592518, 0.000023},
+	}
+	for i, p := range planetary {
+		angle := p[0] + p[1]*k
+		if i == 0 {
+			angle -= 0.009173 * t * t
+		}
+		jde += p[2] * math.Sin(angle*rad)
+	}
+	// Julian Ephemeris Day to Unix time, Terrestrial Time taken as UTC + 69s
+	seconds := (jde-2440587.5)*86400 - 69
+	sec := math.Floor(seconds)
+	return time.Unix(int64(sec), int64((seconds-sec)*1e9)).UTC()
+}
@@ -1 +1,108 @@
 package holiday
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// RuleFixed is a holiday on the same month and day every year, e.g: New Year's Day.
+	RuleFixed = "fixed"
+	// RuleNthWeekday is a holiday on the nth weekday of a month, counted from the end when negative,
+	// e.g: the last Monday of May.
+	RuleNthWeekday = "nth_weekday"
+	// RuleEaster is a holiday relative to Western Easter Sunday, e.g: Good Friday at offset -2.
+	RuleEaster = "easter"
+	// RuleOrthodoxEaster is a holiday relative to Orthodox Easter Sunday, as a Gregorian date.
+	RuleOrthodoxEaster = "orthodox_easter"
+	// RuleLunarNewYear is a holiday relative to the first day of the lunisolar year,
+	// the first new moon on or after January 21 in the timezone of the calendar.
+	RuleLunarNewYear = "lunar_new_year"
+)
+
+var (
+	RuleKinds map[string]bool = map[string]bool{
+		RuleFixed:          true,
+		RuleNthWeekday:     true,
+		RuleEaster:         true,
+		RuleOrthodoxEaster: true,
+		RuleLunarNewYear:   true,
+	}
+)
+
+const (
+	// ObservedNone keeps holidays on their date.
+	ObservedNone = "none"
+	// ObservedNearest moves a holiday on the first weekend day to the day before, and on a later
+	// weekend day to the day after, e.g: Saturday to Friday and Sunday to Monday.
+	ObservedNearest = "nearest"
+	// ObservedFollowing moves a holiday on a weekend to the next day that is neither a weekend day nor a holiday.
+	ObservedFollowing = "following"
+	// ObservedPreceding moves a holiday on a weekend to the previous day that is neither a weekend day nor a holiday.
+	ObservedPreceding = "preceding"
+	// ObservedSundayToMonday moves only holidays on Sunday to the next day that is not a holiday.
+	ObservedSundayToMonday = "sunday_to_monday"
+)
+
+var (
+	ObservedPolicies map[string]bool = map[string]bool{
+		ObservedNone:           true,
+		ObservedNearest:        true,
+		ObservedFollowing:      true,
+		ObservedPreceding:      true,
+		ObservedSundayToMonday: true,
+	}
+)
+
+var (
+	Weekdays map[string]time.Weekday = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+)
+
+var (
+	DefaultWeekends []string = []string{"saturday", "sunday"}
+)
+
+const (
+	DefaultTimezone = "UTC"
+	DateLayout      = "2006-01-02"
+	// MaxBusinessDaysScan bounds the run of consecutive days searched for a business day, e.g: in a calendar where every day is a holiday.
+	MaxBusinessDaysScan = 3660
+)
+
+const (
+	ICSDateLayout        = "20060102"
+	ICSDateTimeLayout    = "20060102T150405"
+	ICSDateTimeUTCLayout = "20060102T150405Z"
+	ICSProductId         = "-//sivaosorg//govm holiday//EN"
+	ICSMaxLineOctets     = 75
+	// ICSPropertyObserved carries the observed policy of a calendar or an event.
+	ICSPropertyObserved = "X-HOLIDAY-OBSERVED"
+	// ICSPropertyWeekends carries the weekend days of a calendar.
+	ICSPropertyWeekends = "X-HOLIDAY-WEEKENDS"
+)
+
+var (
+	ICSWeekdays map[time.Weekday]string = map[time.Weekday]string{
+		time.Sunday:    "SU",
+		time.Monday:    "MO",
+		time.Tuesday:   "TU",
+		time.Wednesday: "WE",
+		time.Thursday:  "TH",
+		time.Friday:    "FR",
+		time.Saturday:  "SA",
+	}
+)
+
+var (
+	// ErrorICSUnsupported is wrapped by the errors of the events whose recurrence cannot be expressed as a rule,
+	// e.g: FREQ=WEEKLY, which ParseICS skips.
+	ErrorICSUnsupported = errors.New("Recurrence is not supported")
+)
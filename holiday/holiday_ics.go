@@ -0,0 +1,556 @@
+package holiday
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+	"github.com/sivaosorg/govm/utils"
+)
+
+// ReadICS reads an iCalendar (RFC 5545) file into a calendar.
+func ReadICS(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseICS(file)
+}
+
+// ParseICS parses an iCalendar (RFC 5545) document into a calendar, one rule per VEVENT.
+// All-day events become fixed dates of their year, and yearly RRULE recurrences become fixed
+// or nth-weekday rules bounded by COUNT or UNTIL; EXDATE exclusions are kept as excludes.
+// The events of other recurrences, e.g: FREQ=WEEKLY, are skipped, see ParseICSWithWarnings.
+// The calendar is named after X-WR-CALNAME and evaluated in X-WR-TIMEZONE when present.
+func ParseICS(r io.Reader) (*Calendar, error) {
+	c, _, err := ParseICSWithWarnings(r)
+	return c, err
+}
+
+// ParseICSWithWarnings parses an iCalendar document as ParseICS does, and returns the events skipped
+// as their recurrence is not supported, each as an error wrapping ErrorICSUnsupported.
+func ParseICSWithWarnings(r io.Reader) (*Calendar, []error, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := NewCalendar()
+	var events []*icsEvent
+	var event *icsEvent
+	for i, line := range lines {
+		if utils.IsEmpty(line) {
+			continue
+		}
+		p, err := parseICSProperty(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Line %d: %v", i+1, err)
+		}
+		switch p.name {
+		case "BEGIN":
+			if strings.EqualFold(p.value, "VEVENT") {
+				event = &icsEvent{}
+			}
+			continue
+		case "END":
+			if strings.EqualFold(p.value, "VEVENT") && event != nil {
+				events = append(events, event)
+				event = nil
+			}
+			continue
+		}
+		if event == nil {
+			switch p.name {
+			case "X-WR-CALNAME":
+				c.SetName(unescapeICS(p.value))
+			case "X-WR-TIMEZONE":
+				c.SetTimezone(p.value)
+			case ICSPropertyObserved:
+				c.SetObserved(p.value)
+			case ICSPropertyWeekends:
+				c.SetWeekends(strings.Split(strings.ToLower(p.value), ","))
+			}
+			continue
+		}
+		switch p.name {
+		case "SUMMARY":
+			event.summary = unescapeICS(p.value)
+		case "DTSTART":
+			v := p
+			event.start = &v
+		case "DTEND":
+			v := p
+			event.end = &v
+		case "DURATION":
+			event.duration = p.value
+		case "RRULE":
+			event.rrule = p.value
+		case "EXDATE":
+			event.exdates = append(event.exdates, p)
+		case ICSPropertyObserved:
+			event.observed = p.value
+		}
+	}
+	if utils.IsEmpty(c.Timezone) {
+		c.SetTimezone(DefaultTimezone)
+	}
+	now, err := timex.ApplyTimezone(time.Now(), c.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := now.Location()
+	var warnings []error
+	for i, e := range events {
+		r, err := e.toRule(loc)
+		if errors.Is(err, ErrorICSUnsupported) {
+			warnings = append(warnings, fmt.Errorf("Event %d (%s): %w", i+1, e.summary, err))
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Event %d (%s): %v", i+1, e.summary, err)
+		}
+		c.AppendRules(*r)
+	}
+	if err := CalendarValidator(c); err != nil {
+		return nil, nil, err
+	}
+	return c, warnings, nil
+}
+
+// WriteICSFile writes the calendar as an iCalendar file, see WriteICS.
+func WriteICSFile(path string, c Calendar, fromYear, toYear int) error {
+	data, err := ExportICS(c, fromYear, toYear)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(data), os.ModePerm)
+}
+
+// ExportICS returns the calendar as an iCalendar document, see WriteICS.
+func ExportICS(c Calendar, fromYear, toYear int) (string, error) {
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, c, fromYear, toYear); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteICS writes the calendar as an iCalendar (RFC 5545) document of all-day events.
+// Fixed and nth-weekday rules are written as yearly RRULE recurrences starting within the years given;
+// rules that RRULE cannot express, e.g: Easter-relative or lunar, are expanded over [fromYear, toYear].
+// The observed policies and weekends are kept as X-HOLIDAY-* properties so that ParseICS restores them.
+func WriteICS(w io.Writer, c Calendar, fromYear, toYear int) error {
+	if err := CalendarValidator(&c); err != nil {
+		return err
+	}
+	if toYear < fromYear {
+		return fmt.Errorf("Year range %d-%d is invalid", fromYear, toYear)
+	}
+	now, err := timex.ApplyTimezone(time.Now(), c.Timezone)
+	if err != nil {
+		return err
+	}
+	loc := now.Location()
+	stamp := time.Now().UTC().Format(ICSDateTimeUTCLayout)
+	out := &icsWriter{w: bufio.NewWriter(w)}
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:" + ICSProductId)
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	if utils.IsNotEmpty(c.Name) {
+		out.line("X-WR-CALNAME:" + escapeICS(c.Name))
+	}
+	out.line("X-WR-TIMEZONE:" + c.Timezone)
+	if c.Observed != ObservedNone {
+		out.line(ICSPropertyObserved + ":" + c.Observed)
+	}
+	out.line(ICSPropertyWeekends + ":" + strings.Join(c.Weekends, ","))
+	for _, r := range c.Rules {
+		if rrule, start, ok := r.recurrence(fromYear, toYear, loc); ok {
+			out.event(r, start, r.Duration, rrule, stamp)
+			continue
+		}
+		for year := fromYear; year <= toYear; year++ {
+			for _, span := range groupConsecutive(r.Dates(year, loc)) {
+				out.event(r, span[0], len(span), "", stamp)
+			}
+		}
+	}
+	out.line("END:VCALENDAR")
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// recurrence returns the yearly RRULE of a fixed or nth-weekday rule along with its first occurrence
+// from fromYear, or false when the rule must be expanded instead.
+func (r *Rule) recurrence(fromYear, toYear int, loc *time.Location) (string, time.Time, bool) {
+	if r.Offset != 0 || r.Kind != RuleFixed && r.Kind != RuleNthWeekday {
+		return "", time.Time{}, false
+	}
+	if r.StartYear > 0 && r.StartYear == r.EndYear {
+		return "", time.Time{}, false
+	}
+	from := fromYear
+	if r.StartYear > from {
+		from = r.StartYear
+	}
+	// the first occurrence is looked up regardless of exclusions, which become EXDATE entries
+	plain := *r
+	plain.Excludes = nil
+	var start time.Time
+	for year := from; year <= toYear && year <= from+8; year++ {
+		if dates := plain.Dates(year, loc); len(dates) > 0 {
+			start = timex.With(dates[0]).BeginningOfDay()
+			break
+		}
+	}
+	if start.IsZero() {
+		return "", time.Time{}, false
+	}
+	rrule := fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d", r.Month)
+	if r.Kind == RuleFixed {
+		rrule += fmt.Sprintf(";BYMONTHDAY=%d", r.Day)
+	} else {
+		rrule += fmt.Sprintf(";BYDAY=%d%s", r.Nth, ICSWeekdays[Weekdays[r.Weekday]])
+	}
+	if r.EndYear > 0 {
+		end := timex.With(time.Date(r.EndYear, time.January, 1, 0, 0, 0, 0, loc)).EndOfYear()
+		rrule += ";UNTIL=" + end.Format(ICSDateLayout)
+	}
+	return rrule, start, true
+}
+
+// toRule converts the event into a rule of the calendar in the location.
+func (e *icsEvent) toRule(loc *time.Location) (*Rule, error) {
+	if e.start == nil {
+		return nil, fmt.Errorf("DTSTART is required")
+	}
+	start, err := parseICSDate(*e.start, loc)
+	if err != nil {
+		return nil, err
+	}
+	duration := 1
+	if e.end != nil {
+		end, err := parseICSDate(*e.end, loc)
+		if err != nil {
+			return nil, err
+		}
+		if days := daysBetween(start, end); days > 1 {
+			duration = days
+		}
+	} else if utils.IsNotEmpty(e.duration) {
+		days, err := parseICSDuration(e.duration)
+		if err != nil {
+			return nil, err
+		}
+		if days > 1 {
+			duration = days
+		}
+	}
+	name := e.summary
+	if utils.IsEmpty(name) {
+		name = start.Format(DateLayout)
+	}
+	r := Fixed(name, start.Month(), start.Day()).
+		SetDuration(duration).
+		SetObserved(e.observed).
+		SetStartYear(start.Year())
+	for _, p := range e.exdates {
+		for _, v := range strings.Split(p.value, ",") {
+			item := p
+			item.value = v
+			date, err := parseICSDate(item, loc)
+			if err != nil {
+				return nil, err
+			}
+			// EXDATE names the start of an excluded occurrence, which spans every day of the event
+			for i := 0; i < duration; i++ {
+				r.AppendExcludeDates(date.AddDate(0, 0, i))
+			}
+		}
+	}
+	if utils.IsEmpty(e.rrule) {
+		return r.SetEndYear(start.Year()), nil
+	}
+	parts := make(map[string]string)
+	for _, v := range strings.Split(e.rrule, ";") {
+		if kv := strings.SplitN(v, "=", 2); len(kv) == 2 {
+			parts[strings.ToUpper(kv[0])] = strings.ToUpper(kv[1])
+		}
+	}
+	if parts["FREQ"] != "YEARLY" {
+		return nil, fmt.Errorf("%w, '%s' only FREQ=YEARLY is", ErrorICSUnsupported, e.rrule)
+	}
+	if v, ok := parts["INTERVAL"]; ok && v != "1" {
+		return nil, fmt.Errorf("%w, '%s' only INTERVAL=1 is", ErrorICSUnsupported, e.rrule)
+	}
+	if v, ok := parts["BYMONTH"]; ok {
+		month, err := strconv.Atoi(v)
+		if err != nil || strings.Contains(v, ",") {
+			return nil, fmt.Errorf("%w, BYMONTH '%s'", ErrorICSUnsupported, v)
+		}
+		r.SetMonth(month)
+	}
+	if v, ok := parts["BYMONTHDAY"]; ok {
+		day, err := strconv.Atoi(v)
+		if err != nil || day <= 0 {
+			return nil, fmt.Errorf("%w, BYMONTHDAY '%s'", ErrorICSUnsupported, v)
+		}
+		r.SetDay(day)
+	}
+	if v, ok := parts["BYDAY"]; ok {
+		nth, weekday, err := parseICSByDay(v)
+		if err != nil {
+			return nil, err
+		}
+		if pos, ok := parts["BYSETPOS"]; ok && nth == 0 {
+			if nth, err = strconv.Atoi(pos); err != nil {
+				return nil, fmt.Errorf("%w, BYSETPOS '%s'", ErrorICSUnsupported, pos)
+			}
+		}
+		if nth == 0 {
+			return nil, fmt.Errorf("%w, BYDAY '%s' without ordinal", ErrorICSUnsupported, v)
+		}
+		r.SetKind(RuleNthWeekday).SetDay(0).SetWeekday(weekday.String()).SetNth(nth)
+	}
+	if v, ok := parts["COUNT"]; ok {
+		count, err := strconv.Atoi(v)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("COUNT '%s' is invalid", v)
+		}
+		r.SetEndYear(start.Year() + count - 1)
+	}
+	if v, ok := parts["UNTIL"]; ok {
+		until, err := parseICSDate(icsProperty{value: v}, loc)
+		if err != nil {
+			return nil, err
+		}
+		end := until.Year()
+		if dates := r.Dates(end, loc); len(dates) > 0 && dates[0].After(until) {
+			end--
+		}
+		r.SetEndYear(end)
+	}
+	return r, nil
+}
+
+// unfoldICS reads the content lines, joining folded lines that continue with a space or a tab.
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICSProperty splits a content line into its name, parameters and value,
+// colons and semicolons within quoted parameter values included.
+func parseICSProperty(line string) (icsProperty, error) {
+	p := icsProperty{params: make(map[string]string)}
+	quoted := false
+	sep := -1
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			quoted = !quoted
+		}
+		if line[i] == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return p, fmt.Errorf("Invalid content line '%s'", line)
+	}
+	p.value = line[sep+1:]
+	head := strings.Split(line[:sep], ";")
+	p.name = strings.ToUpper(head[0])
+	for _, v := range head[1:] {
+		if kv := strings.SplitN(v, "=", 2); len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, nil
+}
+
+// parseICSDate returns midnight of the date of a DATE or DATE-TIME value in the location.
+// UTC date-times are moved to the location and TZID date-times keep their own date.
+func parseICSDate(p icsProperty, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(p.value)
+	var at time.Time
+	var err error
+	switch {
+	case len(value) == len(ICSDateLayout):
+		at, err = time.ParseInLocation(ICSDateLayout, value, loc)
+	case strings.HasSuffix(value, "Z"):
+		at, err = time.Parse(ICSDateTimeUTCLayout, value)
+		at = at.In(loc)
+	default:
+		zone := loc
+		if tzid, ok := p.params["TZID"]; ok {
+			if t, e := timex.ApplyTimezone(time.Now(), tzid); e == nil {
+				zone = t.Location()
+			}
+		}
+		at, err = time.ParseInLocation(ICSDateTimeLayout, value, zone)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date '%s'", value)
+	}
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// parseICSDuration returns the whole days of a duration such as P1D, P2W or P1DT12H.
+func parseICSDuration(value string) (int, error) {
+	v := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "+")
+	if !strings.HasPrefix(v, "P") {
+		return 0, fmt.Errorf("Invalid duration '%s'", value)
+	}
+	v = v[1:]
+	if i := strings.Index(v, "T"); i >= 0 {
+		v = v[:i]
+	}
+	days := 0
+	number := 0
+	for _, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			number = number*10 + int(c-'0')
+		case c == 'W':
+			days += number * 7
+			number = 0
+		case c == 'D':
+			days += number
+			number = 0
+		default:
+			return 0, fmt.Errorf("Invalid duration '%s'", value)
+		}
+	}
+	return days, nil
+}
+
+// parseICSByDay parses a single BYDAY entry such as -1MO or 4TH; the ordinal is zero when absent.
+func parseICSByDay(value string) (int, time.Weekday, error) {
+	if strings.Contains(value, ",") || len(value) < 2 {
+		return 0, 0, fmt.Errorf("%w, BYDAY '%s'", ErrorICSUnsupported, value)
+	}
+	code := value[len(value)-2:]
+	for weekday, v := range ICSWeekdays {
+		if v != code {
+			continue
+		}
+		ordinal := strings.TrimPrefix(value[:len(value)-2], "+")
+		if utils.IsEmpty(ordinal) {
+			return 0, weekday, nil
+		}
+		nth, err := strconv.Atoi(ordinal)
+		if err != nil {
+			return 0, 0, fmt.Errorf("BYDAY '%s' is invalid", value)
+		}
+		return nth, weekday, nil
+	}
+	return 0, 0, fmt.Errorf("BYDAY '%s' is invalid", value)
+}
+
+func escapeICS(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return r.Replace(value)
+}
+
+func unescapeICS(value string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return r.Replace(value)
+}
+
+// daysBetween counts the calendar days from the date of start to the date of end.
+func daysBetween(start, end time.Time) int {
+	a := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// groupConsecutive splits sorted dates into runs of consecutive days.
+func groupConsecutive(dates []time.Time) [][]time.Time {
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	var spans [][]time.Time
+	for _, d := range dates {
+		if n := len(spans); n > 0 && daysBetween(spans[n-1][len(spans[n-1])-1], d) == 1 {
+			spans[n-1] = append(spans[n-1], d)
+			continue
+		}
+		spans = append(spans, []time.Time{d})
+	}
+	return spans
+}
+
+func (o *icsWriter) line(value string) {
+	if o.err != nil {
+		return
+	}
+	for len(value) > ICSMaxLineOctets {
+		cut := ICSMaxLineOctets
+		// never split a UTF-8 sequence
+		for cut > 0 && value[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, o.err = o.w.WriteString(value[:cut] + "\r\n"); o.err != nil {
+			return
+		}
+		value = " " + value[cut:]
+	}
+	_, o.err = o.w.WriteString(value + "\r\n")
+}
+
+func (o *icsWriter) event(r Rule, start time.Time, days int, rrule, stamp string) {
+	if days <= 0 {
+		days = 1
+	}
+	o.line("BEGIN:VEVENT")
+	o.line(fmt.Sprintf("UID:%s-%s@govm", start.Format(ICSDateLayout), slugICS(r.Name)))
+	o.line("DTSTAMP:" + stamp)
+	o.line("DTSTART;VALUE=DATE:" + start.Format(ICSDateLayout))
+	o.line("DTEND;VALUE=DATE:" + start.AddDate(0, 0, days).Format(ICSDateLayout))
+	if utils.IsNotEmpty(rrule) {
+		o.line("RRULE:" + rrule)
+		for _, v := range r.Excludes {
+			if at, err := time.Parse(DateLayout, v); err == nil {
+				o.line("EXDATE;VALUE=DATE:" + at.Format(ICSDateLayout))
+			}
+		}
+	}
+	o.line("SUMMARY:" + escapeICS(r.Name))
+	o.line("TRANSP:TRANSPARENT")
+	if utils.IsNotEmpty(r.Observed) {
+		o.line(ICSPropertyObserved + ":" + r.Observed)
+	}
+	o.line("END:VEVENT")
+}
+
+func slugICS(value string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(value) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			sb.WriteRune(c)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-") {
+			sb.WriteByte('-')
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
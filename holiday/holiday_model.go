@@ -1 +1,78 @@
 package holiday
+
+import (
+	"bufio"
+	"sync"
+	"time"
+)
+
+// Rule describes a recurring holiday:
+// a fixed date, the nth weekday of a month, a day relative to Easter or to the lunar new year.
+// Offset moves the holiday by days from its anchor and Duration spans it over consecutive days.
+// Excludes lists dates (2006-01-02) the rule skips, and Observed overrides the substitution policy of the calendar.
+type Rule struct {
+	Name      string   `json:"name" yaml:"name"`
+	Kind      string   `json:"kind" yaml:"kind"`
+	Month     int      `json:"month,omitempty" yaml:"month"`
+	Day       int      `json:"day,omitempty" yaml:"day"`
+	Weekday   string   `json:"weekday,omitempty" yaml:"weekday"`
+	Nth       int      `json:"nth,omitempty" yaml:"nth"`
+	Offset    int      `json:"offset,omitempty" yaml:"offset"`
+	Duration  int      `json:"duration,omitempty" yaml:"duration"`
+	StartYear int      `json:"start_year,omitempty" yaml:"start_year"`
+	EndYear   int      `json:"end_year,omitempty" yaml:"end_year"`
+	Excludes  []string `json:"excludes,omitempty" yaml:"excludes"`
+	Observed  string   `json:"observed,omitempty" yaml:"observed"`
+}
+
+// Calendar holds the holiday rules and weekend days of a region, evaluated in its timezone.
+// Observed is the policy substituting a business day for holidays falling on a weekend.
+type Calendar struct {
+	Name     string   `json:"name" yaml:"name"`
+	Timezone string   `json:"timezone" yaml:"timezone"`
+	Weekends []string `json:"weekends" yaml:"weekends"`
+	Observed string   `json:"observed,omitempty" yaml:"observed"`
+	Rules    []Rule   `json:"rules" yaml:"rules"`
+}
+
+// Holiday is an occurrence of a rule on a date.
+// An observed holiday is the day off substituted for the holiday originally dated on a weekend.
+type Holiday struct {
+	Name       string    `json:"name"`
+	Date       time.Time `json:"date"`
+	IsObserved bool      `json:"observed"`
+	Original   time.Time `json:"original,omitempty"`
+	Rule       Rule      `json:"-"`
+}
+
+type calendarServiceImpl struct {
+	conf     Calendar
+	location *time.Location
+	weekends map[time.Weekday]bool
+	mutex    sync.RWMutex
+	years    map[int]map[string][]Holiday
+}
+
+// icsProperty is a content line of an iCalendar document: NAME;PARAM=VALUE:VALUE.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icsEvent gathers the properties of a VEVENT relevant to holidays.
+type icsEvent struct {
+	summary  string
+	start    *icsProperty
+	end      *icsProperty
+	duration string
+	rrule    string
+	exdates  []icsProperty
+	observed string
+}
+
+// icsWriter writes content lines folded at 75 octets and terminated by CRLF, keeping the first error.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
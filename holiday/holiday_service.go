@@ -0,0 +1,277 @@
+package holiday
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sivaosorg/govm/timex"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type CalendarService interface {
+	IsHoliday(at time.Time) bool
+	GetHoliday(at time.Time) (Holiday, bool)
+	Holidays(year int) []Holiday
+	HolidaysBetween(start, end time.Time) []Holiday
+	IsWeekend(at time.Time) bool
+	IsBusinessDay(at time.Time) bool
+	AddBusinessDays(at time.Time, days int) time.Time
+	BusinessDaysBetween(start, end time.Time) int
+	NextBusinessDay(at time.Time) time.Time
+	PreviousBusinessDay(at time.Time) time.Time
+	Location() *time.Location
+	Calendar() Calendar
+}
+
+// NewCalendarService creates the service evaluating the calendar in its timezone, resolved once
+// through timex.ApplyTimezone. Times given to the service are moved to that timezone before
+// their date is taken, and times returned keep the clock of the time given.
+func NewCalendarService(conf Calendar) (CalendarService, error) {
+	if err := CalendarValidator(&conf); err != nil {
+		return nil, err
+	}
+	now, err := timex.ApplyTimezone(time.Now(), conf.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	s := &calendarServiceImpl{
+		conf:     conf,
+		location: now.Location(),
+		weekends: make(map[time.Weekday]bool, len(conf.Weekends)),
+		years:    make(map[int]map[string][]Holiday),
+	}
+	for _, v := range conf.Weekends {
+		s.weekends[Weekdays[v]] = true
+	}
+	return s, nil
+}
+
+// IsHoliday checks whether the date of the time, in the timezone of the calendar, is a holiday.
+func (s *calendarServiceImpl) IsHoliday(at time.Time) bool {
+	_, ok := s.GetHoliday(at)
+	return ok
+}
+
+// GetHoliday returns the holiday on the date of the time, the first rule winning when several match.
+func (s *calendarServiceImpl) GetHoliday(at time.Time) (Holiday, bool) {
+	at = s.local(at)
+	holidays := s.year(at.Year())[at.Format(DateLayout)]
+	if len(holidays) == 0 {
+		return Holiday{}, false
+	}
+	return holidays[0], true
+}
+
+// Holidays returns the holidays of the year, sorted by date.
+func (s *calendarServiceImpl) Holidays(year int) []Holiday {
+	var holidays []Holiday
+	for _, v := range s.year(year) {
+		holidays = append(holidays, v...)
+	}
+	sortHolidays(holidays)
+	return holidays
+}
+
+// HolidaysBetween returns the holidays dated within [start, end], sorted by date.
+func (s *calendarServiceImpl) HolidaysBetween(start, end time.Time) []Holiday {
+	from, to := s.date(start), s.date(end)
+	var holidays []Holiday
+	for year := from.Year(); year <= to.Year(); year++ {
+		for _, v := range s.year(year) {
+			for _, h := range v {
+				if !h.Date.Before(from) && !h.Date.After(to) {
+					holidays = append(holidays, h)
+				}
+			}
+		}
+	}
+	sortHolidays(holidays)
+	return holidays
+}
+
+// IsWeekend checks whether the date of the time falls on a weekend day of the calendar.
+func (s *calendarServiceImpl) IsWeekend(at time.Time) bool {
+	return s.weekends[s.local(at).Weekday()]
+}
+
+// IsBusinessDay checks whether the date of the time is neither a weekend day nor a holiday.
+func (s *calendarServiceImpl) IsBusinessDay(at time.Time) bool {
+	return !s.IsWeekend(at) && !s.IsHoliday(at)
+}
+
+// AddBusinessDays moves the time by business days, backwards when negative.
+// The time itself is returned when days is zero, whether or not it is a business day.
+// The search stops after MaxBusinessDaysScan consecutive days without a business day.
+func (s *calendarServiceImpl) AddBusinessDays(at time.Time, days int) time.Time {
+	at = s.local(at)
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+	for run := 0; days > 0 && run < MaxBusinessDaysScan; {
+		at = at.AddDate(0, 0, step)
+		run++
+		if s.IsBusinessDay(at) {
+			days--
+			run = 0
+		}
+	}
+	return at
+}
+
+// BusinessDaysBetween counts the business days after the date of start up to and including the date of end,
+// or from the date of end up to the date of start, negated, when end is before start,
+// so that AddBusinessDays(start, n) reaches end on a business day.
+func (s *calendarServiceImpl) BusinessDaysBetween(start, end time.Time) int {
+	from, to := s.date(start), s.date(end)
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to.AddDate(0, 0, -1), from.AddDate(0, 0, -1), -1
+	}
+	count := 0
+	for day := from.AddDate(0, 0, 1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if s.IsBusinessDay(day) {
+			count++
+		}
+	}
+	return sign * count
+}
+
+// NextBusinessDay returns the first business day after the date of the time, keeping its clock.
+func (s *calendarServiceImpl) NextBusinessDay(at time.Time) time.Time {
+	return s.AddBusinessDays(at, 1)
+}
+
+// PreviousBusinessDay returns the last business day before the date of the time, keeping its clock.
+func (s *calendarServiceImpl) PreviousBusinessDay(at time.Time) time.Time {
+	return s.AddBusinessDays(at, -1)
+}
+
+// Location returns the timezone of the calendar.
+func (s *calendarServiceImpl) Location() *time.Location {
+	return s.location
+}
+
+// Calendar returns the calendar, defaults filled.
+func (s *calendarServiceImpl) Calendar() Calendar {
+	return s.conf
+}
+
+// year returns the holidays of the year indexed by date, observed substitutes included, computed once.
+// Rules spanning several days, or offset from their anchor, may date holidays in the neighbouring years,
+// and substitutes may cross the year boundary, so the rules of the adjacent years are expanded as well.
+func (s *calendarServiceImpl) year(year int) map[string][]Holiday {
+	s.mutex.RLock()
+	holidays, ok := s.years[year]
+	s.mutex.RUnlock()
+	if ok {
+		return holidays
+	}
+	var actual []Holiday
+	taken := make(map[string]bool)
+	for _, r := range s.conf.Rules {
+		for y := year - 1; y <= year+1; y++ {
+			for _, date := range r.Dates(y, s.location) {
+				actual = append(actual, Holiday{Name: r.Name, Date: date, Rule: r})
+				taken[date.Format(DateLayout)] = true
+			}
+		}
+	}
+	sort.SliceStable(actual, func(i, j int) bool {
+		return actual[i].Date.Before(actual[j].Date)
+	})
+	all := actual
+	for _, h := range actual {
+		policy := h.Rule.Observed
+		if utils.IsEmpty(policy) {
+			policy = s.conf.Observed
+		}
+		date, ok := s.observe(h.Date, policy, taken)
+		if !ok {
+			continue
+		}
+		taken[date.Format(DateLayout)] = true
+		all = append(all, Holiday{Name: h.Name, Date: date, IsObserved: true, Original: h.Date, Rule: h.Rule})
+	}
+	holidays = make(map[string][]Holiday)
+	for _, h := range all {
+		if h.Date.Year() != year {
+			continue
+		}
+		key := h.Date.Format(DateLayout)
+		holidays[key] = append(holidays[key], h)
+	}
+	s.mutex.Lock()
+	s.years[year] = holidays
+	s.mutex.Unlock()
+	return holidays
+}
+
+// observe returns the date substituted, per the policy, for a holiday dated on a weekend,
+// skipping dates already taken by other holidays.
+func (s *calendarServiceImpl) observe(date time.Time, policy string, taken map[string]bool) (time.Time, bool) {
+	weekend := func(t time.Time) bool {
+		return s.weekends[t.Weekday()]
+	}
+	free := func(t time.Time) bool {
+		return !weekend(t) && !taken[t.Format(DateLayout)]
+	}
+	step := 0
+	switch policy {
+	case ObservedNearest:
+		if !weekend(date) {
+			return date, false
+		}
+		step = 1
+		if !weekend(date.AddDate(0, 0, -1)) {
+			step = -1
+		}
+	case ObservedFollowing:
+		if !weekend(date) {
+			return date, false
+		}
+		step = 1
+	case ObservedPreceding:
+		if !weekend(date) {
+			return date, false
+		}
+		step = -1
+	case ObservedSundayToMonday:
+		if date.Weekday() != time.Sunday {
+			return date, false
+		}
+		for i := 1; i <= MaxBusinessDaysScan; i++ {
+			if next := date.AddDate(0, 0, i); !taken[next.Format(DateLayout)] {
+				return next, true
+			}
+		}
+		return date, false
+	default:
+		return date, false
+	}
+	for i := 1; i <= MaxBusinessDaysScan; i++ {
+		if next := date.AddDate(0, 0, i*step); free(next) {
+			return next, true
+		}
+	}
+	return date, false
+}
+
+// local moves the time to the timezone of the calendar.
+func (s *calendarServiceImpl) local(at time.Time) time.Time {
+	return at.In(s.location)
+}
+
+// date returns midnight of the date of the time in the timezone of the calendar.
+func (s *calendarServiceImpl) date(at time.Time) time.Time {
+	return timex.With(s.local(at)).BeginningOfDay()
+}
+
+func sortHolidays(holidays []Holiday) {
+	sort.SliceStable(holidays, func(i, j int) bool {
+		if holidays[i].Date.Equal(holidays[j].Date) {
+			return holidays[i].Name < holidays[j].Name
+		}
+		return holidays[i].Date.Before(holidays[j].Date)
+	})
+}
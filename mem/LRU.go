@@ -84,8 +84,8 @@ func NewLRUCacheExpiration(capacity int, expiration time.Duration) *LRUCache {
 
 // Get retrieves a value from the cache based on the key.
 func (c *LRUCache) Get(key string) (value interface{}, ok bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	if element, exists := c.cache[key]; exists {
 		// Check if the entry has expired
@@ -368,7 +368,7 @@ func (c *LRUCache) cleanupExpired() {
 	now := time.Now()
 	for _, element := range c.cache {
 		entry := element.Value.(*cacheEntry)
-		if entry.Expiration.After(now) {
+		if !entry.Expiration.IsZero() && now.After(entry.Expiration) {
 			// Entry has expired, evict it from the cache
 			c.evict(element)
 		}
@@ -26,6 +26,9 @@ func PrettyOptions(json []byte, option *OptionsConfig) []byte {
 	if len(buf) > 0 {
 		buf = append(buf, '\n')
 	}
+	if option.Style != nil {
+		buf = Color(buf, option.Style)
+	}
 	return buf
 }
 
@@ -407,7 +410,9 @@ type Style struct {
 	True, False, Null   [2]string
 	Escape              [2]string
 	Brackets            [2]string
-	Append              func(dst []byte, c byte) []byte
+	// Added, Removed and Changed mark the lines of diff renderings
+	Added, Removed, Changed [2]string
+	Append                  func(dst []byte, c byte) []byte
 }
 
 func hexp(p byte) byte {
@@ -432,6 +437,9 @@ func init() {
 		Null:     [2]string{"\x1B[2m", "\x1B[0m"},
 		Escape:   [2]string{"\x1B[35m", "\x1B[0m"},
 		Brackets: [2]string{"\x1B[1m", "\x1B[0m"},
+		Added:    [2]string{"\x1B[32m", "\x1B[0m"},
+		Removed:  [2]string{"\x1B[31m", "\x1B[0m"},
+		Changed:  [2]string{"\x1B[33m", "\x1B[0m"},
 		Append: func(dst []byte, c byte) []byte {
 			if c < ' ' && (c != '\r' && c != '\n' && c != '\t' && c != '\v') {
 				dst = append(dst, "\\u00"...)
@@ -1 +1,35 @@
 package pretty
+
+import "errors"
+
+// Formats of Render
+const (
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatTOML  = "toml"
+	FormatCSV   = "csv"
+	FormatTable = "table"
+)
+
+// Kinds of the values parsed for the renderers, and of the keys they write
+const (
+	renderKey = iota
+	renderNull
+	renderFalse
+	renderTrue
+	renderNumber
+	renderString
+	renderArray
+	renderObject
+)
+
+// tableSeparator separates the columns of the tables, as coltx.Map2Table does.
+const tableSeparator = "   "
+
+var (
+	ErrorInvalidJson      = errors.New("Invalid json")
+	ErrorUnknownFormat    = errors.New("Unknown render format")
+	ErrorTOMLRootObject   = errors.New("TOML document must be an object")
+	ErrorTOMLDuplicateKey = errors.New("TOML document cannot define a key twice")
+	ErrorTOMLNumberRange  = errors.New("TOML integers must fit in int64 and floats in float64")
+)
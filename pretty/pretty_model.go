@@ -13,7 +13,25 @@ type OptionsConfig struct {
 	// SortKeys will sort the keys alphabetically
 	// Default is false
 	SortKeys bool `json:"sort_keys"`
+	// Style colorizes the output of PrettyOptions and Render
+	// Default is nil, no colors
+	Style *Style `json:"-"`
 }
 
 // DefaultOptionsConfig is the default options for pretty formats.
 var DefaultOptionsConfig = &OptionsConfig{Width: 80, Prefix: "", Indent: "  ", SortKeys: false}
+
+// renderNode is a json value parsed for the renderers of the other formats, the members of objects kept in order.
+type renderNode struct {
+	Kind   int           `json:"-"`
+	Text   string        `json:"-"`
+	Keys   []string      `json:"-"`
+	Values []*renderNode `json:"-"`
+}
+
+// renderer holds the output of a renderer and its options.
+type renderer struct {
+	buf    []byte
+	option *OptionsConfig
+	indent string
+}
@@ -0,0 +1,656 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render converts the input json into the format, one of FormatJSON, FormatYAML, FormatTOML, FormatCSV and FormatTable,
+// colorized per the Style of the options when set, but for CSV.
+// It returns ErrorInvalidJson, ErrorUnknownFormat, or for TOML, ErrorTOMLRootObject, ErrorTOMLDuplicateKey and ErrorTOMLNumberRange.
+func Render(source []byte, format string, option *OptionsConfig) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		if !json.Valid(source) {
+			return nil, ErrorInvalidJson
+		}
+		return PrettyOptions(source, option), nil
+	case FormatYAML:
+		return YAML(source, option)
+	case FormatTOML:
+		return TOML(source, option)
+	case FormatCSV:
+		return CSV(source, option)
+	case FormatTable:
+		return Table(source, option)
+	}
+	return nil, fmt.Errorf("%w '%s'", ErrorUnknownFormat, format)
+}
+
+// YAML converts the input json into a YAML document in block style, e.g:
+//
+//	{"name":"Tom","tags":["a","b"]} ->
+//	name: Tom
+//	tags:
+//	  - a
+//	  - b
+//
+// The strings are double-quoted when they would be read as another value, e.g: "true", "1.5", "a: b".
+// The nested values are indented per the Indent of the options, its tabs replaced by spaces, as YAML requires, two spaces at least.
+func YAML(source []byte, option *OptionsConfig) ([]byte, error) {
+	root, err := parseRenderNode(source)
+	if err != nil {
+		return nil, err
+	}
+	r := newRenderer(option)
+	r.indent = strings.ReplaceAll(r.indent, "\t", "  ")
+	if len(r.indent) < 2 {
+		r.indent = "  "
+	}
+	if root.isBlock() {
+		r.appendYAMLBlock(root, 0, false)
+	} else {
+		r.line(0)
+		r.appendYAMLScalar(root)
+	}
+	return r.finish(), nil
+}
+
+// TOML converts the input json object into a TOML document, the nested objects as tables, e.g: [server.tls],
+// the arrays of objects as arrays of tables, e.g: [[servers]], the other arrays and the empty objects inline.
+// The tables are indented by their depth per the Indent of the options,
+// and the null values, having no TOML equivalent, are omitted.
+// It returns ErrorTOMLDuplicateKey when an object has a key twice, and ErrorTOMLNumberRange
+// when an integer does not fit in int64 or a float in float64, as TOML would reject the document.
+func TOML(source []byte, option *OptionsConfig) ([]byte, error) {
+	root, err := parseRenderNode(source)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != renderObject {
+		return nil, ErrorTOMLRootObject
+	}
+	if err := checkTOML(root, ""); err != nil {
+		return nil, err
+	}
+	r := newRenderer(option)
+	r.appendTOMLTable(root, nil, 0)
+	return r.finish(), nil
+}
+
+// CSV converts the input json into CSV records, a row per element of an array, or a single row otherwise,
+// the values flattened by their dotted paths as the header, e.g: "address.city", "tags.0".
+// The strings are written as they are, the nulls empty and the others as json,
+// the columns in order of appearance, the members of objects sorted per the SortKeys of the options.
+func CSV(source []byte, option *OptionsConfig) ([]byte, error) {
+	root, err := parseRenderNode(source)
+	if err != nil {
+		return nil, err
+	}
+	r := newRenderer(option)
+	columns, rows := r.flattenRows(root)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = ""
+			if v, ok := row[column]; ok {
+				record[i] = csvText(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Table converts the input json into a table without borders, as coltx.Map2Table lays out maps:
+// an object as the lines of its values flattened by their dotted paths, e.g: `address.city   "Paris"`,
+// an array as a header of the flattened paths followed by a line per element.
+// The values are written as json, the columns aligned and separated by three spaces, each line prefixed per the options.
+func Table(source []byte, option *OptionsConfig) ([]byte, error) {
+	root, err := parseRenderNode(source)
+	if err != nil {
+		return nil, err
+	}
+	r := newRenderer(option)
+	var texts [][]string
+	var kinds [][]int
+	if root.Kind == renderArray && len(root.Values) > 0 {
+		columns, rows := r.flattenRows(root)
+		header := make([]int, len(columns))
+		for i := range header {
+			header[i] = renderKey
+		}
+		texts, kinds = append(texts, columns), append(kinds, header)
+		for _, row := range rows {
+			text, kind := make([]string, len(columns)), make([]int, len(columns))
+			for i, column := range columns {
+				if v, ok := row[column]; ok {
+					text[i], kind[i] = jsonText(v), v.Kind
+				}
+			}
+			texts, kinds = append(texts, text), append(kinds, kind)
+		}
+	} else {
+		columns, rows := r.flattenRows(root)
+		for _, column := range columns {
+			v := rows[0][column]
+			texts = append(texts, []string{column, jsonText(v)})
+			kinds = append(kinds, []int{renderKey, v.Kind})
+		}
+	}
+	r.appendColumns(texts, kinds)
+	return r.finish(), nil
+}
+
+// parseRenderNode parses the input json, keeping the members of objects in order, and their duplicates.
+func parseRenderNode(source []byte) (*renderNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(source))
+	dec.UseNumber()
+	root, err := decodeRenderNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrorInvalidJson, err.Error())
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w, unexpected data after the value", ErrorInvalidJson)
+	}
+	return root, nil
+}
+
+func decodeRenderNode(dec *json.Decoder) (*renderNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		v := &renderNode{Kind: renderArray}
+		if t == '{' {
+			v.Kind = renderObject
+		}
+		for dec.More() {
+			if v.Kind == renderObject {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v.Keys = append(v.Keys, key.(string))
+			}
+			child, err := decodeRenderNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.Values = append(v.Values, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case string:
+		return &renderNode{Kind: renderString, Text: t}, nil
+	case json.Number:
+		return &renderNode{Kind: renderNumber, Text: string(t)}, nil
+	case bool:
+		if t {
+			return &renderNode{Kind: renderTrue, Text: "true"}, nil
+		}
+		return &renderNode{Kind: renderFalse, Text: "false"}, nil
+	}
+	return &renderNode{Kind: renderNull, Text: "null"}, nil
+}
+
+// isBlock tells whether the value is an object or an array with members.
+func (v *renderNode) isBlock() bool {
+	return (v.Kind == renderObject || v.Kind == renderArray) && len(v.Values) > 0
+}
+
+// isTable tells whether the value is written as a TOML table, an object with members.
+func (v *renderNode) isTable() bool {
+	return v.Kind == renderObject && len(v.Values) > 0
+}
+
+// isTableArray tells whether the value is written as a TOML array of tables, an array of objects only.
+func (v *renderNode) isTableArray() bool {
+	if v.Kind != renderArray || len(v.Values) == 0 {
+		return false
+	}
+	for _, e := range v.Values {
+		if e.Kind != renderObject {
+			return false
+		}
+	}
+	return true
+}
+
+func newRenderer(option *OptionsConfig) *renderer {
+	if option == nil {
+		option = DefaultOptionsConfig
+	}
+	return &renderer{option: option, indent: option.Indent}
+}
+
+// line starts a line at the level of indentation, prefixed per the options.
+func (r *renderer) line(level int) {
+	if len(r.buf) > 0 {
+		r.buf = append(r.buf, '\n')
+	}
+	r.buf = append(r.buf, r.option.Prefix...)
+	for i := 0; i < level; i++ {
+		r.buf = append(r.buf, r.indent...)
+	}
+}
+
+func (r *renderer) finish() []byte {
+	if len(r.buf) > 0 {
+		r.buf = append(r.buf, '\n')
+	}
+	return r.buf
+}
+
+// appendStyled appends the text, colorized per the style of its kind when the options have a Style.
+func (r *renderer) appendStyled(kind int, text string) {
+	style := r.option.Style
+	if style == nil {
+		r.buf = append(r.buf, text...)
+		return
+	}
+	var colors [2]string
+	switch kind {
+	case renderKey:
+		colors = style.Key
+	case renderNull:
+		colors = style.Null
+	case renderFalse:
+		colors = style.False
+	case renderTrue:
+		colors = style.True
+	case renderNumber:
+		colors = style.Number
+	case renderString:
+		colors = style.String
+	default:
+		colors = style.Brackets
+	}
+	r.buf = append(r.buf, colors[0]...)
+	r.buf = append(r.buf, text...)
+	r.buf = append(r.buf, colors[1]...)
+}
+
+// order returns the indexes of the members of the value, sorted by key per the SortKeys of the options.
+func (r *renderer) order(v *renderNode) []int {
+	indexes := make([]int, len(v.Values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if r.option.SortKeys && v.Kind == renderObject {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return v.Keys[indexes[i]] < v.Keys[indexes[j]]
+		})
+	}
+	return indexes
+}
+
+// appendYAMLBlock appends the members of the object or array, each on its own line at the level,
+// but for the first one when continued, following the dash of an array element.
+func (r *renderer) appendYAMLBlock(v *renderNode, level int, continued bool) {
+	for n, i := range r.order(v) {
+		if n > 0 || !continued {
+			r.line(level)
+		}
+		child := v.Values[i]
+		if v.Kind == renderObject {
+			key := v.Keys[i]
+			if !isYAMLPlain(key) {
+				key = string(appendQuoted(nil, key))
+			}
+			r.appendStyled(renderKey, key)
+			r.buf = append(r.buf, ':')
+			if child.isBlock() {
+				r.appendYAMLBlock(child, level+1, false)
+				continue
+			}
+		} else {
+			r.buf = append(r.buf, '-')
+			if child.isBlock() {
+				// the members of the element align with the next level
+				r.buf = append(r.buf, strings.Repeat(" ", len(r.indent)-1)...)
+				r.appendYAMLBlock(child, level+1, true)
+				continue
+			}
+		}
+		r.buf = append(r.buf, ' ')
+		r.appendYAMLScalar(child)
+	}
+}
+
+func (r *renderer) appendYAMLScalar(v *renderNode) {
+	switch v.Kind {
+	case renderString:
+		if isYAMLPlain(v.Text) {
+			r.appendStyled(renderString, v.Text)
+		} else {
+			r.appendStyled(renderString, string(appendQuoted(nil, v.Text)))
+		}
+	case renderObject:
+		r.appendStyled(renderObject, "{}")
+	case renderArray:
+		r.appendStyled(renderArray, "[]")
+	default:
+		r.appendStyled(v.Kind, v.Text)
+	}
+}
+
+// isYAMLPlain tells whether the string reads back as itself when written without quotes,
+// neither as a null, bool, number or date, e.g: "null", "yes", "0x1F", "2024-01-02", nor as an indicator, e.g: "- a", "a: b", "a #b".
+func isYAMLPlain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`.~") {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	numeric, date := true, len(s) >= 10 && s[4] == '-' && s[7] == '-'
+	for i, c := range s {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+		if (c < '0' || c > '9') && c != ':' && c != '_' && c != '.' {
+			numeric = false
+		}
+		if i < 10 && i != 4 && i != 7 && (c < '0' || c > '9') {
+			date = false
+		}
+	}
+	// the digits with colons, e.g: "1:30", are sexagesimal numbers in YAML 1.1
+	return !numeric && !date
+}
+
+// appendTOMLTable appends the members of the object at the level, the inline values first, then the tables and arrays of tables.
+func (r *renderer) appendTOMLTable(v *renderNode, path []string, level int) {
+	order := r.order(v)
+	for _, i := range order {
+		child := v.Values[i]
+		if child.Kind == renderNull || child.isTable() || child.isTableArray() {
+			continue
+		}
+		r.line(level)
+		r.appendStyled(renderKey, tomlKey(v.Keys[i]))
+		r.buf = append(r.buf, " = "...)
+		r.appendTOMLInline(child)
+	}
+	for _, i := range order {
+		child := v.Values[i]
+		sub := append(path[:len(path):len(path)], tomlKey(v.Keys[i]))
+		switch {
+		case child.isTable():
+			r.appendTOMLHeader(sub, level, "[", "]")
+			r.appendTOMLTable(child, sub, level+1)
+		case child.isTableArray():
+			for _, e := range child.Values {
+				r.appendTOMLHeader(sub, level, "[[", "]]")
+				r.appendTOMLTable(e, sub, level+1)
+			}
+		}
+	}
+}
+
+// checkTOML checks that the value can be written as TOML: keys defined once per object,
+// integers within int64 and floats within float64.
+func checkTOML(v *renderNode, path string) error {
+	switch v.Kind {
+	case renderNumber:
+		if strings.ContainsAny(v.Text, ".eE") {
+			if _, err := strconv.ParseFloat(v.Text, 64); err != nil {
+				return fmt.Errorf("%w, %s at '%s'", ErrorTOMLNumberRange, v.Text, path)
+			}
+		} else if _, err := strconv.ParseInt(v.Text, 10, 64); err != nil {
+			return fmt.Errorf("%w, %s at '%s'", ErrorTOMLNumberRange, v.Text, path)
+		}
+	case renderObject, renderArray:
+		keys := make(map[string]bool, len(v.Keys))
+		for i, child := range v.Values {
+			sub := strconv.Itoa(i)
+			if v.Kind == renderObject {
+				sub = v.Keys[i]
+			}
+			if path != "" {
+				sub = path + "." + sub
+			}
+			if v.Kind == renderObject {
+				if keys[v.Keys[i]] {
+					return fmt.Errorf("%w, '%s'", ErrorTOMLDuplicateKey, sub)
+				}
+				keys[v.Keys[i]] = true
+			}
+			if err := checkTOML(child, sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *renderer) appendTOMLHeader(path []string, level int, open, close string) {
+	if len(r.buf) > 0 {
+		r.buf = append(r.buf, '\n')
+	}
+	r.line(level)
+	r.appendStyled(renderObject, open)
+	r.appendStyled(renderKey, strings.Join(path, "."))
+	r.appendStyled(renderObject, close)
+}
+
+func (r *renderer) appendTOMLInline(v *renderNode) {
+	switch v.Kind {
+	case renderString:
+		r.appendStyled(renderString, string(appendQuoted(nil, v.Text)))
+	case renderArray:
+		r.appendStyled(renderArray, "[")
+		n := 0
+		for _, e := range v.Values {
+			if e.Kind == renderNull {
+				continue
+			}
+			if n > 0 {
+				r.buf = append(r.buf, ", "...)
+			}
+			n++
+			r.appendTOMLInline(e)
+		}
+		r.appendStyled(renderArray, "]")
+	case renderObject:
+		r.appendStyled(renderObject, "{")
+		n := 0
+		for _, i := range r.order(v) {
+			if v.Values[i].Kind == renderNull {
+				continue
+			}
+			if n > 0 {
+				r.buf = append(r.buf, ',')
+			}
+			n++
+			r.buf = append(r.buf, ' ')
+			r.appendStyled(renderKey, tomlKey(v.Keys[i]))
+			r.buf = append(r.buf, " = "...)
+			r.appendTOMLInline(v.Values[i])
+		}
+		if n > 0 {
+			r.buf = append(r.buf, ' ')
+		}
+		r.appendStyled(renderObject, "}")
+	default:
+		r.appendStyled(v.Kind, v.Text)
+	}
+}
+
+// tomlKey returns the key bare when made of letters, digits, '_' and '-' only, quoted otherwise.
+func tomlKey(key string) string {
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' && c != '-' {
+			return string(appendQuoted(nil, key))
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// flattenRows returns the values flattened by their dotted paths of the elements of an array, or of the value itself otherwise,
+// with the paths as columns, in order of appearance, the members of objects sorted per the SortKeys of the options.
+func (r *renderer) flattenRows(root *renderNode) ([]string, []map[string]*renderNode) {
+	items := []*renderNode{root}
+	if root.Kind == renderArray {
+		items = root.Values
+	}
+	var columns []string
+	seen := make(map[string]bool)
+	rows := make([]map[string]*renderNode, len(items))
+	for i, item := range items {
+		rows[i] = make(map[string]*renderNode)
+		for _, column := range r.flatten(item, "", rows[i], nil) {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns, rows
+}
+
+// flatten sets the scalars and the empty objects and arrays of the value in the row by their dotted paths, the first ones kept,
+// returning the paths appended in order.
+func (r *renderer) flatten(v *renderNode, path string, row map[string]*renderNode, paths []string) []string {
+	if v.isBlock() {
+		for _, i := range r.order(v) {
+			key := strconv.Itoa(i)
+			if v.Kind == renderObject {
+				key = v.Keys[i]
+			}
+			if path != "" {
+				key = path + "." + key
+			}
+			paths = r.flatten(v.Values[i], key, row, paths)
+		}
+		return paths
+	}
+	if path == "" {
+		path = "value"
+	}
+	if _, ok := row[path]; ok {
+		return paths
+	}
+	row[path] = v
+	return append(paths, path)
+}
+
+// appendColumns appends the lines of cells, aligned in columns separated by tableSeparator, the trailing empty cells omitted.
+func (r *renderer) appendColumns(texts [][]string, kinds [][]int) {
+	var widths []int
+	for _, text := range texts {
+		for i, cell := range text {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for n, text := range texts {
+		last := len(text) - 1
+		for last >= 0 && text[last] == "" {
+			last--
+		}
+		r.line(0)
+		for i := 0; i <= last; i++ {
+			if i > 0 {
+				r.buf = append(r.buf, tableSeparator...)
+			}
+			r.appendStyled(kinds[n][i], text[i])
+			if i < last {
+				r.buf = append(r.buf, strings.Repeat(" ", widths[i]-utf8.RuneCountInString(text[i]))...)
+			}
+		}
+	}
+}
+
+// jsonText returns the scalar, or the empty object or array, as compact json.
+func jsonText(v *renderNode) string {
+	switch v.Kind {
+	case renderString:
+		return string(appendQuoted(nil, v.Text))
+	case renderObject:
+		return "{}"
+	case renderArray:
+		return "[]"
+	}
+	return v.Text
+}
+
+func csvText(v *renderNode) string {
+	switch v.Kind {
+	case renderString:
+		return v.Text
+	case renderNull:
+		return ""
+	}
+	return jsonText(v)
+}
+
+// appendQuoted appends the string double-quoted, escaped as json, which YAML and TOML read the same,
+// the control characters and the noncharacters escaped.
+func appendQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for _, c := range s {
+		switch c {
+		case '"':
+			dst = append(dst, '\\', '"')
+		case '\\':
+			dst = append(dst, '\\', '\\')
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		case '\f':
+			dst = append(dst, '\\', 'f')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			if c < ' ' || c >= 0x7f && c <= 0x9f || c == 0xfffe || c == 0xffff {
+				dst = append(dst, fmt.Sprintf("\\u%04x", c)...)
+			} else {
+				dst = utf8.AppendRune(dst, c)
+			}
+		}
+	}
+	return append(dst, '"')
+}
@@ -3,6 +3,7 @@ package ratelimitx
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/sivaosorg/govm/utils"
 )
@@ -32,12 +33,63 @@ func (r *RateLimitConfig) SetMaxBurst(value int) *RateLimitConfig {
 	return r
 }
 
+func (r *RateLimitConfig) SetMode(value RateLimitMode) *RateLimitConfig {
+	if !Modes[value] {
+		log.Panicf("Invalid mode: %v", value)
+	}
+	r.Mode = value
+	return r
+}
+
+func (r *RateLimitConfig) SetPeriod(value time.Duration) *RateLimitConfig {
+	if value <= 0 {
+		log.Panicf("Invalid period: %v", value)
+	}
+	r.Period = value
+	return r
+}
+
+func (r *RateLimitConfig) SetIdleTimeout(value time.Duration) *RateLimitConfig {
+	if value < 0 {
+		log.Panicf("Invalid idle_timeout: %v", value)
+	}
+	r.IdleTimeout = value
+	return r
+}
+
 func (r *RateLimitConfig) Json() string {
 	return utils.ToJson(r)
 }
 
+// RateLimitConfigValidator fills missing fields of a config loaded from file with their defaults.
+// The mode defaults to token bucket, the period to one second and the max burst to the rate.
+func RateLimitConfigValidator(r *RateLimitConfig) {
+	if utils.IsEmpty(string(r.Mode)) {
+		r.Mode = ModeTokenBucket
+	}
+	if r.Period <= 0 {
+		r.Period = DefaultPeriod
+	}
+	if r.MaxBurst <= 0 {
+		r.MaxBurst = r.Rate
+	}
+	if r.IdleTimeout <= 0 {
+		r.IdleTimeout = DefaultIdleTimeout
+	}
+	if r.IsEnabled {
+		r.SetRate(r.Rate)
+	}
+	r.SetMode(r.Mode)
+}
+
 func GetRateLimitConfigSample() *RateLimitConfig {
-	r := NewRateLimitConfig().SetEnabled(false).SetRate(100).SetMaxBurst(10)
+	r := NewRateLimitConfig().
+		SetEnabled(false).
+		SetRate(100).
+		SetMaxBurst(10).
+		SetMode(ModeTokenBucket).
+		SetPeriod(DefaultPeriod).
+		SetIdleTimeout(DefaultIdleTimeout)
 	return r
 }
 
@@ -138,3 +190,13 @@ func (c *ClusterMultiTenantRateLimitConfig) FindClusterBy(key string) (MultiTena
 	}
 	return *NewMultiTenantRateLimitConfig(), fmt.Errorf("The ratelimit cluster not found")
 }
+
+// FindUsableDefault returns the first cluster marked as usable default, if any.
+func (c *ClusterMultiTenantRateLimitConfig) FindUsableDefault() (MultiTenantRateLimitConfig, bool) {
+	for _, v := range c.Clusters {
+		if v.IsUsableDefault {
+			return v, true
+		}
+	}
+	return *NewMultiTenantRateLimitConfig(), false
+}
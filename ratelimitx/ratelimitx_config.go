@@ -1 +1,22 @@
 package ratelimitx
+
+import "time"
+
+const (
+	// ModeTokenBucket refills MaxBurst tokens at Rate tokens per Period, allowing short bursts.
+	ModeTokenBucket RateLimitMode = "token_bucket"
+	// ModeSlidingWindow keeps a log of request timestamps and allows at most Rate requests in any Period.
+	ModeSlidingWindow RateLimitMode = "sliding_window"
+)
+
+const (
+	DefaultPeriod      = time.Second
+	DefaultIdleTimeout = 10 * time.Minute
+)
+
+var (
+	Modes map[RateLimitMode]bool = map[RateLimitMode]bool{
+		ModeTokenBucket:   true,
+		ModeSlidingWindow: true,
+	}
+)
@@ -0,0 +1,255 @@
+package ratelimitx
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/charge"
+	"github.com/sivaosorg/govm/common"
+	"github.com/sivaosorg/govm/entity"
+	"github.com/sivaosorg/govm/utils"
+)
+
+type RateLimitMiddlewareService interface {
+	GetLimiter(request *http.Request) RateLimiterService
+	RateLimitMiddleware(next http.Handler) http.Handler
+	Stop()
+}
+
+// NewRateLimitMiddlewareService creates a middleware that enforces a single config,
+// resolving the key of each request with keyFunc (remote address when nil).
+func NewRateLimitMiddlewareService(conf RateLimitConfig, keyFunc KeyExtractorFunc) RateLimitMiddlewareService {
+	if keyFunc == nil {
+		keyFunc = KeyByRemoteAddr()
+	}
+	s := &rateLimitMiddlewareServiceImpl{
+		conf:    conf,
+		keyFunc: keyFunc,
+		limiter: NewRateLimiterService(conf),
+		tenants: make(map[string]RateLimiterService),
+	}
+	return s
+}
+
+// NewMultiTenantRateLimitMiddlewareService creates a middleware that picks the limits of the tenant resolved by tenantFunc
+// through ClusterMultiTenantRateLimitConfig.FindClusterBy. Requests of unknown tenants use the usable default cluster,
+// or are not limited when there is none. Requests are keyed with keyFunc (remote address when nil).
+func NewMultiTenantRateLimitMiddlewareService(cluster ClusterMultiTenantRateLimitConfig, tenantFunc KeyExtractorFunc, keyFunc KeyExtractorFunc) RateLimitMiddlewareService {
+	if keyFunc == nil {
+		keyFunc = KeyByRemoteAddr()
+	}
+	s := &rateLimitMiddlewareServiceImpl{
+		cluster:    cluster,
+		keyFunc:    keyFunc,
+		tenantFunc: tenantFunc,
+		tenants:    make(map[string]RateLimiterService),
+	}
+	if c, ok := cluster.FindUsableDefault(); ok {
+		s.conf = c.Config
+		s.limiter = NewRateLimiterService(c.Config)
+	} else {
+		s.limiter = NewRateLimiterService(*NewRateLimitConfig().SetEnabled(false))
+	}
+	return s
+}
+
+// KeyByClientIP limits requests by the client ip address, without port.
+// The address is the leftmost entry of X-Forwarded-For when present, which any client can set,
+// so it is only safe behind a trusted proxy that overwrites the header, otherwise use KeyByTrustedClientIP.
+func KeyByClientIP() KeyExtractorFunc {
+	return func(request *http.Request) string {
+		ip := charge.GetClientIP(request)
+		if idx := strings.Index(ip, ","); idx >= 0 {
+			ip = ip[:idx]
+		}
+		return hostOf(strings.TrimSpace(ip))
+	}
+}
+
+// KeyByRemoteAddr limits requests by the address of the connection peer, without port, ignoring forwarding headers.
+func KeyByRemoteAddr() KeyExtractorFunc {
+	return func(request *http.Request) string {
+		return hostOf(request.RemoteAddr)
+	}
+}
+
+// KeyByTrustedClientIP limits requests by the client ip address, trusting X-Forwarded-For only as far as it was
+// written by the trusted proxies, given as ip addresses or CIDR ranges, e.g: "10.0.0.0/8", "192.168.1.10".
+// The key is the connection peer when it is not trusted, otherwise the rightmost entry of X-Forwarded-For
+// that is not a trusted proxy. Invalid proxies are ignored.
+func KeyByTrustedClientIP(trustedProxies ...string) KeyExtractorFunc {
+	trusted := parseTrustedProxies(trustedProxies)
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		for _, n := range trusted {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(request *http.Request) string {
+		ip := hostOf(request.RemoteAddr)
+		if !isTrusted(ip) {
+			return ip
+		}
+		hops := strings.Split(strings.Join(request.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := hostOf(strings.TrimSpace(hops[i]))
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		return ip
+	}
+}
+
+// KeyByBearerToken limits requests by the bearer token of the Authorization header.
+func KeyByBearerToken() KeyExtractorFunc {
+	return func(request *http.Request) string {
+		return charge.GetRequestAuthBearerToken(request)
+	}
+}
+
+// KeyBySessionId limits requests by the session id cookie.
+func KeyBySessionId() KeyExtractorFunc {
+	return func(request *http.Request) string {
+		return charge.GetSessionID(request)
+	}
+}
+
+// KeyByHeader limits requests by the value of a custom header.
+func KeyByHeader(name string) KeyExtractorFunc {
+	return func(request *http.Request) string {
+		return charge.GetHeader(request, name)
+	}
+}
+
+// KeyByFirst tries the extractors in order and returns the first non-empty key.
+func KeyByFirst(extractors ...KeyExtractorFunc) KeyExtractorFunc {
+	return func(request *http.Request) string {
+		for _, fn := range extractors {
+			if key := fn(request); utils.IsNotEmpty(key) {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// GetLimiter returns the limiter applied to the request, based on its tenant when multi-tenancy is enabled.
+func (s *rateLimitMiddlewareServiceImpl) GetLimiter(request *http.Request) RateLimiterService {
+	if s.tenantFunc == nil {
+		return s.limiter
+	}
+	tenant := s.tenantFunc(request)
+	if utils.IsEmpty(tenant) {
+		return s.limiter
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if l, ok := s.tenants[tenant]; ok {
+		return l
+	}
+	c, err := s.cluster.FindClusterBy(tenant)
+	if err != nil {
+		return s.limiter
+	}
+	l := NewRateLimiterService(c.Config)
+	s.tenants[tenant] = l
+	return l
+}
+
+// RateLimitMiddleware rejects requests exceeding the limit with 429 Too Many Requests,
+// setting the Retry-After and X-RateLimit-* headers. Requests with an empty key are keyed by remote address.
+func (s *rateLimitMiddlewareServiceImpl) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := s.keyFunc(r)
+		if utils.IsEmpty(key) {
+			key = KeyByRemoteAddr()(r)
+		}
+		limiter := s.GetLimiter(r)
+		reservation := limiter.Reserve(key)
+		now := time.Now()
+		delay := reservation.DelayFrom(now)
+		if reservation.OK() && delay == 0 {
+			s.applyHeaders(w, reservation)
+			next.ServeHTTP(w, r)
+			return
+		}
+		s.applyHeaders(w, reservation)
+		reservation.Cancel()
+		retryAfter := int(math.Ceil(delay.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set(common.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		w.Header().Set(common.HeaderContentType, common.MediaTypeApplicationJSON)
+		w.WriteHeader(http.StatusTooManyRequests)
+		e := entity.NewResponseEntity().TooManyRequest(fmt.Sprintf("Too many requests, retry after %d second(s)", retryAfter), nil)
+		w.Write([]byte(e.Json()))
+	})
+}
+
+// Stop stops the background eviction of every limiter created by the middleware.
+func (s *rateLimitMiddlewareServiceImpl) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.limiter.Stop()
+	for _, l := range s.tenants {
+		l.Stop()
+	}
+}
+
+func (s *rateLimitMiddlewareServiceImpl) applyHeaders(w http.ResponseWriter, r *Reservation) {
+	if r.Limit() == math.MaxInt32 {
+		return
+	}
+	w.Header().Set(common.HeaderXRateLimitLimit, strconv.Itoa(r.Limit()))
+	w.Header().Set(common.HeaderXRateLimitRemaining, strconv.Itoa(r.Remaining()))
+	w.Header().Set(common.HeaderXRateLimitReset, strconv.FormatInt(r.ResetAt().Unix(), 10))
+}
+
+// hostOf returns the host of an address, without port.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// parseTrustedProxies parses the ip addresses and CIDR ranges of trusted proxies, skipping the invalid ones.
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				continue
+			}
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
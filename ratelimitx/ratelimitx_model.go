@@ -1,9 +1,20 @@
 package ratelimitx
 
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type RateLimitMode string
+
 type RateLimitConfig struct {
-	IsEnabled bool `json:"enabled" yaml:"enabled"`
-	Rate      int  `json:"rate" yaml:"rate"`
-	MaxBurst  int  `json:"max_burst" yaml:"max_burst"`
+	IsEnabled   bool          `json:"enabled" yaml:"enabled"`
+	Rate        int           `json:"rate" yaml:"rate"`
+	MaxBurst    int           `json:"max_burst" yaml:"max_burst"`
+	Mode        RateLimitMode `json:"mode,omitempty" yaml:"mode"`
+	Period      time.Duration `json:"period,omitempty" yaml:"period"`
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty" yaml:"idle_timeout"`
 }
 
 type rateLimitOptionConfig struct {
@@ -20,3 +31,53 @@ type MultiTenantRateLimitConfig struct {
 type ClusterMultiTenantRateLimitConfig struct {
 	Clusters []MultiTenantRateLimitConfig `json:"clusters,omitempty" yaml:"clusters"`
 }
+
+// Reservation holds the outcome of a RateLimiterService.Reserve call.
+// A reservation that is OK may still require the caller to wait for Delay() before acting.
+type Reservation struct {
+	ok        bool
+	key       string
+	tokens    int
+	limit     int
+	remaining int
+	timeToAct time.Time
+	resetAt   time.Time
+	cancelled bool
+	limiter   *rateLimiterServiceImpl
+}
+
+// tokenBucket represents the state of a single key in token bucket mode.
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	lastSeen time.Time
+}
+
+// slidingWindow represents the state of a single key in sliding window log mode.
+// The log is kept sorted and may contain timestamps in the future for pending reservations.
+type slidingWindow struct {
+	log      []time.Time
+	lastSeen time.Time
+}
+
+type rateLimiterServiceImpl struct {
+	conf        RateLimitConfig
+	mutex       sync.Mutex
+	buckets     map[string]*tokenBucket
+	windows     map[string]*slidingWindow
+	stopCleanup chan struct{}
+	stopOnce    sync.Once
+}
+
+// KeyExtractorFunc resolves the key a request is limited by, e.g: client ip, bearer token or tenant.
+type KeyExtractorFunc func(request *http.Request) string
+
+type rateLimitMiddlewareServiceImpl struct {
+	conf       RateLimitConfig
+	cluster    ClusterMultiTenantRateLimitConfig
+	keyFunc    KeyExtractorFunc
+	tenantFunc KeyExtractorFunc
+	limiter    RateLimiterService
+	tenants    map[string]RateLimiterService
+	mutex      sync.Mutex
+}
@@ -0,0 +1,360 @@
+package ratelimitx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type RateLimiterService interface {
+	Allow(key string) bool
+	AllowN(key string, n int) bool
+	Wait(ctx context.Context, key string) error
+	WaitN(ctx context.Context, key string, n int) error
+	Reserve(key string) *Reservation
+	ReserveN(key string, n int) *Reservation
+	Remaining(key string) int
+	Reset(key string)
+	Len() int
+	Stop()
+}
+
+// NewRateLimiterService creates a limiter engine that enforces the given config per key.
+// When the config has an idle timeout, a background goroutine evicts keys that have not been seen for that long;
+// call Stop to release it.
+func NewRateLimiterService(conf RateLimitConfig) RateLimiterService {
+	RateLimitConfigValidator(&conf)
+	s := &rateLimiterServiceImpl{
+		conf:        conf,
+		buckets:     make(map[string]*tokenBucket),
+		windows:     make(map[string]*slidingWindow),
+		stopCleanup: make(chan struct{}),
+	}
+	if conf.IsEnabled {
+		go s.startCleanup()
+	}
+	return s
+}
+
+// Allow reports whether a single event for the key may happen now.
+func (s *rateLimiterServiceImpl) Allow(key string) bool {
+	return s.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for the key may happen now, never when n is not positive.
+// Events are only consumed when they are allowed.
+func (s *rateLimiterServiceImpl) AllowN(key string, n int) bool {
+	r := s.ReserveN(key, n)
+	if !r.OK() {
+		return false
+	}
+	if r.Delay() > 0 {
+		r.Cancel()
+		return false
+	}
+	return true
+}
+
+// Wait blocks until a single event for the key is allowed or the context is done.
+func (s *rateLimiterServiceImpl) Wait(ctx context.Context, key string) error {
+	return s.WaitN(ctx, key, 1)
+}
+
+// WaitN blocks until n events for the key are allowed or the context is done.
+// It fails fast when the required delay exceeds the context deadline.
+func (s *rateLimiterServiceImpl) WaitN(ctx context.Context, key string, n int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if n <= 0 {
+		return fmt.Errorf("Rate limit: %d event(s) of key %s must be positive", n, key)
+	}
+	r := s.ReserveN(key, n)
+	if !r.OK() {
+		return fmt.Errorf("Rate limit: %d event(s) exceed limit %d of key %s", n, r.limit, key)
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.timeToAct) {
+		r.Cancel()
+		return fmt.Errorf("Rate limit: wait %v of key %s would exceed context deadline", delay, key)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Reserve reserves a single event for the key.
+func (s *rateLimiterServiceImpl) Reserve(key string) *Reservation {
+	return s.ReserveN(key, 1)
+}
+
+// ReserveN reserves n events for the key and reports how long the caller must wait before acting.
+// The reservation is not OK when n can never be satisfied, e.g: n is not positive or greater than the burst size.
+func (s *rateLimiterServiceImpl) ReserveN(key string, n int) *Reservation {
+	now := time.Now()
+	if n <= 0 {
+		return &Reservation{key: key, tokens: n, timeToAct: now, resetAt: now}
+	}
+	if !s.conf.IsEnabled {
+		return &Reservation{ok: true, key: key, tokens: n, timeToAct: now, resetAt: now, remaining: math.MaxInt32, limit: math.MaxInt32}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conf.Mode == ModeSlidingWindow {
+		return s.reserveWindow(key, n, now)
+	}
+	return s.reserveBucket(key, n, now)
+}
+
+// Remaining returns the number of events the key may still perform without waiting.
+func (s *rateLimiterServiceImpl) Remaining(key string) int {
+	if !s.conf.IsEnabled {
+		return math.MaxInt32
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conf.Mode == ModeSlidingWindow {
+		w, ok := s.windows[key]
+		if !ok {
+			return s.conf.Rate
+		}
+		w.prune(now, s.conf.Period)
+		return maxInt(s.conf.Rate-len(w.log), 0)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		return s.conf.MaxBurst
+	}
+	return maxInt(int(math.Floor(b.advance(now, s.ratePerNanosecond(), float64(s.conf.MaxBurst)))), 0)
+}
+
+// Reset forgets the state of the key, restoring its full capacity.
+func (s *rateLimiterServiceImpl) Reset(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.buckets, key)
+	delete(s.windows, key)
+}
+
+// Len returns the number of keys currently tracked.
+func (s *rateLimiterServiceImpl) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.buckets) + len(s.windows)
+}
+
+// Stop stops the background goroutine that evicts idle keys.
+func (s *rateLimiterServiceImpl) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCleanup)
+	})
+}
+
+// OK reports whether the limiter can provide the requested events.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the caller must wait before acting on the reservation.
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+// DelayFrom returns how long the caller must wait from the given time before acting on the reservation.
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	delay := r.timeToAct.Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Limit returns the maximum number of events allowed within a period.
+func (r *Reservation) Limit() int {
+	return r.limit
+}
+
+// Remaining returns the number of events left right after the reservation was made.
+func (r *Reservation) Remaining() int {
+	return r.remaining
+}
+
+// ResetAt returns the time when the key is expected to regain its full capacity.
+func (r *Reservation) ResetAt() time.Time {
+	return r.resetAt
+}
+
+// Cancel gives the reserved events back to the limiter, as far as possible.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.cancelled || r.limiter == nil {
+		return
+	}
+	r.cancelled = true
+	r.limiter.cancel(r)
+}
+
+// reserveBucket reserves n tokens of the key in token bucket mode.
+// Tokens may go negative, which turns into a delay for the caller.
+func (s *rateLimiterServiceImpl) reserveBucket(key string, n int, now time.Time) *Reservation {
+	burst := float64(s.conf.MaxBurst)
+	rate := s.ratePerNanosecond()
+	r := &Reservation{key: key, tokens: n, limit: s.conf.MaxBurst, limiter: s}
+	if n > s.conf.MaxBurst {
+		r.timeToAct = now
+		r.resetAt = now
+		return r
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	tokens := b.advance(now, rate, burst) - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		wait = time.Duration(math.Ceil(-tokens / rate))
+	}
+	b.tokens = tokens
+	b.last = now
+	b.lastSeen = now.Add(wait)
+	r.ok = true
+	r.timeToAct = now.Add(wait)
+	r.remaining = maxInt(int(math.Floor(tokens)), 0)
+	r.resetAt = now.Add(time.Duration(math.Ceil((burst - tokens) / rate)))
+	return r
+}
+
+// reserveWindow reserves n slots of the key in sliding window log mode.
+// When the window is full, the slots are booked at the time the oldest entries leave the window.
+func (s *rateLimiterServiceImpl) reserveWindow(key string, n int, now time.Time) *Reservation {
+	r := &Reservation{key: key, tokens: n, limit: s.conf.Rate, limiter: s}
+	if n > s.conf.Rate {
+		r.timeToAct = now
+		r.resetAt = now
+		return r
+	}
+	w, ok := s.windows[key]
+	if !ok {
+		w = &slidingWindow{}
+		s.windows[key] = w
+	}
+	w.prune(now, s.conf.Period)
+	at := now
+	if overflow := len(w.log) + n - s.conf.Rate; overflow > 0 {
+		at = w.log[overflow-1].Add(s.conf.Period)
+	}
+	for i := 0; i < n; i++ {
+		w.log = append(w.log, at)
+	}
+	w.lastSeen = at
+	r.ok = true
+	r.timeToAct = at
+	r.remaining = maxInt(s.conf.Rate-len(w.log), 0)
+	r.resetAt = w.log[len(w.log)-1].Add(s.conf.Period)
+	return r
+}
+
+// cancel restores the events of a reservation into the state of its key.
+func (s *rateLimiterServiceImpl) cancel(r *Reservation) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conf.Mode == ModeSlidingWindow {
+		w, ok := s.windows[r.key]
+		if !ok {
+			return
+		}
+		removed := 0
+		for i := len(w.log) - 1; i >= 0 && removed < r.tokens; i-- {
+			if w.log[i].Equal(r.timeToAct) {
+				w.log = append(w.log[:i], w.log[i+1:]...)
+				removed++
+			}
+		}
+		return
+	}
+	b, ok := s.buckets[r.key]
+	if !ok {
+		return
+	}
+	b.tokens = math.Min(b.tokens+float64(r.tokens), float64(s.conf.MaxBurst))
+}
+
+// ratePerNanosecond returns the refill speed of a token bucket.
+func (s *rateLimiterServiceImpl) ratePerNanosecond() float64 {
+	return float64(s.conf.Rate) / float64(s.conf.Period)
+}
+
+// evictIdle removes keys that have not been seen within the idle timeout.
+func (s *rateLimiterServiceImpl) evictIdle() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	threshold := time.Now().Add(-s.conf.IdleTimeout)
+	for k, b := range s.buckets {
+		if b.lastSeen.Before(threshold) {
+			delete(s.buckets, k)
+		}
+	}
+	for k, w := range s.windows {
+		if w.lastSeen.Before(threshold) {
+			delete(s.windows, k)
+		}
+	}
+}
+
+// startCleanup starts a background goroutine for periodic eviction of idle keys.
+func (s *rateLimiterServiceImpl) startCleanup() {
+	ticker := time.NewTicker(s.conf.IdleTimeout / 2) // Run cleanup at half the idle interval
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.evictIdle()
+		case <-s.stopCleanup:
+			return
+		}
+	}
+}
+
+// advance returns the tokens of the bucket refilled up to now, capped at burst.
+func (b *tokenBucket) advance(now time.Time, rate, burst float64) float64 {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(b.tokens+float64(elapsed)*rate, burst)
+}
+
+// prune drops the timestamps that left the window.
+func (w *slidingWindow) prune(now time.Time, period time.Duration) {
+	threshold := now.Add(-period)
+	i := 0
+	for i < len(w.log) && !w.log[i].After(threshold) {
+		i++
+	}
+	if i > 0 {
+		w.log = append(w.log[:0], w.log[i:]...)
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -25,16 +25,25 @@ func (s *session) SetCreatedAt(value time.Time) *session {
 }
 
 func (s *session) SetExpiresAt(value time.Time) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.ExpiresAt = value
 	return s
 }
 
 func (s *session) SetData(value map[string]interface{}) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.Data = value
 	return s
 }
 
 func (s *session) AppendData(key string, value interface{}) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Data == nil {
+		s.Data = make(map[string]interface{})
+	}
 	s.Data[key] = value
 	return s
 }
@@ -47,6 +56,21 @@ func (s *session) Available() bool {
 	return s != nil
 }
 
+// IsExpired checks whether the session has passed its expiration time.
+// A session without expiration never expires.
+func (s *session) IsExpired() bool {
+	expiresAt := s.expiration()
+	return !expiresAt.IsZero() && expiresAt.Before(time.Now())
+}
+
+// expiration returns the expiration time, read under the lock of the session
+// since the stores renew it on sessions shared by concurrent requests.
+func (s *session) expiration() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.ExpiresAt
+}
+
 func GetSessionSample() *session {
 	s := NewSession().
 		SetCreatedAt(time.Now()).
@@ -58,7 +82,7 @@ func GetSessionSample() *session {
 
 func NewSessionManager() *SessionManager {
 	return &SessionManager{
-		SessionStore: make(map[string]*session),
+		SessionStore: NewMemorySessionStore(),
 	}
 }
 
@@ -67,6 +91,35 @@ func (s *SessionManager) SetSecretKey(value string) *SessionManager {
 	return s
 }
 
+func (s *SessionManager) SetPreviousSecretKeys(values []string) *SessionManager {
+	s.PreviousSecretKeys = values
+	return s
+}
+
+func (s *SessionManager) AppendPreviousSecretKeys(values ...string) *SessionManager {
+	s.PreviousSecretKeys = append(s.PreviousSecretKeys, values...)
+	return s
+}
+
+// RotateSecretKey makes value the signing key, keeping the current key to verify cookies issued before the rotation.
+func (s *SessionManager) RotateSecretKey(value string) *SessionManager {
+	if utils.IsNotEmpty(s.SecretKey) {
+		s.PreviousSecretKeys = append([]string{s.SecretKey}, s.PreviousSecretKeys...)
+	}
+	s.SecretKey = value
+	return s
+}
+
+func (s *SessionManager) SetClientSide(value bool) *SessionManager {
+	s.IsClientSide = value
+	return s
+}
+
+func (s *SessionManager) SetSliding(value bool) *SessionManager {
+	s.IsSliding = value
+	return s
+}
+
 func (s *SessionManager) SetExpiration(value time.Duration) *SessionManager {
 	s.Expiration = value
 	return s
@@ -77,13 +130,16 @@ func (s *SessionManager) SetCookie(value cookies.CookieConfig) *SessionManager {
 	return s
 }
 
-func (s *SessionManager) SetStore(value map[string]*session) *SessionManager {
+func (s *SessionManager) SetStore(value SessionStore) *SessionManager {
 	s.SessionStore = value
 	return s
 }
 
 func (s *SessionManager) AppendStore(key string, value *session) *SessionManager {
-	s.SessionStore[key] = value
+	if s.SessionStore == nil {
+		s.SessionStore = NewMemorySessionStore()
+	}
+	s.SessionStore.Set(value.SetId(key))
 	return s
 }
 
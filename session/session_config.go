@@ -0,0 +1,28 @@
+package session
+
+import "errors"
+
+var (
+	// ErrorSecretKeyRequired is returned when a cookie is signed or encrypted without a secret key.
+	ErrorSecretKeyRequired = errors.New("Secret key is required to sign or encrypt session cookies")
+)
+
+const (
+	FileSessionExtension = ".session.json"
+)
+
+const (
+	// SessionIdLength is the number of random bytes of a session id, before encoding.
+	SessionIdLength = 32
+	// MaxCookieSize is the maximum size of a cookie value accepted by most browsers.
+	MaxCookieSize = 4096
+	// SignatureSeparator separates the session id from its signature within the cookie value.
+	SignatureSeparator = "."
+)
+
+const (
+	// FlashKey is the data key under which flash messages are kept until they are read.
+	FlashKey = "_flash"
+	// ContextKeySession is the context key of the session injected by the session middlewares.
+	ContextKeySession contextKey = "govm_session"
+)
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sivaosorg/govm/utils"
+)
+
+// GenSessionIdSecure generates a session id from crypto/rand, encoded as URL safe base64.
+func GenSessionIdSecure() (string, error) {
+	b := make([]byte, SessionIdLength)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SecretKeys returns the current secret key followed by the previous ones, skipping empty keys.
+func (s *SessionManager) SecretKeys() []string {
+	keys := make([]string, 0, len(s.PreviousSecretKeys)+1)
+	if utils.IsNotEmpty(s.SecretKey) {
+		keys = append(keys, s.SecretKey)
+	}
+	for _, v := range s.PreviousSecretKeys {
+		if utils.IsNotEmpty(v) {
+			keys = append(keys, v)
+		}
+	}
+	return keys
+}
+
+// SignValue appends the HMAC-SHA256 signature of the value using the current secret key.
+// It returns ErrorSecretKeyRequired when no secret key is configured, values are never issued unsigned.
+func (s *SessionManager) SignValue(value string) (string, error) {
+	if utils.IsEmpty(s.SecretKey) {
+		return "", ErrorSecretKeyRequired
+	}
+	return value + SignatureSeparator + utils.SighHmac256([]byte(value), []byte(s.SecretKey)), nil
+}
+
+// VerifyValue checks the signature of a value produced by SignValue against the current and previous secret keys,
+// and returns the original value. No value is accepted when no secret key is configured.
+func (s *SessionManager) VerifyValue(signed string) (string, bool) {
+	keys := s.SecretKeys()
+	if len(keys) == 0 {
+		return "", false
+	}
+	idx := strings.LastIndex(signed, SignatureSeparator)
+	if idx <= 0 {
+		return "", false
+	}
+	value, signature := signed[:idx], signed[idx+1:]
+	for _, key := range keys {
+		if ok, err := utils.VerifySignHmac256([]byte(value), []byte(key), signature); err == nil && ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Encrypt seals the session with AES-GCM under the current secret key,
+// producing a cookie-safe value that carries the whole session.
+func (s *SessionManager) Encrypt(value *session) (string, error) {
+	if utils.IsEmpty(s.SecretKey) {
+		return "", ErrorSecretKeyRequired
+	}
+	gcm, err := newGCM(s.SecretKey)
+	if err != nil {
+		return "", err
+	}
+	data, err := utils.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, data, nil)
+	encoded := base64.RawURLEncoding.EncodeToString(sealed)
+	if len(encoded) > MaxCookieSize {
+		return "", fmt.Errorf("Session too large for a cookie: %d bytes", len(encoded))
+	}
+	return encoded, nil
+}
+
+// Decrypt opens a value produced by Encrypt, trying the current and previous secret keys.
+func (s *SessionManager) Decrypt(value string) (*session, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range s.SecretKeys() {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return nil, fmt.Errorf("Invalid session cookie")
+		}
+		nonce, cipherText := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		data, err := gcm.Open(nil, nonce, cipherText, nil)
+		if err != nil {
+			continue
+		}
+		session := NewSession()
+		if err := utils.Unmarshal(data, session); err != nil {
+			return nil, err
+		}
+		if session.Data == nil {
+			session.Data = make(map[string]interface{})
+		}
+		return session, nil
+	}
+	return nil, fmt.Errorf("Invalid session cookie")
+}
+
+// newGCM derives an AES-256 key from the secret and returns its GCM mode.
+func newGCM(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
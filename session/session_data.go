@@ -0,0 +1,224 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Get returns the value of the key from the session data.
+func (s *session) Get(key string) (interface{}, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	v, ok := s.Data[key]
+	return v, ok
+}
+
+// Has checks whether the session data contains the key.
+func (s *session) Has(key string) bool {
+	_, ok := s.Get(key)
+	return ok
+}
+
+// Set stores the value of the key into the session data.
+func (s *session) Set(key string, value interface{}) *session {
+	return s.AppendData(key, value)
+}
+
+// Remove deletes the key from the session data.
+func (s *session) Remove(key string) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.Data, key)
+	return s
+}
+
+// GetString returns the value of the key as a string.
+func (s *session) GetString(key string) (string, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return "", false
+	}
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case []byte:
+		return string(t), true
+	case json.Number:
+		return t.String(), true
+	}
+	return "", false
+}
+
+// GetInt returns the value of the key as an int.
+// Numbers decoded from JSON (float64) and numeric strings are converted.
+func (s *session) GetInt(key string) (int, bool) {
+	v, ok := s.GetInt64(key)
+	return int(v), ok
+}
+
+// GetInt64 returns the value of the key as an int64.
+func (s *session) GetInt64(key string) (int64, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch t := v.(type) {
+	case int:
+		return int64(t), true
+	case int32:
+		return int64(t), true
+	case int64:
+		return t, true
+	case uint:
+		return int64(t), true
+	case uint32:
+		return int64(t), true
+	case uint64:
+		return int64(t), true
+	case float32:
+		return int64(t), true
+	case float64:
+		return int64(t), true
+	case json.Number:
+		n, err := t.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(t, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// GetFloat64 returns the value of the key as a float64.
+func (s *session) GetFloat64(key string) (float64, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		n, err := t.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(t, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// GetBool returns the value of the key as a bool.
+func (s *session) GetBool(key string) (bool, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return false, false
+	}
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case string:
+		b, err := strconv.ParseBool(t)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// GetTime returns the value of the key as a time.Time.
+// Times decoded from JSON are parsed as RFC3339.
+func (s *session) GetTime(key string) (time.Time, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		at, err := time.Parse(time.RFC3339Nano, t)
+		return at, err == nil
+	}
+	return time.Time{}, false
+}
+
+// AddFlash appends a flash message of the key, kept until it is read by Flashes.
+func (s *session) AddFlash(key string, value interface{}) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Data == nil {
+		s.Data = make(map[string]interface{})
+	}
+	flashes := s.flashes()
+	flashes[key] = append(flashes[key], value)
+	s.Data[FlashKey] = flashes
+	return s
+}
+
+// Flashes returns the flash messages of the key and removes them from the session.
+// The session must be saved afterwards for the removal to persist.
+func (s *session) Flashes(key string) []interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	flashes := s.flashes()
+	values, ok := flashes[key]
+	if !ok {
+		return nil
+	}
+	delete(flashes, key)
+	if len(flashes) == 0 {
+		delete(s.Data, FlashKey)
+	} else {
+		s.Data[FlashKey] = flashes
+	}
+	return values
+}
+
+// HasFlashes checks whether there are unread flash messages of the key.
+func (s *session) HasFlashes(key string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.flashes()[key]) > 0
+}
+
+// flashes returns the flash messages of the session, whether they were set in memory
+// or decoded from JSON by a store.
+func (s *session) flashes() map[string][]interface{} {
+	result := make(map[string][]interface{})
+	switch t := s.Data[FlashKey].(type) {
+	case map[string][]interface{}:
+		for k, v := range t {
+			result[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range t {
+			if values, ok := v.([]interface{}); ok {
+				result[k] = values
+			} else {
+				result[k] = []interface{}{v}
+			}
+		}
+	}
+	return result
+}
+
+// WithContext returns a copy of the context carrying the session.
+func WithContext(ctx context.Context, value *session) context.Context {
+	return context.WithValue(ctx, ContextKeySession, value)
+}
+
+// FromContext returns the session injected into the context by the session middlewares, or nil.
+func FromContext(ctx context.Context) *session {
+	s, ok := ctx.Value(ContextKeySession).(*session)
+	if !ok {
+		return nil
+	}
+	return s
+}
@@ -1,9 +1,11 @@
 package session
 
 import (
+	"sync"
 	"time"
 
 	"github.com/sivaosorg/govm/cookies"
+	"github.com/sivaosorg/govm/mem"
 )
 
 type session struct {
@@ -11,11 +13,53 @@ type session struct {
 	CreatedAt time.Time              `json:"created_at,omitempty" yaml:"-"`
 	ExpiresAt time.Time              `json:"expires_at,omitempty" yaml:"-"`
 	Data      map[string]interface{} `json:"data,omitempty" yaml:"data"`
+	mutex     sync.RWMutex           `json:"-" yaml:"-"`
+}
+
+// contextKey is the type of keys the session package stores into a context.Context.
+type contextKey string
+
+// Session is an alias of the session entity, so custom SessionStore implementations can refer to it.
+type Session = session
+
+// SessionStore persists sessions by id. Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Get returns the session of the id, or false when it does not exist or has expired.
+	Get(id string) (*session, bool)
+	// Set creates or replaces the session under its id.
+	Set(value *session) error
+	// Delete removes the session of the id.
+	Delete(id string) error
+	// Touch updates the expiration of the session of the id.
+	Touch(id string, expiresAt time.Time) error
+	// GC removes every expired session and returns how many were removed.
+	GC() int
 }
 
 type SessionManager struct {
-	SecretKey    string               `json:"secret_key" yaml:"secret_key"`
-	Expiration   time.Duration        `json:"expires_at" yaml:"expires_at"`
-	Cookie       cookies.CookieConfig `json:"cookie" yaml:"cookie"`
-	SessionStore map[string]*session  `json:"store" yaml:"store"`
+	SecretKey          string               `json:"secret_key" yaml:"secret_key"`
+	PreviousSecretKeys []string             `json:"previous_secret_keys,omitempty" yaml:"previous_secret_keys"`
+	IsClientSide       bool                 `json:"client_side" yaml:"client_side"`
+	IsSliding          bool                 `json:"sliding" yaml:"sliding"`
+	Expiration         time.Duration        `json:"expires_at" yaml:"expires_at"`
+	Cookie             cookies.CookieConfig `json:"cookie" yaml:"cookie"`
+	// SessionStore is not serialized, it replaced the "store" map of sessions, no longer written
+	SessionStore SessionStore `json:"-" yaml:"-"`
+}
+
+// memorySessionStore keeps sessions in a map guarded by a mutex.
+type memorySessionStore struct {
+	mutex    sync.RWMutex
+	sessions map[string]*session
+}
+
+// lruSessionStore keeps sessions in a bounded mem.LRUCache, evicting the least recently used ones when full.
+type lruSessionStore struct {
+	cache *mem.LRUCache
+}
+
+// fileSessionStore keeps each session as a JSON file named by its id within a directory.
+type fileSessionStore struct {
+	mutex     sync.RWMutex
+	directory string
 }
@@ -1,7 +1,8 @@
 package session
 
 import (
-	"math/rand"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -16,8 +17,19 @@ type SessionManagerService interface {
 	CreateSession(w http.ResponseWriter, data map[string]interface{}) *session
 	GetSessionId(request *http.Request) string
 	GetSession(request *http.Request) *session
+	VerifySessionId(sessionId string) bool
+	GC() int
+
+	// Session Lifecycle
+	Save(w http.ResponseWriter, session *session) error
+	Refresh(w http.ResponseWriter, session *session) error
+	Regenerate(w http.ResponseWriter, request *http.Request) (*session, error)
+	Destroy(w http.ResponseWriter, request *http.Request) error
+	AddFlash(w http.ResponseWriter, request *http.Request, key string, value interface{}) error
+	Flashes(w http.ResponseWriter, request *http.Request, key string) []interface{}
 
 	// Session Middlewares
+	SessionMiddleware(next http.Handler) http.Handler
 	RequireSessionMiddleware(next http.Handler) http.Handler
 }
 
@@ -25,20 +37,31 @@ type sessionManagerServiceImpl struct {
 	conf SessionManager
 }
 
+// NewSessionManagerService creates the session service on top of the store of the config,
+// falling back to an in-memory store when none is set.
 func NewSessionManagerService(conf SessionManager) SessionManagerService {
+	if conf.SessionStore == nil {
+		conf.SessionStore = NewMemorySessionStore()
+	}
 	s := &sessionManagerServiceImpl{
 		conf: conf,
 	}
 	return s
 }
 
+// NewSessionManagerServiceWith creates the session service on top of the given store.
+func NewSessionManagerServiceWith(conf SessionManager, store SessionStore) SessionManagerService {
+	conf.SetStore(store)
+	return NewSessionManagerService(conf)
+}
+
+// GenSessionId generates a session id from crypto/rand.
 func (s *sessionManagerServiceImpl) GenSessionId() string {
-	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
-	b := make([]byte, 32)
-	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
+	id, err := GenSessionIdSecure()
+	if err != nil {
+		log.Panicf("Unable to generate session id: %v", err)
 	}
-	return string(b)
+	return id
 }
 
 func (s *sessionManagerServiceImpl) CreateSession(w http.ResponseWriter, data map[string]interface{}) *session {
@@ -49,55 +72,205 @@ func (s *sessionManagerServiceImpl) CreateSession(w http.ResponseWriter, data ma
 		SetCreatedAt(time.Now()).
 		SetExpiresAt(expiresAt).
 		SetData(data)
-	s.conf.SessionStore[sessionId] = session
-
-	// updating the cookie
-	// set cookie value
-	// set cookie timeout
-	s.conf.Cookie.SetValue(sessionId)
-	s.conf.Cookie.SetTimeout(s.conf.Expiration)
-	svc := cookies.NewCookieService()
-
-	// set cookie based on session
-	svc.SetCookie(w, s.conf.Cookie)
+	if session.Data == nil {
+		session.SetData(make(map[string]interface{}))
+	}
+	if err := s.Save(w, session); err != nil {
+		s.discard(session)
+		return nil
+	}
 	return session
 }
 
+// GetSessionId returns the verified session id carried by the cookie of the configured name.
 func (s *sessionManagerServiceImpl) GetSessionId(request *http.Request) string {
-	cookieValue := request.Cookies()[0].Value
-	return cookieValue
+	cookieValue := s.getCookieValue(request)
+	if utils.IsEmpty(cookieValue) {
+		return ""
+	}
+	if s.conf.IsClientSide {
+		session, err := s.conf.Decrypt(cookieValue)
+		if err != nil {
+			return ""
+		}
+		return session.Id
+	}
+	sessionId, ok := s.conf.VerifyValue(cookieValue)
+	if !ok {
+		return ""
+	}
+	return sessionId
 }
 
 // GetSession retrieves a session based on the session ID from a request.
+// For client-side sessions, the session is decrypted from the cookie itself.
+// With sliding expiration, the expiration of a server-side session is renewed on access.
 func (s *sessionManagerServiceImpl) GetSession(request *http.Request) *session {
-	cookieValue := s.GetSessionId(request)
-	if utils.IsEmpty(cookieValue) {
+	if s.conf.IsClientSide {
+		cookieValue := s.getCookieValue(request)
+		if utils.IsEmpty(cookieValue) {
+			return nil
+		}
+		session, err := s.conf.Decrypt(cookieValue)
+		if err != nil || session.IsExpired() {
+			return nil
+		}
+		return session
+	}
+	sessionId := s.GetSessionId(request)
+	if utils.IsEmpty(sessionId) {
 		return nil
 	}
-	session, ok := s.conf.SessionStore[cookieValue]
-	if !ok || session.ExpiresAt.Before(time.Now()) {
-		delete(s.conf.SessionStore, cookieValue)
+	session, ok := s.conf.SessionStore.Get(sessionId)
+	if !ok {
 		return nil
 	}
+	if s.conf.IsSliding {
+		// the store renews the expiration, copied into the sessions the store does not share, e.g: those of the file store
+		expiresAt := time.Now().Add(s.conf.Expiration)
+		if s.conf.SessionStore.Touch(sessionId, expiresAt) == nil {
+			session.SetExpiresAt(expiresAt)
+		}
+	}
 	return session
 }
 
 // VerifySessionId checks if a session is valid based on session ID.
 func (s *sessionManagerServiceImpl) VerifySessionId(sessionId string) bool {
-	session, ok := s.conf.SessionStore[sessionId]
-	if !ok || session.ExpiresAt.Before(time.Now()) {
-		delete(s.conf.SessionStore, sessionId)
-		return false
+	_, ok := s.conf.SessionStore.Get(sessionId)
+	return ok
+}
+
+// GC removes expired sessions from the store.
+func (s *sessionManagerServiceImpl) GC() int {
+	return s.conf.SessionStore.GC()
+}
+
+// Save persists the session and writes its cookie.
+// It must be called after changing the data of a client-side session, and of a session kept by a store
+// that does not share memory with the caller, e.g: the file store.
+func (s *sessionManagerServiceImpl) Save(w http.ResponseWriter, session *session) error {
+	if session == nil {
+		return fmt.Errorf("Session is required")
+	}
+	value, err := s.encode(session)
+	if err != nil {
+		return err
 	}
-	return true
+	return s.setCookie(w, session, value)
+}
+
+// setCookie writes the session cookie of the value, expiring along with the session.
+func (s *sessionManagerServiceImpl) setCookie(w http.ResponseWriter, session *session, value string) error {
+	// updating the cookie
+	// set cookie value
+	// set cookie timeout
+	cookie := s.conf.Cookie
+	cookie.SetValue(value)
+	cookie.SetTimeout(time.Until(session.expiration()))
+	svc := cookies.NewCookieService()
+
+	// set cookie based on session
+	return svc.SetCookie(w, cookie)
+}
+
+// Refresh renews the expiration of the session from now and re-issues its cookie.
+func (s *sessionManagerServiceImpl) Refresh(w http.ResponseWriter, session *session) error {
+	if session == nil {
+		return fmt.Errorf("Session is required")
+	}
+	session.SetExpiresAt(time.Now().Add(s.conf.Expiration))
+	return s.Save(w, session)
+}
+
+// Regenerate moves the data of the current session under a new id and invalidates the old id,
+// preventing session fixation, e.g: right after a login.
+func (s *sessionManagerServiceImpl) Regenerate(w http.ResponseWriter, request *http.Request) (*session, error) {
+	current := s.GetSession(request)
+	if current == nil {
+		return nil, fmt.Errorf("Session not found")
+	}
+	oldId := current.Id
+	current.mutex.RLock()
+	data := make(map[string]interface{}, len(current.Data))
+	for k, v := range current.Data {
+		data[k] = v
+	}
+	current.mutex.RUnlock()
+	session := NewSession().
+		SetId(s.GenSessionId()).
+		SetCreatedAt(time.Now()).
+		SetExpiresAt(time.Now().Add(s.conf.Expiration)).
+		SetData(data)
+	if err := s.Save(w, session); err != nil {
+		s.discard(session)
+		return nil, err
+	}
+	if !s.conf.IsClientSide {
+		if err := s.conf.SessionStore.Delete(oldId); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Destroy removes the session of the request from the store and deletes its cookie.
+func (s *sessionManagerServiceImpl) Destroy(w http.ResponseWriter, request *http.Request) error {
+	if !s.conf.IsClientSide {
+		if sessionId := s.GetSessionId(request); utils.IsNotEmpty(sessionId) {
+			if err := s.conf.SessionStore.Delete(sessionId); err != nil {
+				return err
+			}
+		}
+	}
+	svc := cookies.NewCookieService()
+	return svc.DeleteCookie(w, s.conf.Cookie)
+}
+
+// AddFlash appends a flash message of the key to the session of the request and saves it.
+func (s *sessionManagerServiceImpl) AddFlash(w http.ResponseWriter, request *http.Request, key string, value interface{}) error {
+	session := s.GetSession(request)
+	if session == nil {
+		return fmt.Errorf("Session not found")
+	}
+	session.AddFlash(key, value)
+	return s.Save(w, session)
+}
+
+// Flashes returns the flash messages of the key from the session of the request,
+// removing them so that they are shown only once.
+func (s *sessionManagerServiceImpl) Flashes(w http.ResponseWriter, request *http.Request, key string) []interface{} {
+	session := s.GetSession(request)
+	if session == nil {
+		return nil
+	}
+	values := session.Flashes(key)
+	if len(values) > 0 {
+		s.Save(w, session)
+	}
+	return values
+}
+
+// SessionMiddleware injects the session of the request, if any, into the request context.
+// Use FromContext to retrieve it. With sliding expiration, the session cookie is re-issued.
+func (s *sessionManagerServiceImpl) SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := s.GetSession(r)
+		if session != nil {
+			s.slide(w, session)
+			r = r.WithContext(WithContext(r.Context(), session))
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // Middleware function to validate the session for protected routes.
+// The session is injected into the request context.
 func (s *sessionManagerServiceImpl) RequireSessionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sessionId := s.GetSessionId(r)
-		if utils.IsNotEmpty(sessionId) && s.VerifySessionId(sessionId) {
-			next.ServeHTTP(w, r)
+		if session := s.GetSession(r); session != nil {
+			s.slide(w, session)
+			next.ServeHTTP(w, r.WithContext(WithContext(r.Context(), session)))
 		} else {
 			w.Header().Set(common.HeaderContentType, common.MediaTypeApplicationJSON)
 			w.WriteHeader(http.StatusUnauthorized)
@@ -106,3 +279,51 @@ func (s *sessionManagerServiceImpl) RequireSessionMiddleware(next http.Handler)
 		}
 	})
 }
+
+// encode returns the cookie value of the session: the session sealed with AES-GCM for client-side sessions,
+// otherwise the signed session id once the session is saved into the store.
+func (s *sessionManagerServiceImpl) encode(session *session) (string, error) {
+	if s.conf.IsClientSide {
+		return s.conf.Encrypt(session)
+	}
+	value, err := s.conf.SignValue(session.Id)
+	if err != nil {
+		return "", err
+	}
+	if err := s.conf.SessionStore.Set(session); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// discard removes a new session from the store when its cookie could not be issued.
+func (s *sessionManagerServiceImpl) discard(session *session) {
+	if !s.conf.IsClientSide {
+		s.conf.SessionStore.Delete(session.Id)
+	}
+}
+
+// slide re-issues the cookie of the session when sliding expiration is enabled.
+// The expiration of a server-side session is already renewed in the store by GetSession, so that the store
+// is written once per request, that of a client-side session is renewed within its sealed cookie.
+func (s *sessionManagerServiceImpl) slide(w http.ResponseWriter, session *session) {
+	if !s.conf.IsSliding {
+		return
+	}
+	if s.conf.IsClientSide {
+		s.Refresh(w, session)
+		return
+	}
+	if value, err := s.conf.SignValue(session.Id); err == nil {
+		s.setCookie(w, session, value)
+	}
+}
+
+// getCookieValue returns the value of the session cookie of the configured name.
+func (s *sessionManagerServiceImpl) getCookieValue(request *http.Request) string {
+	c, err := request.Cookie(s.conf.Cookie.Name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
@@ -0,0 +1,274 @@
+package session
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sivaosorg/govm/mem"
+	"github.com/sivaosorg/govm/utils"
+)
+
+// NewMemorySessionStore creates a concurrency-safe in-memory session store.
+// Sessions are lost when the process exits.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{
+		sessions: make(map[string]*session),
+	}
+}
+
+// Get checks the expiration under the lock of the store, the one Touch renews it under.
+func (m *memorySessionStore) Get(id string) (*session, bool) {
+	m.mutex.RLock()
+	s, ok := m.sessions[id]
+	expired := ok && s.IsExpired()
+	m.mutex.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if expired {
+		m.mutex.Lock()
+		// the session may have been renewed or replaced meanwhile
+		if current, ok := m.sessions[id]; ok && current == s && s.IsExpired() {
+			delete(m.sessions, id)
+		}
+		m.mutex.Unlock()
+		return nil, false
+	}
+	return s, true
+}
+
+func (m *memorySessionStore) Set(value *session) error {
+	if value == nil || utils.IsEmpty(value.Id) {
+		return fmt.Errorf("Session id is required")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sessions[value.Id] = value
+	return nil
+}
+
+func (m *memorySessionStore) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memorySessionStore) Touch(id string, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return fmt.Errorf("Session %s not found", id)
+	}
+	s.SetExpiresAt(expiresAt)
+	return nil
+}
+
+func (m *memorySessionStore) GC() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	counter := 0
+	for k, v := range m.sessions {
+		if v.IsExpired() {
+			delete(m.sessions, k)
+			counter++
+		}
+	}
+	return counter
+}
+
+// NewLRUSessionStore creates a session store backed by mem.LRUCache holding at most capacity sessions.
+// When expiration is positive, the cache also drops entries that have not been written for that long,
+// in addition to the expiration of each session.
+func NewLRUSessionStore(capacity int, expiration time.Duration) SessionStore {
+	var cache *mem.LRUCache
+	if expiration > 0 {
+		cache = mem.NewLRUCacheExpiration(capacity, expiration)
+	} else {
+		cache = mem.NewLRUCache(capacity)
+	}
+	return &lruSessionStore{
+		cache: cache,
+	}
+}
+
+func (l *lruSessionStore) Get(id string) (*session, bool) {
+	v, ok := l.cache.Get(id)
+	if !ok {
+		return nil, false
+	}
+	s, ok := v.(*session)
+	if !ok || s.IsExpired() {
+		l.cache.Remove(id)
+		return nil, false
+	}
+	return s, true
+}
+
+func (l *lruSessionStore) Set(value *session) error {
+	if value == nil || utils.IsEmpty(value.Id) {
+		return fmt.Errorf("Session id is required")
+	}
+	l.cache.Set(value.Id, value)
+	return nil
+}
+
+func (l *lruSessionStore) Delete(id string) error {
+	l.cache.Remove(id)
+	return nil
+}
+
+func (l *lruSessionStore) Touch(id string, expiresAt time.Time) error {
+	s, ok := l.Get(id)
+	if !ok {
+		return fmt.Errorf("Session %s not found", id)
+	}
+	s.SetExpiresAt(expiresAt)
+	l.cache.Update(id, s)
+	return nil
+}
+
+func (l *lruSessionStore) GC() int {
+	counter := 0
+	for _, v := range l.cache.Snapshot() {
+		if s, ok := v.Value.(*session); ok && s.IsExpired() {
+			l.cache.Remove(v.Key)
+			counter++
+		}
+	}
+	return counter
+}
+
+// NewFileSessionStore creates a session store that writes each session as a JSON file within directory.
+// The directory is created when it does not exist.
+func NewFileSessionStore(directory string) (SessionStore, error) {
+	if utils.IsEmpty(directory) {
+		return nil, fmt.Errorf("Directory is required")
+	}
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+	return &fileSessionStore{
+		directory: directory,
+	}, nil
+}
+
+func (f *fileSessionStore) Get(id string) (*session, bool) {
+	f.mutex.RLock()
+	s, err := f.read(id)
+	f.mutex.RUnlock()
+	if err != nil {
+		return nil, false
+	}
+	if s.IsExpired() {
+		f.Delete(id)
+		return nil, false
+	}
+	return s, true
+}
+
+func (f *fileSessionStore) Set(value *session) error {
+	if value == nil || utils.IsEmpty(value.Id) {
+		return fmt.Errorf("Session id is required")
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.write(value)
+}
+
+func (f *fileSessionStore) Delete(id string) error {
+	filename, err := f.filename(id)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	err = os.Remove(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *fileSessionStore) Touch(id string, expiresAt time.Time) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	s, err := f.read(id)
+	if err != nil {
+		return err
+	}
+	s.SetExpiresAt(expiresAt)
+	return f.write(s)
+}
+
+func (f *fileSessionStore) GC() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	files, err := filepath.Glob(filepath.Join(f.directory, "*"+FileSessionExtension))
+	if err != nil {
+		return 0
+	}
+	counter := 0
+	for _, filename := range files {
+		id := strings.TrimSuffix(filepath.Base(filename), FileSessionExtension)
+		s, err := f.read(id)
+		if err != nil || !s.IsExpired() {
+			continue
+		}
+		if os.Remove(filename) == nil {
+			counter++
+		}
+	}
+	return counter
+}
+
+// filename returns the path of the file holding the session of the id,
+// rejecting ids that could escape the directory.
+func (f *fileSessionStore) filename(id string) (string, error) {
+	if utils.IsEmpty(id) || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return "", fmt.Errorf("Invalid session id: %s", id)
+	}
+	return filepath.Join(f.directory, id+FileSessionExtension), nil
+}
+
+func (f *fileSessionStore) read(id string) (*session, error) {
+	filename, err := f.filename(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := NewSession()
+	if err := utils.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Data == nil {
+		s.Data = make(map[string]interface{})
+	}
+	return s, nil
+}
+
+// write stores the session into a temporary file first and renames it,
+// so that readers never observe a partially written session.
+func (f *fileSessionStore) write(value *session) error {
+	filename, err := f.filename(value.Id)
+	if err != nil {
+		return err
+	}
+	data, err := utils.Marshal(value)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
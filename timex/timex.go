@@ -32,8 +32,10 @@ func GetPreviousEndDay(at time.Time, previousDay int) time.Time {
 // ApplyTimezone
 func ApplyTimezone(at time.Time, timezone string) (time.Time, error) {
 	loc, err := time.LoadLocation(timezone)
-	now := at.In(loc)
-	return now, err
+	if err != nil {
+		return at, err
+	}
+	return at.In(loc), nil
 }
 
 // AdjustTimezone
@@ -442,7 +444,19 @@ func (now *Timex) parseWithFormat(s string, location *time.Location) (t time.Tim
 }
 
 // Parse parse string to time
-func (now *Timex) Parse(s ...string) (t time.Time, err error) {
+// A single string no format matches is parsed as a relative expression, e.g: "now-15m", "3 days ago", see ParseRelative
+func (now *Timex) Parse(s ...string) (time.Time, error) {
+	t, err := now.parseAbsolute(s...)
+	if err != nil && len(s) == 1 {
+		if relative, e := now.parseRelative(s[0], false); e == nil {
+			return relative, nil
+		}
+	}
+	return t, err
+}
+
+// parseAbsolute parse strings to time with the formats of the configuration
+func (now *Timex) parseAbsolute(s ...string) (t time.Time, err error) {
 	var (
 		setCurrentTime  bool
 		parseTime       []int
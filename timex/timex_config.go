@@ -1,6 +1,10 @@
 package timex
 
-import "regexp"
+import (
+	"errors"
+	"regexp"
+	"time"
+)
 
 // Deprecated: unsupported
 const (
@@ -35,6 +39,11 @@ const (
 var ApplyTimeRegexp = regexp.MustCompile(`(\s+|^\s*|T)\d{1,2}((:\d{1,2})*|((:\d{1,2}){2}\.(\d{3}|\d{6}|\d{9})))(\s*$|[Z+-])`) // match 15:04:05, 15:04:05.000, 15:04:05.000000 15, 2017-01-01 15:04, 2021-07-20T00:59:10Z, 2021-07-20T00:59:10+08:00, 2021-07-20T00:00:10-07:00 etc
 var OnlyTimeRegexp = regexp.MustCompile(`^\s*\d{1,2}((:\d{1,2})*|((:\d{1,2}){2}\.(\d{3}|\d{6}|\d{9})))\s*$`)                  // match 15:04:05, 15, 15:04:05.000, 15:04:05.000000, etc
 
+var NowMathRegexp = regexp.MustCompile(`^now((?:\s*[+-]\s*\d+\s*[smhdwMy])*)(?:\s*/\s*([smhdwMy]))?$`)                                                  // match now, now-15m, now+1d-2h, now/d, now-1M/M
+var NowMathTermRegexp = regexp.MustCompile(`([+-])\s*(\d+)\s*([smhdwMy])`)                                                                              // match -15m, +1d
+var ISODurationRegexp = regexp.MustCompile(`^([+-])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`) // match P1D, PT2H30M, P1Y2M10DT2H30M, -P1W, PT0.5S
+var ClockRegexp = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?\s*(am|pm)?$`)                                                                // match 9am, 9:30pm, 17:00, 17:00:05
+
 // Timezone constants representing default timezones for specific regions.
 const (
 	// DefaultTimezoneVietnam is a constant that holds the IANA Time Zone identifier
@@ -173,3 +182,207 @@ const (
 	// for the default timezone in Suva, Fiji, which is "Pacific/Fiji".
 	DefaultTimezoneSuva = "Pacific/Fiji"
 )
+
+// Cron descriptors, shortcuts of standard expressions.
+const (
+	CronYearly   = "@yearly"
+	CronAnnually = "@annually"
+	CronMonthly  = "@monthly"
+	CronWeekly   = "@weekly"
+	CronDaily    = "@daily"
+	CronMidnight = "@midnight"
+	CronHourly   = "@hourly"
+	CronEvery    = "@every"
+)
+
+var (
+	CronDescriptors map[string]string = map[string]string{
+		CronYearly:   "0 0 0 1 1 *",
+		CronAnnually: "0 0 0 1 1 *",
+		CronMonthly:  "0 0 0 1 * *",
+		CronWeekly:   "0 0 0 * * 0",
+		CronDaily:    "0 0 0 * * *",
+		CronMidnight: "0 0 0 * * *",
+		CronHourly:   "0 0 * * * *",
+	}
+)
+
+var (
+	CronMonthNames map[string]int = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	CronDayNames map[string]int = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// Overlap policies of a job whose previous run is still in progress when it is due again.
+const (
+	// OverlapSkip drops the run.
+	OverlapSkip = "skip"
+	// OverlapQueue runs it once the previous run finishes, runs of the job never overlapping.
+	OverlapQueue = "queue"
+	// OverlapConcurrent runs it alongside the previous run.
+	OverlapConcurrent = "concurrent"
+)
+
+var (
+	OverlapPolicies map[string]bool = map[string]bool{
+		OverlapSkip:       true,
+		OverlapQueue:      true,
+		OverlapConcurrent: true,
+	}
+)
+
+const (
+	// CronSearchYears bounds the search of the next activation, e.g: for "0 0 30 2 *" that never activates.
+	CronSearchYears = 5
+	// CronIdleWait is how long the scheduler sleeps when no job is due.
+	CronIdleWait = 24 * time.Hour
+)
+
+var (
+	ErrorCronEmpty       = errors.New("Cron expression is required")
+	ErrorCronJobName     = errors.New("Cron job name is required")
+	ErrorCronJobFunc     = errors.New("Cron job function is required")
+	ErrorCronJobExists   = errors.New("Cron job is already registered")
+	ErrorRRuleFreq       = errors.New("Recurrence rule FREQ is required, expected one of YEARLY, MONTHLY, WEEKLY, DAILY, HOURLY, MINUTELY or SECONDLY")
+	ErrorRRuleDtstart    = errors.New("Recurrence rule DTSTART is required")
+	ErrorRRuleCountUntil = errors.New("Recurrence rule must not set both COUNT and UNTIL")
+	ErrorRelativeEmpty   = errors.New("Relative time expression is required")
+	ErrorCronOverlap     = errors.New("Cron job overlap policy is invalid, expected skip, queue or concurrent")
+)
+
+// Frequencies of a recurrence rule (RFC 5545).
+const (
+	FreqYearly   = "YEARLY"
+	FreqMonthly  = "MONTHLY"
+	FreqWeekly   = "WEEKLY"
+	FreqDaily    = "DAILY"
+	FreqHourly   = "HOURLY"
+	FreqMinutely = "MINUTELY"
+	FreqSecondly = "SECONDLY"
+)
+
+var (
+	RRuleFrequencies map[string]time.Duration = map[string]time.Duration{
+		FreqYearly:   0,
+		FreqMonthly:  0,
+		FreqWeekly:   0,
+		FreqDaily:    0,
+		FreqHourly:   time.Hour,
+		FreqMinutely: time.Minute,
+		FreqSecondly: time.Second,
+	}
+	RRuleWeekdays map[string]time.Weekday = map[string]time.Weekday{
+		"SU": time.Sunday,
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+	}
+)
+
+const (
+	RRuleDateLayout        = "20060102"
+	RRuleDateTimeLayout    = "20060102T150405"
+	RRuleDateTimeUTCLayout = "20060102T150405Z"
+	// RRuleMaxEmptyPeriods bounds the periods scanned without an occurrence, e.g: for "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30" that never occurs.
+	RRuleMaxEmptyPeriods = 100000
+)
+
+// Units of relative time expressions.
+const (
+	UnitSecond  = "second"
+	UnitMinute  = "minute"
+	UnitHour    = "hour"
+	UnitDay     = "day"
+	UnitWeek    = "week"
+	UnitMonth   = "month"
+	UnitQuarter = "quarter"
+	UnitHalf    = "half"
+	UnitYear    = "year"
+)
+
+var (
+	// RelativeUnits maps the words of relative time expressions to their unit, e.g: "3 hrs ago".
+	RelativeUnits map[string]string = map[string]string{
+		"s": UnitSecond, "sec": UnitSecond, "secs": UnitSecond, "second": UnitSecond, "seconds": UnitSecond,
+		"m": UnitMinute, "min": UnitMinute, "mins": UnitMinute, "minute": UnitMinute, "minutes": UnitMinute,
+		"h": UnitHour, "hr": UnitHour, "hrs": UnitHour, "hour": UnitHour, "hours": UnitHour,
+		"d": UnitDay, "day": UnitDay, "days": UnitDay,
+		"w": UnitWeek, "wk": UnitWeek, "wks": UnitWeek, "week": UnitWeek, "weeks": UnitWeek,
+		"mo": UnitMonth, "mos": UnitMonth, "month": UnitMonth, "months": UnitMonth,
+		"q": UnitQuarter, "quarter": UnitQuarter, "quarters": UnitQuarter,
+		"half": UnitHalf, "halves": UnitHalf,
+		"y": UnitYear, "yr": UnitYear, "yrs": UnitYear, "year": UnitYear, "years": UnitYear,
+	}
+	// NowMathUnits maps the units of "now" expressions to their unit, "m" being minutes and "M" months.
+	NowMathUnits map[string]string = map[string]string{
+		"s": UnitSecond, "m": UnitMinute, "h": UnitHour, "d": UnitDay, "w": UnitWeek, "M": UnitMonth, "y": UnitYear,
+	}
+	RelativeWeekdays map[string]time.Weekday = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+)
+
+// DefaultHumanizeLocale is the locale of humanized durations when none, or an unknown one, is given.
+const DefaultHumanizeLocale = "en"
+
+var (
+	// HumanizeUnits are the units of humanized durations, largest first; months and years are approximated to 30 and 365 days.
+	HumanizeUnits = []struct {
+		Unit     string
+		Duration time.Duration
+	}{
+		{UnitYear, 365 * 24 * time.Hour},
+		{UnitMonth, 30 * 24 * time.Hour},
+		{UnitWeek, 7 * 24 * time.Hour},
+		{UnitDay, 24 * time.Hour},
+		{UnitHour, time.Hour},
+		{UnitMinute, time.Minute},
+		{UnitSecond, time.Second},
+	}
+	// HumanizeLocales are the locales of humanized durations, extended by AddHumanizeLocale.
+	HumanizeLocales map[string]HumanizeLocale = map[string]HumanizeLocale{
+		"en": {
+			Now:       "just now",
+			Past:      "%s ago",
+			Future:    "in %s",
+			Separator: " ",
+			Units: map[string]HumanizeUnit{
+				UnitYear:   {One: "year", Other: "years", Short: "y"},
+				UnitMonth:  {One: "month", Other: "months", Short: "mo"},
+				UnitWeek:   {One: "week", Other: "weeks", Short: "w"},
+				UnitDay:    {One: "day", Other: "days", Short: "d"},
+				UnitHour:   {One: "hour", Other: "hours", Short: "h"},
+				UnitMinute: {One: "minute", Other: "minutes", Short: "m"},
+				UnitSecond: {One: "second", Other: "seconds", Short: "s"},
+			},
+		},
+		"vi": {
+			Now:       "vừa xong",
+			Past:      "%s trước",
+			Future:    "%s nữa",
+			Separator: " ",
+			Units: map[string]HumanizeUnit{
+				UnitYear:   {One: "năm", Other: "năm", Short: "năm"},
+				UnitMonth:  {One: "tháng", Other: "tháng", Short: "th"},
+				UnitWeek:   {One: "tuần", Other: "tuần", Short: "tuần"},
+				UnitDay:    {One: "ngày", Other: "ngày", Short: "ng"},
+				UnitHour:   {One: "giờ", Other: "giờ", Short: "g"},
+				UnitMinute: {One: "phút", Other: "phút", Short: "ph"},
+				UnitSecond: {One: "giây", Other: "giây", Short: "gi"},
+			},
+		},
+	}
+)
@@ -0,0 +1,448 @@
+package timex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cronBounds struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronSecondBounds = cronBounds{"second", 0, 59, nil}
+	cronMinuteBounds = cronBounds{"minute", 0, 59, nil}
+	cronHourBounds   = cronBounds{"hour", 0, 23, nil}
+	cronDomBounds    = cronBounds{"day of month", 1, 31, nil}
+	cronMonthBounds  = cronBounds{"month", 1, 12, CronMonthNames}
+	cronDowBounds    = cronBounds{"day of week", 0, 7, CronDayNames}
+)
+
+// ParseCron parses a cron expression, evaluated in the timezone of the times given to Next.
+// Expressions have 5 fields (minute, hour, day of month, month, day of week) or 6 fields, seconds first.
+// Fields accept lists, ranges and steps ("1,15", "1-5", "*/10", "10-40/5"), month and day names ("JAN", "MON"),
+// and "?" in place of "*". Day of month accepts "L" (last day), "L-3" (3 days before the last day),
+// "15W" (the weekday nearest to the 15th) and "LW" (the last weekday); day of week accepts "5L" (the last Friday)
+// and "1#2" (the second Monday).
+// Descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly and "@every <duration>" are accepted,
+// and the expression may be prefixed with "TZ=<zone>" or "CRON_TZ=<zone>" to be evaluated in that timezone.
+func ParseCron(spec string) (Schedule, error) {
+	return ParseCronIn(spec, nil)
+}
+
+// ParseCronIn parses a cron expression evaluated in the location given, unless the expression sets its own
+// timezone. A nil location evaluates the expression in the timezone of the times given to Next.
+func ParseCronIn(spec string, loc *time.Location) (Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if expr == "" {
+		return nil, ErrorCronEmpty
+	}
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		i := strings.IndexAny(expr, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("Cron expression '%s' has no fields after its timezone", spec)
+		}
+		zone := expr[strings.Index(expr, "=")+1 : i]
+		l, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("Cron expression '%s' has an invalid timezone: %v", spec, err)
+		}
+		loc, expr = l, strings.TrimSpace(expr[i:])
+	}
+	if strings.HasPrefix(expr, "@") {
+		if strings.HasPrefix(expr, CronEvery) {
+			d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, CronEvery)))
+			if err != nil {
+				return nil, fmt.Errorf("Cron expression '%s' has an invalid interval: %v", spec, err)
+			}
+			if d < time.Second {
+				return nil, fmt.Errorf("Cron expression '%s' has an interval under one second", spec)
+			}
+			return EverySchedule{Every: d}, nil
+		}
+		standard, ok := CronDescriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("Cron expression '%s' has an unknown descriptor", spec)
+		}
+		expr = standard
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("Cron expression '%s' has %d fields, expected 5 or 6", spec, len(fields))
+	}
+	s := &CronSchedule{Spec: spec, Location: loc}
+	var err error
+	if s.Second, _, err = parseCronField(fields[0], cronSecondBounds); err != nil {
+		return nil, err
+	}
+	if s.Minute, _, err = parseCronField(fields[1], cronMinuteBounds); err != nil {
+		return nil, err
+	}
+	if s.Hour, _, err = parseCronField(fields[2], cronHourBounds); err != nil {
+		return nil, err
+	}
+	if err = s.parseDom(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.Month, _, err = parseCronField(fields[4], cronMonthBounds); err != nil {
+		return nil, err
+	}
+	if err = s.parseDow(fields[5]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustParseCron parses a cron expression, panicking when it is invalid.
+func MustParseCron(spec string) Schedule {
+	s, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Next returns the first second after the time given that the expression matches,
+// in the location of the time given, or the zero time when none exists within CronSearchYears.
+// Times skipped by a daylight saving transition are not matched; times repeated by it match once,
+// a time of the same minute as the time given, on the other side of the transition, being skipped.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	origin := t.Location()
+	loc := origin
+	if s.Location != nil {
+		loc = s.Location
+	}
+	prev := t.In(loc)
+	next := s.next(prev, loc)
+	for !next.IsZero() && repeatsMinute(prev, next) {
+		next = s.next(next, loc)
+	}
+	if next.IsZero() {
+		return next
+	}
+	return next.In(origin)
+}
+
+// next returns the first second after the time given that the expression matches, in the location, or the zero time.
+func (s *CronSchedule) next(t time.Time, loc *time.Location) time.Time {
+	t = t.Add(time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+	added := false
+	limit := t.Year() + CronSearchYears
+
+WRAP:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+	for s.Month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.matchDay(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// midnight may not exist, or land an hour off, on the day of a daylight saving transition
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for s.Hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for s.Minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for s.Second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
+
+// repeatsMinute checks whether the time is the wall clock minute of the previous one again,
+// repeated at another offset when the clocks fall back, e.g: 01:30 EST following 01:30 EDT.
+func repeatsMinute(prev, t time.Time) bool {
+	_, prevOffset := prev.Zone()
+	_, offset := t.Zone()
+	return offset != prevOffset && t.Year() == prev.Year() && t.YearDay() == prev.YearDay() &&
+		t.Hour() == prev.Hour() && t.Minute() == prev.Minute()
+}
+
+// String returns the expression parsed.
+func (s *CronSchedule) String() string {
+	return s.Spec
+}
+
+// Next returns the time given plus the interval, truncated to the second.
+func (s EverySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Every - time.Duration(t.Nanosecond())*time.Nanosecond)
+}
+
+// String returns the schedule as its descriptor.
+func (s EverySchedule) String() string {
+	return fmt.Sprintf("%s %v", CronEvery, s.Every)
+}
+
+// matchDay checks the day of month and the day of week of the time.
+// As with the standard cron, the day matches either field when both are restricted, and both otherwise.
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	dom, dow := s.matchDom(t), s.matchDow(t)
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (s *CronSchedule) matchDom(t time.Time) bool {
+	if s.Dom&(1<<uint(t.Day())) != 0 {
+		return true
+	}
+	last := daysIn(t.Year(), t.Month())
+	for _, n := range s.domLast {
+		if t.Day() == last-n {
+			return true
+		}
+	}
+	for _, n := range s.domWeekday {
+		if n <= last && t.Day() == nearestWeekday(t.Year(), t.Month(), n, t.Location()) {
+			return true
+		}
+	}
+	if s.domLastWeek && t.Day() == nearestWeekday(t.Year(), t.Month(), last, t.Location()) {
+		return true
+	}
+	return false
+}
+
+func (s *CronSchedule) matchDow(t time.Time) bool {
+	if s.Dow&(1<<uint(t.Weekday())) != 0 {
+		return true
+	}
+	for _, w := range s.dowLast {
+		if t.Weekday() == w && t.Day()+7 > daysIn(t.Year(), t.Month()) {
+			return true
+		}
+	}
+	for _, n := range s.dowNth[t.Weekday()] {
+		if (t.Day()-1)/7+1 == n {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDom parses the day of month field, its L and W extensions included.
+func (s *CronSchedule) parseDom(field string) error {
+	var plain []string
+	for _, part := range strings.Split(field, ",") {
+		upper := strings.ToUpper(part)
+		switch {
+		case upper == "L":
+			s.domLast = append(s.domLast, 0)
+		case upper == "LW" || upper == "WL":
+			s.domLastWeek = true
+		case strings.HasPrefix(upper, "L-"):
+			n, err := strconv.Atoi(upper[2:])
+			if err != nil || n < 0 || n > 30 {
+				return fmt.Errorf("Cron day of month '%s' has an invalid offset from the last day", part)
+			}
+			s.domLast = append(s.domLast, n)
+		case strings.HasSuffix(upper, "W"):
+			n, err := strconv.Atoi(upper[:len(upper)-1])
+			if err != nil || n < cronDomBounds.min || n > cronDomBounds.max {
+				return fmt.Errorf("Cron day of month '%s' has an invalid day for the nearest weekday", part)
+			}
+			s.domWeekday = append(s.domWeekday, n)
+		default:
+			plain = append(plain, part)
+		}
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+	bits, star, err := parseCronField(strings.Join(plain, ","), cronDomBounds)
+	if err != nil {
+		return err
+	}
+	s.Dom, s.domStar = bits, star && len(plain) == len(strings.Split(field, ","))
+	return nil
+}
+
+// parseDow parses the day of week field, its L and # extensions included. 7 is taken as Sunday.
+func (s *CronSchedule) parseDow(field string) error {
+	var plain []string
+	for _, part := range strings.Split(field, ",") {
+		upper := strings.ToUpper(part)
+		switch {
+		case strings.Contains(upper, "#"):
+			pair := strings.SplitN(upper, "#", 2)
+			w, err := parseCronValue(pair[0], cronDowBounds)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(pair[1])
+			if err != nil || n < 1 || n > 5 {
+				return fmt.Errorf("Cron day of week '%s' has an invalid occurrence, expected 1 to 5", part)
+			}
+			if s.dowNth == nil {
+				s.dowNth = make(map[time.Weekday][]int)
+			}
+			s.dowNth[time.Weekday(w%7)] = append(s.dowNth[time.Weekday(w%7)], n)
+		case len(upper) > 1 && strings.HasSuffix(upper, "L"):
+			w, err := parseCronValue(upper[:len(upper)-1], cronDowBounds)
+			if err != nil {
+				return err
+			}
+			s.dowLast = append(s.dowLast, time.Weekday(w%7))
+		default:
+			plain = append(plain, part)
+		}
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+	bits, star, err := parseCronField(strings.Join(plain, ","), cronDowBounds)
+	if err != nil {
+		return err
+	}
+	if bits&(1<<7) != 0 {
+		bits = bits&^(1<<7) | 1
+	}
+	s.Dow, s.dowStar = bits, star && len(plain) == len(strings.Split(field, ","))
+	return nil
+}
+
+// parseCronField parses a list of values, ranges and steps into a bit set,
+// reporting whether the field is unrestricted ("*" or "?").
+func parseCronField(field string, b cronBounds) (uint64, bool, error) {
+	var bits uint64
+	star := false
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, false, fmt.Errorf("Cron %s '%s' has an invalid step", b.name, part)
+			}
+			expr, step = part[:i], n
+		}
+		var start, end int
+		switch {
+		case expr == "*" || expr == "?":
+			start, end = b.min, b.max
+			if b.names != nil && b.max == 7 {
+				end = 6
+			}
+			if step == 1 {
+				star = true
+			}
+		case strings.Contains(expr, "-"):
+			pair := strings.SplitN(expr, "-", 2)
+			var err error
+			if start, err = parseCronValue(pair[0], b); err != nil {
+				return 0, false, err
+			}
+			if end, err = parseCronValue(pair[1], b); err != nil {
+				return 0, false, err
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(expr, b); err != nil {
+				return 0, false, err
+			}
+			end = start
+			if step > 1 {
+				end = b.max
+			}
+		}
+		if start > end {
+			return 0, false, fmt.Errorf("Cron %s '%s' has a range beginning after its end", b.name, part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+// parseCronValue parses a number, or a name of the field, within its bounds.
+func parseCronValue(value string, b cronBounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Cron %s '%s' is not a number", b.name, value)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("Cron %s '%d' is out of range [%d, %d]", b.name, v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// daysIn returns the number of days of the month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the day, Monday to Friday, nearest to the day of the month without leaving the month.
+func nearestWeekday(year int, month time.Month, day int, loc *time.Location) int {
+	last := daysIn(year, month)
+	switch time.Date(year, month, day, 12, 0, 0, 0, loc).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
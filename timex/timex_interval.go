@@ -0,0 +1,306 @@
+package timex
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var humanizeMutex sync.RWMutex
+
+// NewInterval creates the interval [start, end), the bounds swapped when end is before start.
+func NewInterval(start, end time.Time) Interval {
+	if end.Before(start) {
+		start, end = end, start
+	}
+	return Interval{Start: start, End: end}
+}
+
+// Duration returns the length of the interval.
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// IsEmpty checks whether the interval contains no instant.
+func (i Interval) IsEmpty() bool {
+	return !i.End.After(i.Start)
+}
+
+// Contains checks whether the time falls within [Start, End).
+func (i Interval) Contains(t time.Time) bool {
+	return !t.Before(i.Start) && t.Before(i.End)
+}
+
+// Overlaps checks whether the intervals share an instant.
+func (i Interval) Overlaps(o Interval) bool {
+	return i.Start.Before(o.End) && o.Start.Before(i.End)
+}
+
+// Abuts checks whether one interval ends where the other starts.
+func (i Interval) Abuts(o Interval) bool {
+	return i.End.Equal(o.Start) || o.End.Equal(i.Start)
+}
+
+// Union returns the intervals covering both, merged into one when they overlap or abut.
+func (i Interval) Union(o Interval) []Interval {
+	return MergeIntervals(i, o)
+}
+
+// Intersection returns the instants shared by both intervals, false when they do not overlap.
+func (i Interval) Intersection(o Interval) (Interval, bool) {
+	start, end := i.Start, i.End
+	if o.Start.After(start) {
+		start = o.Start
+	}
+	if o.End.Before(end) {
+		end = o.End
+	}
+	if !end.After(start) {
+		return Interval{}, false
+	}
+	return Interval{Start: start, End: end}, true
+}
+
+// Difference returns the parts of the interval not covered by the other one.
+func (i Interval) Difference(o Interval) []Interval {
+	return SubtractIntervals([]Interval{i}, []Interval{o})
+}
+
+// Gap returns the interval between both, false when they overlap or abut.
+func (i Interval) Gap(o Interval) (Interval, bool) {
+	if i.Overlaps(o) || i.Abuts(o) {
+		return Interval{}, false
+	}
+	if i.End.Before(o.Start) {
+		return Interval{Start: i.End, End: o.Start}, true
+	}
+	return Interval{Start: o.End, End: i.Start}, true
+}
+
+// Gaps returns the parts of the interval not covered by the busy intervals, e.g: the free slots of a day.
+func (i Interval) Gaps(busy ...Interval) []Interval {
+	return SubtractIntervals([]Interval{i}, busy)
+}
+
+// Split cuts the interval at the boundaries of the unit: UnitDay, UnitWeek, UnitMonth,
+// as well as UnitHour, UnitQuarter, UnitHalf and UnitYear, weeks starting on WeekStartDay.
+func (i Interval) Split(unit string) []Interval {
+	var parts []Interval
+	for start := i.Start; start.Before(i.End); {
+		end := nextBoundary(start, unit)
+		if end.After(i.End) {
+			end = i.End
+		}
+		parts = append(parts, Interval{Start: start, End: end})
+		start = end
+	}
+	return parts
+}
+
+// SplitByDay cuts the interval at midnight.
+func (i Interval) SplitByDay() []Interval {
+	return i.Split(UnitDay)
+}
+
+// SplitByWeek cuts the interval at the beginning of weeks.
+func (i Interval) SplitByWeek() []Interval {
+	return i.Split(UnitWeek)
+}
+
+// SplitByMonth cuts the interval at the beginning of months.
+func (i Interval) SplitByMonth() []Interval {
+	return i.Split(UnitMonth)
+}
+
+// Humanize returns the length of the interval in words, e.g: "2 hours 5 minutes", or "2h 5m" in short.
+func (i Interval) Humanize(opts HumanizeOptions) string {
+	return HumanizeDuration(i.Duration(), opts)
+}
+
+// String returns the interval in ISO 8601, e.g: "2024-01-01T00:00:00Z/2024-01-02T00:00:00Z".
+func (i Interval) String() string {
+	return i.Start.Format(time.RFC3339Nano) + "/" + i.End.Format(time.RFC3339Nano)
+}
+
+// MergeIntervals sorts the intervals and merges those that overlap or abut, dropping the empty ones.
+func MergeIntervals(intervals ...Interval) []Interval {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, v := range intervals {
+		if !v.IsEmpty() {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	var merged []Interval
+	for _, v := range sorted {
+		if n := len(merged); n > 0 && !v.Start.After(merged[n-1].End) {
+			if v.End.After(merged[n-1].End) {
+				merged[n-1].End = v.End
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+// UnionIntervals returns the merged intervals covering both lists.
+func UnionIntervals(a, b []Interval) []Interval {
+	return MergeIntervals(append(append([]Interval{}, a...), b...)...)
+}
+
+// IntersectIntervals returns the merged intervals covered by both lists.
+func IntersectIntervals(a, b []Interval) []Interval {
+	a, b = MergeIntervals(a...), MergeIntervals(b...)
+	var intersection []Interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		if v, ok := a[i].Intersection(b[j]); ok {
+			intersection = append(intersection, v)
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return intersection
+}
+
+// SubtractIntervals returns the merged intervals of the first list not covered by the second.
+func SubtractIntervals(a, b []Interval) []Interval {
+	a, b = MergeIntervals(a...), MergeIntervals(b...)
+	var difference []Interval
+	j := 0
+	for _, v := range a {
+		start := v.Start
+		for j < len(b) && !b[j].End.After(start) {
+			j++
+		}
+		for k := j; k < len(b) && b[k].Start.Before(v.End); k++ {
+			if b[k].Start.After(start) {
+				difference = append(difference, Interval{Start: start, End: b[k].Start})
+			}
+			if b[k].End.After(start) {
+				start = b[k].End
+			}
+		}
+		if start.Before(v.End) {
+			difference = append(difference, Interval{Start: start, End: v.End})
+		}
+	}
+	return difference
+}
+
+// GapsBetween returns the intervals between the merged intervals.
+func GapsBetween(intervals ...Interval) []Interval {
+	merged := MergeIntervals(intervals...)
+	var gaps []Interval
+	for i := 1; i < len(merged); i++ {
+		gaps = append(gaps, Interval{Start: merged[i-1].End, End: merged[i].Start})
+	}
+	return gaps
+}
+
+// Humanize returns the time relative to the reference in words, e.g: "3 days ago", "in 2 hours".
+func Humanize(t, reference time.Time) string {
+	return HumanizeWith(t, reference, HumanizeOptions{})
+}
+
+// HumanizeWith returns the time relative to the reference in words, per the options, e.g: "2h 5m ago".
+func HumanizeWith(t, reference time.Time, opts HumanizeOptions) string {
+	locale := GetHumanizeLocale(opts.Locale)
+	d := t.Sub(reference)
+	if d > -time.Second && d < time.Second {
+		return locale.Now
+	}
+	if d < 0 {
+		return fmt.Sprintf(locale.Past, HumanizeDuration(-d, opts))
+	}
+	return fmt.Sprintf(locale.Future, HumanizeDuration(d, opts))
+}
+
+// HumanizeDuration returns the duration in words, its largest units first, as many as the precision, 1 by default,
+// e.g: "3 days", or "2h 5m" in short with a precision of 2.
+func HumanizeDuration(d time.Duration, opts HumanizeOptions) string {
+	locale := GetHumanizeLocale(opts.Locale)
+	precision := opts.Precision
+	if precision <= 0 {
+		precision = 1
+	}
+	if d < 0 {
+		d = -d
+	}
+	var parts []string
+	for _, u := range HumanizeUnits {
+		if len(parts) >= precision {
+			break
+		}
+		n := d / u.Duration
+		if n == 0 {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		d -= n * u.Duration
+		parts = append(parts, humanizeUnit(locale, u.Unit, int64(n), opts.Short))
+	}
+	if len(parts) == 0 {
+		return humanizeUnit(locale, UnitSecond, 0, opts.Short)
+	}
+	return strings.Join(parts, locale.Separator)
+}
+
+// AddHumanizeLocale registers, or replaces, a locale of humanized durations.
+func AddHumanizeLocale(name string, locale HumanizeLocale) {
+	humanizeMutex.Lock()
+	defer humanizeMutex.Unlock()
+	HumanizeLocales[name] = locale
+}
+
+// GetHumanizeLocale returns the locale, or the default locale when unknown.
+func GetHumanizeLocale(name string) HumanizeLocale {
+	humanizeMutex.RLock()
+	defer humanizeMutex.RUnlock()
+	if locale, ok := HumanizeLocales[name]; ok {
+		return locale
+	}
+	return HumanizeLocales[DefaultHumanizeLocale]
+}
+
+func humanizeUnit(locale HumanizeLocale, unit string, n int64, short bool) string {
+	u := locale.Units[unit]
+	if short {
+		return strconv.FormatInt(n, 10) + u.Short
+	}
+	name := u.Other
+	if n == 1 {
+		name = u.One
+	}
+	return strconv.FormatInt(n, 10) + " " + name
+}
+
+// nextBoundary returns the beginning of the unit following the one containing the time.
+func nextBoundary(t time.Time, unit string) time.Time {
+	at := With(t)
+	switch unit {
+	case UnitHour:
+		return at.EndOfHour().Add(time.Nanosecond)
+	case UnitWeek:
+		return at.EndOfWeek().Add(time.Nanosecond)
+	case UnitMonth:
+		return at.EndOfMonth().Add(time.Nanosecond)
+	case UnitQuarter:
+		return at.EndOfQuarter().Add(time.Nanosecond)
+	case UnitHalf:
+		return at.EndOfHalf().Add(time.Nanosecond)
+	case UnitYear:
+		return at.EndOfYear().Add(time.Nanosecond)
+	}
+	return With(at.BeginningOfDay().AddDate(0, 0, 1)).BeginningOfDay()
+}
@@ -1,6 +1,10 @@
 package timex
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 // WeekStartDay set week start day, default is sunday
 var WeekStartDay = time.Sunday
@@ -32,3 +36,171 @@ var TimeFormats = []string{
 	time.RFC1123, time.RFC1123Z, time.RFC3339, time.RFC3339Nano,
 	time.Kitchen, time.Stamp, time.StampMilli, time.StampMicro, time.StampNano,
 }
+
+// Schedule computes the activation times of a recurring job.
+type Schedule interface {
+	// Next returns the first activation time strictly after the time given, or the zero time when none exists.
+	Next(t time.Time) time.Time
+}
+
+// CronSchedule is a parsed cron expression.
+// Fields are bit sets of the values allowed; day of month and day of week keep their L, W and # extensions aside.
+type CronSchedule struct {
+	Spec     string
+	Second   uint64
+	Minute   uint64
+	Hour     uint64
+	Dom      uint64
+	Month    uint64
+	Dow      uint64
+	Location *time.Location
+
+	domStar     bool
+	dowStar     bool
+	domLast     []int // L, L-n: days before the last day of the month
+	domWeekday  []int // nW: the weekday nearest to the day n
+	domLastWeek bool  // LW: the last weekday of the month
+	dowLast     []time.Weekday
+	dowNth      map[time.Weekday][]int
+}
+
+// EverySchedule activates at a fixed interval, e.g: "@every 1h30m".
+type EverySchedule struct {
+	Every time.Duration
+}
+
+// JobFunc is the work of a scheduled job; the context is cancelled when a graceful stop times out.
+type JobFunc func(ctx context.Context)
+
+// CronJobConfig declares a job of the scheduler, e.g: within the YAML of configx.
+type CronJobConfig struct {
+	IsEnabled bool   `json:"enabled" yaml:"enabled"`
+	Name      string `json:"name" yaml:"name"`
+	Spec      string `json:"spec" yaml:"spec"`
+	Timezone  string `json:"timezone,omitempty" yaml:"timezone"`
+	Overlap   string `json:"overlap,omitempty" yaml:"overlap"`
+}
+
+// SchedulerConfig declares the scheduler and its jobs.
+type SchedulerConfig struct {
+	IsEnabled bool            `json:"enabled" yaml:"enabled"`
+	Timezone  string          `json:"timezone,omitempty" yaml:"timezone"`
+	Overlap   string          `json:"overlap,omitempty" yaml:"overlap"`
+	Jobs      []CronJobConfig `json:"jobs,omitempty" yaml:"jobs"`
+}
+
+// CronEntry describes the state of a registered job.
+type CronEntry struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	Overlap string    `json:"overlap"`
+	Next    time.Time `json:"next"`
+	Prev    time.Time `json:"prev,omitempty"`
+	Running int       `json:"running"`
+	Pending int       `json:"pending"`
+	Runs    int64     `json:"runs"`
+	Skipped int64     `json:"skipped"`
+}
+
+type cronEntry struct {
+	conf     CronJobConfig
+	schedule Schedule
+	fn       JobFunc
+	next     time.Time
+	prev     time.Time
+	running  int
+	pending  int
+	runs     int64
+	skipped  int64
+}
+
+type schedulerServiceImpl struct {
+	mutex   sync.Mutex
+	conf    SchedulerConfig
+	entries map[string]*cronEntry
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// RRuleWeekday is a BYDAY value of a recurrence rule, e.g: "MO", or "2TU" and "-1FR" when Nth is set.
+type RRuleWeekday struct {
+	Weekday time.Weekday `json:"weekday"`
+	Nth     int          `json:"nth,omitempty"`
+}
+
+// RRule is a RFC 5545 recurrence rule. Occurrences keep the clock of Dtstart in its location,
+// so that a daily rule at 09:00 stays at 09:00 across daylight saving transitions.
+type RRule struct {
+	Freq       string         `json:"freq"`
+	Interval   int            `json:"interval,omitempty"`
+	Count      int            `json:"count,omitempty"`
+	Until      time.Time      `json:"until,omitempty"`
+	ByDay      []RRuleWeekday `json:"by_day,omitempty"`
+	ByMonthDay []int          `json:"by_month_day,omitempty"`
+	ByMonth    []int          `json:"by_month,omitempty"`
+	BySetPos   []int          `json:"by_set_pos,omitempty"`
+	Wkst       time.Weekday   `json:"wkst"`
+	Dtstart    time.Time      `json:"dtstart"`
+}
+
+// RRuleIterator walks the occurrences of a recurrence rule in order, period by period.
+type RRuleIterator struct {
+	rule    *RRule
+	period  time.Time // the beginning of the current period, civil date in UTC or instant for sub-daily frequencies
+	buffer  []time.Time
+	emitted int
+	empty   int
+	done    bool
+}
+
+// ISODuration is an ISO 8601 duration, e.g: "P1Y2M10DT2H30M".
+// Calendar parts are kept apart from the clock part since their length depends on the date they apply to.
+type ISODuration struct {
+	Negative bool          `json:"negative,omitempty"`
+	Years    int           `json:"years,omitempty"`
+	Months   int           `json:"months,omitempty"`
+	Weeks    int           `json:"weeks,omitempty"`
+	Days     int           `json:"days,omitempty"`
+	Time     time.Duration `json:"time,omitempty"`
+}
+
+type relativeParser struct {
+	now   *Timex
+	t     time.Time
+	words []string
+	pos   int
+}
+
+// Interval is the half-open span of time [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// HumanizeUnit names a unit of time in a locale, e.g: "day", "days", "d".
+type HumanizeUnit struct {
+	One   string `json:"one"`
+	Other string `json:"other"`
+	Short string `json:"short"`
+}
+
+// HumanizeLocale words humanized durations, e.g: "in %s", "%s ago".
+type HumanizeLocale struct {
+	Now       string                  `json:"now"`
+	Past      string                  `json:"past"`
+	Future    string                  `json:"future"`
+	Separator string                  `json:"separator"`
+	Units     map[string]HumanizeUnit `json:"units"`
+}
+
+// HumanizeOptions tunes humanized durations: the locale, the number of units written, and their short form.
+type HumanizeOptions struct {
+	Locale    string `json:"locale,omitempty"`
+	Precision int    `json:"precision,omitempty"`
+	Short     bool   `json:"short,omitempty"`
+}
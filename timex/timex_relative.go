@@ -0,0 +1,450 @@
+package timex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRelative parses a time relative to now, e.g: "now-15m", "3 days ago", "next monday 9am", "end of quarter"
+func ParseRelative(s string) (time.Time, error) {
+	return With(time.Now()).ParseRelative(s)
+}
+
+// MustParseRelative must parse a time relative to now or it will panic
+func MustParseRelative(s string) time.Time {
+	return With(time.Now()).MustParseRelative(s)
+}
+
+// ParseRelative parse a time relative to now based on configuration
+func (config *Config) ParseRelative(s string) (time.Time, error) {
+	return config.With(time.Now()).ParseRelative(s)
+}
+
+// ParseRelative parses a time relative to the time of Timex, in the TimeLocation of its configuration when set.
+// Expressions understood:
+//
+//	now, now-15m, now+1d-2h, now/d, now-1M/M       offsets and rounding, units s m h d w M(onth) y
+//	P1DT2H, -PT15M                                 ISO 8601 durations
+//	today, yesterday, tomorrow, noon, midnight
+//	3 days ago, an hour ago, in 2 weeks, 5 minutes from now, +3d, -2 hours
+//	monday, next monday, last friday, this sunday  weekdays, on or after today when alone
+//	next week, last month, this quarter            periods, shifted by one
+//	beginning of month, start of next week, end of quarter, end of last year
+//	9am, 9:30 pm, 17:00, at 17:00:05               clocks, applied to the date resolved so far
+//
+// Words combine, e.g: "yesterday at 17:00", "next monday 9am", "2 days ago noon".
+// Expressions that are not relative are parsed as absolute times by Parse.
+func (now *Timex) ParseRelative(s string) (time.Time, error) {
+	return now.parseRelative(s, true)
+}
+
+// parseRelative parses a time relative to the time of Timex, falling back to the absolute formats when absolute is set.
+func (now *Timex) parseRelative(s string, absolute bool) (time.Time, error) {
+	base := now.Time
+	if now.Config != nil && now.TimeLocation != nil {
+		base = base.In(now.TimeLocation)
+	}
+	current := &Timex{Time: base, Config: now.Config}
+	expr := strings.TrimSpace(s)
+	if expr == "" {
+		return time.Time{}, ErrorRelativeEmpty
+	}
+	if m := NowMathRegexp.FindStringSubmatch(expr); m != nil {
+		t := base
+		for _, term := range NowMathTermRegexp.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(term[2])
+			if term[1] == "-" {
+				n = -n
+			}
+			t = shiftUnit(t, NowMathUnits[term[3]], n)
+		}
+		if m[2] != "" {
+			t = current.beginningOf(t, NowMathUnits[m[2]])
+		}
+		return t, nil
+	}
+	if ISODurationRegexp.MatchString(expr) {
+		d, err := ParseISODuration(expr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return d.AddTo(base), nil
+	}
+	p := &relativeParser{now: current, t: base, words: strings.Fields(strings.ToLower(expr))}
+	t, err := p.parse()
+	if err != nil {
+		if !absolute {
+			return time.Time{}, err
+		}
+		if t, e := current.parseAbsolute(expr); e == nil {
+			return t, nil
+		}
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// MustParseRelative must parse a time relative to the time of Timex or it will panic
+func (now *Timex) MustParseRelative(s string) time.Time {
+	t, err := now.ParseRelative(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseISODuration parses an ISO 8601 duration, e.g: "P1Y2M10DT2H30M", "PT0.5S", or "-P1W" negated.
+func ParseISODuration(s string) (ISODuration, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	m := ISODurationRegexp.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") || strings.HasSuffix(value, "P") {
+		return ISODuration{}, fmt.Errorf("Can't parse string as ISO 8601 duration: %v", s)
+	}
+	d := ISODuration{Negative: m[1] == "-"}
+	d.Years, _ = strconv.Atoi(m[2])
+	d.Months, _ = strconv.Atoi(m[3])
+	d.Weeks, _ = strconv.Atoi(m[4])
+	d.Days, _ = strconv.Atoi(m[5])
+	hours, _ := strconv.Atoi(m[6])
+	minutes, _ := strconv.Atoi(m[7])
+	seconds, _ := strconv.ParseFloat(strings.Replace(m[8], ",", ".", 1), 64)
+	d.Time = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+	return d, nil
+}
+
+// AddTo adds the duration to the time, the calendar parts on its wall clock, then the clock part.
+func (d ISODuration) AddTo(t time.Time) time.Time {
+	sign := 1
+	if d.Negative {
+		sign = -1
+	}
+	t = t.AddDate(sign*d.Years, sign*d.Months, sign*(d.Weeks*7+d.Days))
+	return t.Add(time.Duration(sign) * d.Time)
+}
+
+// String returns the duration in ISO 8601, e.g: "P1DT2H".
+func (d ISODuration) String() string {
+	var b strings.Builder
+	if d.Negative {
+		b.WriteString("-")
+	}
+	b.WriteString("P")
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{d.Years, "Y"}, {d.Months, "M"}, {d.Weeks, "W"}, {d.Days, "D"}} {
+		if part.value != 0 {
+			b.WriteString(strconv.Itoa(part.value) + part.unit)
+		}
+	}
+	if d.Time != 0 {
+		b.WriteString("T")
+		rest := d.Time
+		if h := rest / time.Hour; h != 0 {
+			b.WriteString(strconv.Itoa(int(h)) + "H")
+			rest -= h * time.Hour
+		}
+		if m := rest / time.Minute; m != 0 {
+			b.WriteString(strconv.Itoa(int(m)) + "M")
+			rest -= m * time.Minute
+		}
+		if rest != 0 {
+			b.WriteString(strconv.FormatFloat(rest.Seconds(), 'f', -1, 64) + "S")
+		}
+	}
+	if b.Len() == 1 || (d.Negative && b.Len() == 2) {
+		b.WriteString("T0S")
+	}
+	return b.String()
+}
+
+func (p *relativeParser) parse() (time.Time, error) {
+	for p.pos < len(p.words) {
+		word := p.next()
+		switch {
+		case word == "now" || word == "at" || word == "and" || word == "on" || word == ",":
+		case word == "today":
+			p.t = p.now.beginningOf(p.t, UnitDay)
+		case word == "yesterday":
+			p.t = p.now.beginningOf(p.t, UnitDay).AddDate(0, 0, -1)
+		case word == "tomorrow":
+			p.t = p.now.beginningOf(p.t, UnitDay).AddDate(0, 0, 1)
+		case word == "noon":
+			p.t = p.clock(12, 0, 0)
+		case word == "midnight":
+			p.t = p.now.beginningOf(p.t, UnitDay)
+		case word == "in":
+			n, unit, err := p.amount(p.next())
+			if err != nil {
+				return time.Time{}, err
+			}
+			p.t = shiftUnit(p.t, unit, n)
+		case word == "next" || word == "last" || word == "previous" || word == "this":
+			if err := p.period(word); err != nil {
+				return time.Time{}, err
+			}
+		case word == "beginning" || word == "start" || word == "end":
+			if p.peek() != "of" {
+				return time.Time{}, fmt.Errorf("Can't parse relative time: expected 'of' after '%s'", word)
+			}
+			p.next()
+			if err := p.boundary(word == "end"); err != nil {
+				return time.Time{}, err
+			}
+		case isRelativeWeekday(word):
+			days := (int(RelativeWeekdays[word]) - int(p.t.Weekday()) + 7) % 7
+			p.t = p.now.beginningOf(p.t, UnitDay).AddDate(0, 0, days)
+		default:
+			if ok, err := p.clockOf(word); ok || err != nil {
+				if err != nil {
+					return time.Time{}, err
+				}
+				continue
+			}
+			n, unit, err := p.amount(word)
+			if err != nil {
+				return time.Time{}, err
+			}
+			switch p.peek() {
+			case "ago", "before":
+				p.next()
+				n = -n
+			case "from", "after":
+				p.next()
+				if p.peek() == "now" {
+					p.next()
+				}
+			case "later", "hence":
+				p.next()
+			}
+			p.t = shiftUnit(p.t, unit, n)
+		}
+	}
+	return p.t, nil
+}
+
+// amount parses a signed quantity and its unit, e.g: "3 days", "an hour", "+3d", "-2 hours".
+func (p *relativeParser) amount(word string) (int, string, error) {
+	if word == "a" || word == "an" {
+		word = "1"
+	}
+	sign := 1
+	if strings.HasPrefix(word, "+") || strings.HasPrefix(word, "-") {
+		if word[0] == '-' {
+			sign = -1
+		}
+		word = word[1:]
+	}
+	digits := 0
+	for digits < len(word) && word[digits] >= '0' && word[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 {
+		return 0, "", fmt.Errorf("Can't parse relative time: unexpected '%s'", word)
+	}
+	n, err := strconv.Atoi(word[:digits])
+	if err != nil {
+		return 0, "", fmt.Errorf("Can't parse relative time: invalid amount '%s'", word)
+	}
+	name := word[digits:]
+	if name == "" {
+		name = p.next()
+	}
+	unit, ok := RelativeUnits[name]
+	if !ok {
+		return 0, "", fmt.Errorf("Can't parse relative time: unknown unit '%s'", name)
+	}
+	return sign * n, unit, nil
+}
+
+// period shifts by one weekday or period, e.g: "next monday", "last month", "this week".
+func (p *relativeParser) period(direction string) error {
+	word := p.next()
+	if w, ok := RelativeWeekdays[word]; ok {
+		day := p.now.beginningOf(p.t, UnitDay)
+		switch direction {
+		case "next":
+			days := (int(w) - int(day.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			p.t = day.AddDate(0, 0, days)
+		case "this":
+			p.t = p.now.beginningOf(day, UnitWeek)
+			p.t = p.t.AddDate(0, 0, (int(w)-int(p.t.Weekday())+7)%7)
+		default:
+			days := (int(day.Weekday()) - int(w) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			p.t = day.AddDate(0, 0, -days)
+		}
+		return nil
+	}
+	unit, ok := RelativeUnits[word]
+	if !ok {
+		return fmt.Errorf("Can't parse relative time: unexpected '%s' after '%s'", word, direction)
+	}
+	switch direction {
+	case "next":
+		p.t = shiftUnit(p.t, unit, 1)
+	case "last", "previous":
+		p.t = shiftUnit(p.t, unit, -1)
+	}
+	return nil
+}
+
+// boundary moves to the beginning or the end of a period, e.g: "end of month", "start of next week".
+func (p *relativeParser) boundary(end bool) error {
+	word := p.next()
+	switch word {
+	case "the":
+		word = p.next()
+	case "next", "last", "previous", "this":
+		if err := p.period(word); err != nil {
+			return err
+		}
+		word = p.words[p.pos-1]
+	}
+	unit, ok := RelativeUnits[word]
+	if !ok {
+		return fmt.Errorf("Can't parse relative time: unknown period '%s'", word)
+	}
+	if end {
+		p.t = p.now.endOf(p.t, unit)
+	} else {
+		p.t = p.now.beginningOf(p.t, unit)
+	}
+	return nil
+}
+
+// clockOf applies a clock word to the date resolved so far, e.g: "9am", "17:00", or "9" followed by "pm".
+// Bare numbers are not clocks, so that "3 days" remains an amount.
+func (p *relativeParser) clockOf(word string) (bool, error) {
+	if suffix := p.peek(); (suffix == "am" || suffix == "pm") && ClockRegexp.MatchString(word) {
+		word += p.next()
+	}
+	m := ClockRegexp.FindStringSubmatch(word)
+	if m == nil || (m[2] == "" && m[4] == "") {
+		return false, nil
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	if m[4] != "" {
+		if h < 1 || h > 12 {
+			return false, fmt.Errorf("Can't parse relative time: invalid clock '%s'", word)
+		}
+		h %= 12
+		if m[4] == "pm" {
+			h += 12
+		}
+	}
+	if h > 23 || min > 59 || sec > 59 {
+		return false, fmt.Errorf("Can't parse relative time: invalid clock '%s'", word)
+	}
+	p.t = p.clock(h, min, sec)
+	return true, nil
+}
+
+func (p *relativeParser) clock(h, min, sec int) time.Time {
+	y, m, d := p.t.Date()
+	return time.Date(y, m, d, h, min, sec, 0, p.t.Location())
+}
+
+func (p *relativeParser) next() string {
+	if p.pos >= len(p.words) {
+		p.pos++
+		return ""
+	}
+	word := p.words[p.pos]
+	p.pos++
+	return word
+}
+
+func (p *relativeParser) peek() string {
+	if p.pos >= len(p.words) {
+		return ""
+	}
+	return p.words[p.pos]
+}
+
+func isRelativeWeekday(word string) bool {
+	_, ok := RelativeWeekdays[word]
+	return ok
+}
+
+// beginningOf returns the beginning of the unit containing the time, weeks starting on the WeekStartDay of Timex.
+func (now *Timex) beginningOf(t time.Time, unit string) time.Time {
+	at := &Timex{Time: t, Config: now.Config}
+	switch unit {
+	case UnitSecond:
+		return t.Truncate(time.Second)
+	case UnitMinute:
+		return at.BeginningOfMinute()
+	case UnitHour:
+		return at.BeginningOfHour()
+	case UnitWeek:
+		return at.BeginningOfWeek()
+	case UnitMonth:
+		return at.BeginningOfMonth()
+	case UnitQuarter:
+		return at.BeginningOfQuarter()
+	case UnitHalf:
+		return at.BeginningOfHalf()
+	case UnitYear:
+		return at.BeginningOfYear()
+	}
+	return at.BeginningOfDay()
+}
+
+// endOf returns the end of the unit containing the time.
+func (now *Timex) endOf(t time.Time, unit string) time.Time {
+	at := &Timex{Time: t, Config: now.Config}
+	switch unit {
+	case UnitSecond:
+		return t.Truncate(time.Second).Add(time.Second - time.Nanosecond)
+	case UnitMinute:
+		return at.EndOfMinute()
+	case UnitHour:
+		return at.EndOfHour()
+	case UnitWeek:
+		return at.EndOfWeek()
+	case UnitMonth:
+		return at.EndOfMonth()
+	case UnitQuarter:
+		return at.EndOfQuarter()
+	case UnitHalf:
+		return at.EndOfHalf()
+	case UnitYear:
+		return at.EndOfYear()
+	}
+	return at.EndOfDay()
+}
+
+// shiftUnit moves the time by n units, days and longer units on its wall clock.
+func shiftUnit(t time.Time, unit string, n int) time.Time {
+	switch unit {
+	case UnitSecond:
+		return t.Add(time.Duration(n) * time.Second)
+	case UnitMinute:
+		return t.Add(time.Duration(n) * time.Minute)
+	case UnitHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case UnitDay:
+		return t.AddDate(0, 0, n)
+	case UnitWeek:
+		return t.AddDate(0, 0, 7*n)
+	case UnitMonth:
+		return t.AddDate(0, n, 0)
+	case UnitQuarter:
+		return t.AddDate(0, 3*n, 0)
+	case UnitHalf:
+		return t.AddDate(0, 6*n, 0)
+	case UnitYear:
+		return t.AddDate(n, 0, 0)
+	}
+	return t
+}
@@ -0,0 +1,717 @@
+package timex
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func NewRRule() *RRule {
+	r := &RRule{
+		Interval: 1,
+		Wkst:     time.Monday,
+	}
+	return r
+}
+
+func (r *RRule) SetFreq(value string) *RRule {
+	r.Freq = strings.ToUpper(strings.TrimSpace(value))
+	return r
+}
+
+func (r *RRule) SetInterval(value int) *RRule {
+	r.Interval = value
+	return r
+}
+
+func (r *RRule) SetCount(value int) *RRule {
+	r.Count = value
+	return r
+}
+
+func (r *RRule) SetUntil(value time.Time) *RRule {
+	r.Until = value
+	return r
+}
+
+func (r *RRule) SetByDay(values []RRuleWeekday) *RRule {
+	r.ByDay = values
+	return r
+}
+
+func (r *RRule) AppendByDay(values ...RRuleWeekday) *RRule {
+	r.ByDay = append(r.ByDay, values...)
+	return r
+}
+
+func (r *RRule) SetByMonthDay(values []int) *RRule {
+	r.ByMonthDay = values
+	return r
+}
+
+func (r *RRule) AppendByMonthDay(values ...int) *RRule {
+	r.ByMonthDay = append(r.ByMonthDay, values...)
+	return r
+}
+
+func (r *RRule) SetByMonth(values []int) *RRule {
+	r.ByMonth = values
+	return r
+}
+
+func (r *RRule) AppendByMonth(values ...int) *RRule {
+	r.ByMonth = append(r.ByMonth, values...)
+	return r
+}
+
+func (r *RRule) SetBySetPos(values []int) *RRule {
+	r.BySetPos = values
+	return r
+}
+
+func (r *RRule) AppendBySetPos(values ...int) *RRule {
+	r.BySetPos = append(r.BySetPos, values...)
+	return r
+}
+
+func (r *RRule) SetWkst(value time.Weekday) *RRule {
+	r.Wkst = value
+	return r
+}
+
+func (r *RRule) SetDtstart(value time.Time) *RRule {
+	r.Dtstart = value
+	return r
+}
+
+// RRuleValidator checks the rule against RFC 5545, defaulting the interval to 1.
+func RRuleValidator(r *RRule) error {
+	r.Freq = strings.ToUpper(strings.TrimSpace(r.Freq))
+	if _, ok := RRuleFrequencies[r.Freq]; !ok {
+		return ErrorRRuleFreq
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("Recurrence rule INTERVAL '%d' must be positive", r.Interval)
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("Recurrence rule COUNT '%d' must be positive", r.Count)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return ErrorRRuleCountUntil
+	}
+	if r.Dtstart.IsZero() {
+		return ErrorRRuleDtstart
+	}
+	for _, v := range r.ByDay {
+		if v.Nth == 0 {
+			continue
+		}
+		if r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return fmt.Errorf("Recurrence rule BYDAY '%s' must not have an occurrence unless FREQ is MONTHLY or YEARLY", v)
+		}
+		if v.Nth < -53 || v.Nth > 53 {
+			return fmt.Errorf("Recurrence rule BYDAY '%s' has an occurrence out of range", v)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == FreqWeekly {
+		return fmt.Errorf("Recurrence rule BYMONTHDAY must not be set when FREQ is WEEKLY")
+	}
+	for _, v := range r.ByMonthDay {
+		if v == 0 || v < -31 || v > 31 {
+			return fmt.Errorf("Recurrence rule BYMONTHDAY '%d' is out of range", v)
+		}
+	}
+	for _, v := range r.ByMonth {
+		if v < 1 || v > 12 {
+			return fmt.Errorf("Recurrence rule BYMONTH '%d' is out of range", v)
+		}
+	}
+	for _, v := range r.BySetPos {
+		if v == 0 || v < -366 || v > 366 {
+			return fmt.Errorf("Recurrence rule BYSETPOS '%d' is out of range", v)
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return fmt.Errorf("Recurrence rule BYSETPOS must be used with another BYxxx rule part")
+	}
+	return nil
+}
+
+// ParseRRule parses a recurrence rule, e.g: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+// optionally prefixed with "RRULE:" and preceded by a DTSTART line, e.g:
+//
+//	DTSTART;TZID=America/New_York:20240105T090000
+//	RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU
+//
+// A DTSTART line takes precedence over the dtstart given; without TZID nor a "Z" suffix it is read in the local timezone.
+// UNTIL is read in the timezone of DTSTART unless it has a "Z" suffix; a date-only UNTIL includes its whole day.
+func ParseRRule(value string, dtstart time.Time) (*RRule, error) {
+	r := NewRRule().SetDtstart(dtstart)
+	var rule string
+	for _, line := range strings.FieldsFunc(value, func(c rune) bool { return c == '\n' || c == '\r' }) {
+		line = strings.TrimSpace(line)
+		upper := strings.ToUpper(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(upper, "DTSTART"):
+			t, err := parseRRuleDtstart(line)
+			if err != nil {
+				return nil, err
+			}
+			r.Dtstart = t
+		case strings.HasPrefix(upper, "RRULE:"):
+			rule = line[len("RRULE:"):]
+		default:
+			rule = line
+		}
+	}
+	if strings.TrimSpace(rule) == "" {
+		return nil, ErrorRRuleFreq
+	}
+	var until string
+	for _, part := range strings.Split(rule, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("Recurrence rule part '%s' is malformed, expected NAME=VALUE", part)
+		}
+		name, v := strings.ToUpper(strings.TrimSpace(pair[0])), strings.ToUpper(strings.TrimSpace(pair[1]))
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = v
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(v)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(v)
+		case "UNTIL":
+			until = v
+		case "BYDAY":
+			for _, s := range strings.Split(v, ",") {
+				d, e := ParseRRuleWeekday(s)
+				if e != nil {
+					return nil, e
+				}
+				r.ByDay = append(r.ByDay, d)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseRRuleInts(v)
+		case "BYMONTH":
+			r.ByMonth, err = parseRRuleInts(v)
+		case "BYSETPOS":
+			r.BySetPos, err = parseRRuleInts(v)
+		case "WKST":
+			w, ok := RRuleWeekdays[v]
+			if !ok {
+				return nil, fmt.Errorf("Recurrence rule WKST '%s' is not a weekday", v)
+			}
+			r.Wkst = w
+		default:
+			return nil, fmt.Errorf("Recurrence rule part '%s' is not supported", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Recurrence rule %s '%s' is not a number", name, v)
+		}
+	}
+	if until != "" {
+		loc := time.Local
+		if !r.Dtstart.IsZero() {
+			loc = r.Dtstart.Location()
+		}
+		t, err := parseRRuleTime(until, loc)
+		if err != nil {
+			return nil, fmt.Errorf("Recurrence rule UNTIL '%s' is invalid: %v", until, err)
+		}
+		if len(until) == len(RRuleDateLayout) {
+			t = With(t).EndOfDay()
+		}
+		r.Until = t
+	}
+	if err := RRuleValidator(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MustParseRRule parses a recurrence rule, panicking when it is invalid.
+func MustParseRRule(value string, dtstart time.Time) *RRule {
+	r, err := ParseRRule(value, dtstart)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ParseRRuleWeekday parses a BYDAY value, e.g: "MO", "2TU" or "-1FR".
+func ParseRRuleWeekday(value string) (RRuleWeekday, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return RRuleWeekday{}, fmt.Errorf("Recurrence rule BYDAY '%s' is not a weekday", value)
+	}
+	w, ok := RRuleWeekdays[value[len(value)-2:]]
+	if !ok {
+		return RRuleWeekday{}, fmt.Errorf("Recurrence rule BYDAY '%s' is not a weekday", value)
+	}
+	d := RRuleWeekday{Weekday: w}
+	if n := value[:len(value)-2]; n != "" {
+		nth, err := strconv.Atoi(n)
+		if err != nil || nth == 0 {
+			return RRuleWeekday{}, fmt.Errorf("Recurrence rule BYDAY '%s' has an invalid occurrence", value)
+		}
+		d.Nth = nth
+	}
+	return d, nil
+}
+
+// String returns the BYDAY value, e.g: "-1FR".
+func (d RRuleWeekday) String() string {
+	code := ""
+	for k, v := range RRuleWeekdays {
+		if v == d.Weekday {
+			code = k
+		}
+	}
+	if d.Nth == 0 {
+		return code
+	}
+	return strconv.Itoa(d.Nth) + code
+}
+
+// String returns the rule value, without DTSTART, e.g: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU".
+// UNTIL is written in UTC.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(RRuleDateTimeUTCLayout))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinRRuleInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinRRuleInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, v := range r.ByDay {
+			days[i] = v.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinRRuleInts(r.BySetPos))
+	}
+	if r.Wkst != time.Monday {
+		parts = append(parts, "WKST="+RRuleWeekday{Weekday: r.Wkst}.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterator returns an iterator over the occurrences of the rule, from Dtstart on.
+// The iterator of an invalid rule yields no occurrence.
+func (r *RRule) Iterator() *RRuleIterator {
+	it := &RRuleIterator{rule: r}
+	if err := RRuleValidator(r); err != nil {
+		it.done = true
+		return it
+	}
+	it.period = r.start()
+	return it
+}
+
+// Next returns the next occurrence, false once the occurrences are exhausted.
+func (it *RRuleIterator) Next() (time.Time, bool) {
+	r := it.rule
+	for !it.done {
+		if len(it.buffer) > 0 {
+			t := it.buffer[0]
+			it.buffer = it.buffer[1:]
+			if !r.Until.IsZero() && t.After(r.Until) {
+				it.done = true
+				break
+			}
+			it.emitted++
+			if r.Count > 0 && it.emitted >= r.Count {
+				it.done = true
+			}
+			return t, true
+		}
+		if it.empty >= RRuleMaxEmptyPeriods || it.period.Year() > 9999 {
+			it.done = true
+			break
+		}
+		if !r.Until.IsZero() && r.periodStart(it.period).After(r.Until) {
+			it.done = true
+			break
+		}
+		it.buffer = r.expand(it.period)
+		if len(it.buffer) == 0 {
+			it.empty++
+		} else {
+			it.empty = 0
+		}
+		it.period = r.advance(it.period, 1)
+	}
+	return time.Time{}, false
+}
+
+// Between returns the occurrences within [start, end], in the location of Dtstart.
+func (r *RRule) Between(start, end time.Time) []time.Time {
+	var occurrences []time.Time
+	it := r.iteratorFrom(start)
+	for {
+		t, ok := it.Next()
+		if !ok || t.After(end) {
+			break
+		}
+		if !t.Before(start) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// Next returns the first occurrence strictly after the time given, or the zero time when none exists,
+// so that a rule is a Schedule of the scheduler.
+func (r *RRule) Next(t time.Time) time.Time {
+	it := r.iteratorFrom(t)
+	for {
+		next, ok := it.Next()
+		if !ok {
+			return time.Time{}
+		}
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
+// All returns the first occurrences of the rule, at most limit of them.
+func (r *RRule) All(limit int) []time.Time {
+	var occurrences []time.Time
+	it := r.Iterator()
+	for len(occurrences) < limit {
+		t, ok := it.Next()
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, t)
+	}
+	return occurrences
+}
+
+// iteratorFrom returns an iterator skipping the whole periods before the time, when COUNT does not require counting them.
+func (r *RRule) iteratorFrom(at time.Time) *RRuleIterator {
+	it := r.Iterator()
+	if it.done || r.Count > 0 || !at.After(r.Dtstart) {
+		return it
+	}
+	var periods int64
+	switch r.Freq {
+	case FreqDaily:
+		periods = int64(civil(at.In(r.Dtstart.Location())).Sub(it.period)/(24*time.Hour)) / int64(r.Interval)
+	case FreqWeekly:
+		periods = int64(civil(at.In(r.Dtstart.Location())).Sub(it.period)/(7*24*time.Hour)) / int64(r.Interval)
+	case FreqHourly, FreqMinutely, FreqSecondly:
+		periods = int64(at.Sub(it.period) / (RRuleFrequencies[r.Freq] * time.Duration(r.Interval)))
+	}
+	if periods > 1 {
+		it.period = r.advance(it.period, periods-1)
+	}
+	return it
+}
+
+// start returns the period of Dtstart.
+func (r *RRule) start() time.Time {
+	d := civil(r.Dtstart)
+	switch r.Freq {
+	case FreqYearly:
+		return time.Date(d.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	case FreqMonthly:
+		return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case FreqWeekly:
+		return d.AddDate(0, 0, -((int(d.Weekday()) - int(r.Wkst) + 7) % 7))
+	case FreqDaily:
+		return d
+	}
+	return r.Dtstart
+}
+
+// advance moves the period by n intervals.
+func (r *RRule) advance(period time.Time, n int64) time.Time {
+	step := int(n) * r.Interval
+	switch r.Freq {
+	case FreqYearly:
+		return period.AddDate(step, 0, 0)
+	case FreqMonthly:
+		return period.AddDate(0, step, 0)
+	case FreqWeekly:
+		return period.AddDate(0, 0, 7*step)
+	case FreqDaily:
+		return period.AddDate(0, 0, step)
+	}
+	return period.Add(RRuleFrequencies[r.Freq] * time.Duration(n) * time.Duration(r.Interval))
+}
+
+// periodStart returns the instant the period begins at.
+func (r *RRule) periodStart(period time.Time) time.Time {
+	if RRuleFrequencies[r.Freq] > 0 {
+		return period
+	}
+	return time.Date(period.Year(), period.Month(), period.Day(), 0, 0, 0, 0, r.Dtstart.Location())
+}
+
+// expand returns the occurrences of the period from Dtstart on, sorted, BYSETPOS applied.
+// Dates of the day-or-longer frequencies take the clock of Dtstart on their wall clock in its location;
+// a clock skipped by a daylight saving transition is moved forward by the length of the gap.
+func (r *RRule) expand(period time.Time) []time.Time {
+	loc := r.Dtstart.Location()
+	var set []time.Time
+	if unit := RRuleFrequencies[r.Freq]; unit > 0 {
+		if r.limit(civil(period.In(loc))) {
+			set = append(set, period.In(loc))
+		}
+	} else {
+		var days []time.Time
+		switch r.Freq {
+		case FreqYearly:
+			days = r.yearDays(period.Year())
+		case FreqMonthly:
+			if len(r.ByMonth) == 0 || containsInt(r.ByMonth, int(period.Month())) {
+				days = r.monthDays(period.Year(), period.Month())
+			}
+		case FreqWeekly:
+			for i := 0; i < 7; i++ {
+				d := period.AddDate(0, 0, i)
+				if r.matchWeekday(d.Weekday()) && (len(r.ByMonth) == 0 || containsInt(r.ByMonth, int(d.Month()))) {
+					days = append(days, d)
+				}
+			}
+		case FreqDaily:
+			if r.limit(period) {
+				days = append(days, period)
+			}
+		}
+		h, m, s := r.Dtstart.Clock()
+		for _, d := range days {
+			set = append(set, wallClock(d.Year(), d.Month(), d.Day(), h, m, s, r.Dtstart.Nanosecond(), loc))
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].Before(set[j]) })
+	if len(r.BySetPos) > 0 && len(set) > 0 {
+		var picked []time.Time
+		for _, pos := range r.BySetPos {
+			i := pos - 1
+			if pos < 0 {
+				i = len(set) + pos
+			}
+			if i >= 0 && i < len(set) {
+				picked = append(picked, set[i])
+			}
+		}
+		sort.Slice(picked, func(i, j int) bool { return picked[i].Before(picked[j]) })
+		set = picked
+	}
+	occurrences := set[:0]
+	for i, t := range set {
+		if t.Before(r.Dtstart) || (i > 0 && t.Equal(set[i-1])) {
+			continue
+		}
+		occurrences = append(occurrences, t)
+	}
+	return occurrences
+}
+
+// yearDays returns the dates of the year matching the rule: BYDAY occurrences count within the year
+// unless BYMONTH or BYMONTHDAY is set, in which case they count within the month.
+func (r *RRule) yearDays(year int) []time.Time {
+	var days []time.Time
+	if len(r.ByDay) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		total := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		for i := 1; i <= total; i++ {
+			d := first.AddDate(0, 0, i-1)
+			if r.matchNthWeekday(d.Weekday(), i, total) {
+				days = append(days, d)
+			}
+		}
+		return days
+	}
+	months := r.ByMonth
+	if len(months) == 0 {
+		if len(r.ByMonthDay) > 0 {
+			months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		} else {
+			months = []int{int(r.Dtstart.Month())}
+		}
+	}
+	for _, m := range months {
+		days = append(days, r.monthDays(year, time.Month(m))...)
+	}
+	return days
+}
+
+// monthDays returns the dates of the month matching BYMONTHDAY and BYDAY, or the day of Dtstart when neither is set.
+// Dates that do not exist, e.g: the 31st of a 30-day month, are ignored.
+func (r *RRule) monthDays(year int, month time.Month) []time.Time {
+	last := daysIn(year, month)
+	var days []time.Time
+	for day := 1; day <= last; day++ {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 && day != r.Dtstart.Day() {
+			continue
+		}
+		if len(r.ByMonthDay) > 0 && !r.matchMonthDay(day, last) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !r.matchNthWeekday(d.Weekday(), day, last) {
+			continue
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+// limit checks the date against BYMONTH, BYMONTHDAY and BYDAY, used as filters by the shorter frequencies.
+func (r *RRule) limit(d time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(d.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !r.matchMonthDay(d.Day(), daysIn(d.Year(), d.Month())) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchWeekday(d.Weekday()) {
+		return false
+	}
+	return true
+}
+
+func (r *RRule) matchMonthDay(day, last int) bool {
+	for _, v := range r.ByMonthDay {
+		if v == day || (v < 0 && last+v+1 == day) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday checks the weekday against BYDAY, or the weekday of Dtstart when BYDAY is not set.
+func (r *RRule) matchWeekday(w time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return w == r.Dtstart.Weekday()
+	}
+	for _, v := range r.ByDay {
+		if v.Weekday == w {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNthWeekday checks the weekday of the index-th day of a span of total days against BYDAY and its occurrences.
+func (r *RRule) matchNthWeekday(w time.Weekday, index, total int) bool {
+	for _, v := range r.ByDay {
+		if v.Weekday != w {
+			continue
+		}
+		if v.Nth == 0 ||
+			(v.Nth > 0 && (index-1)/7+1 == v.Nth) ||
+			(v.Nth < 0 && (total-index)/7+1 == -v.Nth) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRRuleDtstart parses a DTSTART line, e.g: "DTSTART;TZID=Europe/Paris:20240105T090000".
+func parseRRuleDtstart(line string) (time.Time, error) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("Recurrence rule DTSTART '%s' is malformed", line)
+	}
+	loc := time.Local
+	for _, param := range strings.Split(line[:i], ";")[1:] {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) == 2 && strings.EqualFold(pair[0], "TZID") {
+			l, err := time.LoadLocation(pair[1])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("Recurrence rule DTSTART '%s' has an invalid timezone: %v", line, err)
+			}
+			loc = l
+		}
+	}
+	t, err := parseRRuleTime(strings.TrimSpace(line[i+1:]), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Recurrence rule DTSTART '%s' is invalid: %v", line, err)
+	}
+	return t, nil
+}
+
+// parseRRuleTime parses a date, a date-time in the location, or a UTC date-time.
+func parseRRuleTime(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(RRuleDateTimeUTCLayout, value)
+	case len(value) == len(RRuleDateLayout):
+		return time.ParseInLocation(RRuleDateLayout, value, loc)
+	}
+	return time.ParseInLocation(RRuleDateTimeLayout, value, loc)
+}
+
+func parseRRuleInts(value string) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func joinRRuleInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// wallClock returns the time of the wall clock in the location; a clock skipped by a daylight saving transition
+// is read with the offset before the transition, as RFC 5545 requires, e.g: 02:30 becomes 03:30.
+func wallClock(year int, month time.Month, day, hour, min, sec, nsec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, sec, nsec, loc)
+	if t.Hour() == hour && t.Minute() == min && t.Second() == sec {
+		return t
+	}
+	naive := time.Date(year, month, day, hour, min, sec, nsec, time.UTC)
+	_, offset := t.Add(-24 * time.Hour).Zone()
+	return naive.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// civil returns the date of the time on its wall clock, as midnight UTC.
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,430 @@
+package timex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SchedulerService interface {
+	AddFunc(name, spec string, fn JobFunc) error
+	AddJob(conf CronJobConfig, fn JobFunc) error
+	Remove(name string) bool
+	Entries() []CronEntry
+	Entry(name string) (CronEntry, bool)
+	Start()
+	Stop(ctx context.Context) error
+	IsRunning() bool
+	Config() SchedulerConfig
+}
+
+func NewCronJobConfig() *CronJobConfig {
+	c := &CronJobConfig{}
+	return c
+}
+
+func (c *CronJobConfig) SetEnabled(value bool) *CronJobConfig {
+	c.IsEnabled = value
+	return c
+}
+
+func (c *CronJobConfig) SetName(value string) *CronJobConfig {
+	c.Name = strings.TrimSpace(value)
+	return c
+}
+
+func (c *CronJobConfig) SetSpec(value string) *CronJobConfig {
+	c.Spec = strings.TrimSpace(value)
+	return c
+}
+
+func (c *CronJobConfig) SetTimezone(value string) *CronJobConfig {
+	c.Timezone = strings.TrimSpace(value)
+	return c
+}
+
+func (c *CronJobConfig) SetOverlap(value string) *CronJobConfig {
+	c.Overlap = strings.ToLower(strings.TrimSpace(value))
+	return c
+}
+
+// Schedule parses the expression of the job, evaluated in its timezone, or else in the timezone given.
+func (c *CronJobConfig) Schedule(timezone string) (Schedule, error) {
+	if c.Timezone != "" {
+		timezone = c.Timezone
+	}
+	var loc *time.Location
+	if timezone != "" {
+		l, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Cron job '%s' has an invalid timezone: %v", c.Name, err)
+		}
+		loc = l
+	}
+	return ParseCronIn(c.Spec, loc)
+}
+
+func CronJobConfigValidator(c *CronJobConfig) error {
+	if c.Name == "" {
+		return ErrorCronJobName
+	}
+	if c.Spec == "" {
+		return fmt.Errorf("Cron job '%s': %v", c.Name, ErrorCronEmpty)
+	}
+	if c.Overlap != "" && !OverlapPolicies[c.Overlap] {
+		return fmt.Errorf("Cron job '%s': %v", c.Name, ErrorCronOverlap)
+	}
+	if _, err := c.Schedule(""); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewSchedulerConfig() *SchedulerConfig {
+	s := &SchedulerConfig{}
+	return s
+}
+
+func (s *SchedulerConfig) SetEnabled(value bool) *SchedulerConfig {
+	s.IsEnabled = value
+	return s
+}
+
+func (s *SchedulerConfig) SetTimezone(value string) *SchedulerConfig {
+	s.Timezone = strings.TrimSpace(value)
+	return s
+}
+
+func (s *SchedulerConfig) SetOverlap(value string) *SchedulerConfig {
+	s.Overlap = strings.ToLower(strings.TrimSpace(value))
+	return s
+}
+
+func (s *SchedulerConfig) SetJobs(values []CronJobConfig) *SchedulerConfig {
+	s.Jobs = values
+	return s
+}
+
+func (s *SchedulerConfig) AppendJobs(values ...CronJobConfig) *SchedulerConfig {
+	s.Jobs = append(s.Jobs, values...)
+	return s
+}
+
+// SchedulerConfigValidator checks the scheduler and its jobs, defaulting the overlap policy to skip.
+func SchedulerConfigValidator(s *SchedulerConfig) error {
+	if s.Overlap == "" {
+		s.Overlap = OverlapSkip
+	}
+	if !OverlapPolicies[s.Overlap] {
+		return ErrorCronOverlap
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("Scheduler has an invalid timezone: %v", err)
+		}
+	}
+	names := make(map[string]bool, len(s.Jobs))
+	for i := range s.Jobs {
+		if err := CronJobConfigValidator(&s.Jobs[i]); err != nil {
+			return err
+		}
+		if names[s.Jobs[i].Name] {
+			return fmt.Errorf("Cron job '%s': %v", s.Jobs[i].Name, ErrorCronJobExists)
+		}
+		names[s.Jobs[i].Name] = true
+	}
+	return nil
+}
+
+func GetSchedulerConfigSample() *SchedulerConfig {
+	s := NewSchedulerConfig().
+		SetEnabled(true).
+		SetTimezone("Asia/Ho_Chi_Minh").
+		SetOverlap(OverlapSkip).
+		AppendJobs(
+			*NewCronJobConfig().SetEnabled(true).SetName("daily_report").SetSpec("0 7 * * MON-FRI"),
+			*NewCronJobConfig().SetEnabled(true).SetName("monthly_report").SetSpec("0 0 8 LW * *").SetOverlap(OverlapQueue),
+			*NewCronJobConfig().SetEnabled(false).SetName("heartbeat").SetSpec("@every 30s").SetTimezone("UTC").SetOverlap(OverlapConcurrent),
+		)
+	return s
+}
+
+// NewSchedulerService creates the scheduler and registers the enabled jobs of the config,
+// each bound to the function of the same name. The jobs of a disabled config are not registered.
+func NewSchedulerService(conf SchedulerConfig, funcs map[string]JobFunc) (SchedulerService, error) {
+	if err := SchedulerConfigValidator(&conf); err != nil {
+		return nil, err
+	}
+	s := &schedulerServiceImpl{
+		conf:    conf,
+		entries: make(map[string]*cronEntry),
+		wake:    make(chan struct{}, 1),
+	}
+	if !conf.IsEnabled {
+		return s, nil
+	}
+	for _, job := range conf.Jobs {
+		if !job.IsEnabled {
+			continue
+		}
+		fn, ok := funcs[job.Name]
+		if !ok {
+			return nil, fmt.Errorf("Cron job '%s': %v", job.Name, ErrorCronJobFunc)
+		}
+		if err := s.AddJob(job, fn); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// AddFunc registers a job by its name and cron expression, with the overlap policy of the scheduler.
+func (s *schedulerServiceImpl) AddFunc(name, spec string, fn JobFunc) error {
+	return s.AddJob(*NewCronJobConfig().SetEnabled(true).SetName(name).SetSpec(spec), fn)
+}
+
+// AddJob registers a job; when the scheduler is running the job is scheduled from now on.
+func (s *schedulerServiceImpl) AddJob(conf CronJobConfig, fn JobFunc) error {
+	if fn == nil {
+		return fmt.Errorf("Cron job '%s': %v", conf.Name, ErrorCronJobFunc)
+	}
+	if err := CronJobConfigValidator(&conf); err != nil {
+		return err
+	}
+	if conf.Overlap == "" {
+		conf.Overlap = s.conf.Overlap
+	}
+	schedule, err := conf.Schedule(s.conf.Timezone)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.entries[conf.Name]; ok {
+		return fmt.Errorf("Cron job '%s': %v", conf.Name, ErrorCronJobExists)
+	}
+	e := &cronEntry{conf: conf, schedule: schedule, fn: fn}
+	if s.running {
+		e.next = schedule.Next(time.Now())
+	}
+	s.entries[conf.Name] = e
+	s.notify()
+	return nil
+}
+
+// Remove unregisters the job; its running executions complete, its queued ones are dropped.
+func (s *schedulerServiceImpl) Remove(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return false
+	}
+	e.pending = 0
+	delete(s.entries, name)
+	s.notify()
+	return true
+}
+
+// Entries returns the state of the jobs, sorted by their next activation, then by name.
+func (s *schedulerServiceImpl) Entries() []CronEntry {
+	s.mutex.Lock()
+	entries := make([]CronEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e.entry())
+	}
+	s.mutex.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Next.Equal(entries[j].Next) {
+			return entries[i].Name < entries[j].Name
+		}
+		if entries[i].Next.IsZero() || entries[j].Next.IsZero() {
+			return !entries[i].Next.IsZero()
+		}
+		return entries[i].Next.Before(entries[j].Next)
+	})
+	return entries
+}
+
+// Entry returns the state of the job.
+func (s *schedulerServiceImpl) Entry(name string) (CronEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return CronEntry{}, false
+	}
+	return e.entry(), true
+}
+
+// Start schedules the jobs from now on, in the background. Starting a running scheduler does nothing.
+func (s *schedulerServiceImpl) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	now := time.Now()
+	for _, e := range s.entries {
+		e.next = e.schedule.Next(now)
+	}
+	go s.run()
+}
+
+// Stop stops scheduling and waits for the running jobs to complete, queued runs being dropped.
+// When the context is done first, the context of the running jobs is cancelled and its error returned.
+func (s *schedulerServiceImpl) Stop(ctx context.Context) error {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.running = false
+	close(s.stop)
+	for _, e := range s.entries {
+		e.pending = 0
+		e.next = time.Time{}
+	}
+	done, cancel := s.done, s.cancel
+	s.mutex.Unlock()
+	<-done
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+	defer cancel()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *schedulerServiceImpl) IsRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.running
+}
+
+func (s *schedulerServiceImpl) Config() SchedulerConfig {
+	return s.conf
+}
+
+// run sleeps until the earliest activation, or until a job is added or removed, and dispatches the jobs due.
+func (s *schedulerServiceImpl) run() {
+	defer close(s.done)
+	for {
+		s.mutex.Lock()
+		var earliest time.Time
+		for _, e := range s.entries {
+			if !e.next.IsZero() && (earliest.IsZero() || e.next.Before(earliest)) {
+				earliest = e.next
+			}
+		}
+		s.mutex.Unlock()
+		wait := CronIdleWait
+		if !earliest.IsZero() {
+			wait = time.Until(earliest)
+			if wait < 0 {
+				wait = 0
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			s.dispatch(time.Now())
+		case <-s.wake:
+			timer.Stop()
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// dispatch triggers the jobs due at the time, applying their overlap policy.
+func (s *schedulerServiceImpl) dispatch(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.running {
+		return
+	}
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		e.prev = e.next
+		e.next = e.schedule.Next(now)
+		if e.running > 0 {
+			switch e.conf.Overlap {
+			case OverlapSkip:
+				e.skipped++
+				continue
+			case OverlapQueue:
+				e.pending++
+				continue
+			}
+		}
+		s.launch(e)
+	}
+}
+
+// launch runs the job in the background, then its queued run if any. The mutex must be held.
+func (s *schedulerServiceImpl) launch(e *cronEntry) {
+	e.running++
+	e.runs++
+	s.wg.Add(1)
+	ctx := s.ctx
+	go func() {
+		defer s.wg.Done()
+		execute(ctx, e.conf.Name, e.fn)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		e.running--
+		if e.pending > 0 && s.running {
+			e.pending--
+			s.launch(e)
+		}
+	}()
+}
+
+// notify wakes the run loop up to reconsider the earliest activation.
+func (s *schedulerServiceImpl) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *cronEntry) entry() CronEntry {
+	return CronEntry{
+		Name:    e.conf.Name,
+		Spec:    e.conf.Spec,
+		Overlap: e.conf.Overlap,
+		Next:    e.next,
+		Prev:    e.prev,
+		Running: e.running,
+		Pending: e.pending,
+		Runs:    e.runs,
+		Skipped: e.skipped,
+	}
+}
+
+// execute runs the job, recovering from its panic so that the scheduler keeps running.
+func execute(ctx context.Context, name string, fn JobFunc) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Cron job '%s' panicked: %v", name, r)
+		}
+	}()
+	fn(ctx)
+}